@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	stdlog "log"
 	"net/http"
 	"os"
@@ -9,11 +11,21 @@ import (
 	"github.com/elpatron68/dstask-ui/internal/auth"
 	"github.com/elpatron68/dstask-ui/internal/config"
 	"github.com/elpatron68/dstask-ui/internal/dstask"
+	"github.com/elpatron68/dstask-ui/internal/gitauth"
 	applog "github.com/elpatron68/dstask-ui/internal/log"
 	"github.com/elpatron68/dstask-ui/internal/server"
 )
 
 func main() {
+	// Von git aufgerufen als credential helper (siehe gitauth.HelperEnv)
+	if len(os.Args) > 1 && os.Args[1] == gitauth.HelperCommand {
+		if err := gitauth.RunHelper(os.Args[2:], os.Stdin, os.Stdout, os.Getenv); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	listenFlag := flag.String("listen", "", "override listen address (e.g. :8080 or 127.0.0.1:8080)")
 	flag.Parse()
 
@@ -48,11 +60,8 @@ func main() {
 		}
 	}
 
-	// Startup-Checks: dstask-Binary + Repo(s)
-	usernames := make([]string, 0, len(cfg.Repos))
-	for uname := range cfg.Repos {
-		usernames = append(usernames, uname)
-	}
+	// Startup-Checks: dstask-Binary + Repo(s); ein Eintrag pro Repo (auch benannte Repos aus namedRepos)
+	usernames := config.AllRepoKeys(cfg)
 	// Wenn keine Repos konfiguriert, verwende den konfigurierten/an Umgebungsvariablen hängenden Login-Nutzer,
 	// damit zumindest das Repo im Prozess-HOME initialisiert wird.
 	if len(usernames) == 0 {
@@ -63,6 +72,8 @@ func main() {
 	}
 
 	srv := server.NewServerWithConfig(userStore, cfg)
+	// Hintergrund-Jobs (periodischer Sync) für alle Nutzer mit Repo
+	srv.StartBackgroundJobs(context.Background(), usernames)
 
 	stdlog.Printf("dstask web UI listening on %s", listenAddr)
 	if err := http.ListenAndServe(listenAddr, srv.Handler()); err != nil {
@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	applog "github.com/elpatron68/dstask-ui/internal/log"
 	"gopkg.in/yaml.v3"
@@ -15,6 +18,9 @@ import (
 type UserConfig struct {
 	Username     string `yaml:"username"`
 	PasswordHash string `yaml:"passwordHash"` // bcrypt hash
+	// Name/Email: Git-Autor für Commits dieses Nutzers (leer = aus der Server-Umgebung, bei geteilten Repos Nutzername)
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
 }
 
 type LoggingConfig struct {
@@ -26,14 +32,279 @@ type UIConfig struct {
 	CommandLogMax  int  `yaml:"commandLogMax"`
 }
 
+// SyncConfig steuert den periodischen Hintergrund-Sync. Dauern als Go-Duration ("10m", "30s").
+type SyncConfig struct {
+	Interval   string `yaml:"interval"`   // leer oder 0 = deaktiviert
+	Jitter     string `yaml:"jitter"`     // zufälliger Zuschlag pro Lauf
+	MaxBackoff string `yaml:"maxBackoff"` // Obergrenze für das Intervall nach Fehlern
+}
+
+// IntervalDuration liefert das Sync-Intervall; 0 bei leerem oder ungültigem Wert.
+func (c SyncConfig) IntervalDuration() time.Duration { return parseDurationOrZero(c.Interval) }
+
+// JitterDuration liefert den maximalen Jitter.
+func (c SyncConfig) JitterDuration() time.Duration { return parseDurationOrZero(c.Jitter) }
+
+// MaxBackoffDuration liefert die Backoff-Obergrenze (Default: 1h).
+func (c SyncConfig) MaxBackoffDuration() time.Duration {
+	if d := parseDurationOrZero(c.MaxBackoff); d > 0 {
+		return d
+	}
+	return time.Hour
+}
+
+func parseDurationOrZero(s string) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// SSHConfig steuert die Git-Authentifizierung per SSH-Deploy-Key.
+type SSHConfig struct {
+	// KnownHostsFile ist eine vom Betreiber gepflegte known_hosts-Datei, der alle Nutzer zusätzlich
+	// vertrauen (optional, nur lesend). Über die Oberfläche gepinnte Keys liegen pro Nutzer in
+	// <dataDir>/ssh/<user>/known_hosts.
+	KnownHostsFile string `yaml:"knownHostsFile"`
+	// StrictHostKeyChecking: "accept-new" (unbekannte Hosts beim ersten Kontakt pinnen),
+	// "yes" (nur bereits gepinnte Hosts) oder "no" (keine Prüfung, nicht empfohlen).
+	StrictHostKeyChecking string `yaml:"strictHostKeyChecking"`
+}
+
+// StrictMode liefert den normalisierten StrictHostKeyChecking-Wert (Default: accept-new).
+func (c SSHConfig) StrictMode() string {
+	switch strings.ToLower(strings.TrimSpace(c.StrictHostKeyChecking)) {
+	case "yes", "true":
+		return "yes"
+	case "no", "false", "off":
+		return "no"
+	default:
+		return "accept-new"
+	}
+}
+
+// SMTPConfig ist der Mailserver für Erinnerungs-E-Mails; ohne Host ist der Versand deaktiviert.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"` // Default 587
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// Addr liefert host:port des Mailservers.
+func (c SMTPConfig) Addr() string {
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+	return c.Host + ":" + strconv.Itoa(port)
+}
+
+// RemindersConfig steuert die Fälligkeits-Erinnerungen per E-Mail (Opt-in pro Nutzer unter /settings/notifications).
+type RemindersConfig struct {
+	DailyAt      string `yaml:"dailyAt"`      // Uhrzeit der täglichen Übersicht, z. B. "08:00"
+	BeforeDue    string `yaml:"beforeDue"`    // zusätzliche Erinnerung so lange vor Fälligkeit, z. B. "2h" (leer = aus)
+	UpcomingDays int    `yaml:"upcomingDays"` // Tage im Voraus in der Übersicht (Default 7)
+}
+
+// BeforeDueDuration liefert den Vorlauf der Einzel-Erinnerung; 0 = deaktiviert.
+func (c RemindersConfig) BeforeDueDuration() time.Duration { return parseDurationOrZero(c.BeforeDue) }
+
+// DailyTime liefert Stunde und Minute der täglichen Übersicht (Default 08:00).
+func (c RemindersConfig) DailyTime() (hour, minute int) {
+	if t, err := time.Parse("15:04", strings.TrimSpace(c.DailyAt)); err == nil {
+		return t.Hour(), t.Minute()
+	}
+	return 8, 0
+}
+
+// Horizon liefert die Anzahl der Tage für "demnächst fällig" (Default 7).
+func (c RemindersConfig) Horizon() int {
+	if c.UpcomingDays > 0 {
+		return c.UpcomingDays
+	}
+	return 7
+}
+
+// WebhookConfig ist ein Ziel für ausgehende Webhooks bei Task-Ereignissen.
+type WebhookConfig struct {
+	Name   string   `yaml:"name"`
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"` // HMAC-SHA256-Schlüssel für X-Dstask-Signature (leer = unsigniert)
+	Events []string `yaml:"events"` // z. B. task.done; leer = alle
+	Users  []string `yaml:"users"`  // nur Änderungen dieser Nutzer; leer = alle
+}
+
+// Matches meldet, ob der Webhook für Ereignis event des Nutzers user ausgelöst wird.
+func (c WebhookConfig) Matches(event, user string) bool {
+	return (len(c.Events) == 0 || slices.Contains(c.Events, event)) && (len(c.Users) == 0 || slices.Contains(c.Users, user))
+}
+
+// PushConfig steuert Web-Push-Benachrichtigungen im Browser (Abonnement unter /notifications/settings).
+type PushConfig struct {
+	Subject      string   `yaml:"subject"`      // Kontakt für die Push-Dienste (mailto: oder https:), z. B. "mailto:admin@example.org"
+	LongSync     string   `yaml:"longSync"`     // manuelle Syncs ab dieser Dauer melden (Default 10s)
+	AllowPrivate bool     `yaml:"allowPrivate"` // Push-Dienste auf privaten, Loopback- und Link-Local-Adressen generell erlauben
+	AllowHosts   []string `yaml:"allowHosts"`   // Hosts (z. B. ein lokaler Test-Dienst), die auch auf private Adressen zeigen dürfen
+}
+
+// HostAllowed meldet, ob host auch auf private Adressen zeigen darf (allowHosts).
+func (c PushConfig) HostAllowed(host string) bool { return matchHosts(c.AllowHosts, host) }
+
+// LongSyncDuration liefert die Mindestdauer eines Syncs für eine Push-Meldung (Default 10s).
+func (c PushConfig) LongSyncDuration() time.Duration {
+	if d := parseDurationOrZero(c.LongSync); d > 0 {
+		return d
+	}
+	return 10 * time.Second
+}
+
+// SubjectOrDefault liefert den VAPID-Kontakt; ohne Angabe die Projektseite.
+func (c PushConfig) SubjectOrDefault() string {
+	if s := strings.TrimSpace(c.Subject); s != "" {
+		return s
+	}
+	return "https://github.com/elpatron68/dstask-ui"
+}
+
+// PomodoroConfig steuert die Pomodoro-Fokuseinheiten (Start über 🍅 in der Taskliste).
+type PomodoroConfig struct {
+	Focus          string `yaml:"focus"`          // Länge einer Fokus-Einheit (Default 25m)
+	ShortBreak     string `yaml:"shortBreak"`     // kurze Pause (Default 5m)
+	LongBreak      string `yaml:"longBreak"`      // lange Pause (Default 15m)
+	LongBreakEvery int    `yaml:"longBreakEvery"` // jede n-te Pause ist lang (Default 4)
+	StopOnBreak    bool   `yaml:"stopOnBreak"`    // Task in der Pause stoppen (Vorgabe für das Formular)
+}
+
+// FocusDuration liefert die Länge einer Fokus-Einheit (Default 25m).
+func (c PomodoroConfig) FocusDuration() time.Duration {
+	if d := parseDurationOrZero(c.Focus); d > 0 {
+		return d
+	}
+	return 25 * time.Minute
+}
+
+// BreakDuration liefert die Pause nach der n-ten abgeschlossenen Einheit; jede LongBreakEvery-te ist lang.
+func (c PomodoroConfig) BreakDuration(n int) time.Duration {
+	every := c.LongBreakEvery
+	if every <= 0 {
+		every = 4
+	}
+	if n > 0 && n%every == 0 {
+		if d := parseDurationOrZero(c.LongBreak); d > 0 {
+			return d
+		}
+		return 15 * time.Minute
+	}
+	if d := parseDurationOrZero(c.ShortBreak); d > 0 {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// MusicProxyConfig begrenzt, was /music/proxy abruft. Ohne Angaben gilt: nur Stream-URLs aus der
+// music-map.yaml des Nutzers, keine privaten, Loopback- oder Link-Local-Adressen, 2 Streams pro Nutzer,
+// 1024 kbit/s und 4h pro Stream.
+type MusicProxyConfig struct {
+	AnyURL       bool     `yaml:"anyURL"`       // auch URLs, die in keiner music-map.yaml des Nutzers stehen
+	AllowPrivate bool     `yaml:"allowPrivate"` // private, Loopback- und Link-Local-Adressen generell erlauben
+	AllowHosts   []string `yaml:"allowHosts"`   // Hosts ("radio.lan", "*.example.org"), die auch auf private Adressen zeigen dürfen
+	DenyHosts    []string `yaml:"denyHosts"`    // Hosts, die nie abgerufen werden (Vorrang vor allowHosts)
+	MaxStreams   int      `yaml:"maxStreams"`   // gleichzeitige Streams pro Nutzer (Default 2)
+	MaxKbps      int      `yaml:"maxKbps"`      // Bandbreite pro Stream in kbit/s (Default 1024, -1 = unbegrenzt)
+	MaxDuration  string   `yaml:"maxDuration"`  // Höchstdauer eines Streams (Default 4h)
+}
+
+// Streams liefert die erlaubten gleichzeitigen Streams pro Nutzer (Default 2).
+func (c MusicProxyConfig) Streams() int {
+	if c.MaxStreams > 0 {
+		return c.MaxStreams
+	}
+	return 2
+}
+
+// BytesPerSecond liefert die Bandbreite pro Stream; 0 = unbegrenzt.
+func (c MusicProxyConfig) BytesPerSecond() int64 {
+	switch {
+	case c.MaxKbps < 0:
+		return 0
+	case c.MaxKbps == 0:
+		return 1024 * 1000 / 8
+	}
+	return int64(c.MaxKbps) * 1000 / 8
+}
+
+// Duration liefert die Höchstdauer eines Streams (Default 4h).
+func (c MusicProxyConfig) Duration() time.Duration {
+	if d := parseDurationOrZero(c.MaxDuration); d > 0 {
+		return d
+	}
+	return 4 * time.Hour
+}
+
+// HostAllowed meldet, ob host auch auf private Adressen zeigen darf (allowHosts).
+func (c MusicProxyConfig) HostAllowed(host string) bool { return matchHosts(c.AllowHosts, host) }
+
+// HostDenied meldet, ob host gesperrt ist (denyHosts).
+func (c MusicProxyConfig) HostDenied(host string) bool { return matchHosts(c.DenyHosts, host) }
+
+// ChatConfig begrenzt die Chat-Ziele der Nutzer (ntfy, Gotify, Matrix). Ziele werden wie beim
+// Musik-Proxy selbst aufgelöst; private, Loopback- und Link-Local-Adressen sind ohne Freigabe gesperrt.
+type ChatConfig struct {
+	AllowPrivate bool     `yaml:"allowPrivate"` // private, Loopback- und Link-Local-Ziele generell erlauben
+	AllowHosts   []string `yaml:"allowHosts"`   // Hosts ("ntfy.lan", "*.home.arpa"), die auch auf private Adressen zeigen dürfen
+}
+
+// HostAllowed meldet, ob host auch auf private Adressen zeigen darf (allowHosts).
+func (c ChatConfig) HostAllowed(host string) bool { return matchHosts(c.AllowHosts, host) }
+
+// matchHosts vergleicht host ohne Groß-/Kleinschreibung mit Einträgen wie "radio.lan" oder "*.example.org"
+// ("*.example.org" passt auch auf example.org selbst).
+func matchHosts(list []string, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	for _, p := range list {
+		p = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(p)), ".")
+		if p == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(p, "*."); ok {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
+
 type Config struct {
-	DstaskBin   string            `yaml:"dstaskBin"`
-	Listen      string            `yaml:"listen"` // listen address (e.g., ":8080")
-	Users       []UserConfig      `yaml:"users"`
-	Repos       map[string]string `yaml:"repos"` // username -> path to ~/.dstask or home dir
-	Logging     LoggingConfig     `yaml:"logging"`
-	UI          UIConfig          `yaml:"ui"`
-	GitAutoSync bool              `yaml:"gitAutoSync"`
+	DstaskBin string            `yaml:"dstaskBin"`
+	Listen    string            `yaml:"listen"` // listen address (e.g., ":8080")
+	Users     []UserConfig      `yaml:"users"`
+	Repos     map[string]string `yaml:"repos"` // username -> path to ~/.dstask or home dir
+	// NamedRepos: weitere Repos pro Nutzer, username -> name -> Pfad (wie bei repos)
+	NamedRepos  map[string]map[string]string `yaml:"namedRepos,omitempty"`
+	Logging     LoggingConfig                `yaml:"logging"`
+	UI          UIConfig                     `yaml:"ui"`
+	GitAutoSync bool                         `yaml:"gitAutoSync"`
+	Sync        SyncConfig                   `yaml:"sync"`
+	DataDir     string                       `yaml:"dataDir"` // App-Daten (z. B. SSH-Keys); leer = <HOME>/.dstask-ui
+	SSH         SSHConfig                    `yaml:"ssh"`
+	Secret      string                       `yaml:"secret"` // Schlüsselmaterial für gespeicherte Git-Zugangsdaten
+	SMTP        SMTPConfig                   `yaml:"smtp"`
+	Reminders   RemindersConfig              `yaml:"reminders"`
+	Webhooks    []WebhookConfig              `yaml:"webhooks"`
+	Push        PushConfig                   `yaml:"push"`
+	Pomodoro    PomodoroConfig               `yaml:"pomodoro"`
+	// MusicRoots: username -> Musikordner, aus dem lokale Playlists (Typ "folder" in music-map.yaml) gespielt werden dürfen
+	MusicRoots map[string]string `yaml:"musicRoots,omitempty"`
+	// MusicProxy: Grenzen für den Stream-Proxy /music/proxy (SSRF-Schutz, Streams, Bandbreite)
+	MusicProxy MusicProxyConfig `yaml:"musicProxy"`
+	// Chat: erlaubte Ziele der Chat-Benachrichtigungen (SSRF-Schutz)
+	Chat ChatConfig `yaml:"chat"`
 }
 
 func Default() *Config {
@@ -45,6 +316,9 @@ func Default() *Config {
 		Logging:     LoggingConfig{Level: "info"},
 		UI:          UIConfig{ShowCommandLog: true, CommandLogMax: 200},
 		GitAutoSync: false,
+		Sync:        SyncConfig{Interval: "", Jitter: "30s", MaxBackoff: "1h"},
+		SSH:         SSHConfig{StrictHostKeyChecking: "accept-new"},
+		Reminders:   RemindersConfig{DailyAt: "08:00", UpcomingDays: 7},
 	}
 }
 
@@ -130,6 +404,18 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.UI.CommandLogMax = n
 		}
 	}
+	if v := os.Getenv("DSTWEB_SYNC_INTERVAL"); v != "" {
+		cfg.Sync.Interval = v
+	}
+	if v := os.Getenv("DSTWEB_SECRET"); v != "" {
+		cfg.Secret = v
+	}
+	if v := os.Getenv("DSTWEB_SMTP_PASSWORD"); v != "" {
+		cfg.SMTP.Password = v
+	}
+	if v := os.Getenv("DSTWEB_DATA_DIR"); v != "" {
+		cfg.DataDir = v
+	}
 	if v := os.Getenv("DSTWEB_GIT_AUTOSYNC"); v != "" {
 		if v == "1" || strings.EqualFold(v, "true") {
 			cfg.GitAutoSync = true
@@ -139,10 +425,83 @@ func applyEnvOverrides(cfg *Config) {
 	}
 }
 
+// DefaultRepoName ist der Name des Repos aus `repos` im Repo-Umschalter.
+const DefaultRepoName = "default"
+
+// repoKeySep trennt Nutzer und Repo-Name in einem Repo-Schlüssel ("alice/work").
+const repoKeySep = "/"
+
+// RepoKey bildet den Schlüssel, unter dem Runner, Music-Map, Command-Log und Sync-Status ein Repo führen.
+// Für das Standard-Repo ist das der Nutzername selbst.
+func RepoKey(username, repo string) string {
+	if repo == "" || repo == DefaultRepoName {
+		return username
+	}
+	return username + repoKeySep + repo
+}
+
+// SplitRepoKey zerlegt einen Repo-Schlüssel in Nutzer und Repo-Name (leer = Standard-Repo).
+func SplitRepoKey(key string) (username, repo string) {
+	if i := strings.LastIndex(key, repoKeySep); i > 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, ""
+}
+
+// RepoNames liefert die Repos eines Nutzers: "default" (falls in repos konfiguriert), dann die
+// benannten Repos alphabetisch.
+func RepoNames(cfg *Config, username string) []string {
+	var names []string
+	if p, ok := cfg.Repos[username]; ok && p != "" {
+		names = append(names, DefaultRepoName)
+	}
+	named := make([]string, 0, len(cfg.NamedRepos[username]))
+	for n, p := range cfg.NamedRepos[username] {
+		if n != DefaultRepoName && p != "" {
+			named = append(named, n)
+		}
+	}
+	sort.Strings(named)
+	return append(names, named...)
+}
+
+// AllRepoKeys liefert die Schlüssel aller konfigurierten Repos aller Nutzer.
+func AllRepoKeys(cfg *Config) []string {
+	users := map[string]bool{}
+	for u := range cfg.Repos {
+		users[u] = true
+	}
+	for u := range cfg.NamedRepos {
+		users[u] = true
+	}
+	var keys []string
+	for u := range users {
+		for _, n := range RepoNames(cfg, u) {
+			keys = append(keys, RepoKey(u, n))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ResolveHomeForUsername bestimmt das HOME für dstask anhand der Repo-Konfiguration.
 // Erwartet, dass Repos[username] entweder auf ~/.dstask oder auf das Home-Verzeichnis zeigt.
+// username darf ein Repo-Schlüssel (RepoKey) sein; dann wird das benannte Repo aus namedRepos verwendet.
+// Hat ein Nutzer nur benannte Repos, gilt das erste davon als Standard.
 func ResolveHomeForUsername(cfg *Config, username string) (string, bool) {
-	p, ok := cfg.Repos[username]
+	user, repo := SplitRepoKey(username)
+	p, ok := cfg.Repos[user]
+	if exact, found := cfg.Repos[username]; found {
+		// Nutzername, der selbst den Trenner enthält
+		p, ok, repo = exact, true, ""
+	}
+	if repo != "" {
+		p, ok = cfg.NamedRepos[user][repo]
+	} else if !ok || p == "" {
+		if names := RepoNames(cfg, user); len(names) > 0 {
+			p, ok = cfg.NamedRepos[user][names[0]]
+		}
+	}
 	if !ok || p == "" {
 		return "", false
 	}
@@ -159,6 +518,84 @@ func ResolveHomeForUsername(cfg *Config, username string) (string, bool) {
 	return p, true
 }
 
+// RepoUsers liefert alle Nutzer (alphabetisch), deren Standard-Repo oder benanntes Repo auf dasselbe
+// Verzeichnis zeigt wie der Repo-Schlüssel key. Bei einem nicht geteilten Repo ist das nur der Nutzer selbst.
+func RepoUsers(cfg *Config, key string) []string {
+	home, ok := ResolveHomeForUsername(cfg, key)
+	if !ok {
+		user, _ := SplitRepoKey(key)
+		return []string{user}
+	}
+	seen := map[string]bool{}
+	for _, k := range AllRepoKeys(cfg) {
+		if h, ok := ResolveHomeForUsername(cfg, k); ok && h == home {
+			user, _ := SplitRepoKey(k)
+			seen[user] = true
+		}
+	}
+	users := make([]string, 0, len(seen))
+	for u := range seen {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// GitIdentity liefert Autorname und E-Mail für Commits unter dem Repo-Schlüssel key.
+// Konfigurierte Werte (users[].name/email) gelten immer; in geteilten Repos wird sonst der Nutzername
+// verwendet (E-Mail <user>@dstask-ui.local), damit Commits unterscheidbar sind. Leere Werte bedeuten:
+// Identität aus der Server-Umgebung übernehmen.
+func GitIdentity(cfg *Config, key string) (name, email string) {
+	user, _ := SplitRepoKey(key)
+	for _, u := range cfg.Users {
+		if u.Username == user {
+			name, email = strings.TrimSpace(u.Name), strings.TrimSpace(u.Email)
+			break
+		}
+	}
+	if name == "" && email == "" && len(RepoUsers(cfg, key)) < 2 {
+		return "", ""
+	}
+	if name == "" {
+		name = user
+	}
+	if email == "" {
+		email = user + "@dstask-ui.local"
+	}
+	return name, email
+}
+
+// ResolveDataDir liefert das Verzeichnis für App-Daten (dataDir oder <HOME>/.dstask-ui).
+func ResolveDataDir(cfg *Config) string {
+	if cfg != nil && strings.TrimSpace(cfg.DataDir) != "" {
+		return filepath.Clean(os.ExpandEnv(expandUserPath(cfg.DataDir)))
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return filepath.Join(home, ".dstask-ui")
+	}
+	return ".dstask-ui"
+}
+
+// ResolveMusicRoot liefert den freigegebenen Musikordner des Nutzers (musicRoots); false, wenn keiner
+// konfiguriert ist. username darf ein Repo-Schlüssel sein, maßgeblich ist der Nutzer.
+func ResolveMusicRoot(cfg *Config, username string) (string, bool) {
+	user, _ := SplitRepoKey(username)
+	p := strings.TrimSpace(cfg.MusicRoots[user])
+	if p == "" {
+		return "", false
+	}
+	return filepath.Clean(os.ExpandEnv(expandUserPath(p))), true
+}
+
+// ResolveKnownHostsFile liefert die gemeinsame known_hosts-Datei (ssh.knownHostsFile); "", wenn keine
+// konfiguriert ist.
+func ResolveKnownHostsFile(cfg *Config) string {
+	if cfg == nil || strings.TrimSpace(cfg.SSH.KnownHostsFile) == "" {
+		return ""
+	}
+	return filepath.Clean(os.ExpandEnv(expandUserPath(strings.TrimSpace(cfg.SSH.KnownHostsFile))))
+}
+
 // expandUserPath ersetzt führendes "~" durch das Home-Verzeichnis des aktuellen Prozesses.
 // Unterstützt nur "~" (nicht "~user").
 func expandUserPath(path string) string {
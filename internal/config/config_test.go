@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestDefaultHasReasonableValues(t *testing.T) {
@@ -120,3 +121,116 @@ func TestLoadFromFileAndResolveHome(t *testing.T) {
 		t.Fatalf("expected %q, got %q", want, home)
 	}
 }
+
+func TestSyncConfigDurations(t *testing.T) {
+	c := SyncConfig{Interval: "10m", Jitter: "bogus"}
+	if c.IntervalDuration() != 10*time.Minute {
+		t.Fatalf("interval: %s", c.IntervalDuration())
+	}
+	if c.JitterDuration() != 0 {
+		t.Fatalf("invalid jitter should be 0, got %s", c.JitterDuration())
+	}
+	if c.MaxBackoffDuration() != time.Hour {
+		t.Fatalf("default max backoff should be 1h, got %s", c.MaxBackoffDuration())
+	}
+	if Default().Sync.IntervalDuration() != 0 {
+		t.Fatalf("background sync must be disabled by default")
+	}
+}
+
+func TestPomodoroConfigDurations(t *testing.T) {
+	var c PomodoroConfig
+	if c.FocusDuration() != 25*time.Minute || c.BreakDuration(1) != 5*time.Minute || c.BreakDuration(4) != 15*time.Minute {
+		t.Fatalf("unexpected defaults: %s %s %s", c.FocusDuration(), c.BreakDuration(1), c.BreakDuration(4))
+	}
+	c = PomodoroConfig{Focus: "50m", ShortBreak: "10m", LongBreak: "30m", LongBreakEvery: 2}
+	if c.FocusDuration() != 50*time.Minute || c.BreakDuration(1) != 10*time.Minute || c.BreakDuration(2) != 30*time.Minute {
+		t.Fatalf("configured durations ignored: %s %s %s", c.FocusDuration(), c.BreakDuration(1), c.BreakDuration(2))
+	}
+}
+
+func TestMusicProxyConfigDefaultsAndHosts(t *testing.T) {
+	var c MusicProxyConfig
+	if c.Streams() != 2 || c.BytesPerSecond() != 128000 || c.Duration() != 4*time.Hour {
+		t.Fatalf("unexpected defaults: %d %d %s", c.Streams(), c.BytesPerSecond(), c.Duration())
+	}
+	c = MusicProxyConfig{MaxKbps: -1, AllowHosts: []string{"radio.lan"}, DenyHosts: []string{"*.Example.org"}}
+	if c.BytesPerSecond() != 0 {
+		t.Fatal("maxKbps -1 should disable the bandwidth cap")
+	}
+	if !c.HostAllowed("RADIO.lan.") || c.HostAllowed("other.lan") {
+		t.Fatal("allowHosts must match exactly (case-insensitive)")
+	}
+	if !c.HostDenied("example.org") || !c.HostDenied("a.b.example.org") || c.HostDenied("badexample.org") {
+		t.Fatal("wildcard denyHosts mismatch")
+	}
+}
+
+func TestNamedReposResolution(t *testing.T) {
+	cfg := Default()
+	cfg.Repos = map[string]string{"alice": "/data/alice"}
+	cfg.NamedRepos = map[string]map[string]string{
+		"alice": {"work": "/data/work/.dstask", "personal": "/data/personal"},
+		"bob":   {"tasks": "/data/bob"},
+	}
+	if got := RepoNames(cfg, "alice"); len(got) != 3 || got[0] != "default" || got[1] != "personal" || got[2] != "work" {
+		t.Fatalf("unexpected repo names: %v", got)
+	}
+	if k := RepoKey("alice", "work"); k != "alice/work" {
+		t.Fatalf("RepoKey = %q", k)
+	}
+	if k := RepoKey("alice", "default"); k != "alice" {
+		t.Fatalf("default RepoKey = %q", k)
+	}
+	if u, r := SplitRepoKey("alice/work"); u != "alice" || r != "work" {
+		t.Fatalf("SplitRepoKey = %q %q", u, r)
+	}
+	cases := map[string]string{
+		"alice":      "/data/alice",
+		"alice/work": "/data/work",
+		"bob":        "/data/bob", // nur benannte Repos: erstes gilt als Standard
+		"bob/tasks":  "/data/bob",
+	}
+	for key, want := range cases {
+		if got, ok := ResolveHomeForUsername(cfg, key); !ok || got != want {
+			t.Fatalf("ResolveHomeForUsername(%q) = %q, %v; want %q", key, got, ok, want)
+		}
+	}
+	if _, ok := ResolveHomeForUsername(cfg, "alice/missing"); ok {
+		t.Fatalf("unknown named repo resolved")
+	}
+	keys := AllRepoKeys(cfg)
+	if len(keys) != 4 || keys[0] != "alice" || keys[1] != "alice/personal" || keys[2] != "alice/work" || keys[3] != "bob/tasks" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestSharedRepoUsersAndGitIdentity(t *testing.T) {
+	cfg := Default()
+	cfg.Repos = map[string]string{"alice": "/data/team", "bob": "/data/team/.dstask", "carol": "/data/carol"}
+	cfg.NamedRepos = map[string]map[string]string{"carol": {"team": "/data/team"}}
+	cfg.Users = []UserConfig{{Username: "alice", Name: "Alice Example", Email: "alice@example.org"}, {Username: "carol", Name: "Carol"}}
+
+	if got := RepoUsers(cfg, "bob"); len(got) != 3 || got[0] != "alice" || got[1] != "bob" || got[2] != "carol" {
+		t.Fatalf("unexpected team: %v", got)
+	}
+	if got := RepoUsers(cfg, "carol"); len(got) != 1 || got[0] != "carol" {
+		t.Fatalf("unexpected users for private repo: %v", got)
+	}
+
+	cases := []struct{ key, name, email string }{
+		{"alice", "Alice Example", "alice@example.org"},
+		{"bob", "bob", "bob@dstask-ui.local"},       // geteilt, nichts konfiguriert
+		{"carol", "Carol", "carol@dstask-ui.local"}, // konfiguriert gilt auch im eigenen Repo
+		{"carol/team", "Carol", "carol@dstask-ui.local"},
+	}
+	for _, c := range cases {
+		if n, e := GitIdentity(cfg, c.key); n != c.name || e != c.email {
+			t.Fatalf("GitIdentity(%q) = %q %q; want %q %q", c.key, n, e, c.name, c.email)
+		}
+	}
+	cfg.Repos["dave"] = "/data/dave"
+	if n, e := GitIdentity(cfg, "dave"); n != "" || e != "" {
+		t.Fatalf("private repo without config should inherit identity, got %q %q", n, e)
+	}
+}
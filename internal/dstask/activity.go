@@ -0,0 +1,429 @@
+package dstask
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ActivityOptions filtert und paginiert den Aktivitäts-Feed.
+type ActivityOptions struct {
+	Author  string // Teilstring des Git-Autors
+	Project string // exakter Projektname (vorher oder nachher)
+	Since   time.Time
+	Until   time.Time
+	Offset  int
+	Limit   int
+}
+
+// ActivityEntry beschreibt, was ein Commit an einem Task geändert hat.
+type ActivityEntry struct {
+	Commit  string
+	Author  string
+	Date    time.Time
+	Message string
+	UUID    string
+	ID      string
+	Summary string
+	Project string
+	// Verb ist die Hauptaktion, z. B. "added", "completed", "modified"
+	Verb    string
+	Details []string
+}
+
+// Text liefert eine lesbare Beschreibung, z. B. `alice completed #12 "Fix VPN"`.
+func (e ActivityEntry) Text() string {
+	var b strings.Builder
+	b.WriteString(e.Author)
+	b.WriteString(" ")
+	b.WriteString(e.Verb)
+	if e.ID != "" && e.ID != "0" {
+		b.WriteString(" #" + e.ID)
+	}
+	if e.Summary != "" {
+		b.WriteString(" “" + e.Summary + "”")
+	}
+	return b.String()
+}
+
+const (
+	activityBatch    = 200
+	activityMaxScans = 5000
+)
+
+// activityCache hält je Repo-Verzeichnis die bereits beschriebenen Commits (unveränderlich, also über
+// HEAD-Wechsel hinweg gültig) und die zuletzt gelesenen Feeds für den aktuellen HEAD.
+type activityCache struct {
+	mu      sync.Mutex
+	head    string
+	commits map[string][]ActivityEntry
+	feeds   map[string]activityFeed
+}
+
+// activityFeed ist ein gelesener Feed-Anfang für eine Filterkombination; complete, wenn der Log zu Ende ist.
+type activityFeed struct {
+	entries  []ActivityEntry
+	complete bool
+}
+
+// Activity liest den Git-Log des Repos und liefert einen Feed (neueste zuerst).
+// hasMore ist true, wenn es nach der angeforderten Seite weitere Einträge gibt.
+// Pro Stapel laufen nur `git log --raw` und ein `git cat-file --batch`; Ergebnisse werden pro HEAD gecacht.
+// Task-IDs setzt der Aufrufer (ID), da sie nur in der aktuellen Task-Liste stehen.
+func (r *Runner) Activity(username string, opts ActivityOptions) (entries []ActivityEntry, hasMore bool, err error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, false, err
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 50
+	}
+	head, err := r.gitOutput(username, dir, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return nil, false, err
+	}
+	head = strings.TrimSpace(head)
+	v, _ := r.activity.LoadOrStore(dir, &activityCache{})
+	cache := v.(*activityCache)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.head != head || len(cache.commits) > 2*activityMaxScans {
+		if len(cache.commits) > 2*activityMaxScans {
+			cache.commits = nil
+		}
+		cache.head, cache.feeds = head, map[string]activityFeed{}
+	}
+	if cache.commits == nil {
+		cache.commits = map[string][]ActivityEntry{}
+	}
+	feedKey := strings.Join([]string{opts.Author, strings.ToLower(opts.Project), opts.Since.Format(time.RFC3339), opts.Until.Format(time.RFC3339)}, "\x1f")
+	want := opts.Offset + opts.Limit + 1
+	feed := cache.feeds[feedKey]
+	if len(feed.entries) < want && !feed.complete {
+		feed = activityFeed{}
+		for skip := 0; skip < activityMaxScans && len(feed.entries) < want; skip += activityBatch {
+			args := []string{"log", "--no-renames", "--raw", "--no-abbrev", "--format=%x1e%H%x1f%an%x1f%aI%x1f%s",
+				"--skip=" + strconv.Itoa(skip), "--max-count=" + strconv.Itoa(activityBatch)}
+			if opts.Author != "" {
+				args = append(args, "--fixed-strings", "--author="+opts.Author)
+			}
+			if !opts.Since.IsZero() {
+				args = append(args, "--since="+opts.Since.Format(time.RFC3339))
+			}
+			if !opts.Until.IsZero() {
+				args = append(args, "--until="+opts.Until.Format(time.RFC3339))
+			}
+			args = append(args, head, "--", "*.yml")
+			out, err := r.gitOutput(username, dir, args...)
+			if err != nil {
+				return nil, false, err
+			}
+			commits := parseActivityLog(out)
+			if err := r.describeCommits(username, dir, commits, cache.commits); err != nil {
+				return nil, false, err
+			}
+			for _, c := range commits {
+				for _, e := range cache.commits[c.Hash] {
+					if opts.Project != "" && !strings.EqualFold(e.Project, opts.Project) {
+						continue
+					}
+					feed.entries = append(feed.entries, e)
+				}
+			}
+			if len(commits) < activityBatch {
+				feed.complete = true
+				break
+			}
+		}
+		cache.feeds[feedKey] = feed
+	}
+	all := feed.entries
+	if opts.Offset >= len(all) {
+		return nil, false, nil
+	}
+	end := opts.Offset + opts.Limit
+	if end < len(all) {
+		hasMore = true
+	} else {
+		end = len(all)
+	}
+	// Kopie, damit Aufrufer (IDs setzen) den Cache nicht verändern
+	return append([]ActivityEntry(nil), all[opts.Offset:end]...), hasMore, nil
+}
+
+// ActivityAuthors liefert alle Autoren des Repos (für Filter-Auswahl).
+func (r *Runner) ActivityAuthors(username string) ([]string, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.gitOutput(username, dir, "log", "--format=%an")
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]struct{}{}
+	for _, l := range strings.Split(out, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			set[l] = struct{}{}
+		}
+	}
+	authors := make([]string, 0, len(set))
+	for a := range set {
+		authors = append(authors, a)
+	}
+	sort.Strings(authors)
+	return authors, nil
+}
+
+type activityCommit struct {
+	Hash, Author, Message string
+	Date                  time.Time
+	// je UUID: Blob vorher (M/D) und nachher (A/M)
+	Before, After map[string]activityBlob
+	Order         []string
+}
+
+// activityBlob ist eine Task-Datei in einem Commit (Pfad für den Status, Objekt-ID für den Inhalt).
+type activityBlob struct {
+	Path, Object string
+}
+
+func parseActivityLog(out string) []activityCommit {
+	var commits []activityCommit
+	for _, rec := range strings.Split(out, "\x1e") {
+		rec = strings.TrimSpace(rec)
+		if rec == "" {
+			continue
+		}
+		lines := strings.Split(rec, "\n")
+		head := strings.Split(lines[0], "\x1f")
+		if len(head) < 4 {
+			continue
+		}
+		c := activityCommit{Hash: head[0], Author: head[1], Message: head[3], Before: map[string]activityBlob{}, After: map[string]activityBlob{}}
+		c.Date, _ = time.Parse(time.RFC3339, head[2])
+		for _, l := range lines[1:] {
+			// --raw: ":<mode> <mode> <alt> <neu> <status>\t<pfad>"
+			meta, p, ok := strings.Cut(strings.TrimSpace(l), "\t")
+			fields := strings.Fields(meta)
+			if !ok || len(fields) < 5 || !strings.HasPrefix(fields[0], ":") {
+				continue
+			}
+			uuid := strings.TrimSuffix(path.Base(p), ".yml")
+			if !LooksLikeUUID(uuid) {
+				continue
+			}
+			if _, ok := c.Before[uuid]; !ok {
+				if _, ok := c.After[uuid]; !ok {
+					c.Order = append(c.Order, uuid)
+				}
+			}
+			oldObj, newObj := fields[2], fields[3]
+			switch fields[4] {
+			case "A":
+				c.After[uuid] = activityBlob{p, newObj}
+			case "D":
+				c.Before[uuid] = activityBlob{p, oldObj}
+			default:
+				c.Before[uuid] = activityBlob{p, oldObj}
+				c.After[uuid] = activityBlob{p, newObj}
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// describeCommits erzeugt je berührtem Task einen Feed-Eintrag und legt sie pro Commit in done ab;
+// bereits beschriebene Commits werden übersprungen. Alle Dateiinhalte kommen aus einem `git cat-file --batch`.
+func (r *Runner) describeCommits(username, dir string, commits []activityCommit, done map[string][]ActivityEntry) error {
+	var objects []string
+	for _, c := range commits {
+		if _, ok := done[c.Hash]; ok {
+			continue
+		}
+		for _, uuid := range c.Order {
+			for _, b := range []activityBlob{c.Before[uuid], c.After[uuid]} {
+				if b.Object != "" {
+					objects = append(objects, b.Object)
+				}
+			}
+		}
+	}
+	blobs, err := r.catBlobs(username, dir, objects)
+	if err != nil {
+		return err
+	}
+	for _, c := range commits {
+		if _, ok := done[c.Hash]; ok {
+			continue
+		}
+		entries := make([]ActivityEntry, 0, len(c.Order))
+		for _, uuid := range c.Order {
+			var before, after map[string]string
+			if b, ok := c.Before[uuid]; ok {
+				if blob, ok := blobs[b.Object]; ok {
+					before = TaskFieldsFromYAML(blob, statusFromPath(b.Path))
+				}
+			}
+			if b, ok := c.After[uuid]; ok {
+				if blob, ok := blobs[b.Object]; ok {
+					after = TaskFieldsFromYAML(blob, statusFromPath(b.Path))
+				}
+			}
+			if before == nil && after == nil {
+				continue
+			}
+			e := ActivityEntry{Commit: c.Hash, Author: c.Author, Date: c.Date, Message: c.Message, UUID: uuid}
+			cur := after
+			if cur == nil {
+				cur = before
+			}
+			e.Summary = cur["summary"]
+			e.Project = cur["project"]
+			e.Verb, e.Details = describeChange(before, after)
+			entries = append(entries, e)
+		}
+		done[c.Hash] = entries
+	}
+	return nil
+}
+
+// catBlobs liest die Objekte mit einem einzigen `git cat-file --batch` (Objekt-ID -> Inhalt).
+// Fehlende Objekte fehlen in der Map.
+func (r *Runner) catBlobs(username, dir string, objects []string) (map[string]string, error) {
+	blobs := map[string]string{}
+	if len(objects) == 0 {
+		return blobs, nil
+	}
+	cmd := exec.Command("git", "-C", dir, "cat-file", "--batch")
+	cmd.Env = r.gitEnvForUser(username)
+	cmd.Stdin = strings.NewReader(strings.Join(objects, "\n") + "\n")
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %v: %s", err, strings.TrimSpace(errBuf.String()))
+	}
+	br := bufio.NewReader(bytes.NewReader(out))
+	for {
+		header, err := br.ReadString('\n')
+		if err != nil {
+			break
+		}
+		// "<oid> <typ> <größe>" oder "<oid> missing"
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			break
+		}
+		buf := make([]byte, size+1) // Inhalt + abschließendes LF
+		if _, err := io.ReadFull(br, buf); err != nil {
+			break
+		}
+		blobs[fields[0]] = normalizeNewlines(string(buf[:size]))
+	}
+	return blobs, nil
+}
+
+// describeChange formuliert die Änderung zwischen zwei Feldständen.
+func describeChange(before, after map[string]string) (string, []string) {
+	switch {
+	case before == nil:
+		return "added", nil
+	case after == nil:
+		return "removed", nil
+	}
+	verb := "modified"
+	var details []string
+	for _, ch := range DiffTaskFields(before, after) {
+		switch ch.Field {
+		case "status":
+			verb = statusVerb(ch.Old, ch.New)
+		case "tags":
+			added, removed := diffTagLists(ch.Old, ch.New)
+			for _, t := range added {
+				details = append(details, "added +"+t)
+			}
+			for _, t := range removed {
+				details = append(details, "removed +"+t)
+			}
+		case "notes":
+			details = append(details, "edited notes")
+		case "summary":
+			details = append(details, "renamed to “"+ch.New+"”")
+		default:
+			switch {
+			case ch.Old == "":
+				details = append(details, "set "+ch.Field+" to "+ch.New)
+			case ch.New == "":
+				details = append(details, "cleared "+ch.Field)
+			default:
+				details = append(details, "changed "+ch.Field+" "+ch.Old+" → "+ch.New)
+			}
+		}
+	}
+	// Reine Tag-Änderung: "bob added +urgent to #40"
+	if verb == "modified" && len(details) == 1 && (strings.HasPrefix(details[0], "added +") || strings.HasPrefix(details[0], "removed +")) {
+		verb = details[0]
+		if strings.HasPrefix(verb, "added") {
+			verb += " to"
+		} else {
+			verb += " from"
+		}
+		details = nil
+	}
+	return verb, details
+}
+
+func statusVerb(old, new string) string {
+	switch {
+	case new == "resolved":
+		return "completed"
+	case old == "resolved":
+		return "reopened"
+	case new == "active":
+		return "started"
+	case old == "active" && (new == "paused" || new == "pending"):
+		return "stopped"
+	default:
+		return "moved to " + new
+	}
+}
+
+func diffTagLists(old, new string) (added, removed []string) {
+	split := func(s string) map[string]bool {
+		m := map[string]bool{}
+		for _, t := range strings.Split(s, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				m[t] = true
+			}
+		}
+		return m
+	}
+	o, n := split(old), split(new)
+	for t := range n {
+		if !o[t] {
+			added = append(added, t)
+		}
+	}
+	for t := range o {
+		if !n[t] {
+			removed = append(removed, t)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,82 @@
+package dstask
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestActivity_Feed(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	// Bob fügt einen Tag hinzu
+	p := filepath.Join(repo, "active", historyTestUUID+".yml")
+	if err := os.WriteFile(p, []byte("summary: Fix VPN\ntags:\n- net\n- urgent\npriority: P2\ndue: 2025-01-20T00:00:00Z\nnotes: \"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "-c", "user.name=Bob", "-c", "user.email=bob@example.com", "commit", "-am", "Modified: Fix VPN")
+
+	entries, hasMore, err := r.Activity("u", ActivityOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Activity: %v", err)
+	}
+	if hasMore || len(entries) != 4 {
+		t.Fatalf("expected 4 entries without more, got %d (more=%v)", len(entries), hasMore)
+	}
+	want := []string{
+		`Bob added +urgent to “Fix VPN”`,
+		`Alice started “Fix VPN”`,
+		`Bob modified “Fix VPN”`,
+		`Alice added “Fix VPN”`,
+	}
+	for i, w := range want {
+		if got := entries[i].Text(); got != w {
+			t.Fatalf("entry %d: got %q, want %q", i, got, w)
+		}
+	}
+	if len(entries[2].Details) != 1 || !strings.HasPrefix(entries[2].Details[0], "changed due") {
+		t.Fatalf("unexpected details: %v", entries[2].Details)
+	}
+
+	// Filter nach Autor und Pagination
+	bob, _, err := r.Activity("u", ActivityOptions{Author: "Bob", Limit: 10})
+	if err != nil || len(bob) != 2 {
+		t.Fatalf("author filter: %d entries, err=%v", len(bob), err)
+	}
+	page, more, _ := r.Activity("u", ActivityOptions{Offset: 1, Limit: 2})
+	if len(page) != 2 || !more || page[0].Verb != "started" {
+		t.Fatalf("unexpected page: %+v more=%v", page, more)
+	}
+	none, _, _ := r.Activity("u", ActivityOptions{Project: "other"})
+	if len(none) != 0 {
+		t.Fatalf("project filter should exclude all entries")
+	}
+
+	// neuer Commit (neuer HEAD): gecachter Feed wird neu gelesen
+	if err := os.WriteFile(p, []byte("summary: Fix VPN\ntags:\n- net\npriority: P2\ndue: 2025-01-20T00:00:00Z\nnotes: \"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "-c", "user.name=Carol", "-c", "user.email=carol@example.com", "commit", "-am", "Modified: Fix VPN")
+	entries, _, err = r.Activity("u", ActivityOptions{Limit: 10})
+	if err != nil || len(entries) != 5 {
+		t.Fatalf("expected 5 entries after new commit, got %d (err=%v)", len(entries), err)
+	}
+	if got := entries[0].Text(); got != `Carol removed +urgent from “Fix VPN”` {
+		t.Fatalf("newest entry: %q", got)
+	}
+}
+
+func TestStatusVerb(t *testing.T) {
+	cases := map[[2]string]string{
+		{"active", "resolved"}:  "completed",
+		{"resolved", "pending"}: "reopened",
+		{"pending", "active"}:   "started",
+		{"active", "paused"}:    "stopped",
+		{"pending", "paused"}:   "moved to paused",
+	}
+	for in, want := range cases {
+		if got := statusVerb(in[0], in[1]); got != want {
+			t.Fatalf("statusVerb(%q,%q)=%q want %q", in[0], in[1], got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package dstask
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AssignmentsFile liegt im .dstask-Repo und wird mit committet, damit alle Nutzer eines geteilten
+// Repos sehen, wer eine Aufgabe delegiert hat. Der Zuständige selbst steht im Task als Tag +@user.
+const AssignmentsFile = "assignments.yaml"
+
+// Assignment hält fest, wer eine Aufgabe wem wann zugewiesen hat.
+type Assignment struct {
+	Assignee string    `yaml:"assignee"`
+	By       string    `yaml:"by"`
+	At       time.Time `yaml:"at"`
+}
+
+type assignmentsDoc struct {
+	Version int                   `yaml:"version"`
+	Tasks   map[string]Assignment `yaml:"tasks"` // UUID -> Zuweisung
+}
+
+// Assignments liefert die Zuweisungen aus assignments.yaml (UUID -> Assignment); leer, wenn es die Datei nicht gibt.
+func (r *Runner) Assignments(username string) (map[string]Assignment, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	return readAssignments(filepath.Join(dir, AssignmentsFile))
+}
+
+func readAssignments(path string) (map[string]Assignment, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Assignment{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc assignmentsDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Tasks == nil {
+		doc.Tasks = map[string]Assignment{}
+	}
+	return doc.Tasks, nil
+}
+
+// RecordAssignment trägt die Zuweisung eines Tasks in assignments.yaml ein (assignee leer = entfernen)
+// und committet die Datei.
+func (r *Runner) RecordAssignment(username, taskUUID, assignee, by string) error {
+	if !LooksLikeUUID(taskUUID) {
+		return errors.New("invalid task uuid")
+	}
+	defer r.LockRepo(username)()
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, AssignmentsFile)
+	tasks, err := readAssignments(path)
+	if err != nil {
+		return err
+	}
+	if assignee == "" {
+		delete(tasks, taskUUID)
+	} else {
+		tasks[taskUUID] = Assignment{Assignee: assignee, By: by, At: time.Now().UTC().Truncate(time.Second)}
+	}
+	out, err := yaml.Marshal(assignmentsDoc{Version: 1, Tasks: tasks})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return err
+	}
+	return r.commitTaskFiles(username, dir, "dstask-ui: assign "+taskUUID[:8]+" to "+orNone(assignee), AssignmentsFile)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "nobody"
+	}
+	return s
+}
@@ -0,0 +1,523 @@
+package dstask
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+const (
+	BackupFormatTarGz = "tar.gz"
+	BackupFormatZip   = "zip"
+
+	// backupRoot is the top-level directory inside every archive.
+	backupRoot = ".dstask"
+	// maxRestoreBytes limits the uncompressed size of an uploaded archive.
+	maxRestoreBytes int64 = 1 << 30
+	// keepRollbackPoints is the number of rollback directories kept next to the repo.
+	keepRollbackPoints = 5
+	rollbackMarker     = ".bak-"
+	stagingMarker      = ".restore-"
+	backupTimeLayout   = "20060102-150405"
+)
+
+// RollbackPoint describes a previous repository state kept after a restore.
+type RollbackPoint struct {
+	Name    string
+	Path    string
+	Created time.Time
+}
+
+// WriteBackup writes the complete .dstask directory of the user (task files,
+// music-map.yaml and the git history) as tar.gz or zip archive to w.
+func (r *Runner) WriteBackup(username, format string, w io.Writer) error {
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return err
+	}
+	if st, err := os.Stat(repo); err != nil || !st.IsDir() {
+		return errors.New("repository directory not found")
+	}
+	switch format {
+	case BackupFormatTarGz, "":
+		return writeTarGz(repo, w)
+	case BackupFormatZip:
+		return writeZip(repo, w)
+	default:
+		return fmt.Errorf("unsupported backup format %q", format)
+	}
+}
+
+func writeTarGz(repo string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := walkRepo(repo, func(rel string, info fs.FileInfo, full string) error {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFileTo(tw, full)
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(repo string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := walkRepo(repo, func(rel string, info fs.FileInfo, full string) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		} else {
+			hdr.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return copyFileTo(fw, full)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// walkRepo calls fn for every directory and regular file below repo with an
+// archive path of the form ".dstask/<rel>". Symlinks and special files are skipped.
+func walkRepo(repo string, fn func(rel string, info fs.FileInfo, full string) error) error {
+	return filepath.Walk(repo, func(full string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			applog.Debugf("backup: skipping non-regular file %s", full)
+			return nil
+		}
+		rel, err := filepath.Rel(repo, full)
+		if err != nil {
+			return err
+		}
+		name := backupRoot
+		if rel != "." {
+			name = path.Join(backupRoot, filepath.ToSlash(rel))
+		}
+		return fn(name, info, full)
+	})
+}
+
+func copyFileTo(w io.Writer, full string) error {
+	f, err := os.Open(full)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// RestoreBackup validates the archive at archivePath (tar.gz or zip, detected by content),
+// extracts it into a staging directory next to the repository and swaps it in.
+// The previous repository is kept as rollback point; its path is returned.
+func (r *Runner) RestoreBackup(username, archivePath string) (string, error) {
+	defer r.LockRepo(username)()
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(repo)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	stamp := time.Now().Format(backupTimeLayout)
+	staging := repo + stagingMarker + stamp
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", err
+	}
+	// Staging wird bei Fehlern immer entfernt; nach erfolgreichem Swap existiert es nicht mehr.
+	defer os.RemoveAll(staging)
+
+	format, err := detectArchiveFormat(archivePath)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case BackupFormatTarGz:
+		err = extractTarGz(archivePath, staging)
+	case BackupFormatZip:
+		err = extractZip(archivePath, staging)
+	}
+	if err != nil {
+		applog.Warnf("RestoreBackup: extracting archive for %s failed: %v", username, err)
+		return "", err
+	}
+	if err := validateRestoredRepo(staging); err != nil {
+		return "", err
+	}
+	rollback, err := swapDirs(repo, staging, stamp)
+	if err != nil {
+		return "", err
+	}
+	applog.Infof("RestoreBackup: restored %s from archive (rollback point: %s)", repo, rollback)
+	pruneRollbackPoints(repo, keepRollbackPoints)
+	return rollback, nil
+}
+
+// ListRollbackPoints returns the rollback directories of the user's repository, newest first.
+func (r *Runner) ListRollbackPoints(username string) ([]RollbackPoint, error) {
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return listRollbackPoints(repo)
+}
+
+// RollbackTo swaps the named rollback point back in. The current repository
+// becomes a new rollback point, so a rollback can itself be undone.
+func (r *Runner) RollbackTo(username, name string) (string, error) {
+	defer r.LockRepo(username)()
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return "", err
+	}
+	points, err := listRollbackPoints(repo)
+	if err != nil {
+		return "", err
+	}
+	var target string
+	for _, p := range points {
+		if p.Name == name {
+			target = p.Path
+			break
+		}
+	}
+	if target == "" {
+		return "", errors.New("rollback point not found")
+	}
+	rollback, err := swapDirs(repo, target, time.Now().Format(backupTimeLayout))
+	if err != nil {
+		return "", err
+	}
+	applog.Infof("RollbackTo: %s restored from %s (previous state kept as %s)", repo, name, rollback)
+	return rollback, nil
+}
+
+// swapDirs ersetzt repo durch replacement. Ein vorhandenes repo wird zuvor nach
+// repo.bak-<stamp> umbenannt; schlägt der zweite Schritt fehl, wird es zurückbenannt.
+func swapDirs(repo, replacement, stamp string) (string, error) {
+	rollback := ""
+	if _, err := os.Stat(repo); err == nil {
+		rollback = repo + rollbackMarker + stamp
+		for i := 1; ; i++ {
+			if _, err := os.Stat(rollback); errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			rollback = fmt.Sprintf("%s%s%s-%d", repo, rollbackMarker, stamp, i)
+		}
+		if err := os.Rename(repo, rollback); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(replacement, repo); err != nil {
+		if rollback != "" {
+			if rerr := os.Rename(rollback, repo); rerr != nil {
+				applog.Errorf("swapDirs: could not move %s back to %s: %v", rollback, repo, rerr)
+			}
+		}
+		return "", err
+	}
+	return rollback, nil
+}
+
+func listRollbackPoints(repo string) ([]RollbackPoint, error) {
+	parent := filepath.Dir(repo)
+	prefix := filepath.Base(repo) + rollbackMarker
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]RollbackPoint, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(e.Name(), prefix)
+		if len(stamp) > len(backupTimeLayout) {
+			stamp = stamp[:len(backupTimeLayout)]
+		}
+		created, _ := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+		points = append(points, RollbackPoint{Name: e.Name(), Path: filepath.Join(parent, e.Name()), Created: created})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Name > points[j].Name })
+	return points, nil
+}
+
+func pruneRollbackPoints(repo string, keep int) {
+	points, err := listRollbackPoints(repo)
+	if err != nil || len(points) <= keep {
+		return
+	}
+	for _, p := range points[keep:] {
+		if err := os.RemoveAll(p.Path); err != nil {
+			applog.Warnf("pruneRollbackPoints: removing %s failed: %v", p.Path, err)
+		}
+	}
+}
+
+func detectArchiveFormat(archivePath string) (string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(f, magic)
+	magic = magic[:n]
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return BackupFormatTarGz, nil
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		return BackupFormatZip, nil
+	default:
+		return "", errors.New("unknown archive format (expected tar.gz or zip)")
+	}
+}
+
+// archiveTarget prüft einen Archivpfad und liefert das Ziel unterhalb von dest.
+// Erlaubt sind nur relative Pfade unterhalb von ".dstask/".
+func archiveTarget(dest, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, ":") {
+		return "", fmt.Errorf("invalid path in archive: %q", name)
+	}
+	if clean != backupRoot && !strings.HasPrefix(clean, backupRoot+"/") {
+		return "", fmt.Errorf("unexpected entry outside %s/: %q", backupRoot, name)
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(clean, backupRoot), "/")
+	target := filepath.Join(dest, filepath.FromSlash(rel))
+	if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in archive: %q", name)
+	}
+	return target, nil
+}
+
+func extractTarGz(archivePath, dest string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := archiveTarget(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if restoreSkipped(dest, target) {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			total += hdr.Size
+			if total > maxRestoreBytes {
+				return errors.New("archive exceeds maximum restore size")
+			}
+			if err := writeExtracted(target, tr, hdr.Size, fs.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry type in archive: %q", hdr.Name)
+		}
+	}
+}
+
+func extractZip(archivePath, dest string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	var total int64
+	for _, zf := range zr.File {
+		target, err := archiveTarget(dest, zf.Name)
+		if err != nil {
+			return err
+		}
+		if restoreSkipped(dest, target) {
+			continue
+		}
+		mode := zf.Mode()
+		if mode.IsDir() || strings.HasSuffix(zf.Name, "/") {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			return fmt.Errorf("unsupported entry type in archive: %q", zf.Name)
+		}
+		total += int64(zf.UncompressedSize64)
+		if total > maxRestoreBytes {
+			return errors.New("archive exceeds maximum restore size")
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtracted(target, rc, int64(zf.UncompressedSize64), mode)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreSkipped reports archive entries that are not restored: hooks would run as the service
+// user on every later commit or sync.
+func restoreSkipped(dest, target string) bool {
+	rel, err := filepath.Rel(dest, target)
+	if err != nil {
+		return true
+	}
+	rel = filepath.ToSlash(rel)
+	return rel == ".git/hooks" || strings.HasPrefix(rel, ".git/hooks/")
+}
+
+func writeExtracted(target string, src io.Reader, size int64, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Executable bits are never restored; a .dstask repository contains no programs.
+	perm := mode.Perm()&0644 | 0600
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	// Nicht mehr lesen als im Header angegeben (Schutz vor manipulierten Größenangaben)
+	n, err := io.Copy(out, io.LimitReader(src, size+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if n > size {
+		return fmt.Errorf("archive entry larger than declared: %s", target)
+	}
+	return nil
+}
+
+// restoredConfigKeys are the .git/config entries a restore keeps. Everything else (core.*, include*,
+// filters, credential helpers, aliases, …) can run commands and is dropped.
+var restoredConfigKeys = regexp.MustCompile(`(?i)^(core\.repositoryformatversion|extensions\.objectformat|user\.(name|email)|remote\..+\.(url|pushurl|fetch)|branch\..+\.(remote|merge))$`)
+
+// sanitizeRestoredConfig rewrites .git/config of the extracted repository with the whitelisted
+// entries only. git reads the uploaded file with --file and --no-includes, so nothing in it is run.
+func sanitizeRestoredConfig(dir string) error {
+	cfgPath := filepath.Join(dir, ".git", "config")
+	if _, err := os.Stat(cfgPath); err != nil {
+		return errors.New("archive does not contain a .dstask git repository")
+	}
+	list := exec.Command("git", "config", "--file", cfgPath, "--no-includes", "--null", "--list")
+	list.Dir = os.TempDir()
+	out, err := list.Output()
+	if err != nil {
+		return fmt.Errorf("restored repository has an unreadable git config: %v", err)
+	}
+	clean := cfgPath + ".clean"
+	_ = os.Remove(clean)
+	if err := os.WriteFile(clean, nil, 0644); err != nil {
+		return err
+	}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		key, value, _ := strings.Cut(entry, "\n")
+		if key == "" || !restoredConfigKeys.MatchString(key) {
+			if key != "" {
+				applog.Infof("RestoreBackup: dropping git config entry %s", key)
+			}
+			continue
+		}
+		add := exec.Command("git", "config", "--file", clean, "--add", key, value)
+		add.Dir = os.TempDir()
+		if out, err := add.CombinedOutput(); err != nil {
+			_ = os.Remove(clean)
+			return fmt.Errorf("rewriting git config failed: %s", strings.TrimSpace(string(out)))
+		}
+	}
+	return os.Rename(clean, cfgPath)
+}
+
+// validateRestoredRepo stellt sicher, dass das entpackte Verzeichnis ein nutzbares Git-Repository ist.
+// fsmonitor und Hooks sind dabei abgeschaltet, auch wenn die Konfiguration schon bereinigt ist.
+func validateRestoredRepo(dir string) error {
+	if st, err := os.Stat(filepath.Join(dir, ".git")); err != nil || !st.IsDir() {
+		return errors.New("archive does not contain a .dstask git repository")
+	}
+	if err := sanitizeRestoredConfig(dir); err != nil {
+		return err
+	}
+	cmd := exec.Command("git", "-c", "core.fsmonitor=", "-c", "core.hooksPath=/dev/null", "-C", dir, "status", "--porcelain")
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("restored repository is not usable: %s", strings.TrimSpace(errBuf.String()))
+	}
+	return nil
+}
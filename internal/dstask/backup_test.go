@@ -0,0 +1,186 @@
+package dstask
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+func newBackupTestRunner(t *testing.T) (*Runner, string) {
+	t.Helper()
+	home := t.TempDir()
+	repo := filepath.Join(home, ".dstask")
+	if err := os.MkdirAll(filepath.Join(repo, "pending"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "init")
+	runGit(t, repo, nil, "config", "user.email", "test@example.com")
+	runGit(t, repo, nil, "config", "user.name", "Test User")
+	if err := os.WriteFile(filepath.Join(repo, "pending", "abc.yml"), []byte("summary: original\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "music-map.yaml"), []byte("version: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "add", "-A")
+	runGit(t, repo, nil, "commit", "-m", "init")
+	cfg := &config.Config{DstaskBin: "/bin/true", Repos: map[string]string{"u": home}}
+	return NewRunner(cfg), repo
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	for _, format := range []string{BackupFormatTarGz, BackupFormatZip} {
+		t.Run(format, func(t *testing.T) {
+			r, repo := newBackupTestRunner(t)
+			archive := filepath.Join(t.TempDir(), "backup."+format)
+			f, err := os.Create(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.WriteBackup("u", format, f); err != nil {
+				t.Fatalf("WriteBackup: %v", err)
+			}
+			f.Close()
+
+			// Repo nach dem Backup verändern
+			if err := os.WriteFile(filepath.Join(repo, "pending", "abc.yml"), []byte("summary: changed\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			rollback, err := r.RestoreBackup("u", archive)
+			if err != nil {
+				t.Fatalf("RestoreBackup: %v", err)
+			}
+			b, err := os.ReadFile(filepath.Join(repo, "pending", "abc.yml"))
+			if err != nil || string(b) != "summary: original\n" {
+				t.Fatalf("restored content mismatch: %q err=%v", string(b), err)
+			}
+			if _, err := os.Stat(filepath.Join(repo, "music-map.yaml")); err != nil {
+				t.Fatalf("music-map.yaml missing after restore: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(repo, ".git", "HEAD")); err != nil {
+				t.Fatalf("git history missing after restore: %v", err)
+			}
+			if rollback == "" {
+				t.Fatalf("expected rollback point")
+			}
+			points, err := r.ListRollbackPoints("u")
+			if err != nil || len(points) != 1 {
+				t.Fatalf("expected 1 rollback point, got %v err=%v", points, err)
+			}
+
+			// Rollback bringt den veränderten Stand zurück
+			if _, err := r.RollbackTo("u", points[0].Name); err != nil {
+				t.Fatalf("RollbackTo: %v", err)
+			}
+			b, _ = os.ReadFile(filepath.Join(repo, "pending", "abc.yml"))
+			if string(b) != "summary: changed\n" {
+				t.Fatalf("rollback content mismatch: %q", string(b))
+			}
+		})
+	}
+}
+
+func TestRestoreBackupRejectsTraversal(t *testing.T) {
+	r, repo := newBackupTestRunner(t)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	body := []byte("evil")
+	_ = tw.WriteHeader(&tar.Header{Name: ".dstask/../../evil.txt", Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(body)
+	tw.Close()
+	gz.Close()
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RestoreBackup("u", archive); err == nil {
+		t.Fatalf("expected error for path traversal")
+	}
+	b, _ := os.ReadFile(filepath.Join(repo, "pending", "abc.yml"))
+	if string(b) != "summary: original\n" {
+		t.Fatalf("repository changed after rejected restore")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(repo))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), stagingMarker) {
+			t.Fatalf("staging directory left behind: %s", e.Name())
+		}
+	}
+}
+
+func TestRestoreBackupRejectsNonRepo(t *testing.T) {
+	r, _ := newBackupTestRunner(t)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	body := []byte("summary: x\n")
+	_ = tw.WriteHeader(&tar.Header{Name: ".dstask/pending/x.yml", Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(body)
+	tw.Close()
+	gz.Close()
+	archive := filepath.Join(t.TempDir(), "norepo.tar.gz")
+	if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RestoreBackup("u", archive); err == nil {
+		t.Fatalf("expected error for archive without git repository")
+	}
+}
+
+func TestRestoreBackupDropsHooksAndUnsafeGitConfig(t *testing.T) {
+	r, repo := newBackupTestRunner(t)
+	marker := filepath.Join(t.TempDir(), "pwned")
+	script := "#!/bin/sh\ntouch " + marker + "\n"
+	if err := os.WriteFile(filepath.Join(repo, ".git", "hooks", "post-commit"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	monitor := filepath.Join(repo, "fsmonitor.sh")
+	if err := os.WriteFile(monitor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "remote", "add", "origin", "https://example.org/tasks.git")
+	runGit(t, repo, nil, "config", "alias.st", "!touch "+marker)
+	runGit(t, repo, nil, "config", "core.fsmonitor", monitor) // ab hier kein git mehr im Quell-Repo
+	archive := filepath.Join(t.TempDir(), "poisoned.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteBackup("u", BackupFormatTarGz, f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := r.RestoreBackup("u", archive); err != nil {
+		t.Fatalf("RestoreBackup: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("fsmonitor or hook ran during restore")
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "hooks", "post-commit")); err == nil {
+		t.Fatal("hook restored")
+	}
+	if st, err := os.Stat(filepath.Join(repo, "fsmonitor.sh")); err != nil || st.Mode()&0111 != 0 {
+		t.Fatalf("restored file kept exec bits: %v %v", st, err)
+	}
+	cfg, _ := os.ReadFile(filepath.Join(repo, ".git", "config"))
+	if strings.Contains(string(cfg), "fsmonitor") || strings.Contains(string(cfg), "alias") || !strings.Contains(string(cfg), "https://example.org/tasks.git") {
+		t.Fatalf("unexpected restored config:\n%s", cfg)
+	}
+	// späterer Commit im wiederhergestellten Repo führt nichts aus
+	if err := os.WriteFile(filepath.Join(repo, "pending", "abc.yml"), []byte("summary: after\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "-c", "user.email=t@example.com", "-c", "user.name=T", "commit", "-qam", "after restore")
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("command from the archive ran on a later commit")
+	}
+}
@@ -0,0 +1,256 @@
+package dstask
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Conflict sides as used in forms and ResolveConflict.
+const (
+	ConflictBase   = "base"
+	ConflictOurs   = "ours"
+	ConflictTheirs = "theirs"
+	ConflictDelete = "delete"
+)
+
+// ConflictField vergleicht ein Feld über die drei Merge-Stände.
+type ConflictField struct {
+	Field  string
+	Base   string
+	Ours   string
+	Theirs string
+	// Conflicting ist true, wenn beide Seiten das Feld unterschiedlich geändert haben.
+	Conflicting bool
+}
+
+// ConflictFile ist eine Task-Datei mit ungelöstem Merge-Konflikt.
+type ConflictFile struct {
+	Path    string
+	UUID    string
+	Summary string
+	// Has* geben an, ob die Datei auf der jeweiligen Seite existiert (modify/delete-Konflikte).
+	HasBase, HasOurs, HasTheirs bool
+	Fields                      []ConflictField
+	raw                         map[string]map[string]any
+}
+
+// conflictFields sind die Felder, die im Konflikt-Dialog einzeln gewählt werden können.
+// Der Status ergibt sich aus dem Ordner und ist daher nicht wählbar.
+var conflictFields = []string{"summary", "project", "priority", "tags", "due", "notes"}
+
+// MergeInProgress meldet, ob im Repo des Nutzers ein Merge (z. B. von `dstask sync`) offen ist.
+func (r *Runner) MergeInProgress(username string) bool {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(dir, ".git", "MERGE_HEAD"))
+	return err == nil
+}
+
+// Conflicts liest alle ungemergten Task-Dateien aus `git ls-files -u` samt Base/Ours/Theirs-Stand.
+func (r *Runner) Conflicts(username string) ([]ConflictFile, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.gitOutput(username, dir, "ls-files", "-u")
+	if err != nil {
+		return nil, err
+	}
+	// <mode> <sha> <stage>\t<path>
+	stages := map[string]map[string]string{}
+	var paths []string
+	for _, l := range strings.Split(out, "\n") {
+		meta, p, ok := strings.Cut(l, "\t")
+		if !ok {
+			continue
+		}
+		f := strings.Fields(meta)
+		if len(f) != 3 {
+			continue
+		}
+		if _, ok := stages[p]; !ok {
+			stages[p] = map[string]string{}
+			paths = append(paths, p)
+		}
+		stages[p][f[2]] = f[1]
+	}
+	sort.Strings(paths)
+	files := make([]ConflictFile, 0, len(paths))
+	for _, p := range paths {
+		cf := ConflictFile{Path: p, UUID: strings.TrimSuffix(path.Base(p), ".yml"), raw: map[string]map[string]any{}}
+		fields := map[string]map[string]string{}
+		for stage, side := range map[string]string{"1": ConflictBase, "2": ConflictOurs, "3": ConflictTheirs} {
+			sha, ok := stages[p][stage]
+			if !ok {
+				continue
+			}
+			blob, err := r.gitOutput(username, dir, "cat-file", "-p", sha)
+			if err != nil {
+				return nil, err
+			}
+			var m map[string]any
+			if err := yaml.Unmarshal([]byte(blob), &m); err != nil {
+				applog.Warnf("Conflicts: %s (%s) is not valid YAML: %v", p, side, err)
+			}
+			cf.raw[side] = m
+			fields[side] = TaskFieldsFromYAML(blob, "")
+		}
+		_, cf.HasBase = fields[ConflictBase]
+		_, cf.HasOurs = fields[ConflictOurs]
+		_, cf.HasTheirs = fields[ConflictTheirs]
+		for _, k := range conflictFields {
+			f := ConflictField{Field: k, Base: fields[ConflictBase][k], Ours: fields[ConflictOurs][k], Theirs: fields[ConflictTheirs][k]}
+			f.Conflicting = f.Ours != f.Theirs && f.Ours != f.Base && f.Theirs != f.Base
+			cf.Fields = append(cf.Fields, f)
+		}
+		cf.Summary = firstNonEmpty(fields[ConflictOurs]["summary"], fields[ConflictTheirs]["summary"], fields[ConflictBase]["summary"])
+		files = append(files, cf)
+	}
+	return files, nil
+}
+
+// ResolveConflict löst den Konflikt einer Task-Datei auf.
+// whole == ConflictDelete entfernt die Datei, ConflictOurs/ConflictTheirs übernimmt eine Seite komplett.
+// Ist whole leer, wird pro Feld aus choices (Feld -> base|ours|theirs) gewählt; fehlende Felder
+// übernehmen die automatisch gemergte Seite (die geänderte, sonst ours).
+func (r *Runner) ResolveConflict(username, relPath, whole string, choices map[string]string) error {
+	defer r.LockRepo(username)()
+	conflicts, err := r.Conflicts(username)
+	if err != nil {
+		return err
+	}
+	var cf *ConflictFile
+	for i := range conflicts {
+		if conflicts[i].Path == relPath {
+			cf = &conflicts[i]
+			break
+		}
+	}
+	if cf == nil {
+		return errors.New("file is not in conflict")
+	}
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	if whole == ConflictDelete {
+		_, err := r.gitOutput(username, dir, "rm", "-q", "--", relPath)
+		return err
+	}
+	var result map[string]any
+	switch whole {
+	case ConflictOurs, ConflictTheirs:
+		if cf.raw[whole] == nil {
+			return errors.New("selected side does not contain this task")
+		}
+		result = cloneMap(cf.raw[whole])
+	case "":
+		base := cf.raw[ConflictOurs]
+		if base == nil {
+			base = cf.raw[ConflictTheirs]
+		}
+		if base == nil {
+			return errors.New("task exists on neither side")
+		}
+		result = cloneMap(base)
+		for _, f := range cf.Fields {
+			side := choices[f.Field]
+			if side == "" {
+				// nicht konfliktbehaftet: geänderte Seite gewinnt
+				side = ConflictOurs
+				if f.Ours == f.Base && f.Theirs != f.Base {
+					side = ConflictTheirs
+				}
+			}
+			src, ok := cf.raw[side]
+			if !ok || src == nil {
+				delete(result, f.Field)
+				continue
+			}
+			if v, ok := src[f.Field]; ok {
+				result[f.Field] = v
+			} else {
+				delete(result, f.Field)
+			}
+		}
+	default:
+		return errors.New("invalid resolution")
+	}
+	data, err := yaml.Marshal(&result)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+	_, err = r.gitOutput(username, dir, "add", "--", relPath)
+	return err
+}
+
+// FinishMerge committet den Merge, sobald keine Konflikte mehr offen sind, und pusht ihn.
+// remaining ist die Anzahl noch offener Konflikt-Dateien.
+func (r *Runner) FinishMerge(username string) (remaining int, err error) {
+	defer r.LockRepo(username)()
+	conflicts, err := r.Conflicts(username)
+	if err != nil {
+		return 0, err
+	}
+	if len(conflicts) > 0 {
+		return len(conflicts), nil
+	}
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return 0, err
+	}
+	if r.MergeInProgress(username) {
+		if _, err := r.gitOutput(username, dir, "commit", "--no-edit"); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := r.gitOutput(username, dir, "push"); err != nil {
+		return 0, err
+	}
+	applog.Infof("FinishMerge: merge committed and pushed for %s", username)
+	return 0, nil
+}
+
+// AbortMerge bricht einen offenen Merge ab und stellt den lokalen Stand wieder her.
+func (r *Runner) AbortMerge(username string) error {
+	defer r.LockRepo(username)()
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	_, err = r.gitOutput(username, dir, "merge", "--abort")
+	return err
+}
+
+func cloneMap(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
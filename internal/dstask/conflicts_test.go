@@ -0,0 +1,119 @@
+package dstask
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+// newConflictRepo erzeugt ein Repo mit offenem Merge-Konflikt: lokal wurden notes und priority,
+// remote notes und tags geändert.
+func newConflictRepo(t *testing.T) (*Runner, string, string) {
+	t.Helper()
+	tmp := t.TempDir()
+	remote := filepath.Join(tmp, "remote.git")
+	runGit(t, tmp, nil, "init", "--bare", "-b", "main", remote)
+	home := filepath.Join(tmp, "home")
+	local := filepath.Join(home, ".dstask")
+	other := filepath.Join(tmp, "other")
+	rel := filepath.Join("pending", historyTestUUID+".yml")
+	setup := func(dir string) {
+		runGit(t, dir, nil, "config", "user.email", "test@example.com")
+		runGit(t, dir, nil, "config", "user.name", "Test")
+	}
+	if err := os.MkdirAll(filepath.Join(local, "pending"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, local, nil, "init", "-b", "main")
+	setup(local)
+	write := func(dir, content string) {
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(local, "summary: Fix VPN\npriority: P2\ntags:\n- net\nnotes: base notes\n")
+	runGit(t, local, nil, "add", "-A")
+	runGit(t, local, nil, "commit", "-m", "init")
+	runGit(t, local, nil, "remote", "add", "origin", remote)
+	runGit(t, local, nil, "push", "-u", "origin", "main")
+	runGit(t, tmp, nil, "clone", "-q", remote, other)
+	setup(other)
+	write(other, "summary: Fix VPN\npriority: P2\ntags:\n- net\n- urgent\nnotes: remote notes\n")
+	runGit(t, other, nil, "commit", "-qam", "remote change")
+	runGit(t, other, nil, "push", "-q")
+	write(local, "summary: Fix VPN\npriority: P1\ntags:\n- net\nnotes: local notes\n")
+	runGit(t, local, nil, "commit", "-qam", "local change")
+	cmd := exec.Command("git", "-C", local, "pull", "--no-rebase", "--no-edit")
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("expected merge conflict, got: %s", out)
+	}
+	cfg := &config.Config{DstaskBin: "/bin/true", Repos: map[string]string{"u": home}}
+	return NewRunner(cfg), local, remote
+}
+
+func TestConflicts_ResolveAndFinish(t *testing.T) {
+	r, local, remote := newConflictRepo(t)
+	if !r.MergeInProgress("u") {
+		t.Fatalf("expected merge in progress")
+	}
+	conflicts, err := r.Conflicts("u")
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %d err=%v", len(conflicts), err)
+	}
+	cf := conflicts[0]
+	if cf.UUID != historyTestUUID || !cf.HasBase || !cf.HasOurs || !cf.HasTheirs {
+		t.Fatalf("unexpected conflict file: %+v", cf)
+	}
+	byField := map[string]ConflictField{}
+	for _, f := range cf.Fields {
+		byField[f.Field] = f
+	}
+	if n := byField["notes"]; !n.Conflicting || n.Base != "base notes" || n.Ours != "local notes" || n.Theirs != "remote notes" {
+		t.Fatalf("unexpected notes field: %+v", n)
+	}
+	if byField["priority"].Conflicting || byField["tags"].Conflicting {
+		t.Fatalf("one-sided changes must not be conflicting: %+v", cf.Fields)
+	}
+
+	// notes vom Remote, übrige Felder automatisch: priority lokal (P1), tags remote (+urgent)
+	if err := r.ResolveConflict("u", cf.Path, "", map[string]string{"notes": ConflictTheirs}); err != nil {
+		t.Fatalf("ResolveConflict: %v", err)
+	}
+	remaining, err := r.FinishMerge("u")
+	if err != nil || remaining != 0 {
+		t.Fatalf("FinishMerge: remaining=%d err=%v", remaining, err)
+	}
+	b, _ := os.ReadFile(filepath.Join(local, cf.Path))
+	fields := TaskFieldsFromYAML(string(b), "")
+	if fields["notes"] != "remote notes" || fields["priority"] != "P1" || fields["tags"] != "net, urgent" {
+		t.Fatalf("unexpected resolved fields: %+v", fields)
+	}
+	if r.MergeInProgress("u") {
+		t.Fatalf("merge should be committed")
+	}
+	out, err := exec.Command("git", "-C", remote, "log", "-1", "--format=%P").Output()
+	if err != nil || len(strings.Fields(string(out))) != 2 {
+		t.Fatalf("expected merge commit on remote, parents=%q err=%v", out, err)
+	}
+}
+
+func TestConflicts_Abort(t *testing.T) {
+	r, local, _ := newConflictRepo(t)
+	if err := r.ResolveConflict("u", "pending/none.yml", ConflictOurs, nil); err == nil {
+		t.Fatalf("expected error for file not in conflict")
+	}
+	if err := r.AbortMerge("u"); err != nil {
+		t.Fatalf("AbortMerge: %v", err)
+	}
+	if r.MergeInProgress("u") {
+		t.Fatalf("merge still in progress after abort")
+	}
+	b, _ := os.ReadFile(filepath.Join(local, "pending", historyTestUUID+".yml"))
+	if !strings.Contains(string(b), "local notes") {
+		t.Fatalf("local state not restored: %s", b)
+	}
+}
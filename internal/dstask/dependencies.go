@@ -0,0 +1,121 @@
+package dstask
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DependenciesFile liegt wie assignments.yaml im .dstask-Repo und wird mit committet.
+// Abhängigkeiten werden über UUIDs gespeichert, da sich die IDs offener Tasks ändern.
+const DependenciesFile = "dependencies.yaml"
+
+type dependenciesDoc struct {
+	Version int                 `yaml:"version"`
+	Tasks   map[string][]string `yaml:"tasks"` // UUID -> UUIDs der blockierenden Tasks
+}
+
+// Dependencies liefert die gespeicherten Abhängigkeiten (UUID -> blockierende UUIDs); leer, wenn es die Datei nicht gibt.
+func (r *Runner) Dependencies(username string) (map[string][]string, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	return readDependencies(filepath.Join(dir, DependenciesFile))
+}
+
+func readDependencies(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc dependenciesDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Tasks == nil {
+		doc.Tasks = map[string][]string{}
+	}
+	return doc.Tasks, nil
+}
+
+// SetBlockers ersetzt die blockierenden Tasks von taskUUID (leere Liste = keine Abhängigkeiten mehr)
+// und committet dependencies.yaml. Zyklen werden abgelehnt.
+func (r *Runner) SetBlockers(username, taskUUID string, blockers []string) error {
+	taskUUID = strings.ToLower(taskUUID)
+	if !LooksLikeUUID(taskUUID) {
+		return errors.New("invalid task uuid")
+	}
+	clean := make([]string, 0, len(blockers))
+	seen := map[string]bool{}
+	for _, b := range blockers {
+		b = strings.ToLower(strings.TrimSpace(b))
+		if !LooksLikeUUID(b) {
+			return errors.New("invalid blocker uuid: " + b)
+		}
+		if b == taskUUID {
+			return errors.New("a task cannot block itself")
+		}
+		if !seen[b] {
+			seen[b] = true
+			clean = append(clean, b)
+		}
+	}
+	sort.Strings(clean)
+	defer r.LockRepo(username)()
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, DependenciesFile)
+	deps, err := readDependencies(path)
+	if err != nil {
+		return err
+	}
+	if WouldCycle(deps, taskUUID, clean) {
+		return errors.New("dependency would create a cycle")
+	}
+	if len(clean) == 0 {
+		if _, ok := deps[taskUUID]; !ok {
+			return nil
+		}
+		delete(deps, taskUUID)
+	} else {
+		deps[taskUUID] = clean
+	}
+	out, err := yaml.Marshal(dependenciesDoc{Version: 1, Tasks: deps})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return err
+	}
+	return r.commitTaskFiles(username, dir, "dstask-ui: dependencies of "+taskUUID[:8], DependenciesFile)
+}
+
+// WouldCycle meldet, ob taskUUID mit den Blockern blockers einen Zyklus in deps erzeugen würde,
+// d. h. ob einer der Blocker (transitiv) selbst auf taskUUID wartet.
+func WouldCycle(deps map[string][]string, taskUUID string, blockers []string) bool {
+	seen := map[string]bool{}
+	stack := append([]string{}, blockers...)
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == taskUUID {
+			return true
+		}
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		stack = append(stack, deps[cur]...)
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package dstask
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetBlockers_PersistsAndRejectsCycles(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	other := "22222222-2222-2222-2222-222222222222"
+
+	if err := r.SetBlockers("u", historyTestUUID, []string{other, strings.ToUpper(other)}); err != nil {
+		t.Fatalf("SetBlockers: %v", err)
+	}
+	deps, err := r.Dependencies("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deps[historyTestUUID]; len(got) != 1 || got[0] != other {
+		t.Fatalf("unexpected blockers: %v", got)
+	}
+	msg, err := r.gitOutput("u", repo, "log", "-1", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg, "dependencies of "+historyTestUUID[:8]) {
+		t.Fatalf("dependencies.yaml not committed: %q", msg)
+	}
+
+	if err := r.SetBlockers("u", other, []string{historyTestUUID}); err == nil {
+		t.Fatalf("expected cycle to be rejected")
+	}
+	if err := r.SetBlockers("u", historyTestUUID, []string{historyTestUUID}); err == nil {
+		t.Fatalf("expected self-dependency to be rejected")
+	}
+
+	if err := r.SetBlockers("u", historyTestUUID, nil); err != nil {
+		t.Fatalf("clearing blockers: %v", err)
+	}
+	deps, _ = r.Dependencies("u")
+	if _, ok := deps[historyTestUUID]; ok {
+		t.Fatalf("blockers not removed: %v", deps)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/elpatron68/dstask-ui/internal/config"
@@ -36,18 +37,11 @@ func IsRepoDirty(cfg *config.Config, username string) (bool, error) {
 	if cfg == nil {
 		return false, errors.New("nil config")
 	}
-	r := &Runner{cfg: cfg}
-	repo, err := r.RepoDirForUser(username)
+	files, err := (&Runner{cfg: cfg}).RepoPorcelain(username)
 	if err != nil {
 		return false, err
 	}
-	cmd := exec.Command("git", "-C", repo, "status", "--porcelain")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
-		return false, err
-	}
-	return strings.TrimSpace(out.String()) != "", nil
+	return len(files) > 0, nil
 }
 
 // GitRemoteURL gibt die URL von remote "origin" zurück (leer wenn nicht gesetzt).
@@ -57,33 +51,21 @@ func (r *Runner) GitRemoteURL(username string) (string, error) {
 		return "", err
 	}
 	cmd := exec.Command("git", "-C", repo, "config", "--get", "remote.origin.url")
-	// HOME setzen wie in Run()
-	env := os.Environ()
-	if home, ok := config.ResolveHomeForUsername(r.cfg, username); ok && home != "" {
-		replacedHome := false
-		for i, e := range env {
-			if strings.HasPrefix(e, "HOME=") {
-				env[i] = "HOME=" + home
-				replacedHome = true
-				break
-			}
-		}
-		if !replacedHome {
-			env = append(env, "HOME="+home)
-		}
-	}
-	cmd.Env = env
+	// HOME und Git-Zugangsdaten wie in Run()
+	cmd.Env = r.gitEnvForUser(username)
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	if err := cmd.Run(); err != nil {
 		applog.Debugf("GitRemoteURL: kein remote.origin.url gefunden in %s (%v)", repo, err)
 		return "", nil
 	}
-	return strings.TrimSpace(out.String()), nil
+	// Zugangsdaten aus alten, direkt in die URL geschriebenen Remotes nie ausgeben
+	return applog.Redact(strings.TrimSpace(out.String())), nil
 }
 
 // GitSetRemoteOrigin setzt remote "origin" auf url. Falls vorhanden, wird die URL aktualisiert.
 func (r *Runner) GitSetRemoteOrigin(username, url string) error {
+	defer r.LockRepo(username)()
 	repo, err := r.RepoDirForUser(username)
 	if err != nil {
 		return err
@@ -93,20 +75,7 @@ func (r *Runner) GitSetRemoteOrigin(username, url string) error {
 		applog.Warnf("GitSetRemoteOrigin: no Git repository in %s – cannot set remote", repo)
 		return errors.New("no Git repository present – please clone the repository")
 	}
-	env := os.Environ()
-	if home, ok := config.ResolveHomeForUsername(r.cfg, username); ok && home != "" {
-		replacedHome := false
-		for i, e := range env {
-			if strings.HasPrefix(e, "HOME=") {
-				env[i] = "HOME=" + home
-				replacedHome = true
-				break
-			}
-		}
-		if !replacedHome {
-			env = append(env, "HOME="+home)
-		}
-	}
+	env := r.gitEnvForUser(username)
 
 	// Prüfe, ob origin existiert
 	check := exec.Command("git", "-C", repo, "remote")
@@ -143,6 +112,7 @@ func (r *Runner) GitSetRemoteOrigin(username, url string) error {
 // - Wenn es existiert und leer ist: in diesem Verzeichnis git clone <url> .
 // - Andernfalls Fehler.
 func (r *Runner) GitCloneRemote(username, url string) error {
+	defer r.LockRepo(username)()
 	repo, err := r.RepoDirForUser(username)
 	if err != nil {
 		return err
@@ -151,20 +121,7 @@ func (r *Runner) GitCloneRemote(username, url string) error {
 	if err := os.MkdirAll(filepath.Dir(repo), 0755); err != nil {
 		return err
 	}
-	env := os.Environ()
-	if home, ok := config.ResolveHomeForUsername(r.cfg, username); ok && home != "" {
-		replacedHome := false
-		for i, e := range env {
-			if strings.HasPrefix(e, "HOME=") {
-				env[i] = "HOME=" + home
-				replacedHome = true
-				break
-			}
-		}
-		if !replacedHome {
-			env = append(env, "HOME="+home)
-		}
-	}
+	env := r.gitEnvForUser(username)
 
 	// Existenz prüfen
 	if _, err := os.Stat(repo); errors.Is(err, os.ErrNotExist) {
@@ -225,20 +182,7 @@ func (r *Runner) GitSetUpstreamIfMissing(username string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	env := os.Environ()
-	if home, ok := config.ResolveHomeForUsername(r.cfg, username); ok && home != "" {
-		replacedHome := false
-		for i, e := range env {
-			if strings.HasPrefix(e, "HOME=") {
-				env[i] = "HOME=" + home
-				replacedHome = true
-				break
-			}
-		}
-		if !replacedHome {
-			env = append(env, "HOME="+home)
-		}
-	}
+	env := r.gitEnvForUser(username)
 
 	// Ermittle aktuellen Branch
 	curr := exec.Command("git", "-C", repo, "rev-parse", "--abbrev-ref", "HEAD")
@@ -288,20 +232,7 @@ func (r *Runner) GitRemoteHeadBranch(username string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	env := os.Environ()
-	if home, ok := config.ResolveHomeForUsername(r.cfg, username); ok && home != "" {
-		replacedHome := false
-		for i, e := range env {
-			if strings.HasPrefix(e, "HOME=") {
-				env[i] = "HOME=" + home
-				replacedHome = true
-				break
-			}
-		}
-		if !replacedHome {
-			env = append(env, "HOME="+home)
-		}
-	}
+	env := r.gitEnvForUser(username)
 	// 1) Schnellweg: symbolic-ref
 	cmd := exec.Command("git", "-C", repo, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
 	cmd.Env = env
@@ -333,3 +264,23 @@ func (r *Runner) GitRemoteHeadBranch(username string) (string, error) {
 	}
 	return "", errors.New("remote-head konnte nicht ermittelt werden")
 }
+
+// GitAheadBehind liefert, wie viele Commits der lokale Branch dem Upstream voraus bzw. hinterher ist.
+// Ohne Upstream wird ein Fehler geliefert.
+func (r *Runner) GitAheadBehind(username string) (ahead, behind int, err error) {
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return 0, 0, err
+	}
+	out, err := r.gitOutput(username, repo, "rev-list", "--left-right", "--count", "HEAD...@{u}")
+	if err != nil {
+		return 0, 0, err
+	}
+	f := strings.Fields(out)
+	if len(f) != 2 {
+		return 0, 0, errors.New("unexpected rev-list output")
+	}
+	ahead, _ = strconv.Atoi(f[0])
+	behind, _ = strconv.Atoi(f[1])
+	return ahead, behind, nil
+}
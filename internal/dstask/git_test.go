@@ -4,9 +4,11 @@ import (
     "os"
     "os/exec"
     "path/filepath"
+    "strings"
     "testing"
 
     "github.com/elpatron68/dstask-ui/internal/config"
+    "github.com/elpatron68/dstask-ui/internal/gitauth"
 )
 
 func runGit(t *testing.T, dir string, env []string, args ...string) {
@@ -157,3 +159,29 @@ func TestGitRemoteHeadBranch_DetectsMain(t *testing.T) {
 }
 
 
+
+func TestGitEnv_InjectsSSHCommandWhenKeyExists(t *testing.T) {
+    tmp := t.TempDir()
+    cfg := &config.Config{DstaskBin: "/bin/true", DataDir: filepath.Join(tmp, "data"), Repos: map[string]string{"testuser": filepath.Join(tmp, "home")}}
+    r := NewRunner(cfg)
+    for _, e := range r.gitEnvForUser("testuser") {
+        if strings.HasPrefix(e, "GIT_SSH_COMMAND=ssh -i") {
+            t.Fatalf("unexpected GIT_SSH_COMMAND without key: %s", e)
+        }
+    }
+    if _, err := gitauth.GenerateKey(cfg, "testuser"); err != nil {
+        t.Fatal(err)
+    }
+    found := 0
+    for _, e := range r.gitEnvForUser("testuser") {
+        if strings.HasPrefix(e, "GIT_SSH_COMMAND=") {
+            found++
+            if !strings.Contains(e, filepath.Join(tmp, "data", "ssh", "testuser", "id_ed25519")) {
+                t.Fatalf("GIT_SSH_COMMAND does not use the user's key: %s", e)
+            }
+        }
+    }
+    if found != 1 {
+        t.Fatalf("expected exactly one GIT_SSH_COMMAND, got %d", found)
+    }
+}
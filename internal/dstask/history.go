@@ -0,0 +1,423 @@
+package dstask
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"gopkg.in/yaml.v3"
+)
+
+// HistoryFields are the task fields compared between revisions, in display order.
+var HistoryFields = []string{"summary", "status", "project", "priority", "tags", "due", "notes"}
+
+// FieldChange beschreibt die Änderung eines Feldes zwischen zwei Revisionen.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// TaskRevision ist ein Commit, der die YAML-Datei eines Tasks verändert hat.
+type TaskRevision struct {
+	Commit  string
+	Author  string
+	Email   string
+	Date    time.Time
+	Message string
+	// Path der Task-Datei nach dem Commit (relativ zum Repo); leer, wenn der Task entfernt wurde.
+	Path    string
+	Deleted bool
+	Fields  map[string]string
+	Changes []FieldChange
+}
+
+// ShortCommit liefert die ersten 8 Zeichen des Commit-Hashes.
+func (rev TaskRevision) ShortCommit() string {
+	if len(rev.Commit) > 8 {
+		return rev.Commit[:8]
+	}
+	return rev.Commit
+}
+
+// TaskHistory liefert alle Commits, die die Datei des Tasks berührt haben, neueste zuerst.
+// Jede Revision enthält den Feldstand nach dem Commit und die Änderungen gegenüber der Vorgänger-Revision.
+func (r *Runner) TaskHistory(username, taskUUID string) ([]TaskRevision, error) {
+	if !LooksLikeUUID(taskUUID) {
+		return nil, errors.New("invalid task uuid")
+	}
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.gitOutput(username, dir, "log", "--no-renames", "--name-status",
+		"--format=%x1e%H%x1f%an%x1f%ae%x1f%aI%x1f%s", "--", "*/"+taskUUID+".yml")
+	if err != nil {
+		return nil, err
+	}
+	revs := parseHistoryLog(out, taskUUID)
+	// Feldstände laden (ältester zuerst), um Diffs zu bilden
+	var prev map[string]string
+	for i := len(revs) - 1; i >= 0; i-- {
+		rev := &revs[i]
+		if rev.Deleted {
+			rev.Fields = map[string]string{}
+		} else {
+			blob, err := r.gitOutput(username, dir, "show", rev.Commit+":"+rev.Path)
+			if err != nil {
+				applog.Warnf("TaskHistory: reading %s at %s failed: %v", rev.Path, rev.ShortCommit(), err)
+				continue
+			}
+			rev.Fields = TaskFieldsFromYAML(blob, statusFromPath(rev.Path))
+		}
+		rev.Changes = DiffTaskFields(prev, rev.Fields)
+		prev = rev.Fields
+	}
+	return revs, nil
+}
+
+// parseHistoryLog parst die Ausgabe von `git log --name-status` mit dem Format aus TaskHistory.
+func parseHistoryLog(out, taskUUID string) []TaskRevision {
+	var revs []TaskRevision
+	for _, rec := range strings.Split(out, "\x1e") {
+		rec = strings.TrimSpace(rec)
+		if rec == "" {
+			continue
+		}
+		lines := strings.Split(rec, "\n")
+		head := strings.Split(lines[0], "\x1f")
+		if len(head) < 5 {
+			continue
+		}
+		rev := TaskRevision{Commit: head[0], Author: head[1], Email: head[2], Message: head[4]}
+		rev.Date, _ = time.Parse(time.RFC3339, head[3])
+		deleted := false
+		for _, l := range lines[1:] {
+			fields := strings.Split(strings.TrimSpace(l), "\t")
+			if len(fields) < 2 || path.Base(fields[1]) != taskUUID+".yml" {
+				continue
+			}
+			switch fields[0] {
+			case "D":
+				deleted = true
+			default:
+				// A/M: Datei existiert nach dem Commit unter diesem Pfad
+				rev.Path = fields[1]
+			}
+		}
+		rev.Deleted = rev.Path == "" && deleted
+		if rev.Path == "" && !rev.Deleted {
+			continue
+		}
+		revs = append(revs, rev)
+	}
+	return revs
+}
+
+// statusFromPath leitet den Status aus dem Ordner der Task-Datei ab.
+func statusFromPath(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return path.Base(dir)
+}
+
+// TaskFieldsFromYAML extrahiert die verglichenen Felder aus einer Task-Datei als Strings.
+func TaskFieldsFromYAML(blob, status string) map[string]string {
+	fields := map[string]string{"status": status}
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal([]byte(blob), &raw); err != nil {
+		return fields
+	}
+	for _, k := range HistoryFields {
+		if k == "status" {
+			continue
+		}
+		n := raw[k]
+		fields[k] = yamlFieldString(k, &n)
+	}
+	return fields
+}
+
+func yamlFieldString(key string, n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return ""
+		case "!!timestamp":
+			// dstask schreibt ungesetzte Zeitpunkte als Nullwert
+			var ts time.Time
+			if err := n.Decode(&ts); err != nil {
+				return n.Value
+			}
+			if ts.IsZero() || ts.Year() <= 1 {
+				return ""
+			}
+			return ts.Format("2006-01-02 15:04")
+		}
+		return n.Value
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(n.Content))
+		for _, e := range n.Content {
+			parts = append(parts, yamlFieldString("", e))
+		}
+		if key == "tags" {
+			sort.Strings(parts)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return ""
+	}
+}
+
+// DiffTaskFields vergleicht zwei Feldstände; prev == nil bedeutet "Task neu angelegt".
+func DiffTaskFields(prev, cur map[string]string) []FieldChange {
+	var changes []FieldChange
+	for _, k := range HistoryFields {
+		o, n := "", cur[k]
+		if prev != nil {
+			o = prev[k]
+		}
+		if o != n {
+			changes = append(changes, FieldChange{Field: k, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+// gitOutput führt git im Repo mit der Umgebung des Nutzers aus und liefert stdout.
+func (r *Runner) gitOutput(username, dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = r.gitEnvForUser(username)
+	var out, errBuf bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(errBuf.String()))
+	}
+	return normalizeNewlines(out.String()), nil
+}
+
+// RemovedTask ist ein Task, dessen Datei gelöscht wurde und der aktuell in keinem Status-Ordner existiert.
+type RemovedTask struct {
+	UUID    string
+	Summary string
+	Commit  string
+	Author  string
+	Date    time.Time
+}
+
+// RemovedTasks listet entfernte Tasks aus `git log --diff-filter=D`, zuletzt entfernte zuerst.
+func (r *Runner) RemovedTasks(username string, limit int) ([]RemovedTask, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.gitOutput(username, dir, "log", "--no-renames", "--diff-filter=D", "--name-only",
+		"--format=%x1e%H%x1f%an%x1f%aI", "--", "*.yml")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var removed []RemovedTask
+	for _, rec := range strings.Split(out, "\x1e") {
+		rec = strings.TrimSpace(rec)
+		if rec == "" {
+			continue
+		}
+		lines := strings.Split(rec, "\n")
+		head := strings.Split(lines[0], "\x1f")
+		if len(head) < 3 {
+			continue
+		}
+		for _, l := range lines[1:] {
+			p := strings.TrimSpace(l)
+			uuid := strings.TrimSuffix(path.Base(p), ".yml")
+			if !LooksLikeUUID(uuid) || seen[uuid] {
+				continue
+			}
+			seen[uuid] = true
+			// Statuswechsel erscheinen ohne Rename-Erkennung ebenfalls als Löschung
+			if findTaskFile(dir, uuid) != "" {
+				continue
+			}
+			rt := RemovedTask{UUID: uuid, Commit: head[0], Author: head[1]}
+			rt.Date, _ = time.Parse(time.RFC3339, head[2])
+			if blob, err := r.gitOutput(username, dir, "show", head[0]+"^:"+p); err == nil {
+				rt.Summary = TaskFieldsFromYAML(blob, "")["summary"]
+			}
+			removed = append(removed, rt)
+			if limit > 0 && len(removed) >= limit {
+				return removed, nil
+			}
+		}
+	}
+	return removed, nil
+}
+
+// RestoreTaskRevision stellt den Task aus dem Stand nach commit wieder her.
+// Mit field == "" wird die komplette Datei (inkl. Status-Ordner) zurückgeschrieben, sonst nur das eine Feld.
+// Ist commit leer, wird die jüngste Revision verwendet, in der die Datei noch existierte.
+// Die Änderung wird wie bei UpdateTaskNotesDirectly direkt per git committet.
+func (r *Runner) RestoreTaskRevision(username, taskUUID, commit, field string) error {
+	defer r.LockRepo(username)()
+	if field != "" && !isHistoryField(field) {
+		return fmt.Errorf("unknown field %q", field)
+	}
+	revs, err := r.TaskHistory(username, taskUUID)
+	if err != nil {
+		return err
+	}
+	var rev *TaskRevision
+	for i := range revs {
+		if revs[i].Deleted {
+			continue
+		}
+		if commit == "" || (len(commit) >= 7 && strings.HasPrefix(revs[i].Commit, commit)) {
+			rev = &revs[i]
+			break
+		}
+	}
+	if rev == nil {
+		return errors.New("revision not found for this task")
+	}
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	blob, err := r.gitOutput(username, dir, "show", rev.Commit+":"+rev.Path)
+	if err != nil {
+		return err
+	}
+	current := findTaskFile(dir, taskUUID)
+	target := filepath.Join(dir, filepath.FromSlash(rev.Path))
+	var content []byte
+	msg := "Restore task " + taskUUID + " from " + rev.ShortCommit() + " via web UI"
+	switch {
+	case field == "":
+		content = []byte(blob)
+	case current == "":
+		return errors.New("task does not exist anymore; restore the whole task first")
+	case field == "status":
+		// Status ist der Ordner: aktuelle Datei in den Ordner der Revision verschieben
+		if content, err = os.ReadFile(current); err != nil {
+			return err
+		}
+		msg = "Restore status of task " + taskUUID + " from " + rev.ShortCommit() + " via web UI"
+	default:
+		data, err := os.ReadFile(current)
+		if err != nil {
+			return err
+		}
+		if content, err = replaceYAMLField(data, []byte(blob), field); err != nil {
+			return err
+		}
+		// Nur den Inhalt ändern, Status-Ordner bleibt
+		target = current
+		msg = "Restore " + field + " of task " + taskUUID + " from " + rev.ShortCommit() + " via web UI"
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return err
+	}
+	paths := []string{rev.Path}
+	if current != "" && current != target {
+		if err := os.Remove(current); err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(dir, current); err == nil {
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+	if field != "" && field != "status" {
+		rel, _ := filepath.Rel(dir, target)
+		paths = []string{filepath.ToSlash(rel)}
+	}
+	if err := r.commitTaskFiles(username, dir, msg, paths...); err != nil {
+		return err
+	}
+	applog.Infof("RestoreTaskRevision: %s", msg)
+	return nil
+}
+
+// replaceYAMLField übernimmt den Eintrag field (Schlüssel samt Wert, wie er in src steht) nach dst;
+// fehlt er in src, wird er aus dst entfernt. Alle anderen Zeilen von dst bleiben unverändert, damit der
+// Commit nur das eine Feld ändert.
+func replaceYAMLField(dst, src []byte, field string) ([]byte, error) {
+	dstLines, start, end, err := yamlFieldLines(dst, field)
+	if err != nil {
+		return nil, err
+	}
+	srcLines, srcStart, srcEnd, err := yamlFieldLines(src, field)
+	if err != nil {
+		return nil, err
+	}
+	var entry []string
+	if srcStart >= 0 {
+		entry = append(entry, srcLines[srcStart:srcEnd]...)
+		if n := len(entry); n > 0 && !strings.HasSuffix(entry[n-1], "\n") {
+			entry[n-1] += "\n"
+		}
+	}
+	if start < 0 {
+		// neu in dst: hinten anhängen
+		start, end = len(dstLines), len(dstLines)
+		if start > 0 && !strings.HasSuffix(dstLines[start-1], "\n") {
+			dstLines[start-1] += "\n"
+		}
+	}
+	out := append(append(append([]string{}, dstLines[:start]...), entry...), dstLines[end:]...)
+	return []byte(strings.Join(out, "")), nil
+}
+
+// yamlFieldLines teilt data in Zeilen (mit Zeilenende) und liefert den Zeilenbereich [start, end) des
+// Eintrags field auf oberster Ebene, bis zum nächsten Schlüssel; start = -1, wenn es ihn nicht gibt.
+func yamlFieldLines(data []byte, field string) ([]string, int, int, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, 0, err
+	}
+	if len(doc.Content) == 0 {
+		return lines, -1, -1, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode || root.Style&yaml.FlowStyle != 0 {
+		return nil, 0, 0, errors.New("task file is not a block mapping")
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != field {
+			continue
+		}
+		end := len(lines)
+		if i+2 < len(root.Content) {
+			end = root.Content[i+2].Line - 1
+		}
+		return lines, root.Content[i].Line - 1, end, nil
+	}
+	return lines, -1, -1, nil
+}
+
+func isHistoryField(field string) bool {
+	for _, f := range HistoryFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package dstask
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+const historyTestUUID = "0f8c3e2a-1b2c-4d5e-8f90-123456789abc"
+
+// newHistoryTestRepo erstellt ein .dstask-Repo mit drei Commits für einen Task:
+// anlegen (pending), Due verschieben, starten (Wechsel nach active).
+func newHistoryTestRepo(t *testing.T) (*Runner, string) {
+	t.Helper()
+	home := t.TempDir()
+	repo := filepath.Join(home, ".dstask")
+	for _, d := range []string{"pending", "active"} {
+		if err := os.MkdirAll(filepath.Join(repo, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, repo, nil, "init")
+	runGit(t, repo, nil, "config", "user.email", "alice@example.com")
+	runGit(t, repo, nil, "config", "user.name", "Alice")
+	write := func(rel, content string) {
+		if err := os.WriteFile(filepath.Join(repo, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending := filepath.Join("pending", historyTestUUID+".yml")
+	active := filepath.Join("active", historyTestUUID+".yml")
+	write(pending, "summary: Fix VPN\ntags:\n- net\npriority: P2\ndue: 2025-01-10T00:00:00Z\nnotes: \"\"\n")
+	runGit(t, repo, nil, "add", "-A")
+	runGit(t, repo, nil, "commit", "-m", "Added: Fix VPN")
+	write(pending, "summary: Fix VPN\ntags:\n- net\npriority: P2\ndue: 2025-01-20T00:00:00Z\nnotes: \"\"\n")
+	runGit(t, repo, nil, "-c", "user.name=Bob", "-c", "user.email=bob@example.com", "commit", "-am", "Modified: Fix VPN")
+	if err := os.Rename(filepath.Join(repo, pending), filepath.Join(repo, active)); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, nil, "add", "-A")
+	runGit(t, repo, nil, "commit", "-m", "Started: Fix VPN")
+	cfg := &config.Config{DstaskBin: "/bin/true", Repos: map[string]string{"u": home}}
+	return NewRunner(cfg), repo
+}
+
+func TestTaskHistory_FieldDiffs(t *testing.T) {
+	r, _ := newHistoryTestRepo(t)
+	revs, err := r.TaskHistory("u", historyTestUUID)
+	if err != nil {
+		t.Fatalf("TaskHistory: %v", err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("expected 3 revisions, got %d: %+v", len(revs), revs)
+	}
+	// neueste zuerst
+	if revs[0].Message != "Started: Fix VPN" || revs[2].Message != "Added: Fix VPN" {
+		t.Fatalf("unexpected order: %q, %q", revs[0].Message, revs[2].Message)
+	}
+	if len(revs[0].Changes) != 1 || revs[0].Changes[0].Field != "status" || revs[0].Changes[0].Old != "pending" || revs[0].Changes[0].New != "active" {
+		t.Fatalf("unexpected status change: %+v", revs[0].Changes)
+	}
+	due := revs[1]
+	if due.Author != "Bob" || len(due.Changes) != 1 || due.Changes[0].Field != "due" {
+		t.Fatalf("unexpected due change: %+v", due)
+	}
+	if due.Changes[0].Old != "2025-01-10 00:00" || due.Changes[0].New != "2025-01-20 00:00" {
+		t.Fatalf("unexpected due values: %+v", due.Changes[0])
+	}
+	if revs[2].Fields["summary"] != "Fix VPN" || revs[2].Fields["tags"] != "net" {
+		t.Fatalf("unexpected initial fields: %+v", revs[2].Fields)
+	}
+}
+
+func TestTaskFieldsFromYAML_Values(t *testing.T) {
+	f := TaskFieldsFromYAML("summary: x\ntags: [vpn, net]\ndue: 0001-01-01T00:00:00Z\nnotes: null\npriority: P1\n", "pending")
+	if f["tags"] != "net, vpn" || f["due"] != "" || f["notes"] != "" || f["priority"] != "P1" || f["status"] != "pending" {
+		t.Fatalf("unexpected fields: %+v", f)
+	}
+}
+
+func TestTaskHistory_InvalidUUID(t *testing.T) {
+	r, _ := newHistoryTestRepo(t)
+	if _, err := r.TaskHistory("u", "../etc"); err == nil {
+		t.Fatalf("expected error for invalid uuid")
+	}
+}
+
+func TestLooksLikeUUID(t *testing.T) {
+	if !LooksLikeUUID(historyTestUUID) {
+		t.Fatalf("expected valid uuid")
+	}
+	for _, s := range []string{"", "12", "0f8c3e2a-1b2c-4d5e-8f90-123456789abz", "0f8c3e2a11b2c-4d5e-8f90-123456789abc"} {
+		if LooksLikeUUID(s) {
+			t.Fatalf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestRestoreTaskRevision_FieldAndRemovedTask(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	active := filepath.Join(repo, "active", historyTestUUID+".yml")
+	revs, err := r.TaskHistory("u", historyTestUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldest := revs[len(revs)-1].Commit
+
+	// Einzelnes Feld: due auf den ursprünglichen Wert zurücksetzen, Status bleibt active
+	if err := r.RestoreTaskRevision("u", historyTestUUID, oldest, "due"); err != nil {
+		t.Fatalf("restore field: %v", err)
+	}
+	b, err := os.ReadFile(active)
+	if err != nil {
+		t.Fatalf("task file moved unexpectedly: %v", err)
+	}
+	if fields := TaskFieldsFromYAML(string(b), "active"); fields["due"] != "2025-01-10 00:00" || fields["summary"] != "Fix VPN" {
+		t.Fatalf("unexpected fields after field restore: %+v", fields)
+	}
+	// nur die due-Zeile ändert sich, Reihenfolge und Formatierung der übrigen Felder bleiben
+	if want := "summary: Fix VPN\ntags:\n- net\npriority: P2\ndue: 2025-01-10T00:00:00Z\nnotes: \"\"\n"; string(b) != want {
+		t.Fatalf("field restore rewrote the file:\n%s", b)
+	}
+
+	// Task entfernen (wie dstask remove) und wieder herstellen
+	runGit(t, repo, nil, "rm", "-q", filepath.Join("active", historyTestUUID+".yml"))
+	runGit(t, repo, nil, "commit", "-m", "Removed: Fix VPN")
+	removed, err := r.RemovedTasks("u", 0)
+	if err != nil || len(removed) != 1 || removed[0].UUID != historyTestUUID || removed[0].Summary != "Fix VPN" {
+		t.Fatalf("unexpected removed tasks: %+v err=%v", removed, err)
+	}
+	if err := r.RestoreTaskRevision("u", historyTestUUID, "", ""); err != nil {
+		t.Fatalf("restore removed task: %v", err)
+	}
+	if _, err := os.Stat(active); err != nil {
+		t.Fatalf("removed task not restored: %v", err)
+	}
+	if removed, _ := r.RemovedTasks("u", 0); len(removed) != 0 {
+		t.Fatalf("restored task still listed as removed: %+v", removed)
+	}
+
+	// Status aus der ersten Revision: Datei wandert zurück nach pending
+	if err := r.RestoreTaskRevision("u", historyTestUUID, oldest, "status"); err != nil {
+		t.Fatalf("restore status: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "pending", historyTestUUID+".yml")); err != nil {
+		t.Fatalf("status not restored: %v", err)
+	}
+	if _, err := os.Stat(active); !os.IsNotExist(err) {
+		t.Fatalf("old status file still present")
+	}
+}
+
+func TestReplaceYAMLField(t *testing.T) {
+	cur := "summary: Fix VPN\ntags:\n- net\nnotes: |-\n  a\n  b\npriority: P2\n"
+	old := "summary: Fix VPN\ntags:\n- net\n- vpn\npriority: P1\n"
+	for _, c := range []struct{ field, want string }{
+		{"tags", "summary: Fix VPN\ntags:\n- net\n- vpn\nnotes: |-\n  a\n  b\npriority: P2\n"},
+		{"priority", "summary: Fix VPN\ntags:\n- net\nnotes: |-\n  a\n  b\npriority: P1\n"},
+		{"notes", "summary: Fix VPN\ntags:\n- net\npriority: P2\n"},
+		{"due", cur},
+	} {
+		got, err := replaceYAMLField([]byte(cur), []byte(old), c.field)
+		if err != nil || string(got) != c.want {
+			t.Fatalf("%s: %v\n%s", c.field, err, got)
+		}
+	}
+	got, err := replaceYAMLField([]byte("summary: x"), []byte("due: 2025-01-10T00:00:00Z\nsummary: x\n"), "due")
+	if err != nil || string(got) != "summary: x\ndue: 2025-01-10T00:00:00Z\n" {
+		t.Fatalf("added field: %v %q", err, got)
+	}
+}
+
+func TestRestoreTaskRevision_UnknownField(t *testing.T) {
+	r, _ := newHistoryTestRepo(t)
+	if err := r.RestoreTaskRevision("u", historyTestUUID, "", "created"); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+}
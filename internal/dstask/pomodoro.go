@@ -0,0 +1,92 @@
+package dstask
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PomodoroFile liegt neben timelog.yaml im .dstask-Repo und wird mit committet. Gespeichert werden nur
+// abgeschlossene Fokus-Einheiten, über die Task-UUID.
+const PomodoroFile = "pomodoros.yaml"
+
+// Pomodoro ist eine abgeschlossene Fokus-Einheit an einem Task.
+type Pomodoro struct {
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end"`
+	User  string    `yaml:"user,omitempty"`
+}
+
+// Duration liefert die Länge der Fokus-Einheit.
+func (p Pomodoro) Duration() time.Duration {
+	if p.End.Before(p.Start) {
+		return 0
+	}
+	return p.End.Sub(p.Start)
+}
+
+type pomodoroDoc struct {
+	Version int                   `yaml:"version"`
+	Tasks   map[string][]Pomodoro `yaml:"tasks"` // UUID -> Einheiten (chronologisch)
+}
+
+// Pomodoros liefert die abgeschlossenen Einheiten (UUID -> Einheiten); leer, wenn es die Datei nicht gibt.
+func (r *Runner) Pomodoros(username string) (map[string][]Pomodoro, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	return readPomodoros(filepath.Join(dir, PomodoroFile))
+}
+
+func readPomodoros(path string) (map[string][]Pomodoro, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]Pomodoro{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc pomodoroDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Tasks == nil {
+		doc.Tasks = map[string][]Pomodoro{}
+	}
+	return doc.Tasks, nil
+}
+
+// AddPomodoro hängt eine abgeschlossene Einheit an den Task uuid an und committet pomodoros.yaml.
+func (r *Runner) AddPomodoro(username, uuid string, p Pomodoro) error {
+	uuid = strings.ToLower(strings.TrimSpace(uuid))
+	if !LooksLikeUUID(uuid) {
+		return fmt.Errorf("invalid task uuid %q", uuid)
+	}
+	defer r.LockRepo(username)()
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, PomodoroFile)
+	all, err := readPomodoros(path)
+	if err != nil {
+		return err
+	}
+	p.Start = p.Start.UTC().Truncate(time.Second)
+	p.End = p.End.UTC().Truncate(time.Second)
+	all[uuid] = append(all[uuid], p)
+	out, err := yaml.Marshal(pomodoroDoc{Version: 1, Tasks: all})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return err
+	}
+	return r.commitTaskFiles(username, dir, "dstask-ui: pomodoro completed", PomodoroFile)
+}
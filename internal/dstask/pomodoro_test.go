@@ -0,0 +1,34 @@
+package dstask
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddPomodoro_AppendsAndCommits(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	t0 := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	if err := r.AddPomodoro("u", "not-a-uuid", Pomodoro{Start: t0, End: t0.Add(25 * time.Minute)}); err == nil {
+		t.Fatal("expected an error for an invalid uuid")
+	}
+	for i := 0; i < 2; i++ {
+		start := t0.Add(time.Duration(i) * 30 * time.Minute)
+		if err := r.AddPomodoro("u", strings.ToUpper(historyTestUUID), Pomodoro{Start: start, End: start.Add(25 * time.Minute), User: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg, err := r.gitOutput("u", repo, "log", "-1", "--format=%s")
+	if err != nil || !strings.Contains(msg, "pomodoro completed") {
+		t.Fatalf("pomodoros.yaml not committed: %q %v", msg, err)
+	}
+	all, err := r.Pomodoros("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := all[historyTestUUID]
+	if len(ps) != 2 || ps[1].User != "alice" || ps[1].Duration() != 25*time.Minute || !ps[1].Start.Equal(t0.Add(30*time.Minute)) {
+		t.Fatalf("unexpected pomodoros: %+v", ps)
+	}
+}
@@ -0,0 +1,256 @@
+package dstask
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RecurrenceFile liegt wie dependencies.yaml im .dstask-Repo und wird mit committet, damit der
+// Stand (zuletzt erzeugte Instanz) mit dem Repo synchronisiert wird.
+const RecurrenceFile = "recurrence.yaml"
+
+// Wiederholungsarten einer RecurrenceRule.
+const (
+	RecurDaily     = "daily"
+	RecurWeekly    = "weekly"
+	RecurMonthly   = "monthly"
+	RecurAfterDone = "after" // Every Tage nach Erledigung der vorigen Instanz
+)
+
+// DateLayout ist das Datumsformat von Start/Last.
+const DateLayout = "2006-01-02"
+
+// RecurrenceRule beschreibt, wann eine neue Instanz eines Templates (add template:<id>) bzw.
+// eine Kopie eines Tasks angelegt wird.
+type RecurrenceRule struct {
+	Template string   `yaml:"template,omitempty"` // Template-ID
+	Task     string   `yaml:"task,omitempty"`     // alternativ: UUID des zu kopierenden Tasks
+	Freq     string   `yaml:"freq"`
+	Weekdays []string `yaml:"weekdays,omitempty"` // weekly: mon … sun
+	Day      int      `yaml:"day,omitempty"`      // monthly: Tag im Monat; in kürzeren Monaten der letzte Tag
+	Every    int      `yaml:"every,omitempty"`    // after: Tage nach Erledigung
+	Start    string   `yaml:"start"`              // frühestes Fälligkeitsdatum
+	Last     string   `yaml:"last,omitempty"`     // Fälligkeit der zuletzt erzeugten Instanz
+	LastUUID string   `yaml:"lastUuid,omitempty"` // UUID der zuletzt erzeugten Instanz
+}
+
+type recurrenceDoc struct {
+	Version int                       `yaml:"version"`
+	Rules   map[string]RecurrenceRule `yaml:"rules"` // RecurrenceKey -> Regel
+}
+
+// RecurrenceKey bildet den Schlüssel einer Regel: "template:<id>" oder "task:<uuid>".
+func RecurrenceKey(templateID, taskUUID string) string {
+	if templateID != "" {
+		return "template:" + templateID
+	}
+	return "task:" + strings.ToLower(taskUUID)
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// WeekdayNames liefert die Kürzel der Wochentage in der Reihenfolge Montag bis Sonntag.
+func WeekdayNames() []string {
+	return append(append([]string{}, weekdayNames[1:]...), weekdayNames[0])
+}
+
+// Validate prüft die Regel auf Vollständigkeit.
+func (r RecurrenceRule) Validate() error {
+	if (r.Template == "") == (r.Task == "") {
+		return errors.New("either a template or a task is required")
+	}
+	if r.Task != "" && !LooksLikeUUID(r.Task) {
+		return errors.New("invalid task uuid")
+	}
+	if _, err := time.ParseInLocation(DateLayout, r.Start, time.Local); err != nil {
+		return errors.New("invalid start date")
+	}
+	switch r.Freq {
+	case RecurDaily:
+	case RecurWeekly:
+		if len(r.Weekdays) == 0 {
+			return errors.New("weekly rules need at least one weekday")
+		}
+		for _, d := range r.Weekdays {
+			if weekdayIndex(d) < 0 {
+				return errors.New("unknown weekday: " + d)
+			}
+		}
+	case RecurMonthly:
+		if r.Day < 1 || r.Day > 31 {
+			return errors.New("day of month must be between 1 and 31")
+		}
+	case RecurAfterDone:
+		if r.Every < 1 {
+			return errors.New("number of days after completion must be at least 1")
+		}
+	default:
+		return errors.New("unknown frequency: " + r.Freq)
+	}
+	return nil
+}
+
+func weekdayIndex(name string) int {
+	for i, n := range weekdayNames {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Describe liefert eine kurze Beschreibung wie "weekly on mon, thu".
+func (r RecurrenceRule) Describe() string {
+	switch r.Freq {
+	case RecurDaily:
+		return "daily"
+	case RecurWeekly:
+		return "weekly on " + strings.Join(r.Weekdays, ", ")
+	case RecurMonthly:
+		return fmt.Sprintf("monthly on day %d", r.Day)
+	case RecurAfterDone:
+		return fmt.Sprintf("%d day(s) after completion", r.Every)
+	}
+	return r.Freq
+}
+
+// Calendar meldet, ob die Regel an feste Kalendertage gebunden ist (alles außer RecurAfterDone).
+func (r RecurrenceRule) Calendar() bool {
+	return r.Freq != RecurAfterDone
+}
+
+// matches prüft, ob an Tag d eine Instanz fällig ist.
+func (r RecurrenceRule) matches(d time.Time) bool {
+	switch r.Freq {
+	case RecurDaily:
+		return true
+	case RecurWeekly:
+		for _, n := range r.Weekdays {
+			if weekdayIndex(n) == int(d.Weekday()) {
+				return true
+			}
+		}
+	case RecurMonthly:
+		last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, d.Location()).Day()
+		return d.Day() == min(r.Day, last)
+	}
+	return false
+}
+
+// base ist der Tag, nach dem die nächste Instanz gesucht wird: die letzte Instanz, frühestens der Tag vor Start.
+func (r RecurrenceRule) base() time.Time {
+	start, _ := time.ParseInLocation(DateLayout, r.Start, time.Local)
+	b := start.AddDate(0, 0, -1)
+	if last, err := time.ParseInLocation(DateLayout, r.Last, time.Local); err == nil && last.After(b) {
+		b = last
+	}
+	return b
+}
+
+// NextAfter liefert den ersten fälligen Kalendertag nach Tag d (nur für Kalenderregeln; sonst Nullzeit).
+func (r RecurrenceRule) NextAfter(d time.Time) time.Time {
+	if !r.Calendar() {
+		return time.Time{}
+	}
+	day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+	for i := 0; i < 400; i++ {
+		day = day.AddDate(0, 0, 1)
+		if r.matches(day) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+// Next liefert die Fälligkeit der nächsten Instanz einer Kalenderregel.
+func (r RecurrenceRule) Next() time.Time {
+	return r.NextAfter(r.base())
+}
+
+// Upcoming liefert die nächsten n Fälligkeiten einer Kalenderregel (Vorschau).
+func (r RecurrenceRule) Upcoming(n int) []time.Time {
+	var out []time.Time
+	for d := r.base(); len(out) < n; {
+		d = r.NextAfter(d)
+		if d.IsZero() {
+			break
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// DueOn liefert die Fälligkeit, die am Tag today anzulegen ist: die jüngste fällige, noch nicht erzeugte
+// Instanz (verpasste ältere werden übersprungen). ok ist false, wenn nichts fällig ist.
+func (r RecurrenceRule) DueOn(today time.Time) (time.Time, bool) {
+	next := r.Next()
+	if next.IsZero() || next.After(today) {
+		return time.Time{}, false
+	}
+	for {
+		after := r.NextAfter(next)
+		if after.IsZero() || after.After(today) {
+			return next, true
+		}
+		next = after
+	}
+}
+
+// RecurrenceRules liefert die Regeln aus recurrence.yaml; leer, wenn es die Datei nicht gibt.
+func (r *Runner) RecurrenceRules(username string) (map[string]RecurrenceRule, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	return readRecurrence(filepath.Join(dir, RecurrenceFile))
+}
+
+func readRecurrence(path string) (map[string]RecurrenceRule, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]RecurrenceRule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc recurrenceDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Rules == nil {
+		doc.Rules = map[string]RecurrenceRule{}
+	}
+	return doc.Rules, nil
+}
+
+// UpdateRecurrenceRules liest recurrence.yaml unter der Repo-Sperre, wendet fn an und committet das
+// Ergebnis, sofern fn true liefert.
+func (r *Runner) UpdateRecurrenceRules(username, message string, fn func(rules map[string]RecurrenceRule) bool) error {
+	defer r.LockRepo(username)()
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, RecurrenceFile)
+	rules, err := readRecurrence(path)
+	if err != nil {
+		return err
+	}
+	if !fn(rules) {
+		return nil
+	}
+	out, err := yaml.Marshal(recurrenceDoc{Version: 1, Rules: rules})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return err
+	}
+	return r.commitTaskFiles(username, dir, message, RecurrenceFile)
+}
@@ -0,0 +1,53 @@
+package dstask
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	d, _ := time.ParseInLocation(DateLayout, s, time.Local)
+	return d
+}
+
+func TestRecurrenceRule_Schedule(t *testing.T) {
+	weekly := RecurrenceRule{Template: "3", Freq: RecurWeekly, Weekdays: []string{"mon", "thu"}, Start: "2026-10-14"}
+	if err := weekly.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range weekly.Upcoming(3) {
+		got = append(got, d.Format(DateLayout))
+	}
+	if len(got) != 3 || got[0] != "2026-10-15" || got[1] != "2026-10-19" || got[2] != "2026-10-22" {
+		t.Fatalf("weekly upcoming: %v", got)
+	}
+
+	// Tag 31 fällt in kürzeren Monaten auf den Monatsletzten
+	monthly := RecurrenceRule{Template: "3", Freq: RecurMonthly, Day: 31, Start: "2027-01-01", Last: "2027-01-31"}
+	if n := monthly.Next().Format(DateLayout); n != "2027-02-28" {
+		t.Fatalf("monthly next: %s", n)
+	}
+
+	// verpasste Termine werden übersprungen, nur der jüngste wird angelegt
+	daily := RecurrenceRule{Template: "3", Freq: RecurDaily, Start: "2026-10-01", Last: "2026-10-10"}
+	if d, ok := daily.DueOn(day("2026-10-13")); !ok || d.Format(DateLayout) != "2026-10-13" {
+		t.Fatalf("daily due: %v %v", d, ok)
+	}
+	daily.Last = "2026-10-13"
+	if _, ok := daily.DueOn(day("2026-10-13")); ok {
+		t.Fatalf("instance created twice on the same day")
+	}
+
+	for _, bad := range []RecurrenceRule{
+		{Freq: RecurDaily, Start: "2026-10-01"},
+		{Template: "1", Freq: RecurWeekly, Start: "2026-10-01"},
+		{Template: "1", Freq: RecurMonthly, Day: 32, Start: "2026-10-01"},
+		{Template: "1", Freq: RecurAfterDone, Start: "2026-10-01"},
+		{Template: "1", Freq: RecurDaily, Start: "tomorrow"},
+	} {
+		if bad.Validate() == nil {
+			t.Fatalf("expected validation error for %+v", bad)
+		}
+	}
+}
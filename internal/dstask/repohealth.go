@@ -0,0 +1,163 @@
+package dstask
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DirtyFile ist ein Eintrag aus `git status --porcelain`.
+type DirtyFile struct {
+	Status string // zweistelliger XY-Code, z. B. " M", "??"
+	Path   string
+}
+
+// RepoHealth fasst den Zustand des .dstask-Repos für das /repo-Dashboard zusammen.
+type RepoHealth struct {
+	Dir       string
+	IsRepo    bool
+	RemoteURL string
+	Branch    string
+	Detached  bool
+	Upstream  string // leer, wenn kein Upstream gesetzt ist
+	Ahead     int
+	Behind    int
+	Dirty     []DirtyFile
+	// Letzter Commit auf HEAD (leer bei Repos ohne Commits)
+	LastCommit        string
+	LastCommitAuthor  string
+	LastCommitDate    time.Time
+	LastCommitSubject string
+	SizeBytes         int64 // Größe des Arbeitsverzeichnisses inkl. .git
+	GitSizeBytes      int64 // Größe von .git allein
+}
+
+// RepoHealth sammelt Branch, Upstream, Ahead/Behind, Dirty-Dateien, letzten Commit und Größe.
+// Einzelne Git-Fehler (z. B. fehlender Upstream) führen nicht zum Abbruch, sondern zu leeren Feldern.
+func (r *Runner) RepoHealth(username string) (RepoHealth, error) {
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return RepoHealth{}, err
+	}
+	h := RepoHealth{Dir: repo}
+	if fi, err := os.Stat(filepath.Join(repo, ".git")); err != nil || !fi.IsDir() {
+		return h, nil
+	}
+	h.IsRepo = true
+	h.RemoteURL, _ = r.GitRemoteURL(username)
+
+	if out, err := r.gitOutput(username, repo, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		h.Branch = strings.TrimSpace(out)
+	} else {
+		h.Detached = true
+	}
+	if out, err := r.gitOutput(username, repo, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}"); err == nil {
+		h.Upstream = strings.TrimSpace(out)
+		h.Ahead, h.Behind, _ = r.GitAheadBehind(username)
+	}
+	if files, err := r.RepoPorcelain(username); err == nil {
+		h.Dirty = files
+	}
+	if out, err := r.gitOutput(username, repo, "log", "-1", "--format=%H%x1f%an%x1f%aI%x1f%s"); err == nil {
+		if f := strings.Split(strings.TrimSpace(out), "\x1f"); len(f) == 4 {
+			h.LastCommit, h.LastCommitAuthor, h.LastCommitSubject = f[0], f[1], f[3]
+			h.LastCommitDate, _ = time.Parse(time.RFC3339, f[2])
+		}
+	}
+	h.GitSizeBytes = dirSize(filepath.Join(repo, ".git"))
+	h.SizeBytes = dirSize(repo)
+	return h, nil
+}
+
+// ShortCommit liefert den gekürzten Hash des letzten Commits.
+func (h RepoHealth) ShortCommit() string {
+	if len(h.LastCommit) > 8 {
+		return h.LastCommit[:8]
+	}
+	return h.LastCommit
+}
+
+// RepoPorcelain liefert die Einträge von `git status --porcelain` (dieselbe Quelle wie IsRepoDirty).
+func (r *Runner) RepoPorcelain(username string) ([]DirtyFile, error) {
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.gitOutput(username, repo, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	return parsePorcelain(out), nil
+}
+
+func parsePorcelain(out string) []DirtyFile {
+	var files []DirtyFile
+	for _, l := range strings.Split(out, "\n") {
+		if len(l) < 4 {
+			continue
+		}
+		p := l[3:]
+		if unq, err := strconv.Unquote(p); err == nil && strings.HasPrefix(p, `"`) {
+			p = unq
+		}
+		files = append(files, DirtyFile{Status: l[:2], Path: p})
+	}
+	return files
+}
+
+// CommitStrayFiles committet alle nicht committeten Änderungen im Repo (git add -A).
+func (r *Runner) CommitStrayFiles(username string) (int, error) {
+	defer r.LockRepo(username)()
+	files, err := r.RepoPorcelain(username)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.commitTaskFiles(username, repo, "dstask-ui: commit stray files"); err != nil {
+		return 0, err
+	}
+	return len(files), nil
+}
+
+// GitGC führt `git gc` im Repo aus und liefert die Größe von .git vorher und nachher.
+func (r *Runner) GitGC(username string) (before, after int64, err error) {
+	defer r.LockRepo(username)()
+	repo, err := r.RepoDirForUser(username)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git")); err != nil {
+		return 0, 0, errors.New("no Git repository present")
+	}
+	before = dirSize(filepath.Join(repo, ".git"))
+	if _, err := r.gitOutput(username, repo, "gc", "--quiet"); err != nil {
+		return before, before, err
+	}
+	return before, dirSize(filepath.Join(repo, ".git")), nil
+}
+
+func dirSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				total += fi.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
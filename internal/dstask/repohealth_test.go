@@ -0,0 +1,57 @@
+package dstask
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoHealth_DirtyFilesAndCommitStray(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "pending", "stray file.yml"), []byte("summary: x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := r.RepoHealth("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.IsRepo || h.Detached || h.Branch == "" {
+		t.Fatalf("unexpected repo state: %+v", h)
+	}
+	if h.Upstream != "" {
+		t.Fatalf("expected no upstream, got %q", h.Upstream)
+	}
+	if h.LastCommit == "" || h.LastCommitAuthor == "" || h.LastCommitDate.IsZero() {
+		t.Fatalf("last commit not parsed: %+v", h)
+	}
+	if h.SizeBytes <= 0 || h.GitSizeBytes <= 0 || h.GitSizeBytes > h.SizeBytes {
+		t.Fatalf("unexpected sizes: %d / %d", h.SizeBytes, h.GitSizeBytes)
+	}
+	if len(h.Dirty) != 1 || h.Dirty[0].Status != "??" || h.Dirty[0].Path != "pending/stray file.yml" {
+		t.Fatalf("unexpected dirty files: %+v", h.Dirty)
+	}
+
+	n, err := r.CommitStrayFiles("u")
+	if err != nil || n != 1 {
+		t.Fatalf("CommitStrayFiles = %d, %v", n, err)
+	}
+	if dirty, err := IsRepoDirty(r.cfg, "u"); err != nil || dirty {
+		t.Fatalf("repo still dirty after commit: %v %v", dirty, err)
+	}
+	if _, _, err := r.GitGC("u"); err != nil {
+		t.Fatalf("GitGC: %v", err)
+	}
+}
+
+func TestRepoHealth_DetachedHead(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	runGit(t, repo, nil, "checkout", "-q", "--detach", "HEAD~1")
+	h, err := r.RepoHealth("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Detached || h.Branch != "" {
+		t.Fatalf("expected detached HEAD, got %+v", h)
+	}
+}
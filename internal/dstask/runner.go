@@ -8,15 +8,22 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/gitauth"
 	applog "github.com/elpatron68/dstask-ui/internal/log"
 	"gopkg.in/yaml.v3"
 )
 
 type Runner struct {
 	cfg *config.Config
+	// repoLocks: ein Mutex pro .dstask-Verzeichnis, damit Nutzer, die sich ein Repo teilen,
+	// nicht gleichzeitig dstask/git darin ausführen.
+	repoLocks sync.Map
+	// activity: *activityCache pro .dstask-Verzeichnis (Aktivitäts-Feed)
+	activity sync.Map
 }
 
 func NewRunner(cfg *config.Config) *Runner {
@@ -33,6 +40,7 @@ type Result struct {
 
 // RunWithStdin führt dstask mit gegebenen Argumenten und stdin-Input aus.
 func (r *Runner) RunWithStdin(username string, timeout time.Duration, stdin string, args ...string) Result {
+	defer r.LockRepo(username)()
 	bin := r.cfg.DstaskBin
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -64,7 +72,7 @@ func (r *Runner) RunWithStdin(username string, timeout time.Duration, stdin stri
 		}
 		cmd.Dir = home
 	}
-	cmd.Env = env
+	cmd.Env = r.withGitIdentity(username, r.withDstaskAuth(username, env, args))
 
 	// Set stdin
 	stdinReader := strings.NewReader(stdin)
@@ -102,6 +110,7 @@ func (r *Runner) RunWithStdin(username string, timeout time.Duration, stdin stri
 // Run führt dstask mit gegebenen Argumenten für einen Benutzer aus.
 // timeout bestimmt die maximale Laufzeit.
 func (r *Runner) Run(username string, timeout time.Duration, args ...string) Result {
+	defer r.LockRepo(username)()
 	bin := r.cfg.DstaskBin
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -135,7 +144,7 @@ func (r *Runner) Run(username string, timeout time.Duration, args ...string) Res
 		// Arbeitsverzeichnis optional auf HOME setzen
 		cmd.Dir = home
 	}
-	cmd.Env = env
+	cmd.Env = r.withGitIdentity(username, r.withDstaskAuth(username, env, args))
 
 	var outBuf, errBuf bytes.Buffer
 	cmd.Stdout = &outBuf
@@ -272,84 +281,25 @@ func decodeTasksJSONFlexible(raw string) ([]map[string]any, bool) {
 // UpdateTaskNotesDirectly aktualisiert die Notes eines Tasks, indem die YAML-Datei direkt bearbeitet wird.
 // Dies umgeht das Problem, dass dstask note einen interaktiven Editor öffnet.
 func (r *Runner) UpdateTaskNotesDirectly(username string, taskID string, notes string) error {
-	// 1. Hole UUID des Tasks (mit längerem Timeout)
-	res := r.Run(username, 10*time.Second, taskID)
-	if res.Err != nil {
-		applog.Warnf("UpdateTaskNotesDirectly: failed to get task %s: %v (timeout=%v)", taskID, res.Err, res.TimedOut)
-		return res.Err
-	}
-	if res.ExitCode != 0 {
-		applog.Warnf("UpdateTaskNotesDirectly: dstask %s returned exit code %d, stderr=%q", taskID, res.ExitCode, truncate(res.Stderr, 200))
-		return context.DeadlineExceeded
-	}
-	if res.TimedOut {
-		applog.Warnf("UpdateTaskNotesDirectly: dstask %s timed out", taskID)
-		return context.DeadlineExceeded
-	}
-
-	applog.Debugf("UpdateTaskNotesDirectly: dstask %s stdout (first 200 chars): %q", taskID, truncate(res.Stdout, 200))
-	tasks, ok := decodeTasksJSONFlexible(res.Stdout)
-	if !ok || len(tasks) == 0 {
-		applog.Warnf("UpdateTaskNotesDirectly: failed to parse JSON from dstask %s output", taskID)
-		return context.DeadlineExceeded
-	}
-
-	var taskUUID string
-	for _, t := range tasks {
-		if str(firstOf(t, "id", "ID", "Id")) == taskID {
-			taskUUID = str(firstOf(t, "uuid", "UUID"))
-			break
-		}
-	}
-	if taskUUID == "" {
-		applog.Warnf("UpdateTaskNotesDirectly: task %s UUID not found in response", taskID)
-		return context.DeadlineExceeded
+	// 1. Hole UUID des Tasks
+	taskUUID, err := r.ResolveTaskUUID(username, taskID)
+	if err != nil {
+		return err
 	}
-	applog.Debugf("UpdateTaskNotesDirectly: found UUID %s for task %s", taskUUID, taskID)
 
 	// 2. Finde YAML-Datei in .dstask Verzeichnis
-	home, ok := config.ResolveHomeForUsername(r.cfg, username)
-	if !ok || home == "" {
-		// Fallback: Versuche, das Home-Verzeichnis aus dem letzten erfolgreichen dstask-Aufruf zu bestimmen
-		// Wir können auch versuchen, es aus der Umgebungsvariable zu holen, aber das ist weniger zuverlässig
-		applog.Warnf("UpdateTaskNotesDirectly: failed to resolve home for username %s (cfg.Repos=%v), trying fallback", username, r.cfg.Repos)
-
-		// Fallback: Verwende dstask export, um zu sehen, wo dstask die Tasks speichert
-		// Oder versuche, das Home-Verzeichnis aus der Umgebungsvariable zu holen
-		if homeEnv := os.Getenv("HOME"); homeEnv != "" {
-			home = homeEnv
-		} else if homeEnv := os.Getenv("USERPROFILE"); homeEnv != "" {
-			home = homeEnv
-		} else {
-			applog.Warnf("UpdateTaskNotesDirectly: no fallback home directory found")
-			return context.DeadlineExceeded
-		}
-		applog.Infof("UpdateTaskNotesDirectly: using fallback home directory %s", home)
-	}
-
-	dstaskDir := home
-	if !strings.HasSuffix(dstaskDir, ".dstask") {
-		dstaskDir = filepath.Join(home, ".dstask")
-	}
-	applog.Debugf("UpdateTaskNotesDirectly: using dstask directory %s", dstaskDir)
-
-	// Suche in allen Status-Ordnern
-	statusDirs := []string{"active", "pending", "paused", "resolved"}
-	var yamlPath string
-	for _, status := range statusDirs {
-		candidate := filepath.Join(dstaskDir, status, taskUUID+".yml")
-		if _, err := os.Stat(candidate); err == nil {
-			yamlPath = candidate
-			applog.Debugf("UpdateTaskNotesDirectly: found YAML file at %s", yamlPath)
-			break
-		}
+	dstaskDir, err := r.taskRepoDir(username)
+	if err != nil {
+		return err
 	}
-
+	yamlPath := findTaskFile(dstaskDir, taskUUID)
 	if yamlPath == "" {
 		applog.Warnf("UpdateTaskNotesDirectly: YAML file not found for task %s (UUID: %s) in %s", taskID, taskUUID, dstaskDir)
 		return context.DeadlineExceeded
 	}
 
+	defer r.LockRepo(username)()
+
 	// 3. Lade YAML-Datei
 	data, err := os.ReadFile(yamlPath)
 	if err != nil {
@@ -376,12 +326,116 @@ func (r *Runner) UpdateTaskNotesDirectly(username string, taskID string, notes s
 	}
 
 	// 7. Führe git add und commit direkt aus (nicht über dstask git)
-	// Wir müssen im .dstask Verzeichnis arbeiten
 	relPath, err := filepath.Rel(dstaskDir, yamlPath)
 	if err != nil {
 		relPath = yamlPath // Fallback to absolute path if relative fails
 	}
-	gitCmd := exec.CommandContext(context.Background(), "git", "-C", dstaskDir, "add", relPath)
+	r.commitTaskFiles(username, dstaskDir, "Update task notes via web UI", relPath)
+
+	applog.Infof("UpdateTaskNotesDirectly: successfully updated notes for task %s (UUID: %s)", taskID, taskUUID)
+	return nil
+}
+
+// ResolveTaskUUID liefert die UUID zu einer Task-ID über `dstask <id>`.
+// Ist taskID bereits eine UUID, wird sie unverändert zurückgegeben.
+func (r *Runner) ResolveTaskUUID(username string, taskID string) (string, error) {
+	if LooksLikeUUID(taskID) {
+		return strings.ToLower(taskID), nil
+	}
+	res := r.Run(username, 10*time.Second, taskID)
+	if res.Err != nil {
+		applog.Warnf("ResolveTaskUUID: failed to get task %s: %v (timeout=%v)", taskID, res.Err, res.TimedOut)
+		return "", res.Err
+	}
+	if res.ExitCode != 0 {
+		applog.Warnf("ResolveTaskUUID: dstask %s returned exit code %d, stderr=%q", taskID, res.ExitCode, truncate(res.Stderr, 200))
+		return "", context.DeadlineExceeded
+	}
+	if res.TimedOut {
+		applog.Warnf("ResolveTaskUUID: dstask %s timed out", taskID)
+		return "", context.DeadlineExceeded
+	}
+
+	applog.Debugf("ResolveTaskUUID: dstask %s stdout (first 200 chars): %q", taskID, truncate(res.Stdout, 200))
+	tasks, ok := decodeTasksJSONFlexible(res.Stdout)
+	if !ok || len(tasks) == 0 {
+		applog.Warnf("ResolveTaskUUID: failed to parse JSON from dstask %s output", taskID)
+		return "", context.DeadlineExceeded
+	}
+
+	for _, t := range tasks {
+		if str(firstOf(t, "id", "ID", "Id")) == taskID {
+			if u := str(firstOf(t, "uuid", "UUID")); u != "" {
+				applog.Debugf("ResolveTaskUUID: found UUID %s for task %s", u, taskID)
+				return u, nil
+			}
+		}
+	}
+	applog.Warnf("ResolveTaskUUID: task %s UUID not found in response", taskID)
+	return "", context.DeadlineExceeded
+}
+
+// LooksLikeUUID prüft auf das kanonische 8-4-4-4-12 Hex-Format (UUIDs von dstask).
+func LooksLikeUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// taskRepoDir bestimmt das .dstask-Verzeichnis für direkte Dateizugriffe.
+// Fällt auf HOME/USERPROFILE des Prozesses zurück, wenn kein Repo konfiguriert ist.
+func (r *Runner) taskRepoDir(username string) (string, error) {
+	home, ok := config.ResolveHomeForUsername(r.cfg, username)
+	if !ok || home == "" {
+		applog.Warnf("taskRepoDir: failed to resolve home for username %s (cfg.Repos=%v), trying fallback", username, r.cfg.Repos)
+		if homeEnv := os.Getenv("HOME"); homeEnv != "" {
+			home = homeEnv
+		} else if homeEnv := os.Getenv("USERPROFILE"); homeEnv != "" {
+			home = homeEnv
+		} else {
+			applog.Warnf("taskRepoDir: no fallback home directory found")
+			return "", context.DeadlineExceeded
+		}
+		applog.Infof("taskRepoDir: using fallback home directory %s", home)
+	}
+	dstaskDir := home
+	if !strings.HasSuffix(dstaskDir, ".dstask") {
+		dstaskDir = filepath.Join(home, ".dstask")
+	}
+	applog.Debugf("taskRepoDir: using dstask directory %s", dstaskDir)
+	return dstaskDir, nil
+}
+
+// taskStatusDirs sind die Status-Ordner, in denen dstask Task-Dateien ablegt.
+var taskStatusDirs = []string{"active", "pending", "paused", "resolved"}
+
+// findTaskFile sucht <uuid>.yml in allen Status-Ordnern und liefert den absoluten Pfad oder "".
+func findTaskFile(dstaskDir, taskUUID string) string {
+	for _, status := range taskStatusDirs {
+		candidate := filepath.Join(dstaskDir, status, taskUUID+".yml")
+		if _, err := os.Stat(candidate); err == nil {
+			applog.Debugf("findTaskFile: found YAML file at %s", candidate)
+			return candidate
+		}
+	}
+	return ""
+}
+
+// gitEnvForUser liefert die Prozessumgebung mit HOME des Nutzers für direkte git-Aufrufe.
+func (r *Runner) gitEnvForUser(username string) []string {
 	env := os.Environ()
 	if home, ok := config.ResolveHomeForUsername(r.cfg, username); ok && home != "" {
 		replacedHome := false
@@ -396,20 +450,96 @@ func (r *Runner) UpdateTaskNotesDirectly(username string, taskID string, notes s
 			env = append(env, "HOME="+home)
 		}
 	}
+	return r.withGitIdentity(username, r.withGitAuth(username, env))
+}
+
+// withGitIdentity setzt Autor und Committer auf die Identität des Nutzers (config.GitIdentity),
+// damit Commits in geteilten Repos dem richtigen Nutzer zugeordnet werden.
+func (r *Runner) withGitIdentity(username string, env []string) []string {
+	name, email := config.GitIdentity(r.cfg, username)
+	if name == "" {
+		return env
+	}
+	env = setEnv(env, "GIT_AUTHOR_NAME", name)
+	env = setEnv(env, "GIT_COMMITTER_NAME", name)
+	env = setEnv(env, "GIT_AUTHOR_EMAIL", email)
+	return setEnv(env, "GIT_COMMITTER_EMAIL", email)
+}
+
+// LockRepo sperrt das Repo-Verzeichnis des Nutzers und liefert die Freigabefunktion. Die Sperre gilt pro Verzeichnis, also über alle Nutzer eines geteilten Repos hinweg.
+// Run/RunWithStdin und die direkten Schreiboperationen (Restore, Konflikte, Backup, …) sperren selbst;
+// sie dürfen nicht mit gehaltener Sperre aufgerufen werden.
+func (r *Runner) LockRepo(username string) func() {
+	dir, ok := config.ResolveHomeForUsername(r.cfg, username)
+	if !ok || dir == "" {
+		dir = "\x00" + username
+	} else if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	mu, _ := r.repoLocks.LoadOrStore(dir, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// withGitAuth ergänzt env um die Git-Zugangsdaten des Nutzers: SSH-Deploy-Key via GIT_SSH_COMMAND und
+// HTTPS-Token über den eingebauten credential helper. Alle Umgebungen für dstask und git laufen hier durch,
+// damit Sync, Clone und Push dieselben Credentials nutzen.
+// Zugangsdaten gehören zum Nutzer und gelten für alle seine Repos.
+func (r *Runner) withGitAuth(username string, env []string) []string {
+	user, _ := config.SplitRepoKey(username)
+	if cmd := gitauth.SSHCommand(r.cfg, user); cmd != "" {
+		env = setEnv(env, "GIT_SSH_COMMAND", cmd)
+	}
+	for _, kv := range gitauth.HelperEnv(r.cfg, user, env) {
+		k, v, _ := strings.Cut(kv, "=")
+		env = setEnv(env, k, v)
+	}
+	return env
+}
+
+// withDstaskAuth ergänzt die Zugangsdaten nur für dstask-Befehle, die das Remote erreichen (sync);
+// alle anderen Befehle und ihre git-Hooks bekommen weder Deploy-Key noch credential helper.
+func (r *Runner) withDstaskAuth(username string, env, args []string) []string {
+	if len(args) > 0 && args[0] == "sync" {
+		return r.withGitAuth(username, env)
+	}
+	return env
+}
+
+// setEnv ersetzt oder ergänzt key=value in env.
+func setEnv(env []string, key, value string) []string {
+	prefix := key + "="
+	for i, e := range env {
+		if strings.HasPrefix(e, prefix) {
+			env[i] = prefix + value
+			return env
+		}
+	}
+	return append(env, prefix+value)
+}
+
+// commitTaskFiles staged die angegebenen Pfade (inkl. Löschungen) und committet sie direkt mit git.
+// Der Aufrufer hält die Repo-Sperre (LockRepo).
+// Fehler werden geloggt und zurückgegeben; "nothing to commit" ist kein Fehler.
+func (r *Runner) commitTaskFiles(username, dstaskDir, message string, relPaths ...string) error {
+	env := r.gitEnvForUser(username)
+	addArgs := append([]string{"-C", dstaskDir, "add", "-A", "--"}, relPaths...)
+	gitCmd := exec.CommandContext(context.Background(), "git", addArgs...)
 	gitCmd.Env = env
-	if err := gitCmd.Run(); err != nil {
-		applog.Warnf("git add failed for notes update: %v", err)
-		// Continue anyway - dstask might handle this
+	if out, err := gitCmd.CombinedOutput(); err != nil {
+		applog.Warnf("git add failed (%s): %v: %s", message, err, truncate(string(out), 200))
+		return err
 	}
 
-	gitCommitCmd := exec.CommandContext(context.Background(), "git", "-C", dstaskDir, "commit", "-m", "Update task notes via web UI")
+	gitCommitCmd := exec.CommandContext(context.Background(), "git", "-C", dstaskDir, "commit", "-m", message)
 	gitCommitCmd.Env = env
-	if err := gitCommitCmd.Run(); err != nil {
-		applog.Warnf("git commit failed for notes update: %v", err)
-		// Continue anyway - might be no changes or other git issues
+	if out, err := gitCommitCmd.CombinedOutput(); err != nil {
+		if strings.Contains(string(out), "nothing to commit") || strings.Contains(string(out), "nothing added to commit") {
+			return nil
+		}
+		applog.Warnf("git commit failed (%s): %v: %s", message, err, truncate(string(out), 200))
+		return err
 	}
-
-	applog.Infof("UpdateTaskNotesDirectly: successfully updated notes for task %s (UUID: %s)", taskID, taskUUID)
 	return nil
 }
 
@@ -1,6 +1,10 @@
 package dstask
 
-import "testing"
+import (
+    "strings"
+    "testing"
+    "time"
+)
 
 func TestNormalizeNewlines(t *testing.T) {
     in := "a\r\nb\rc\n"
@@ -17,4 +21,36 @@ func TestTruncate(t *testing.T) {
     if truncate("helloworld", 5) != "hello..." { t.Fatalf("truncate short") }
 }
 
+func TestSharedRepo_LockAndAuthor(t *testing.T) {
+    r, repo := newHistoryTestRepo(t)
+    r.cfg.Repos["v"] = repo // gleiches Repo, einmal als HOME, einmal als .dstask angegeben
 
+    unlock := r.LockRepo("u")
+    acquired := make(chan struct{})
+    go func() {
+        r.LockRepo("v")()
+        close(acquired)
+    }()
+    select {
+    case <-acquired:
+        t.Fatalf("lock for shared repo acquired twice")
+    case <-time.After(50 * time.Millisecond):
+    }
+    unlock()
+    select {
+    case <-acquired:
+    case <-time.After(2 * time.Second):
+        t.Fatalf("lock not released")
+    }
+
+    first, err := r.gitOutput("v", repo, "rev-list", "--max-parents=0", "HEAD")
+    if err != nil { t.Fatal(err) }
+    if err := r.RestoreTaskRevision("v", historyTestUUID, strings.TrimSpace(first), "due"); err != nil {
+        t.Fatalf("RestoreTaskRevision: %v", err)
+    }
+    author, err := r.gitOutput("v", repo, "log", "-1", "--format=%an <%ae>|%cn")
+    if err != nil { t.Fatal(err) }
+    if strings.TrimSpace(author) != "v <v@dstask-ui.local>|v" {
+        t.Fatalf("unexpected author: %q", author)
+    }
+}
@@ -0,0 +1,139 @@
+package dstask
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TimeLogFile liegt wie music-map.yaml im .dstask-Repo und wird mit committet, damit erfasste Zeiten
+// mit dem Repo synchronisiert werden. Intervalle werden über UUIDs gespeichert.
+const TimeLogFile = "timelog.yaml"
+
+// TimeInterval ist ein Arbeitsintervall zwischen start und stop eines Tasks.
+type TimeInterval struct {
+	Start time.Time `yaml:"start"`
+	End   time.Time `yaml:"end,omitempty"`  // Nullwert = läuft noch
+	User  string    `yaml:"user,omitempty"` // wer den Task gestartet hat
+}
+
+// Running meldet, ob das Intervall noch offen ist.
+func (iv TimeInterval) Running() bool { return iv.End.IsZero() }
+
+// Duration liefert die Dauer; offene Intervalle zählen bis now.
+func (iv TimeInterval) Duration(now time.Time) time.Duration {
+	end := iv.End
+	if end.IsZero() {
+		end = now
+	}
+	if end.Before(iv.Start) {
+		return 0
+	}
+	return end.Sub(iv.Start)
+}
+
+// TrackedTime summiert die Intervalle eines Tasks.
+func TrackedTime(ivs []TimeInterval, now time.Time) time.Duration {
+	var d time.Duration
+	for _, iv := range ivs {
+		d += iv.Duration(now)
+	}
+	return d
+}
+
+type timeLogDoc struct {
+	Version int                       `yaml:"version"`
+	Tasks   map[string][]TimeInterval `yaml:"tasks"` // UUID -> Intervalle (chronologisch)
+}
+
+// TimeLog liefert die erfassten Intervalle (UUID -> Intervalle); leer, wenn es die Datei nicht gibt.
+func (r *Runner) TimeLog(username string) (map[string][]TimeInterval, error) {
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return nil, err
+	}
+	return readTimeLog(filepath.Join(dir, TimeLogFile))
+}
+
+func readTimeLog(path string) (map[string][]TimeInterval, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]TimeInterval{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc timeLogDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Tasks == nil {
+		doc.Tasks = map[string][]TimeInterval{}
+	}
+	return doc.Tasks, nil
+}
+
+// SyncTimers gleicht die offenen Intervalle mit den aktiven Tasks ab: für aktive Tasks ohne offenes
+// Intervall wird eines ab at begonnen, offene Intervalle nicht mehr aktiver Tasks (gestoppt, erledigt,
+// gelöscht) werden bei at beendet. Geändert wird nur bei Bedarf; dann wird timelog.yaml committet.
+// Liefert die Anzahl begonnener und beendeter Intervalle.
+func (r *Runner) SyncTimers(username, user string, active []string, at time.Time) (started, stopped int, err error) {
+	isActive := map[string]bool{}
+	for _, u := range active {
+		if u = strings.ToLower(strings.TrimSpace(u)); LooksLikeUUID(u) {
+			isActive[u] = true
+		}
+	}
+	defer r.LockRepo(username)()
+	dir, err := r.taskRepoDir(username)
+	if err != nil {
+		return 0, 0, err
+	}
+	path := filepath.Join(dir, TimeLogFile)
+	log, err := readTimeLog(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	at = at.UTC().Truncate(time.Second)
+	for uuid, ivs := range log {
+		if n := len(ivs); n > 0 && ivs[n-1].Running() && !isActive[uuid] {
+			ivs[n-1].End = at
+			stopped++
+		}
+	}
+	uuids := make([]string, 0, len(isActive))
+	for u := range isActive {
+		uuids = append(uuids, u)
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		ivs := log[uuid]
+		if n := len(ivs); n == 0 || !ivs[n-1].Running() {
+			log[uuid] = append(ivs, TimeInterval{Start: at, User: user})
+			started++
+		}
+	}
+	if started+stopped == 0 {
+		return 0, 0, nil
+	}
+	out, err := yaml.Marshal(timeLogDoc{Version: 1, Tasks: log})
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return 0, 0, err
+	}
+	msg := "dstask-ui: time log"
+	switch {
+	case started > 0 && stopped == 0:
+		msg += " (started)"
+	case stopped > 0 && started == 0:
+		msg += " (stopped)"
+	}
+	return started, stopped, r.commitTaskFiles(username, dir, msg, TimeLogFile)
+}
@@ -0,0 +1,41 @@
+package dstask
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncTimers_OpensAndClosesIntervals(t *testing.T) {
+	r, repo := newHistoryTestRepo(t)
+	t0 := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	started, stopped, err := r.SyncTimers("u", "alice", []string{strings.ToUpper(historyTestUUID)}, t0)
+	if err != nil || started != 1 || stopped != 0 {
+		t.Fatalf("start: %d %d %v", started, stopped, err)
+	}
+	// erneuter Abgleich ohne Änderung: kein zweites Intervall, kein Commit
+	if started, stopped, err := r.SyncTimers("u", "alice", []string{historyTestUUID}, t0.Add(time.Minute)); started+stopped != 0 || err != nil {
+		t.Fatalf("expected no change: %d %d %v", started, stopped, err)
+	}
+	if _, stopped, err := r.SyncTimers("u", "alice", nil, t0.Add(90*time.Minute)); stopped != 1 || err != nil {
+		t.Fatalf("stop: %d %v", stopped, err)
+	}
+	msg, err := r.gitOutput("u", repo, "log", "-1", "--format=%s")
+	if err != nil || !strings.Contains(msg, "time log (stopped)") {
+		t.Fatalf("timelog.yaml not committed: %q %v", msg, err)
+	}
+
+	log, err := r.TimeLog("u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ivs := log[historyTestUUID]
+	if len(ivs) != 1 || ivs[0].User != "alice" || ivs[0].Running() || TrackedTime(ivs, t0.Add(5*time.Hour)) != 90*time.Minute {
+		t.Fatalf("unexpected intervals: %+v", ivs)
+	}
+	running := TimeInterval{Start: t0}
+	if got := TrackedTime(append(ivs, running), t0.Add(2*time.Hour)); got != 210*time.Minute {
+		t.Fatalf("running interval not counted up to now: %s", got)
+	}
+}
@@ -0,0 +1,165 @@
+package gitauth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// helperTicketTTL begrenzt, wie lange ein Ticket aus HelperEnv gilt (länger als jeder git-/dstask-Aufruf).
+const helperTicketTTL = 30 * time.Minute
+
+// helperTicket berechtigt den credential helper eines git-Aufrufs, die Zugangsdaten eines Nutzers abzufragen.
+type helperTicket struct {
+	cfg      *config.Config
+	username string
+	expires  time.Time
+}
+
+// credentialBroker beantwortet die Anfragen des credential helpers über einen Unix-Socket in einem
+// privaten Temp-Verzeichnis (0700). Entschlüsselt wird nur im Serverprozess: in der Umgebung der
+// git-/dstask-Prozesse stehen lediglich Socket-Pfad und ein Ticket, nie der Schlüssel.
+type credentialBroker struct {
+	mu      sync.Mutex
+	path    string
+	tickets map[string]helperTicket
+}
+
+var broker = &credentialBroker{tickets: map[string]helperTicket{}}
+
+// helperRequest ist die Anfrage des Helpers (eine JSON-Zeile), helperResponse die Antwort.
+type helperRequest struct {
+	Ticket   string `json:"ticket"`
+	Protocol string `json:"protocol"`
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+}
+
+type helperResponse struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// issue startet bei Bedarf den Socket und liefert Socket-Pfad und ein neues Ticket für username.
+func (b *credentialBroker) issue(cfg *config.Config, username string) (string, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.path == "" {
+		dir, err := os.MkdirTemp("", "dstask-web-cred-")
+		if err != nil {
+			return "", "", err
+		}
+		p := filepath.Join(dir, "helper.sock")
+		ln, err := net.Listen("unix", p)
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return "", "", err
+		}
+		b.path = p
+		go b.serve(ln)
+	}
+	now := time.Now()
+	for t, tk := range b.tickets {
+		if now.After(tk.expires) {
+			delete(b.tickets, t)
+		}
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	ticket := hex.EncodeToString(raw)
+	b.tickets[ticket] = helperTicket{cfg: cfg, username: username, expires: now.Add(helperTicketTTL)}
+	return b.path, ticket, nil
+}
+
+func (b *credentialBroker) ticket(t string) (helperTicket, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tk, ok := b.tickets[t]
+	if !ok || time.Now().After(tk.expires) {
+		return helperTicket{}, false
+	}
+	return tk, true
+}
+
+func (b *credentialBroker) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			applog.Warnf("credential helper socket: %v", err)
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *credentialBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	var req helperRequest
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+	var resp helperResponse
+	if err != nil {
+		resp.Error = "invalid request"
+	} else if tk, ok := b.ticket(req.Ticket); !ok {
+		resp.Error = "invalid or expired ticket"
+	} else if user, token, err := lookupCredential(tk.cfg, tk.username, req); err != nil {
+		applog.Warnf("credential helper for %s: %v", tk.username, err)
+		resp.Error = "cannot decrypt token"
+	} else {
+		resp.Username, resp.Password = user, token
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// lookupCredential sucht die gespeicherten Zugangsdaten von username für die Anfrage; leer, wenn Host
+// oder Nutzername nicht passen.
+func lookupCredential(cfg *config.Config, username string, req helperRequest) (string, string, error) {
+	m, err := readStore(CredentialStorePath(cfg))
+	if err != nil {
+		return "", "", err
+	}
+	c, ok := m[username]
+	if !ok {
+		return "", "", nil
+	}
+	host := strings.ToLower(req.Host)
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.Contains(c.Host, ":") {
+		host = h
+	}
+	if c.Host != "" && c.Host != host {
+		return "", "", nil
+	}
+	if req.Username != "" && req.Username != c.Username {
+		return "", "", nil
+	}
+	secret, err := masterSecret(cfg)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := userKey(secret, username)
+	if err != nil {
+		return "", "", err
+	}
+	token, err := unseal(key, c.Token, credentialAAD(username, c))
+	if err != nil {
+		return "", "", errors.New("cannot decrypt token")
+	}
+	return c.Username, string(token), nil
+}
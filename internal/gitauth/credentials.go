@@ -0,0 +1,315 @@
+package gitauth
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"golang.org/x/crypto/hkdf"
+)
+
+// HelperCommand ist das Unterkommando von dstask-web, das git als credential helper aufruft.
+const HelperCommand = "git-credential"
+
+// Umgebungsvariablen, über die der Server dem Helper-Prozess Socket und Ticket übergibt (siehe credentialBroker).
+const (
+	envCredSocket = "DSTWEB_CRED_SOCKET"
+	envCredTicket = "DSTWEB_CRED_TICKET"
+)
+
+// HTTPSCredential sind HTTPS-Zugangsdaten (Nutzername + Personal Access Token) eines Nutzers.
+type HTTPSCredential struct {
+	Host     string // leer = für jeden HTTPS-Host
+	Username string
+	Token    string
+}
+
+type storedCredential struct {
+	Host     string    `json:"host"`
+	Username string    `json:"username"`
+	Token    string    `json:"token"` // base64(nonce || AES-GCM-Ciphertext)
+	Updated  time.Time `json:"updated"`
+}
+
+// CredentialStorePath liefert <dataDir>/git-credentials.json.
+func CredentialStorePath(cfg *config.Config) string {
+	return filepath.Join(config.ResolveDataDir(cfg), "git-credentials.json")
+}
+
+// masterSecret liefert das Server-Secret aus der Konfiguration (secret / DSTWEB_SECRET).
+// Ist keines gesetzt, wird einmalig ein zufälliges in <dataDir>/secret.key angelegt.
+func masterSecret(cfg *config.Config) ([]byte, error) {
+	if cfg != nil && strings.TrimSpace(cfg.Secret) != "" {
+		return []byte(cfg.Secret), nil
+	}
+	p := filepath.Join(config.ResolveDataDir(cfg), "secret.key")
+	if b, err := os.ReadFile(p); err == nil && len(b) >= 32 {
+		return b, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(p, b, 0600); err != nil {
+		return nil, err
+	}
+	applog.Warnf("no `secret` configured; generated %s to encrypt stored credentials", p)
+	return b, nil
+}
+
+// userKey leitet per HKDF-SHA256 einen eigenen AES-256-Schlüssel pro Nutzer ab.
+func userKey(secret []byte, username string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, []byte("dstask-ui"), []byte("git-credentials:"+username)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func credentialAAD(username string, c storedCredential) []byte {
+	return []byte(username + "\x00" + c.Host + "\x00" + c.Username)
+}
+
+func seal(key, plain, aad []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plain, aad)), nil
+}
+
+func unseal(key []byte, sealed string, aad []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], aad)
+}
+
+func readStore(path string) (map[string]storedCredential, error) {
+	m := map[string]storedCredential{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("credential store %s: %v", path, err)
+	}
+	return m, nil
+}
+
+func writeStore(path string, m map[string]storedCredential) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b, 0600)
+}
+
+// SaveHTTPSCredential speichert Nutzername und Token verschlüsselt (AES-256-GCM).
+func SaveHTTPSCredential(cfg *config.Config, username string, c HTTPSCredential) error {
+	if !safeUsername.MatchString(username) {
+		return fmt.Errorf("invalid username %q", username)
+	}
+	c.Host = strings.ToLower(strings.TrimSpace(c.Host))
+	c.Username = strings.TrimSpace(c.Username)
+	if c.Username == "" || c.Token == "" {
+		return errors.New("username and token are required")
+	}
+	secret, err := masterSecret(cfg)
+	if err != nil {
+		return err
+	}
+	key, err := userKey(secret, username)
+	if err != nil {
+		return err
+	}
+	sc := storedCredential{Host: c.Host, Username: c.Username, Updated: time.Now().UTC()}
+	if sc.Token, err = seal(key, []byte(c.Token), credentialAAD(username, sc)); err != nil {
+		return err
+	}
+	path := CredentialStorePath(cfg)
+	m, err := readStore(path)
+	if err != nil {
+		return err
+	}
+	m[username] = sc
+	return writeStore(path, m)
+}
+
+// DeleteHTTPSCredential entfernt die gespeicherten Zugangsdaten des Nutzers.
+func DeleteHTTPSCredential(cfg *config.Config, username string) error {
+	path := CredentialStorePath(cfg)
+	m, err := readStore(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := m[username]; !ok {
+		return nil
+	}
+	delete(m, username)
+	return writeStore(path, m)
+}
+
+// HTTPSCredentialInfo liefert Host und Nutzername der gespeicherten Zugangsdaten (ohne Token).
+func HTTPSCredentialInfo(cfg *config.Config, username string) (host, user string, updated time.Time, ok bool) {
+	m, err := readStore(CredentialStorePath(cfg))
+	if err != nil {
+		return "", "", time.Time{}, false
+	}
+	c, ok := m[username]
+	return c.Host, c.Username, c.Updated, ok
+}
+
+// HelperEnv liefert die Umgebungsvariablen, mit denen git den eingebauten credential helper nutzt;
+// nil, wenn der Nutzer keine HTTPS-Zugangsdaten gespeichert hat. Bestehende GIT_CONFIG_COUNT-Einträge
+// in env bleiben erhalten. Der Helper fragt das Token mit einem Ticket beim Server ab; Schlüssel und
+// Token stehen nie in der Umgebung.
+func HelperEnv(cfg *config.Config, username string, env []string) []string {
+	if _, _, _, ok := HTTPSCredentialInfo(cfg, username); !ok {
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		applog.Warnf("credential helper: cannot determine executable: %v", err)
+		return nil
+	}
+	socket, ticket, err := broker.issue(cfg, username)
+	if err != nil {
+		applog.Warnf("credential helper: %v", err)
+		return nil
+	}
+	n := 0
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, "GIT_CONFIG_COUNT="); ok {
+			n, _ = strconv.Atoi(v)
+		}
+	}
+	return []string{
+		// leerer Wert setzt bereits konfigurierte Helper zurück
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=credential.helper", n),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=", n),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=credential.helper", n+1),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=!%s %s", n+1, shellQuote(exe), HelperCommand),
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+2),
+		"GIT_TERMINAL_PROMPT=0",
+		envCredSocket + "=" + socket,
+		envCredTicket + "=" + ticket,
+	}
+}
+
+// RunHelper implementiert das git-credential-Protokoll (`get`; `store`/`erase` werden ignoriert).
+// args sind die Argumente nach dem Unterkommando, getenv liefert die vom Server gesetzten Variablen.
+// Die Zugangsdaten liefert der Server über den Socket aus DSTWEB_CRED_SOCKET.
+func RunHelper(args []string, stdin io.Reader, stdout io.Writer, getenv func(string) string) error {
+	if len(args) == 0 || args[0] != "get" {
+		return nil
+	}
+	req := map[string]string{}
+	sc := bufio.NewScanner(stdin)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			req[k] = v
+		}
+	}
+	if req["protocol"] != "https" && req["protocol"] != "http" {
+		return nil
+	}
+	socket, ticket := getenv(envCredSocket), getenv(envCredTicket)
+	if socket == "" || ticket == "" {
+		return errors.New("credential helper: missing or invalid environment")
+	}
+	conn, err := net.DialTimeout("unix", socket, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("credential helper: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	if err := json.NewEncoder(conn).Encode(helperRequest{Ticket: ticket, Protocol: req["protocol"], Host: req["host"], Username: req["username"]}); err != nil {
+		return fmt.Errorf("credential helper: %v", err)
+	}
+	var resp helperResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("credential helper: %v", err)
+	}
+	if resp.Error != "" {
+		return errors.New("credential helper: " + resp.Error)
+	}
+	if resp.Password == "" {
+		return nil
+	}
+	_, err = fmt.Fprintf(stdout, "username=%s\npassword=%s\n\n", resp.Username, resp.Password)
+	return err
+}
+
+// TokenOnlyUsername ist der Nutzername für Tokens, die ohne Nutzer in der URL stehen (https://<token>@host).
+// GitHub und GitLab akzeptieren bei Personal Access Tokens einen beliebigen Nutzernamen.
+const TokenOnlyUsername = "x-access-token"
+
+// SplitURLCredentials entfernt Nutzer/Passwort aus einer HTTP(S)-URL und liefert sie getrennt zurück,
+// damit Tokens nicht in .git/config landen. Ein Nutzerteil ohne Passwort (https://ghp_…@github.com)
+// gilt als Token (Nutzer TokenOnlyUsername), ebenso ein Passwort ohne Nutzer. Andere URLs bleiben unverändert.
+func SplitURLCredentials(raw string) (clean, user, password string) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User == nil {
+		return raw, "", ""
+	}
+	user = u.User.Username()
+	password, _ = u.User.Password()
+	switch {
+	case password == "":
+		user, password = TokenOnlyUsername, user
+	case user == "":
+		user = TokenOnlyUsername
+	}
+	u.User = nil
+	return u.String(), user, password
+}
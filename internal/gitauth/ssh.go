@@ -0,0 +1,276 @@
+// Package gitauth verwaltet Zugangsdaten, mit denen git das Remote des .dstask-Repos erreicht.
+package gitauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+const keyFileName = "id_ed25519"
+
+var safeUsername = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// ErrNoKey wird geliefert, wenn für den Nutzer noch kein SSH-Key erzeugt wurde.
+var ErrNoKey = errors.New("no SSH key for this user")
+
+// sshDir liefert <dataDir>/ssh.
+func sshDir(cfg *config.Config) string {
+	return filepath.Join(config.ResolveDataDir(cfg), "ssh")
+}
+
+// KeyPath liefert den Pfad des privaten Schlüssels eines Nutzers.
+func KeyPath(cfg *config.Config, username string) (string, error) {
+	if !safeUsername.MatchString(username) || strings.Trim(username, ".") == "" {
+		return "", fmt.Errorf("invalid username %q", username)
+	}
+	return filepath.Join(sshDir(cfg), username, keyFileName), nil
+}
+
+// KnownHostsPath liefert die known_hosts-Datei des Nutzers (<dataDir>/ssh/<user>/known_hosts). Jeder
+// Nutzer pinnt seine Host-Keys selbst; ein von einem Nutzer gepinnter Key gilt nicht für andere.
+func KnownHostsPath(cfg *config.Config, username string) (string, error) {
+	p, err := KeyPath(cfg, username)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), "known_hosts"), nil
+}
+
+// SharedKnownHostsPath liefert die vom Betreiber gepflegte known_hosts-Datei (ssh.knownHostsFile, mit
+// "~" und Umgebungsvariablen); "", wenn keine konfiguriert ist. Die Oberfläche schreibt nie hinein.
+func SharedKnownHostsPath(cfg *config.Config) string {
+	return config.ResolveKnownHostsFile(cfg)
+}
+
+// HasKey meldet, ob für den Nutzer ein Schlüssel existiert.
+func HasKey(cfg *config.Config, username string) bool {
+	p, err := KeyPath(cfg, username)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+// GenerateKey erzeugt ein neues ed25519-Schlüsselpaar (ersetzt ein vorhandenes) und liefert
+// den öffentlichen Schlüssel im authorized_keys-Format.
+func GenerateKey(cfg *config.Config, username string) (string, error) {
+	p, err := KeyPath(cfg, username)
+	if err != nil {
+		return "", err
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	comment := "dstask-ui " + username
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		return "", err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + comment + "\n"
+
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return "", err
+	}
+	// erst in temporäre Dateien schreiben, dann umbenennen: ein halb geschriebener Key bricht sonst jeden Sync
+	if err := writeFileAtomic(p, pem.EncodeToMemory(block), 0600); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(p+".pub", []byte(authorized), 0644); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(authorized), nil
+}
+
+// PublicKey liefert den öffentlichen Schlüssel des Nutzers im authorized_keys-Format.
+func PublicKey(cfg *config.Config, username string) (string, error) {
+	p, err := KeyPath(cfg, username)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(p + ".pub")
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoKey
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Fingerprint liefert den SHA256-Fingerprint eines öffentlichen Schlüssels im authorized_keys-Format.
+func Fingerprint(authorizedKey string) string {
+	k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return ""
+	}
+	return ssh.FingerprintSHA256(k)
+}
+
+// SSHCommand baut den Wert für GIT_SSH_COMMAND; leer, wenn der Nutzer keinen Schlüssel hat.
+func SSHCommand(cfg *config.Config, username string) string {
+	if !HasKey(cfg, username) {
+		return ""
+	}
+	p, _ := KeyPath(cfg, username)
+	kh, _ := KnownHostsPath(cfg, username)
+	args := []string{
+		"ssh",
+		"-i", shellQuote(p),
+		"-o", "IdentitiesOnly=yes",
+		"-o", "UserKnownHostsFile=" + shellQuote(kh),
+	}
+	// die gemeinsame Datei nur lesend als globale known_hosts; accept-new schreibt in die des Nutzers
+	if shared := SharedKnownHostsPath(cfg); shared != "" {
+		args = append(args, "-o", "GlobalKnownHostsFile="+shellQuote(shared))
+	}
+	return strings.Join(append(args,
+		"-o", "StrictHostKeyChecking="+cfg.SSH.StrictMode(),
+		"-o", "BatchMode=yes",
+	), " ")
+}
+
+// KnownHost ist ein Eintrag der known_hosts-Datei.
+type KnownHost struct {
+	Hosts       []string
+	Type        string
+	Fingerprint string
+}
+
+// KnownHosts liest die vom Nutzer gepinnten Host-Keys.
+func KnownHosts(cfg *config.Config, username string) ([]KnownHost, error) {
+	p, err := KnownHostsPath(cfg, username)
+	if err != nil {
+		return nil, err
+	}
+	return readKnownHosts(p)
+}
+
+// SharedKnownHosts liest die Host-Keys der gemeinsamen Datei (ssh.knownHostsFile); nil ohne Konfiguration.
+func SharedKnownHosts(cfg *config.Config) ([]KnownHost, error) {
+	p := SharedKnownHostsPath(cfg)
+	if p == "" {
+		return nil, nil
+	}
+	return readKnownHosts(p)
+}
+
+func readKnownHosts(path string) ([]KnownHost, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []KnownHost
+	rest := b
+	for len(rest) > 0 {
+		_, hosts, key, _, r, err := ssh.ParseKnownHosts(rest)
+		if err != nil {
+			break
+		}
+		out = append(out, KnownHost{Hosts: hosts, Type: key.Type(), Fingerprint: ssh.FingerprintSHA256(key)})
+		rest = r
+	}
+	return out, nil
+}
+
+// AddKnownHosts prüft die übergebenen known_hosts-Zeilen (z. B. Ausgabe von ssh-keyscan) und hängt sie an
+// die known_hosts-Datei des Nutzers an.
+func AddKnownHosts(cfg *config.Config, username, lines string) (int, error) {
+	p, err := KnownHostsPath(cfg, username)
+	if err != nil {
+		return 0, err
+	}
+	var valid []string
+	for _, l := range strings.Split(strings.ReplaceAll(lines, "\r\n", "\n"), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		if _, _, _, _, _, err := ssh.ParseKnownHosts([]byte(l)); err != nil {
+			return 0, fmt.Errorf("invalid known_hosts line %q: %v", truncate(l, 40), err)
+		}
+		valid = append(valid, l)
+	}
+	if len(valid) == 0 {
+		return 0, errors.New("no known_hosts entries given")
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strings.Join(valid, "\n") + "\n"); err != nil {
+		return 0, err
+	}
+	return len(valid), nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// shellQuote quotet für die Shell, über die git GIT_SSH_COMMAND ausführt.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// HostFromRemote liefert den Host einer SSH-Remote-URL (git@host:pfad oder ssh://[user@]host[:port]/pfad);
+// leer bei HTTPS- oder lokalen Remotes.
+func HostFromRemote(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if rest, ok := strings.CutPrefix(remote, "ssh://"); ok {
+		host, _, _ := strings.Cut(rest, "/")
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		if h, _, ok := strings.Cut(host, ":"); ok {
+			host = h
+		}
+		return host
+	}
+	if strings.Contains(remote, "://") {
+		return ""
+	}
+	// scp-artige Syntax: [user@]host:pfad
+	hostPart, _, ok := strings.Cut(remote, ":")
+	// Windows-Laufwerksbuchstaben (C:\...) sind lokale Pfade
+	if !ok || len(hostPart) < 2 || strings.ContainsAny(hostPart, "/\\") {
+		return ""
+	}
+	if i := strings.LastIndex(hostPart, "@"); i >= 0 {
+		hostPart = hostPart[i+1:]
+	}
+	return hostPart
+}
@@ -0,0 +1,123 @@
+package gitauth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	return cfg
+}
+
+func TestGenerateKey_WritesUsableKeyPair(t *testing.T) {
+	cfg := testConfig(t)
+	if HasKey(cfg, "alice") || SSHCommand(cfg, "alice") != "" {
+		t.Fatalf("no key expected before generation")
+	}
+	pub, err := GenerateKey(cfg, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(pub, "ssh-ed25519 ") || !strings.HasSuffix(pub, "dstask-ui alice") {
+		t.Fatalf("unexpected public key: %q", pub)
+	}
+	p, _ := KeyPath(cfg, "alice")
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("private key mode %v", fi.Mode().Perm())
+	}
+	pem, _ := os.ReadFile(p)
+	signer, err := ssh.ParsePrivateKey(pem)
+	if err != nil {
+		t.Fatalf("private key not parseable: %v", err)
+	}
+	if ssh.FingerprintSHA256(signer.PublicKey()) != Fingerprint(pub) {
+		t.Fatalf("public and private key do not match")
+	}
+	if got, err := PublicKey(cfg, "alice"); err != nil || got != pub {
+		t.Fatalf("PublicKey = %q, %v", got, err)
+	}
+
+	cmd := SSHCommand(cfg, "alice")
+	for _, want := range []string{"-i '" + p + "'", "IdentitiesOnly=yes", "StrictHostKeyChecking=accept-new", "UserKnownHostsFile='" + filepath.Join(cfg.DataDir, "ssh", "alice", "known_hosts") + "'"} {
+		if !strings.Contains(cmd, want) {
+			t.Fatalf("ssh command %q missing %q", cmd, want)
+		}
+	}
+}
+
+func TestKeyPath_RejectsUnsafeUsernames(t *testing.T) {
+	cfg := testConfig(t)
+	for _, u := range []string{"", "..", "a/b", `a\b`, "a b"} {
+		if _, err := KeyPath(cfg, u); err == nil {
+			t.Fatalf("username %q accepted", u)
+		}
+	}
+}
+
+func TestAddKnownHosts(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SSH.StrictHostKeyChecking = "yes"
+	line := "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	if _, err := AddKnownHosts(cfg, "alice", "not a host key"); err == nil {
+		t.Fatalf("invalid line accepted")
+	}
+	if n, err := AddKnownHosts(cfg, "alice", "# comment\n"+line+"\n"); err != nil || n != 1 {
+		t.Fatalf("AddKnownHosts = %d, %v", n, err)
+	}
+	hosts, err := KnownHosts(cfg, "alice")
+	if err != nil || len(hosts) != 1 || hosts[0].Hosts[0] != "github.com" || hosts[0].Type != "ssh-ed25519" {
+		t.Fatalf("unexpected known hosts: %+v, %v", hosts, err)
+	}
+	// von alice gepinnte Keys gelten nicht für bob
+	if other, err := KnownHosts(cfg, "bob"); err != nil || len(other) != 0 {
+		t.Fatalf("bob sees alice's host keys: %+v, %v", other, err)
+	}
+	if _, err := GenerateKey(cfg, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	cmd := SSHCommand(cfg, "bob")
+	if !strings.Contains(cmd, "StrictHostKeyChecking=yes") {
+		t.Fatalf("strict mode not applied")
+	}
+	if !strings.Contains(cmd, filepath.Join("bob", "known_hosts")) || strings.Contains(cmd, "GlobalKnownHostsFile") {
+		t.Fatalf("unexpected known_hosts options: %q", cmd)
+	}
+
+	// gemeinsame Datei des Betreibers: "~" wird aufgelöst, nur als GlobalKnownHostsFile
+	home, _ := os.UserHomeDir()
+	cfg.SSH.KnownHostsFile = "~/ssh_known_hosts"
+	if want := filepath.Join(home, "ssh_known_hosts"); SharedKnownHostsPath(cfg) != want {
+		t.Fatalf("SharedKnownHostsPath = %q, want %q", SharedKnownHostsPath(cfg), want)
+	}
+	if !strings.Contains(SSHCommand(cfg, "bob"), "GlobalKnownHostsFile='"+filepath.Join(home, "ssh_known_hosts")+"'") {
+		t.Fatalf("shared known_hosts not passed: %q", SSHCommand(cfg, "bob"))
+	}
+}
+
+func TestHostFromRemote(t *testing.T) {
+	cases := map[string]string{
+		"git@github.com:owner/repo.git":        "github.com",
+		"ssh://git@git.example.org:2222/x.git": "git.example.org",
+		"ssh://host/x.git":                     "host",
+		"https://github.com/owner/repo.git":    "",
+		"/srv/git/tasks.git":                   "",
+		"C:\\repos\\tasks":                     "",
+	}
+	for in, want := range cases {
+		if got := HostFromRemote(in); got != want {
+			t.Fatalf("HostFromRemote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package music
+
+import (
+    "errors"
+    "io/fs"
+    "math/rand"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// MaxTracks begrenzt die Länge einer Ordner-Playlist.
+const MaxTracks = 2000
+
+// ErrOutsideRoot wird geliefert, wenn ein Pfad (auch über Symlinks) aus dem Musikordner herausführt.
+var ErrOutsideRoot = errors.New("path outside music root")
+
+// ErrHidden wird geliefert, wenn ein Pfad eine versteckte Datei oder einen versteckten Ordner enthält;
+// die liefert Resolve ebenso wenig aus, wie Tracks sie listet.
+var ErrHidden = errors.New("hidden path in music root")
+
+var audioTypes = map[string]string{
+    ".mp3":  "audio/mpeg",
+    ".ogg":  "audio/ogg",
+    ".oga":  "audio/ogg",
+    ".opus": "audio/ogg",
+    ".m4a":  "audio/mp4",
+    ".aac":  "audio/aac",
+    ".flac": "audio/flac",
+    ".wav":  "audio/wav",
+    ".webm": "audio/webm",
+}
+
+// AudioContentType liefert den MIME-Typ einer Audiodatei anhand der Endung; leer für andere Dateien.
+func AudioContentType(name string) string {
+    return audioTypes[strings.ToLower(filepath.Ext(name))]
+}
+
+// Resolve bildet rel (relativ zum Musikordner root, mit "/" getrennt) auf einen existierenden Pfad ab.
+// Symlinks werden aufgelöst; führt das Ergebnis aus root heraus, kommt ErrOutsideRoot, enthält der
+// Pfad (vor oder nach dem Auflösen) eine versteckte Komponente, ErrHidden.
+func Resolve(root, rel string) (string, error) {
+    rootAbs, err := filepath.Abs(root)
+    if err != nil {
+        return "", err
+    }
+    if rootAbs, err = filepath.EvalSymlinks(rootAbs); err != nil {
+        return "", err
+    }
+    // führende Trenner ignorieren: Pfade sind immer relativ zum Musikordner
+    rel = strings.TrimLeft(filepath.FromSlash(strings.TrimSpace(rel)), `/\`)
+    if filepath.VolumeName(rel) != "" {
+        return "", ErrOutsideRoot
+    }
+    if hidden(rel) {
+        return "", ErrHidden
+    }
+    real, err := filepath.EvalSymlinks(filepath.Join(rootAbs, rel))
+    if err != nil {
+        return "", err
+    }
+    if !within(rootAbs, real) {
+        return "", ErrOutsideRoot
+    }
+    if r, _ := filepath.Rel(rootAbs, real); hidden(r) {
+        return "", ErrHidden
+    }
+    return real, nil
+}
+
+// hidden meldet, ob eine Komponente von rel mit "." beginnt ("." und ".." ausgenommen).
+func hidden(rel string) bool {
+    for _, part := range strings.Split(rel, string(filepath.Separator)) {
+        if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+            return true
+        }
+    }
+    return false
+}
+
+func within(root, p string) bool {
+    r, err := filepath.Rel(root, p)
+    return err == nil && !filepath.IsAbs(r) && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator))
+}
+
+// Tracks listet die Audiodateien unter dir (rekursiv, alphabetisch) als Pfade relativ zu root mit "/".
+// Versteckte Dateien und Ordner werden übersprungen, Symlinks nur innerhalb von root verfolgt.
+func Tracks(root, dir string) ([]string, error) {
+    start, err := Resolve(root, dir)
+    if err != nil {
+        return nil, err
+    }
+    rootAbs, err := Resolve(root, "")
+    if err != nil {
+        return nil, err
+    }
+    var out []string
+    err = filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return nil // unlesbare Einträge überspringen
+        }
+        if p != start && strings.HasPrefix(d.Name(), ".") {
+            if d.IsDir() {
+                return filepath.SkipDir
+            }
+            return nil
+        }
+        if d.IsDir() || AudioContentType(d.Name()) == "" {
+            return nil
+        }
+        if d.Type()&fs.ModeSymlink != 0 {
+            if real, err := filepath.EvalSymlinks(p); err != nil || !within(rootAbs, real) {
+                return nil
+            }
+        }
+        rel, err := filepath.Rel(rootAbs, p)
+        if err != nil {
+            return nil
+        }
+        out = append(out, filepath.ToSlash(rel))
+        if len(out) >= MaxTracks {
+            return filepath.SkipAll
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    sort.Strings(out)
+    return out, nil
+}
+
+// Shuffle mischt die Playlist in place.
+func Shuffle(tracks []string) {
+    rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+}
+
+// TrackTitle liefert einen Anzeigenamen (Dateiname ohne Endung).
+func TrackTitle(rel string) string {
+    base := filepath.Base(filepath.FromSlash(rel))
+    return strings.TrimSuffix(base, filepath.Ext(base))
+}
@@ -0,0 +1,62 @@
+package music
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestFolderTracksStayInsideRoot(t *testing.T) {
+    base := t.TempDir()
+    root := filepath.Join(base, "music")
+    for _, f := range []string{"focus/b.mp3", "focus/a.ogg", "focus/cover.jpg", "focus/.hidden.mp3", "focus/deep/c.flac", "other/d.mp3"} {
+        p := filepath.Join(root, filepath.FromSlash(f))
+        if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    secret := filepath.Join(base, "secret.mp3")
+    if err := os.WriteFile(secret, []byte("x"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    // Symlink aus dem Musikordner heraus darf weder gelistet noch aufgelöst werden
+    if err := os.Symlink(secret, filepath.Join(root, "focus", "escape.mp3")); err != nil {
+        t.Skipf("symlinks not supported: %v", err)
+    }
+
+    got, err := Tracks(root, "/focus")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := []string{"focus/a.ogg", "focus/b.mp3", "focus/deep/c.flac"}; !reflect.DeepEqual(got, want) {
+        t.Fatalf("tracks = %v, want %v", got, want)
+    }
+    for _, rel := range []string{"../secret.mp3", "focus/../../secret.mp3", "focus/escape.mp3"} {
+        if _, err := Resolve(root, rel); !errors.Is(err, ErrOutsideRoot) && !errors.Is(err, os.ErrNotExist) {
+            t.Fatalf("Resolve(%q) = %v, expected rejection", rel, err)
+        }
+    }
+    if _, err := Resolve(root, "focus/escape.mp3"); !errors.Is(err, ErrOutsideRoot) {
+        t.Fatalf("symlink escape not detected: %v", err)
+    }
+    // versteckte Dateien liefert auch Resolve nicht aus, auch nicht über einen Symlink
+    if err := os.Symlink(filepath.Join(root, "focus", ".hidden.mp3"), filepath.Join(root, "focus", "visible.mp3")); err != nil {
+        t.Fatal(err)
+    }
+    for _, rel := range []string{"focus/.hidden.mp3", "/focus/./.hidden.mp3", "focus/visible.mp3"} {
+        if _, err := Resolve(root, rel); !errors.Is(err, ErrHidden) {
+            t.Fatalf("Resolve(%q) = %v, want ErrHidden", rel, err)
+        }
+    }
+    if p, err := Resolve(root, "focus/deep/c.flac"); err != nil || filepath.Base(p) != "c.flac" {
+        t.Fatalf("Resolve inside root: %q %v", p, err)
+    }
+    if AudioContentType("x.MP3") != "audio/mpeg" || AudioContentType("cover.jpg") != "" || TrackTitle("focus/deep/c.flac") != "c" {
+        t.Fatal("unexpected content type or title")
+    }
+}
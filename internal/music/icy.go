@@ -0,0 +1,87 @@
+package music
+
+import (
+    "io"
+    "strings"
+)
+
+// ICYReader entfernt die in einen Shoutcast/Icecast-Stream eingebetteten Metadaten-Blöcke
+// (Anfrage mit "Icy-MetaData: 1", Abstand laut Antwort-Header "icy-metaint"), sodass nur Audio
+// beim Browser ankommt. onTitle wird bei jedem geänderten StreamTitle aufgerufen.
+type ICYReader struct {
+    r       io.Reader
+    metaint int
+    left    int // Audio-Bytes bis zum nächsten Metadaten-Block
+    title   string
+    onTitle func(title string)
+    metaBuf []byte
+    lenByte [1]byte
+}
+
+// NewICYReader liefert einen Reader für einen Stream mit Metadaten alle metaint Bytes.
+func NewICYReader(r io.Reader, metaint int, onTitle func(string)) *ICYReader {
+    return &ICYReader{r: r, metaint: metaint, left: metaint, onTitle: onTitle}
+}
+
+// Read liefert nur Audio-Bytes; Metadaten-Blöcke werden dazwischen gelesen und ausgewertet.
+func (ir *ICYReader) Read(p []byte) (int, error) {
+    if ir.left == 0 {
+        if err := ir.readMeta(); err != nil {
+            return 0, err
+        }
+    }
+    if len(p) > ir.left {
+        p = p[:ir.left]
+    }
+    n, err := ir.r.Read(p)
+    ir.left -= n
+    return n, err
+}
+
+// readMeta liest einen Block: ein Längenbyte (x16 Bytes), danach z. B. "StreamTitle='…';", mit Nullen aufgefüllt.
+func (ir *ICYReader) readMeta() error {
+    if _, err := io.ReadFull(ir.r, ir.lenByte[:]); err != nil {
+        return err
+    }
+    ir.left = ir.metaint
+    size := int(ir.lenByte[0]) * 16
+    if size == 0 {
+        return nil
+    }
+    if cap(ir.metaBuf) < size {
+        ir.metaBuf = make([]byte, size)
+    }
+    buf := ir.metaBuf[:size]
+    if _, err := io.ReadFull(ir.r, buf); err != nil {
+        if err == io.EOF {
+            err = io.ErrUnexpectedEOF
+        }
+        return err
+    }
+    title, ok := StreamTitle(strings.TrimRight(string(buf), "\x00"))
+    if ok && title != ir.title {
+        ir.title = title
+        if ir.onTitle != nil {
+            ir.onTitle(title)
+        }
+    }
+    return nil
+}
+
+// StreamTitle liest StreamTitle aus einem ICY-Metadaten-Block wie "StreamTitle='Artist - Song';StreamUrl='';".
+// ok ist false, wenn der Block keinen Titel enthält.
+func StreamTitle(meta string) (string, bool) {
+    const key = "StreamTitle='"
+    i := strings.Index(meta, key)
+    if i < 0 {
+        return "", false
+    }
+    rest := meta[i+len(key):]
+    // Titel dürfen selbst Apostrophe enthalten; das Ende ist "';" bzw. das letzte "'"
+    if j := strings.Index(rest, "';"); j >= 0 {
+        rest = rest[:j]
+    } else if j := strings.LastIndex(rest, "'"); j >= 0 {
+        rest = rest[:j]
+    }
+    return strings.TrimSpace(rest), true
+}
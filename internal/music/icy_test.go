@@ -0,0 +1,63 @@
+package music
+
+import (
+    "bytes"
+    "io"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// icyBlock baut einen Metadaten-Block (Längenbyte + auf 16 Bytes aufgefüllter Text).
+func icyBlock(meta string) []byte {
+    n := (len(meta) + 15) / 16
+    b := make([]byte, 1+n*16)
+    b[0] = byte(n)
+    copy(b[1:], meta)
+    return b
+}
+
+func TestICYReaderStripsMetadata(t *testing.T) {
+    var stream bytes.Buffer
+    stream.WriteString("aaaa")
+    stream.Write(icyBlock("StreamTitle='Miles Davis - So What';StreamUrl='';"))
+    stream.WriteString("bbbb")
+    stream.Write([]byte{0}) // leerer Block: Titel unverändert
+    stream.WriteString("cccc")
+    stream.Write(icyBlock("StreamTitle='Rock 'n' Roll';"))
+    stream.WriteString("dd")
+
+    var titles []string
+    r := NewICYReader(&stream, 4, func(title string) { titles = append(titles, title) })
+    audio, err := io.ReadAll(r)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(audio) != "aaaabbbbccccdd" {
+        t.Fatalf("audio not cleaned: %q", audio)
+    }
+    if len(titles) != 2 || titles[0] != "Miles Davis - So What" || titles[1] != "Rock 'n' Roll" {
+        t.Fatalf("titles: %q", titles)
+    }
+    if _, ok := StreamTitle("StreamUrl='x';"); ok {
+        t.Fatal("block without StreamTitle should not report a title")
+    }
+}
+
+func TestPlayedStoreNewestFirst(t *testing.T) {
+    st := NewPlayedStore(filepath.Join(t.TempDir(), "music-played.json"))
+    const uuid = "11111111-1111-1111-1111-111111111111"
+    now := time.Now()
+    for i, title := range []string{"One", "Two"} {
+        if err := st.Add("admin", uuid, Played{Time: now.Add(time.Duration(i) * time.Minute), Title: title}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := st.Add("admin", "7", Played{Title: "x"}); err == nil {
+        t.Fatal("task id instead of uuid should be rejected")
+    }
+    list, err := st.List("admin", uuid)
+    if err != nil || len(list) != 2 || list[0].Title != "Two" {
+        t.Fatalf("list: %+v %v", list, err)
+    }
+}
@@ -4,6 +4,7 @@ import (
     "errors"
     "os"
     "path/filepath"
+    "strings"
 
     "github.com/elpatron68/dstask-ui/internal/config"
     "gopkg.in/yaml.v3"
@@ -22,11 +23,16 @@ type TaskMusic struct {
 type Map struct {
     Version       int                  `yaml:"version"`
     DefaultVolume float64              `yaml:"defaultVolume,omitempty"`
-    Tasks         map[string]TaskMusic `yaml:"tasks"`
+    Tasks         map[string]TaskMusic `yaml:"tasks"`              // Task-UUID -> Musik (Version 1: Task-ID)
+    Projects      map[string]TaskMusic `yaml:"projects,omitempty"` // Projekt -> Musik für alle Tasks des Projekts
+    Tags          map[string]TaskMusic `yaml:"tags,omitempty"`     // Tag (ohne "+") -> Musik für alle Tasks mit dem Tag
+    // Unresolved: alte Task-ID -> Musik für Einträge, deren Task bei der Umstellung auf UUIDs nicht offen
+    // war. Sie werden nie angewendet (dstask vergibt IDs neu) und nur zum Aufräumen angezeigt.
+    Unresolved map[string]TaskMusic `yaml:"unresolved,omitempty"`
 }
 
 func DefaultMap() *Map {
-    return &Map{Version: 1, DefaultVolume: 0.8, Tasks: map[string]TaskMusic{}}
+    return &Map{Version: CurrentVersion, DefaultVolume: 0.8, Tasks: map[string]TaskMusic{}}
 }
 
 // LoadForUser loads ~/.dstask/music-map.yaml for the given username.
@@ -96,3 +102,19 @@ func SaveForUser(cfg *config.Config, username string, m *Map) (string, error) {
 }
 
 
+
+// HasStreamURL meldet, ob u als Radio-Stream einer Zuordnung (Task, Projekt oder Tag) eingetragen ist.
+func (m *Map) HasStreamURL(u string) bool {
+    u = strings.TrimSpace(u)
+    if u == "" {
+        return false
+    }
+    for _, table := range []map[string]TaskMusic{m.Tasks, m.Projects, m.Tags} {
+        for _, tm := range table {
+            if tm.Type == "radio" && strings.TrimSpace(tm.URL) == u {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,98 @@
+package music
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/elpatron68/dstask-ui/internal/dstask"
+)
+
+// MaxPlayed begrenzt die gespeicherten Titel pro Task (die ältesten fallen heraus).
+const MaxPlayed = 200
+
+// Played ist ein im Radio-Stream gespielter Titel (ICY StreamTitle).
+type Played struct {
+    Time    time.Time `json:"time"`
+    Title   string    `json:"title"`
+    Station string    `json:"station,omitempty"`
+}
+
+// PlayedStore speichert die gespielten Titel pro Repo-Schlüssel und Task-UUID in einer JSON-Datei,
+// z. B. <dataDir>/music-played.json.
+type PlayedStore struct {
+    mu   sync.Mutex
+    path string
+}
+
+// NewPlayedStore liefert einen Store für path.
+func NewPlayedStore(path string) *PlayedStore {
+    return &PlayedStore{path: path}
+}
+
+func (st *PlayedStore) load() (map[string]map[string][]Played, error) {
+    m := map[string]map[string][]Played{}
+    b, err := os.ReadFile(st.path)
+    if errors.Is(err, os.ErrNotExist) {
+        return m, nil
+    }
+    if err != nil {
+        return m, err
+    }
+    if err := json.Unmarshal(b, &m); err != nil {
+        return m, fmt.Errorf("%s: %v", st.path, err)
+    }
+    return m, nil
+}
+
+// Add hängt einen Titel an die Liste des Tasks an.
+func (st *PlayedStore) Add(key, uuid string, p Played) error {
+    uuid = strings.ToLower(strings.TrimSpace(uuid))
+    if !dstask.LooksLikeUUID(uuid) || strings.TrimSpace(p.Title) == "" {
+        return errors.New("task uuid and title required")
+    }
+    st.mu.Lock()
+    defer st.mu.Unlock()
+    m, err := st.load()
+    if err != nil {
+        return err
+    }
+    if m[key] == nil {
+        m[key] = map[string][]Played{}
+    }
+    list := append(m[key][uuid], p)
+    if len(list) > MaxPlayed {
+        list = list[len(list)-MaxPlayed:]
+    }
+    m[key][uuid] = list
+    b, err := json.MarshalIndent(m, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(st.path), 0700); err != nil {
+        return err
+    }
+    tmp := st.path + ".tmp"
+    if err := os.WriteFile(tmp, b, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, st.path)
+}
+
+// List liefert die gespielten Titel eines Tasks, neueste zuerst.
+func (st *PlayedStore) List(key, uuid string) ([]Played, error) {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+    m, err := st.load()
+    list := m[key][strings.ToLower(strings.TrimSpace(uuid))]
+    out := make([]Played, len(list))
+    for i, p := range list {
+        out[len(list)-1-i] = p
+    }
+    return out, err
+}
@@ -0,0 +1,153 @@
+package music
+
+import (
+    "sort"
+    "strings"
+
+    "github.com/elpatron68/dstask-ui/internal/dstask"
+)
+
+// CurrentVersion: ab Version 2 sind Tasks nach UUID geschlüsselt (Version 1: nach Task-ID).
+const CurrentVersion = 2
+
+// Präfixe der Regel-Schlüssel für Projekte und Tags; Task-Regeln haben die UUID als Schlüssel.
+const (
+    projectPrefix    = "project:"
+    tagPrefix        = "tag:"
+    unresolvedPrefix = "unresolved:"
+)
+
+// TaskRef beschreibt einen Task für die Auflösung der Musik-Regeln.
+type TaskRef struct {
+    ID, UUID, Project string
+    Tags              []string
+}
+
+// ProjectKey liefert den Regel-Schlüssel für ein Projekt, z. B. "project:acme".
+func ProjectKey(project string) string { return projectPrefix + strings.TrimSpace(project) }
+
+// TagKey liefert den Regel-Schlüssel für einen Tag, z. B. "tag:deepwork" (ein führendes "+" wird entfernt).
+func TagKey(tag string) string { return tagPrefix + normTag(tag) }
+
+// UnresolvedKey liefert den Schlüssel eines nicht zugeordneten alten Eintrags, z. B. "unresolved:9".
+func UnresolvedKey(id string) string { return unresolvedPrefix + strings.TrimSpace(id) }
+
+func normTag(tag string) string { return strings.TrimPrefix(strings.TrimSpace(tag), "+") }
+
+// table liefert die Tabelle und den Schlüssel darin für einen Regel-Schlüssel. Eine bloße Task-ID gibt
+// es nur in Version 1; danach liefert table für sie keine Tabelle (nil).
+func (m *Map) table(key string) (*map[string]TaskMusic, string) {
+    switch {
+    case strings.HasPrefix(key, projectPrefix):
+        return &m.Projects, strings.TrimSpace(strings.TrimPrefix(key, projectPrefix))
+    case strings.HasPrefix(key, tagPrefix):
+        return &m.Tags, normTag(strings.TrimPrefix(key, tagPrefix))
+    case strings.HasPrefix(key, unresolvedPrefix):
+        return &m.Unresolved, strings.TrimSpace(strings.TrimPrefix(key, unresolvedPrefix))
+    case dstask.LooksLikeUUID(key):
+        return &m.Tasks, strings.ToLower(key)
+    case m.Version < CurrentVersion:
+        return &m.Tasks, strings.TrimSpace(key) // alte Task-ID (Version 1)
+    }
+    return nil, ""
+}
+
+// Entry liefert die Zuordnung zu einem Regel-Schlüssel (UUID, "project:…", "tag:…", "unresolved:…" oder
+// in Version 1 eine alte Task-ID).
+func (m *Map) Entry(key string) (TaskMusic, bool) {
+    t, k := m.table(key)
+    if t == nil || k == "" {
+        return TaskMusic{}, false
+    }
+    tm, ok := (*t)[k]
+    return tm, ok
+}
+
+// Set speichert die Zuordnung unter einem Regel-Schlüssel; false, wenn der Schlüssel nicht taugt (z. B.
+// eine Task-ID, die sich nicht in eine UUID übersetzen ließ).
+func (m *Map) Set(key string, tm TaskMusic) bool {
+    t, k := m.table(key)
+    if t == nil || k == "" {
+        return false
+    }
+    if *t == nil {
+        *t = map[string]TaskMusic{}
+    }
+    (*t)[k] = tm
+    return true
+}
+
+// Delete entfernt die Zuordnung zu einem Regel-Schlüssel.
+func (m *Map) Delete(key string) {
+    if t, k := m.table(key); t != nil {
+        delete(*t, k)
+    }
+}
+
+// Lookup löst die Musik für einen Task auf. Vorrang: Zuordnung des Tasks (nur per UUID; Einträge zu
+// Task-IDs gelten nie, da dstask IDs neu vergibt), dann Tags (bei mehreren passenden alphabetisch der
+// erste), dann das Projekt. Geliefert wird auch der Regel-Schlüssel, über den der Player Lautstärke &
+// Co. speichert.
+func (m *Map) Lookup(t TaskRef) (TaskMusic, string, bool) {
+    if u := strings.ToLower(strings.TrimSpace(t.UUID)); dstask.LooksLikeUUID(u) {
+        if tm, ok := m.Tasks[u]; ok {
+            return tm, u, true
+        }
+    }
+    tags := make([]string, 0, len(t.Tags))
+    for _, tag := range t.Tags {
+        if tag = normTag(tag); tag != "" {
+            tags = append(tags, tag)
+        }
+    }
+    sort.Strings(tags)
+    for _, tag := range tags {
+        if tm, ok := m.Tags[tag]; ok {
+            return tm, TagKey(tag), true
+        }
+    }
+    if p := strings.TrimSpace(t.Project); p != "" {
+        if tm, ok := m.Projects[p]; ok {
+            return tm, ProjectKey(p), true
+        }
+    }
+    return TaskMusic{}, "", false
+}
+
+// LegacyIDs liefert die noch nach Task-ID geschlüsselten Einträge (sortiert).
+func (m *Map) LegacyIDs() []string {
+    var out []string
+    for k := range m.Tasks {
+        if !dstask.LooksLikeUUID(k) {
+            out = append(out, k)
+        }
+    }
+    sort.Strings(out)
+    return out
+}
+
+// MigrateIDs stellt eine Map der Version 1 auf Version 2 um: alte ID-Einträge werden auf die UUID des
+// offenen Tasks umgeschlüsselt (uuidByID aus dem aktuellen Export); Einträge, deren ID zu keinem offenen
+// Task gehört, kommen nach Unresolved. Liefert die Anzahl umgestellter und zurückgestellter Einträge.
+func (m *Map) MigrateIDs(uuidByID map[string]string) (migrated, unresolved int) {
+    for _, id := range m.LegacyIDs() {
+        tm := m.Tasks[id]
+        delete(m.Tasks, id)
+        uuid := strings.ToLower(uuidByID[id])
+        if !dstask.LooksLikeUUID(uuid) {
+            if m.Unresolved == nil {
+                m.Unresolved = map[string]TaskMusic{}
+            }
+            m.Unresolved[id] = tm
+            unresolved++
+            continue
+        }
+        // eine vorhandene UUID-Zuordnung hat Vorrang
+        if _, exists := m.Tasks[uuid]; !exists {
+            m.Tasks[uuid] = tm
+        }
+        migrated++
+    }
+    m.Version = CurrentVersion
+    return migrated, unresolved
+}
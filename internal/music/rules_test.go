@@ -0,0 +1,56 @@
+package music
+
+import "testing"
+
+func TestLookupPrecedenceAndMigration(t *testing.T) {
+    const uuid = "11111111-1111-1111-1111-111111111111"
+    m := &Map{Version: 1, Tasks: map[string]TaskMusic{
+        "3": {Type: "radio", Name: "Legacy", URL: "https://legacy"},
+        "9": {Type: "radio", Name: "Gone", URL: "https://gone"},
+    }}
+    m.Set(TagKey("+deepwork"), TaskMusic{Type: "radio", Name: "Deep"})
+    m.Set(TagKey("admin"), TaskMusic{Type: "radio", Name: "Admin"})
+    m.Set(ProjectKey("acme"), TaskMusic{Type: "folder", Name: "Acme", Path: "acme"})
+
+    task := TaskRef{ID: "3", UUID: uuid, Project: "acme", Tags: []string{"deepwork", "admin"}}
+    if tm, key, ok := m.Lookup(task); !ok || tm.Name != "Admin" || key != "tag:admin" {
+        t.Fatalf("id entries must never apply, even before migration: %+v %q", tm, key)
+    }
+    if migrated, unresolved := m.MigrateIDs(map[string]string{"3": uuid}); migrated != 1 || unresolved != 1 || m.Version != CurrentVersion {
+        t.Fatalf("migrated %d, unresolved %d, version %d", migrated, unresolved, m.Version)
+    }
+    if tm, key, ok := m.Lookup(task); !ok || tm.Name != "Legacy" || key != uuid {
+        t.Fatalf("task entry should win after migration: %+v %q", tm, key)
+    }
+    // "9" gehörte zu keinem offenen Task: zurückgestellt, gilt nicht für einen neuen Task mit ID 9
+    if ids := m.LegacyIDs(); len(ids) != 0 {
+        t.Fatalf("legacy ids left after migration: %v", ids)
+    }
+    if tm, ok := m.Entry(UnresolvedKey("9")); !ok || tm.Name != "Gone" {
+        t.Fatalf("unresolved entry not kept: %+v", m.Unresolved)
+    }
+    if tm, _, ok := m.Lookup(TaskRef{ID: "9", UUID: "99999999-9999-9999-9999-999999999999"}); ok {
+        t.Fatalf("new task with a reused id inherited music: %+v", tm)
+    }
+    if m.Set("9", TaskMusic{Type: "radio"}) {
+        t.Fatal("a bare task id must not be stored after migration")
+    }
+    m.Delete(UnresolvedKey("9"))
+    if len(m.Unresolved) != 0 {
+        t.Fatalf("unresolved entry not removed: %+v", m.Unresolved)
+    }
+    // ohne Task-Eintrag: alphabetisch erster passender Tag vor dem Projekt
+    m.Delete(uuid)
+    if tm, key, ok := m.Lookup(task); !ok || tm.Name != "Admin" || key != "tag:admin" {
+        t.Fatalf("expected tag rule: %+v %q", tm, key)
+    }
+    if tm, key, ok := m.Lookup(TaskRef{ID: "4", Project: "acme", Tags: []string{"other"}}); !ok || tm.Name != "Acme" || key != "project:acme" {
+        t.Fatalf("expected project rule: %+v %q", tm, key)
+    }
+    if _, _, ok := m.Lookup(TaskRef{ID: "5", Project: "other"}); ok {
+        t.Fatal("unexpected match without rules")
+    }
+    if tm, ok := m.Entry("tag:deepwork"); !ok || tm.Name != "Deep" {
+        t.Fatalf("Entry by tag key: %+v", tm)
+    }
+}
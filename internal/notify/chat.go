@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ereignisse, die Nutzer für ihre Chat-Ziele abonnieren können.
+const (
+	EventTaskAssigned = "task.assigned"
+	EventDueTomorrow  = "due.tomorrow"
+	EventSyncFailed   = "sync.failed"
+	EventP0Created    = "p0.created"
+)
+
+// ChatEvents sind alle abonnierbaren Ereignisse in Anzeige-Reihenfolge.
+var ChatEvents = []string{EventTaskAssigned, EventDueTomorrow, EventSyncFailed, EventP0Created}
+
+// Typen von Chat-Zielen.
+const (
+	TargetNtfy   = "ntfy"
+	TargetGotify = "gotify"
+	TargetMatrix = "matrix"
+)
+
+// Message ist eine Chat-Benachrichtigung.
+type Message struct {
+	Title  string
+	Body   string
+	Urgent bool // hohe Priorität (z. B. P0)
+}
+
+// Notifier stellt eine Nachricht an ein Ziel zu.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Target ist ein Chat-Ziel eines Nutzers.
+type Target struct {
+	ID     string   `json:"id"`
+	Type   string   `json:"type"`            // ntfy | gotify | matrix
+	URL    string   `json:"url"`             // ntfy-/Gotify-Server bzw. Matrix-Homeserver
+	Topic  string   `json:"topic,omitempty"` // ntfy-Topic
+	Room   string   `json:"room,omitempty"`  // Matrix-Raum-ID (!abc:example.org)
+	Token  string   `json:"token,omitempty"` // ntfy-Access-Token (optional), Gotify-App-Token, Matrix-Access-Token
+	Events []string `json:"events"`
+}
+
+// Wants meldet, ob das Ziel event abonniert hat.
+func (t Target) Wants(event string) bool { return slices.Contains(t.Events, event) }
+
+// Describe liefert eine kurze Bezeichnung wie "ntfy ntfy.sh/alerts".
+func (t Target) Describe() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(strings.TrimRight(t.URL, "/"), "https://"), "http://")
+	switch t.Type {
+	case TargetNtfy:
+		return "ntfy " + host + "/" + t.Topic
+	case TargetMatrix:
+		return "matrix " + t.Room + " on " + host
+	}
+	return t.Type + " " + host
+}
+
+// Validate prüft, ob alle für den Typ nötigen Felder gesetzt sind.
+func (t Target) Validate() error {
+	u, err := url.Parse(strings.TrimSpace(t.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("server URL must be http(s)://host")
+	}
+	switch t.Type {
+	case TargetNtfy:
+		if strings.TrimSpace(t.Topic) == "" || strings.Contains(t.Topic, "/") {
+			return errors.New("ntfy topic required")
+		}
+	case TargetGotify:
+		if t.Token == "" {
+			return errors.New("gotify app token required")
+		}
+	case TargetMatrix:
+		if !strings.HasPrefix(t.Room, "!") || t.Token == "" {
+			return errors.New("matrix room id (!…) and access token required")
+		}
+	default:
+		return fmt.Errorf("unknown target type %q", t.Type)
+	}
+	for _, e := range t.Events {
+		if !slices.Contains(ChatEvents, e) {
+			return fmt.Errorf("unknown event %q", e)
+		}
+	}
+	return nil
+}
+
+// Notifier liefert den Adapter für das Ziel.
+func (t Target) Notifier(client *http.Client) (Notifier, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	base := strings.TrimRight(t.URL, "/")
+	switch t.Type {
+	case TargetNtfy:
+		return ntfy{client: client, url: base + "/" + url.PathEscape(t.Topic), token: t.Token}, nil
+	case TargetGotify:
+		return gotify{client: client, url: base + "/message", token: t.Token}, nil
+	default:
+		return matrix{client: client, base: base, room: t.Room, token: t.Token}, nil
+	}
+}
+
+// ntfy: POST <server>/<topic>, Text im Body, Titel und Priorität als Header.
+type ntfy struct {
+	client     *http.Client
+	url, token string
+}
+
+func (n ntfy) Notify(ctx context.Context, m Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, strings.NewReader(m.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", m.Title)
+	if m.Urgent {
+		req.Header.Set("Priority", "high")
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return do(n.client, req)
+}
+
+// gotify: POST <server>/message mit App-Token im Header X-Gotify-Key.
+type gotify struct {
+	client     *http.Client
+	url, token string
+}
+
+func (g gotify) Notify(ctx context.Context, m Message) error {
+	prio := 5
+	if m.Urgent {
+		prio = 8
+	}
+	body, _ := json.Marshal(map[string]any{"title": m.Title, "message": m.Body, "priority": prio})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.token)
+	return do(g.client, req)
+}
+
+// matrix: PUT /_matrix/client/v3/rooms/{room}/send/m.room.message/{txnId} (Client-Server-API).
+type matrix struct {
+	client            *http.Client
+	base, room, token string
+}
+
+func (mx matrix) Notify(ctx context.Context, m Message) error {
+	text := m.Title
+	if m.Body != "" {
+		text += "\n" + m.Body
+	}
+	body, _ := json.Marshal(map[string]string{"msgtype": "m.text", "body": text})
+	txn := "dstask-ui-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	u := mx.base + "/_matrix/client/v3/rooms/" + url.PathEscape(mx.room) + "/send/m.room.message/" + txn
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+mx.token)
+	return do(mx.client, req)
+}
+
+func do(client *http.Client, req *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Antwort verwerfen statt zurückgeben: der Inhalt eines fremden Servers gehört nicht in Fehlermeldungen
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	return nil
+}
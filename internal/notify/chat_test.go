@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type capturedRequest struct {
+	Method, Path string
+	Header       http.Header
+	Body         string
+}
+
+func standIn(t *testing.T, status int) (*httptest.Server, *[]capturedRequest) {
+	t.Helper()
+	var reqs []capturedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, capturedRequest{Method: r.Method, Path: r.URL.EscapedPath(), Header: r.Header.Clone(), Body: string(b)})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errcode":"M_FORBIDDEN"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestNotifiers_LocalStandIns(t *testing.T) {
+	msg := Message{Title: "P0 task created", Body: "#4 Prod down", Urgent: true}
+	srv, reqs := standIn(t, http.StatusOK)
+	targets := []Target{
+		{Type: TargetNtfy, URL: srv.URL, Topic: "alerts", Token: "tk_1"},
+		{Type: TargetGotify, URL: srv.URL + "/", Token: "AppTok"},
+		{Type: TargetMatrix, URL: srv.URL, Room: "!room:example.org", Token: "syt_x"},
+	}
+	for _, target := range targets {
+		n, err := target.Notifier(srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Notify(context.Background(), msg); err != nil {
+			t.Fatalf("%s: %v", target.Type, err)
+		}
+	}
+	if len(*reqs) != 3 {
+		t.Fatalf("got %d requests", len(*reqs))
+	}
+	ntfy, gotify, matrix := (*reqs)[0], (*reqs)[1], (*reqs)[2]
+	if ntfy.Method != http.MethodPost || ntfy.Path != "/alerts" || ntfy.Body != "#4 Prod down" ||
+		ntfy.Header.Get("Title") != "P0 task created" || ntfy.Header.Get("Priority") != "high" || ntfy.Header.Get("Authorization") != "Bearer tk_1" {
+		t.Fatalf("unexpected ntfy request: %+v", ntfy)
+	}
+	var g struct {
+		Title, Message string
+		Priority       int
+	}
+	if err := json.Unmarshal([]byte(gotify.Body), &g); err != nil || gotify.Path != "/message" || gotify.Header.Get("X-Gotify-Key") != "AppTok" ||
+		g.Title != "P0 task created" || g.Message != "#4 Prod down" || g.Priority != 8 {
+		t.Fatalf("unexpected gotify request: %+v %v", gotify, err)
+	}
+	var m struct{ Msgtype, Body string }
+	if err := json.Unmarshal([]byte(matrix.Body), &m); err != nil || matrix.Method != http.MethodPut ||
+		!strings.HasPrefix(matrix.Path, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") ||
+		matrix.Header.Get("Authorization") != "Bearer syt_x" || m.Msgtype != "m.text" || m.Body != "P0 task created\n#4 Prod down" {
+		t.Fatalf("unexpected matrix request: %+v %v", matrix, err)
+	}
+
+	failing, _ := standIn(t, http.StatusForbidden)
+	n, _ := Target{Type: TargetGotify, URL: failing.URL, Token: "x"}.Notifier(failing.Client())
+	if err := n.Notify(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "M_FORBIDDEN") {
+		t.Fatalf("expected 403 error without the response body, got %v", err)
+	}
+}
+
+func TestTarget_Validate(t *testing.T) {
+	bad := []Target{
+		{Type: TargetNtfy, URL: "ftp://x", Topic: "a"},
+		{Type: TargetNtfy, URL: "https://ntfy.sh"},
+		{Type: TargetGotify, URL: "https://gotify.example.org"},
+		{Type: TargetMatrix, URL: "https://matrix.org", Room: "#alias:matrix.org", Token: "t"},
+		{Type: TargetNtfy, URL: "https://ntfy.sh", Topic: "a", Events: []string{"task.everything"}},
+		{Type: "slack", URL: "https://hooks.example.org"},
+	}
+	for _, target := range bad {
+		if target.Validate() == nil {
+			t.Errorf("expected error for %+v", target)
+		}
+	}
+	ok := Target{Type: TargetNtfy, URL: "https://ntfy.sh", Topic: "a", Events: []string{EventP0Created}}
+	if err := ok.Validate(); err != nil || !ok.Wants(EventP0Created) || ok.Wants(EventSyncFailed) {
+		t.Fatalf("unexpected: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package notify verschickt Benachrichtigungen (Erinnerungs-E-Mails, Chat-Nachrichten über ntfy,
+// Gotify und Matrix) und verwaltet die Benachrichtigungs-Einstellungen der Nutzer im Datenverzeichnis.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+// Mail ist eine reine Text-E-Mail.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// ErrMailDisabled: smtp.host ist nicht konfiguriert.
+var ErrMailDisabled = errors.New("smtp not configured")
+
+// SendMail verschickt m über den konfigurierten SMTP-Server. STARTTLS wird genutzt, wenn der Server
+// es anbietet; angemeldet wird nur, wenn smtp.username gesetzt ist.
+func SendMail(cfg config.SMTPConfig, m Mail) error {
+	if strings.TrimSpace(cfg.Host) == "" {
+		return ErrMailDisabled
+	}
+	from := strings.TrimSpace(cfg.From)
+	if from == "" {
+		from = "dstask-ui@" + cfg.Host
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(from, "\r\n") {
+		return errors.New("invalid address")
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return smtp.SendMail(cfg.Addr(), auth, from, []string{m.To}, buildMessage(from, m, time.Now()))
+}
+
+// buildMessage setzt Header und Text (UTF-8, CRLF-Zeilenenden) zusammen.
+func buildMessage(from string, m Mail, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	for _, line := range strings.Split(body, "\n") {
+		// Punkt am Zeilenanfang verdoppelt net/smtp selbst (dot-stuffing)
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/notify/notifytest"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 5, 1, h, m, 0, 0, time.Local) }
+	cases := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"22:00-07:00", at(23, 30), true},
+		{"22:00-07:00", at(6, 59), true},
+		{"22:00-07:00", at(7, 0), false},
+		{"12:00-13:00", at(12, 30), true},
+		{"12:00-13:00", at(13, 0), false},
+		{"", at(3, 0), false},
+		{"garbage", at(3, 0), false},
+	}
+	for _, c := range cases {
+		if got := InQuietHours(c.spec, c.t); got != c.want {
+			t.Errorf("InQuietHours(%q, %s) = %v, want %v", c.spec, c.t.Format("15:04"), got, c.want)
+		}
+	}
+}
+
+func TestPrefs_SaveLoadAndAddress(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.Users = []config.UserConfig{{Username: "alice", Email: "alice@example.org"}}
+	if err := SavePrefs(cfg, "alice", Prefs{QuietHours: "22-7"}); err == nil {
+		t.Fatalf("invalid quiet hours accepted")
+	}
+	if err := SavePrefs(cfg, "alice", Prefs{Email: EmailPrefs{Enabled: true}, QuietHours: " 22:00-07:00 "}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadPrefs(cfg, "alice")
+	if err != nil || !p.Email.Enabled || p.QuietHours != "22:00-07:00" {
+		t.Fatalf("unexpected prefs: %+v %v", p, err)
+	}
+	if got := EmailAddress(cfg, "alice", p); got != "alice@example.org" {
+		t.Fatalf("address = %q", got)
+	}
+	p.Email.Address = "a@work.example"
+	if got := EmailAddress(cfg, "alice", p); got != "a@work.example" {
+		t.Fatalf("address = %q", got)
+	}
+}
+
+func TestSendMail_LocalServer(t *testing.T) {
+	srv := notifytest.NewSMTPServer(t)
+	port, _ := strconv.Atoi(srv.Port)
+	smtpCfg := config.SMTPConfig{Host: srv.Host, Port: port, From: "dstask@example.org"}
+	err := SendMail(smtpCfg, Mail{To: "bob@example.org", Subject: "Fällig: Steuer", Body: "Overdue (1):\n.dot line\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 || msgs[0].From != "dstask@example.org" || msgs[0].To[0] != "bob@example.org" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	data := msgs[0].Data
+	if !strings.Contains(data, "Subject: =?utf-8?q?F=C3=A4llig:_Steuer?=") || !strings.Contains(data, "\n.dot line\n") {
+		t.Fatalf("unexpected data: %s", data)
+	}
+	if err := SendMail(config.SMTPConfig{}, Mail{To: "x@example.org"}); err != ErrMailDisabled {
+		t.Fatalf("expected ErrMailDisabled, got %v", err)
+	}
+}
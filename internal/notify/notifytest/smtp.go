@@ -0,0 +1,106 @@
+// Package notifytest enthält Test-Stand-ins für die Benachrichtigungskanäle.
+package notifytest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Message ist eine vom SMTPServer empfangene E-Mail.
+type Message struct {
+	From string
+	To   []string
+	Data string // Header und Text, CRLF durch LF ersetzt
+}
+
+// SMTPServer ist ein minimaler SMTP-Server auf 127.0.0.1 (ohne TLS und Anmeldung), der alle E-Mails annimmt.
+type SMTPServer struct {
+	Host, Port string
+
+	ln   net.Listener
+	mu   sync.Mutex
+	msgs []Message
+}
+
+// NewSMTPServer startet den Server; er wird am Testende beendet.
+func NewSMTPServer(t testing.TB) *SMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &SMTPServer{ln: ln}
+	s.Host, s.Port, _ = net.SplitHostPort(ln.Addr().String())
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+// Messages liefert die bisher empfangenen E-Mails.
+func (s *SMTPServer) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.msgs...)
+}
+
+func (s *SMTPServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	reply := func(line string) { _, _ = c.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	var msg Message
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = Message{From: strings.Trim(line[len("MAIL FROM:"):], "<> ")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				l = strings.TrimRight(l, "\r\n")
+				if l == "." {
+					break
+				}
+				b.WriteString(strings.TrimPrefix(l, "."))
+				b.WriteString("\n")
+			}
+			msg.Data = b.String()
+			s.mu.Lock()
+			s.msgs = append(s.msgs, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+// Prefs sind die Benachrichtigungs-Einstellungen eines Nutzers.
+type Prefs struct {
+	Email      EmailPrefs `json:"email"`
+	Targets    []Target   `json:"targets,omitempty"`    // Chat-Ziele (ntfy, Gotify, Matrix)
+	QuietHours string     `json:"quietHours,omitempty"` // z. B. "22:00-07:00"; leer = keine Ruhezeit
+}
+
+// Wants meldet, ob mindestens ein Chat-Ziel event abonniert hat.
+func (p Prefs) Wants(event string) bool {
+	for _, t := range p.Targets {
+		if t.Wants(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailPrefs: Opt-in für Erinnerungs-E-Mails.
+type EmailPrefs struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address,omitempty"` // leer = E-Mail aus users[].email
+}
+
+// storeMu serialisiert Zugriffe auf die JSON-Dateien im Datenverzeichnis (Web-Handler und Hintergrundjobs).
+var storeMu sync.Mutex
+
+// PrefsPath liefert <dataDir>/notifications.json.
+func PrefsPath(cfg *config.Config) string {
+	return filepath.Join(config.ResolveDataDir(cfg), "notifications.json")
+}
+
+// LoadPrefs liefert die Einstellungen eines Nutzers (Nullwert, wenn keine gespeichert sind).
+func LoadPrefs(cfg *config.Config, username string) (Prefs, error) {
+	all, err := AllPrefs(cfg)
+	if err != nil {
+		return Prefs{}, err
+	}
+	return all[username], nil
+}
+
+// AllPrefs liefert die Einstellungen aller Nutzer.
+func AllPrefs(cfg *config.Config) (map[string]Prefs, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	m := map[string]Prefs{}
+	return m, readJSON(PrefsPath(cfg), &m)
+}
+
+// SavePrefs prüft und speichert die Einstellungen eines Nutzers.
+func SavePrefs(cfg *config.Config, username string, p Prefs) error {
+	p.Email.Address = strings.TrimSpace(p.Email.Address)
+	p.QuietHours = strings.TrimSpace(p.QuietHours)
+	if _, _, err := parseQuietHours(p.QuietHours); err != nil {
+		return err
+	}
+	if p.Email.Address != "" && !strings.Contains(p.Email.Address, "@") {
+		return errors.New("invalid email address")
+	}
+	for _, t := range p.Targets {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	m := map[string]Prefs{}
+	path := PrefsPath(cfg)
+	if err := readJSON(path, &m); err != nil {
+		return err
+	}
+	m[username] = p
+	return writeJSON(path, m)
+}
+
+// EmailAddress liefert die Empfängeradresse: die eingestellte oder die aus der Nutzerkonfiguration.
+func EmailAddress(cfg *config.Config, username string, p Prefs) string {
+	if p.Email.Address != "" {
+		return p.Email.Address
+	}
+	for _, u := range cfg.Users {
+		if u.Username == username {
+			return strings.TrimSpace(u.Email)
+		}
+	}
+	return ""
+}
+
+// parseQuietHours zerlegt "HH:MM-HH:MM" in Minuten seit Mitternacht; leer = keine Ruhezeit (from == to).
+func parseQuietHours(spec string) (from, to int, err error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, 0, nil
+	}
+	a, b, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("quiet hours %q: expected HH:MM-HH:MM", spec)
+	}
+	ta, errA := time.Parse("15:04", strings.TrimSpace(a))
+	tb, errB := time.Parse("15:04", strings.TrimSpace(b))
+	if errA != nil || errB != nil {
+		return 0, 0, fmt.Errorf("quiet hours %q: expected HH:MM-HH:MM", spec)
+	}
+	return ta.Hour()*60 + ta.Minute(), tb.Hour()*60 + tb.Minute(), nil
+}
+
+// InQuietHours meldet, ob t in die Ruhezeit spec fällt; Zeiträume über Mitternacht ("22:00-07:00") werden unterstützt.
+func InQuietHours(spec string, t time.Time) bool {
+	from, to, err := parseQuietHours(spec)
+	if err != nil || from == to {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	if from < to {
+		return m >= from && m < to
+	}
+	return m >= from || m < to
+}
+
+func readJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"path/filepath"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+// ReminderState merkt sich pro Nutzer, was bereits verschickt wurde, damit Erinnerungen einen Neustart
+// des Servers überstehen, ohne doppelt zu kommen.
+type ReminderState struct {
+	LastDigest      string            `json:"lastDigest,omitempty"`      // Datum (YYYY-MM-DD) der letzten Tagesübersicht
+	LastDueTomorrow string            `json:"lastDueTomorrow,omitempty"` // Datum der letzten Chat-Meldung "morgen fällig"
+	Sent            map[string]string `json:"sent,omitempty"`            // Task-UUID -> Fälligkeit, für die erinnert wurde
+	PushSent        map[string]string `json:"pushSent,omitempty"`        // Task-UUID -> "due:<Fälligkeit>" bzw. "overdue:<Fälligkeit>" (Web Push)
+}
+
+func reminderStatePath(cfg *config.Config) string {
+	return filepath.Join(config.ResolveDataDir(cfg), "reminders-state.json")
+}
+
+// LoadReminderState liefert den Erinnerungs-Stand aller Nutzer.
+func LoadReminderState(cfg *config.Config) (map[string]ReminderState, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	m := map[string]ReminderState{}
+	return m, readJSON(reminderStatePath(cfg), &m)
+}
+
+// SaveReminderState speichert den Erinnerungs-Stand eines Nutzers.
+func SaveReminderState(cfg *config.Config, username string, st ReminderState) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	m := map[string]ReminderState{}
+	path := reminderStatePath(cfg)
+	if err := readJSON(path, &m); err != nil {
+		return err
+	}
+	m[username] = st
+	return writeJSON(path, m)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+const activityPageSize = 50
+
+// handleActivity zeigt den Git-Log des .dstask-Repos als lesbaren Feed.
+// Query: author, project, from, to (YYYY-MM-DD), page (ab 1).
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	opts := dstask.ActivityOptions{
+		Author:  strings.TrimSpace(q.Get("author")),
+		Project: strings.TrimSpace(q.Get("project")),
+		Offset:  (page - 1) * activityPageSize,
+		Limit:   activityPageSize,
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("from"), time.Local); err == nil {
+		opts.Since = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("to"), time.Local); err == nil {
+		opts.Until = t.Add(24*time.Hour - time.Second)
+	}
+	entries, hasMore, err := s.runner.Activity(username, opts)
+	if err != nil {
+		applog.Warnf("/activity: %v", err)
+		http.Error(w, "failed to read git history", http.StatusBadGateway)
+		return
+	}
+	// IDs stehen nicht in der Task-Datei; offene Tasks bekommen ihre aktuelle ID aus dem Export
+	if len(entries) > 0 {
+		ids := map[string]string{}
+		for id, ref := range s.taskRefs(username) {
+			ids[strings.ToLower(ref.UUID)] = id
+		}
+		for i := range entries {
+			entries[i].ID = ids[strings.ToLower(entries[i].UUID)]
+		}
+	}
+	authors, _ := s.runner.ActivityAuthors(username)
+
+	pageURL := func(p int) string {
+		v := url.Values{}
+		for _, k := range []string{"author", "project", "from", "to"} {
+			if val := q.Get(k); val != "" {
+				v.Set(k, val)
+			}
+		}
+		v.Set("page", strconv.Itoa(p))
+		return "/activity?" + v.Encode()
+	}
+	prevURL, nextURL := "", ""
+	if page > 1 {
+		prevURL = pageURL(page - 1)
+	}
+	if hasMore {
+		nextURL = pageURL(page + 1)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Activity</h2>
+<form method="get" action="/activity" style="margin-bottom:8px;">
+  <label>User:
+    <select name="author">
+      <option value="">(all)</option>
+      {{range .Authors}}<option value="{{.}}" {{if eq $.Author .}}selected{{end}}>{{.}}</option>{{end}}
+    </select>
+  </label>
+  <label>Project: <input name="project" value="{{.Project}}" size="12"/></label>
+  <label>From: <input type="date" name="from" value="{{.From}}"/></label>
+  <label>To: <input type="date" name="to" value="{{.To}}"/></label>
+  <button type="submit">Filter</button>
+  <a href="/activity" style="margin-left:8px;">reset</a>
+</form>
+{{if not .Entries}}<p>No activity found.</p>{{end}}
+<ul style="list-style:none;padding-left:0;">
+{{range .Entries}}
+  <li style="padding:4px 0;border-bottom:1px solid #eee;">
+    <span style="color:#57606a;white-space:nowrap;">{{.Date.Format "2006-01-02 15:04"}}</span>
+    <a href="/tasks/{{.UUID}}/history">{{.Text}}</a>
+    {{if .Project}}<span class="pill" title="project">{{.Project}}</span>{{end}}
+    {{if .Details}}<div style="margin-left:130px;color:#57606a;">{{range $i, $d := .Details}}{{if $i}} · {{end}}{{$d}}{{end}}</div>{{end}}
+  </li>
+{{end}}
+</ul>
+<div>
+  {{if .PrevURL}}<a href="{{.PrevURL}}">← newer</a>{{end}}
+  <span style="margin:0 8px;">page {{.Page}}</span>
+  {{if .NextURL}}<a href="{{.NextURL}}">older →</a>{{end}}
+</div>`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Entries": entries,
+		"Authors": authors,
+		"Author":  opts.Author,
+		"Project": opts.Project,
+		"From":    q.Get("from"),
+		"To":      q.Get("to"),
+		"Page":    page,
+		"PrevURL": prevURL,
+		"NextURL": nextURL,
+	}))
+}
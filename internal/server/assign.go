@@ -0,0 +1,136 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// findTask sucht einen Task per ID oder UUID im Export des Repos.
+func (s *Server) findTask(username, id string) (map[string]any, bool) {
+	res := s.runner.Run(username, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		return nil, false
+	}
+	tasks, _ := decodeTasksJSONFlexible(res.Stdout)
+	for _, t := range tasks {
+		if str(firstOf(t, "id", "ID", "Id")) == id || strings.EqualFold(str(firstOf(t, "uuid", "UUID")), id) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// taskAssignees liefert die Zuständigen (@-Tags) eines exportierten Tasks.
+func taskAssignees(task map[string]any) []string {
+	return rowAssignees(map[string]string{"tags": joinTags(firstOf(task, "tags", "Tags"))})
+}
+
+// isRepoMember prüft, ob user im Repo des Schlüssels key arbeitet (config.RepoUsers).
+func (s *Server) isRepoMember(key, user string) bool {
+	for _, u := range config.RepoUsers(s.cfg, key) {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+var errUnknownAssignee = errors.New("assignee is not a member of this repository")
+
+// errAssignmentNotRecorded: der Tag wurde gesetzt, assignments.yaml aber nicht gespeichert/committet.
+var errAssignmentNotRecorded = errors.New("assignment not recorded")
+
+// assignTask setzt den Zuständigen eines Tasks (assignee leer = Zuweisung entfernen): alte +@-Tags werden
+// entfernt, der neue gesetzt, die Delegation in assignments.yaml vermerkt und ein task.assigned-Event
+// veröffentlicht. changed ist false, wenn der Task bereits so zugewiesen war. Schlägt nur das Vermerken
+// fehl, ist changed true und err wrappt errAssignmentNotRecorded.
+func (s *Server) assignTask(key, actor, id, assignee string) (changed bool, err error) {
+	task, ok := s.findTask(key, id)
+	if !ok {
+		return false, errors.New("task " + id + " not found")
+	}
+	current := taskAssignees(task)
+	if (assignee == "" && len(current) == 0) || (len(current) == 1 && current[0] == assignee) {
+		return false, nil
+	}
+	if assignee != "" && !s.isRepoMember(key, assignee) {
+		return false, errUnknownAssignee
+	}
+	args := []string{id, "modify"}
+	for _, a := range current {
+		if a != assignee {
+			args = append(args, "-"+assigneePrefix+a)
+		}
+	}
+	if assignee != "" {
+		args = append(args, "+"+assigneePrefix+assignee)
+	}
+	res := s.runner.Run(key, 10*time.Second, args...)
+	s.cmdStore.Append(key, "Assign task", args)
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		return false, errors.New(firstNonEmptyString(stripANSI(res.Stderr), errString(res.Err), "dstask modify failed"))
+	}
+	uuid := str(firstOf(task, "uuid", "UUID"))
+	var recErr error
+	if uuid != "" {
+		if err := s.runner.RecordAssignment(key, uuid, assignee, actor); err != nil {
+			applog.Warnf("recording assignment of %s failed: %v", uuid, err)
+			recErr = fmt.Errorf("%w: %v", errAssignmentNotRecorded, err)
+		}
+	}
+	if assignee != "" {
+		s.events.Publish(Event{
+			Type:     EventTaskAssigned,
+			Repo:     key,
+			Actor:    actor,
+			Target:   assignee,
+			TaskID:   str(firstOf(task, "id", "ID", "Id")),
+			TaskUUID: uuid,
+			Summary:  trimQuotes(str(firstOf(task, "summary", "Summary"))),
+		})
+	}
+	return true, recErr
+}
+
+// handleDelegatedTasks zeigt offene Aufgaben, die der angemeldete Nutzer anderen zugewiesen hat.
+func (s *Server) handleDelegatedTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	key := s.repoKey(r)
+	s.cmdStore.Append(key, "List delegated tasks", []string{"export"})
+	rows, ok := s.openTaskRows(r)
+	if !ok {
+		http.Error(w, "failed to export tasks", http.StatusBadGateway)
+		return
+	}
+	assignments, err := s.runner.Assignments(key)
+	if err != nil {
+		applog.Warnf("/delegated: %v", err)
+	}
+	out := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		a, ok := assignments[strings.ToLower(row["uuid"])]
+		if !ok || a.By != user || a.Assignee == user {
+			continue
+		}
+		// nur, solange der Task noch diesem Zuständigen gehört
+		for _, as := range rowAssignees(row) {
+			if as == a.Assignee {
+				out = append(out, row)
+				break
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	s.renderExportTableWith(w, r, "Delegated by me", out, nil)
+}
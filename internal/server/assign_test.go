@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+)
+
+const assignTestUUID = "0f8c3e2a-1b2c-4d5e-8f90-123456789abc"
+
+// newAssignTestServer: admin und bob teilen ein Git-Repo; der Stub merkt sich "modify"-Aufrufe
+// und liefert danach den Task mit +@bob.
+func newAssignTestServer(t *testing.T) (*Server, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	home, repo := newTaskHome(t, dir, true)
+	modLog := filepath.Join(dir, "modify.log")
+	script := `if [ "$2" = "modify" ]; then echo "$@" >> ` + modLog + `; exit 0; fi
+if [ "$1" = "export" ]; then
+  if [ -f ` + modLog + ` ]; then tags='["@bob"]'; else tags='[]'; fi
+  echo '[{"id":1,"uuid":"` + assignTestUUID + `","status":"pending","summary":"Review PR","tags":'"$tags"'}]'
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.Repos["bob"] = home
+	if err := s.userStore.(*auth.InMemoryUserStore).AddUserPlain("bob", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	return s, repo, modLog
+}
+
+func TestBatchAssign_RecordsDelegationAndNotifies(t *testing.T) {
+	s, repo, modLog := newAssignTestServer(t)
+
+	rr := testPost(s, "/tasks/batch", url.Values{"ids": {"1"}, "action": {"assign"}, "assignee": {"bob"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("batch assign: %d %s", rr.Code, rr.Body.String())
+	}
+	if b, _ := os.ReadFile(modLog); !strings.Contains(string(b), "1 modify +@bob") {
+		t.Fatalf("unexpected modify call: %q", b)
+	}
+	if b, err := os.ReadFile(filepath.Join(repo, "assignments.yaml")); err != nil || !strings.Contains(string(b), "by: admin") {
+		t.Fatalf("assignment not recorded: %v %s", err, b)
+	}
+
+	get := func(path, user string) *httptest.ResponseRecorder { return serveAs(s, user, http.MethodGet, path, nil) }
+	var inbox struct {
+		Unread int `json:"unread"`
+		Items  []struct {
+			Actor   string `json:"actor"`
+			Summary string `json:"summary"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(get("/notifications?format=json", "bob").Body.Bytes(), &inbox); err != nil {
+		t.Fatal(err)
+	}
+	if inbox.Unread != 1 || inbox.Items[0].Actor != "admin" || inbox.Items[0].Summary != "Review PR" {
+		t.Fatalf("unexpected inbox: %+v", inbox)
+	}
+	if err := json.Unmarshal(get("/notifications?format=json", "admin").Body.Bytes(), &inbox); err != nil || inbox.Unread != 0 {
+		t.Fatalf("assigner got a notification: %+v", inbox)
+	}
+
+	if body := get("/delegated", "admin").Body.String(); !strings.Contains(body, "Review PR") {
+		t.Fatalf("delegated view misses task: %s", body)
+	}
+	if body := get("/delegated", "bob").Body.String(); strings.Contains(body, "Review PR") {
+		t.Fatalf("delegated view of assignee shows task: %s", body)
+	}
+	if body := get("/my", "bob").Body.String(); !strings.Contains(body, "Review PR") {
+		t.Fatalf("my view of assignee misses task: %s", body)
+	}
+}
+
+func TestBatchAssign_ReportsUnsavedAssignment(t *testing.T) {
+	s, repo, _ := newAssignTestServer(t)
+	// assignments.yaml als Verzeichnis: Schreiben schlägt fehl
+	if err := os.MkdirAll(filepath.Join(repo, "assignments.yaml"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rr := testPost(s, "/tasks/batch", url.Values{"ids": {"1"}, "action": {"assign"}, "assignee": {"bob"}})
+	var flash string
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "flash" {
+			flash, _ = url.QueryUnescape(c.Value)
+		}
+	}
+	if !strings.HasPrefix(flash, "error") || !strings.Contains(flash, "saving the assignments failed") {
+		t.Fatalf("expected error flash, got %q", flash)
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// maxBackupUploadBytes limits the size of an uploaded restore archive.
+const maxBackupUploadBytes = 256 << 20
+
+func (s *Server) handleBackupPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	points, err := s.runner.ListRollbackPoints(username)
+	if err != nil {
+		applog.Debugf("/backup: listing rollback points for %s failed: %v", username, err)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Backup &amp; restore</h2>
+<p>Download the complete <code>.dstask</code> repository including git history and <code>music-map.yaml</code>.</p>
+<p>
+  <a href="/backup/download?format=tar.gz">Download .tar.gz</a>
+  <a href="/backup/download?format=zip" style="margin-left:12px;">Download .zip</a>
+</p>
+<p>Single tasks removed by mistake can be brought back from the git history: <a href="/tasks/removed">Removed tasks</a>.</p>
+<h3>Restore</h3>
+<p>Uploading an archive replaces the current repository. The current state is kept as a rollback point.</p>
+<form method="post" action="/backup/restore" enctype="multipart/form-data" onsubmit="return confirm('Replace the current repository with this archive?');">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+  <input type="file" name="archive" accept=".tar.gz,.tgz,.zip" required />
+  <button type="submit">Restore</button>
+</form>
+{{if .Points}}
+<h3>Rollback points</h3>
+<table>
+  <thead><tr><th>Name</th><th>Created</th><th></th></tr></thead>
+  <tbody>
+  {{range .Points}}
+  <tr>
+    <td><code>{{.Name}}</code></td>
+    <td>{{if not .Created.IsZero}}{{.Created.Format "2006-01-02 15:04:05"}}{{end}}</td>
+    <td>
+      <form method="post" action="/backup/rollback" style="display:inline;" onsubmit="return confirm('Roll back to this state?');">
+        <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}" />
+        <input type="hidden" name="name" value="{{.Name}}" />
+        <button type="submit">Roll back</button>
+      </form>
+    </td>
+  </tr>
+  {{end}}
+  </tbody>
+</table>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"CSRFToken": csrfToken,
+		"Points":    points,
+	}))
+}
+
+func (s *Server) handleBackupDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	format := r.URL.Query().Get("format")
+	contentType := "application/gzip"
+	switch format {
+	case "", dstask.BackupFormatTarGz:
+		format = dstask.BackupFormatTarGz
+	case dstask.BackupFormatZip:
+		contentType = "application/zip"
+	default:
+		http.Error(w, "unsupported format", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.runner.RepoDirForUser(username); err != nil {
+		http.Error(w, "repository not found", http.StatusNotFound)
+		return
+	}
+	// Erst in eine Temp-Datei schreiben, damit Fehler noch als HTTP-Status gemeldet werden können
+	tmp, err := os.CreateTemp("", "dstask-backup-*")
+	if err != nil {
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := s.runner.WriteBackup(username, format, tmp); err != nil {
+		applog.Warnf("/backup/download: backup for %s failed: %v", username, err)
+		http.Error(w, "backup failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	name := fmt.Sprintf("dstask-%s-%s.%s", username, time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"\"")
+	applog.Infof("/backup/download: %s downloaded %s", username, name)
+	_, _ = io.Copy(w, tmp)
+}
+
+func (s *Server) handleBackupRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupUploadBytes)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "invalid upload", http.StatusBadRequest)
+		return
+	}
+	if !validateCSRFToken(r, r.FormValue("csrf_token")) {
+		http.Error(w, "invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	file, _, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "missing archive", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	tmp, err := os.CreateTemp("", "dstask-restore-*")
+	if err != nil {
+		http.Error(w, "restore failed", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, file)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		http.Error(w, "restore failed", http.StatusInternalServerError)
+		return
+	}
+	rollback, err := s.runner.RestoreBackup(username, tmp.Name())
+	if err != nil {
+		applog.Warnf("/backup/restore: restore for %s failed: %v", username, err)
+		s.setFlash(w, "error", "Restore failed: "+err.Error())
+		http.Redirect(w, r, "/backup", http.StatusSeeOther)
+		return
+	}
+	msg := "Repository restored from archive."
+	if rollback != "" {
+		msg += " Previous state kept as rollback point."
+	}
+	s.setFlash(w, "success", msg)
+	http.Redirect(w, r, "/backup", http.StatusSeeOther)
+}
+
+func (s *Server) handleBackupRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	if !validateCSRFToken(r, r.FormValue("csrf_token")) {
+		http.Error(w, "invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	if _, err := s.runner.RollbackTo(username, r.FormValue("name")); err != nil {
+		s.setFlash(w, "error", "Rollback failed: "+err.Error())
+	} else {
+		s.setFlash(w, "success", "Repository rolled back.")
+	}
+	http.Redirect(w, r, "/backup", http.StatusSeeOther)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupDownload_TarGz(t *testing.T) {
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	if err := os.MkdirAll(filepath.Join(home, ".dstask", "pending"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(home, ".dstask", "pending", "x.yml"), []byte("summary: x\n"), 0644)
+	s := newTestServerWithStub(t, "/bin/true", home)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/backup/download?format=tar.gz", nil)
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/gzip" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), ".tar.gz") {
+		t.Fatalf("missing attachment filename: %q", rr.Header().Get("Content-Disposition"))
+	}
+	if b := rr.Body.Bytes(); len(b) < 2 || b[0] != 0x1f || b[1] != 0x8b {
+		t.Fatalf("body is not gzip")
+	}
+}
+
+func TestBackupRestore_RequiresCSRF(t *testing.T) {
+	s := newTestServer(t)
+	rr := httptest.NewRecorder()
+	body := strings.NewReader("--x\r\nContent-Disposition: form-data; name=\"csrf_token\"\r\n\r\nnope\r\n--x--\r\n")
+	req := httptest.NewRequest(http.MethodPost, "/backup/restore", body)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/notify"
+)
+
+// chatTimeout begrenzt eine Zustellung an ein Chat-Ziel.
+const chatTimeout = 10 * time.Second
+
+// errChatBlocked: das Chat-Ziel zeigt auf eine private, Loopback- oder Link-Local-Adresse (chat.allowHosts).
+var errChatBlocked = errors.New("chat: destination not allowed")
+
+// chatClient liefert den HTTP-Client der Chat-Adapter: eigene Auflösung wie beim Musik-Proxy,
+// kein Umgebungs-Proxy und keine Weiterleitungen.
+func (s *Server) chatClient() *http.Client {
+	cc := s.cfg.Chat
+	return &http.Client{
+		Timeout: chatTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				return dialPublic(ctx, network, addr, cc.AllowPrivate || cc.HostAllowed(host), "chat", errChatBlocked)
+			},
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return errors.New("redirects are not followed")
+		},
+	}
+}
+
+// notifyChat ist der Abonnent für Chat-Ziele: Zuweisungen gehen an den neuen Zuständigen, fehlgeschlagene
+// Syncs an den Nutzer des Repos, neue P0-Tasks an alle Nutzer des Repos. Zugestellt wird im Hintergrund.
+func (s *Server) notifyChat(ev Event) {
+	switch ev.Type {
+	case EventTaskAssigned:
+		if ev.Target != "" && ev.Target != ev.Actor {
+			go s.sendChat(ev.Target, notify.EventTaskAssigned, notify.Message{
+				Title: ev.Actor + " assigned you a task",
+				Body:  taskLabel(ev),
+			})
+		}
+	case EventSyncFailed:
+		go s.sendChat(ev.Actor, notify.EventSyncFailed, notify.Message{
+			Title: "Sync failed" + repoSuffix(ev.Repo),
+			Body:  firstNonEmptyString(ev.Summary, "dstask sync failed"),
+		})
+	case EventTaskCreated:
+		if !strings.EqualFold(str(ev.After["priority"]), "P0") {
+			return
+		}
+		msg := notify.Message{Title: "P0 task created by " + ev.Actor + repoSuffix(ev.Repo), Body: taskLabel(ev), Urgent: true}
+		for _, u := range config.RepoUsers(s.cfg, ev.Repo) {
+			go s.sendChat(u, notify.EventP0Created, msg)
+		}
+	}
+}
+
+// chatWantsSnapshots meldet, ob ein Nutzer neue P0-Tasks abonniert hat (dafür braucht es den Task-Stand).
+func (s *Server) chatWantsSnapshots() bool {
+	all, err := notify.AllPrefs(s.cfg)
+	if err != nil {
+		return false
+	}
+	for _, p := range all {
+		if p.Wants(notify.EventP0Created) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendChat stellt m an alle Ziele des Nutzers zu, die event abonniert haben (nicht während der Ruhezeit).
+func (s *Server) sendChat(user, event string, m notify.Message) int {
+	p, err := notify.LoadPrefs(s.cfg, user)
+	if err != nil {
+		applog.Warnf("chat: reading preferences of %s failed: %v", user, err)
+		return 0
+	}
+	if !p.Wants(event) {
+		return 0
+	}
+	if notify.InQuietHours(p.QuietHours, time.Now()) {
+		applog.Debugf("chat: %s for %s skipped (quiet hours)", event, user)
+		return 0
+	}
+	sent := 0
+	for _, t := range p.Targets {
+		if !t.Wants(event) {
+			continue
+		}
+		if err := s.sendToTarget(t, m); err != nil {
+			applog.Warnf("chat: %s to %s for %s failed: %v", event, t.Describe(), user, err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+func (s *Server) sendToTarget(t notify.Target, m notify.Message) error {
+	n, err := t.Notifier(s.chatClient())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chatTimeout)
+	defer cancel()
+	return n.Notify(ctx, m)
+}
+
+// chatDueTomorrow meldet einmal täglich (ab reminders.dailyAt) die morgen fälligen Tasks des Nutzers.
+func (s *Server) chatDueTomorrow(user string, p notify.Prefs, now time.Time, st *notify.ReminderState, reminderRows func() ([]map[string]string, bool)) (sent int, changed bool) {
+	today := now.Format("2006-01-02")
+	h, m := s.cfg.Reminders.DailyTime()
+	if st.LastDueTomorrow == today || now.Before(time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, now.Location())) {
+		return 0, false
+	}
+	rows, ok := reminderRows()
+	if !ok {
+		return 0, false
+	}
+	st.LastDueTomorrow = today
+	due := applyDueFilter(rows, "due.on:"+now.AddDate(0, 0, 1).Format("2006-01-02"))
+	if len(due) == 0 {
+		return 0, true
+	}
+	sortRowsByDue(due)
+	lines := make([]string, 0, len(due))
+	for _, row := range due {
+		lines = append(lines, strings.TrimSpace(reminderLine(row)))
+	}
+	msg := notify.Message{Title: fmt.Sprintf("%d task(s) due tomorrow", len(due)), Body: strings.Join(lines, "\n")}
+	return s.sendChat(user, notify.EventDueTomorrow, msg), true
+}
+
+func taskLabel(ev Event) string {
+	if ev.TaskID != "" {
+		return "#" + ev.TaskID + " " + ev.Summary
+	}
+	return ev.Summary
+}
+
+func repoSuffix(key string) string {
+	if _, repo := config.SplitRepoKey(key); repo != "" {
+		return " (" + repo + ")"
+	}
+	return ""
+}
+
+// chatErrorText beschreibt einen Zustellfehler für die Oberfläche, ohne Adressen oder Netzwerkdetails
+// preiszugeben (Einzelheiten stehen im Log).
+func chatErrorText(err error) string {
+	var ue *url.Error
+	switch {
+	case errors.Is(err, errChatBlocked):
+		return "destination not allowed (private or local address)"
+	case errors.As(err, &ue):
+		return "target not reachable"
+	}
+	return err.Error()
+}
+
+// chatEventLabels sind die Anzeigenamen der abonnierbaren Ereignisse.
+var chatEventLabels = map[string]string{
+	notify.EventTaskAssigned: "task assigned",
+	notify.EventDueTomorrow:  "due tomorrow",
+	notify.EventSyncFailed:   "sync failed",
+	notify.EventP0Created:    "P0 created",
+}
+
+// handleChatTargetAction legt ein Chat-Ziel an, entfernt es oder schickt eine Testnachricht.
+func (s *Server) handleChatTargetAction(w http.ResponseWriter, r *http.Request, username string, p notify.Prefs) {
+	defer http.Redirect(w, r, "/notifications/settings", http.StatusSeeOther)
+	id := r.FormValue("id")
+	switch r.FormValue("action") {
+	case "addTarget":
+		t := notify.Target{
+			ID:     strconv.FormatInt(time.Now().UnixNano(), 36),
+			Type:   r.FormValue("type"),
+			URL:    strings.TrimSpace(r.FormValue("url")),
+			Topic:  strings.TrimSpace(r.FormValue("topic")),
+			Room:   strings.TrimSpace(r.FormValue("room")),
+			Token:  strings.TrimSpace(r.FormValue("token")),
+			Events: r.Form["events"],
+		}
+		p.Targets = append(p.Targets, t)
+		if err := notify.SavePrefs(s.cfg, username, p); err != nil {
+			s.setFlash(w, "error", "Adding target failed: "+err.Error())
+			return
+		}
+		s.setFlash(w, "success", "Target added: "+t.Describe())
+	case "deleteTarget":
+		kept := p.Targets[:0]
+		for _, t := range p.Targets {
+			if t.ID != id {
+				kept = append(kept, t)
+			}
+		}
+		p.Targets = kept
+		if err := notify.SavePrefs(s.cfg, username, p); err != nil {
+			s.setFlash(w, "error", "Removing target failed: "+err.Error())
+			return
+		}
+		s.setFlash(w, "success", "Target removed.")
+	case "testTarget":
+		for _, t := range p.Targets {
+			if t.ID != id {
+				continue
+			}
+			if err := s.sendToTarget(t, notify.Message{Title: "dstask-ui test", Body: "Notifications for " + username + " arrive here."}); err != nil {
+				applog.Warnf("chat: test to %s for %s failed: %v", t.Describe(), username, err)
+				s.setFlash(w, "error", "Test message failed: "+chatErrorText(err))
+			} else {
+				s.setFlash(w, "success", "Test message sent to "+t.Describe()+".")
+			}
+			return
+		}
+		s.setFlash(w, "error", "Target not found.")
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/notify"
+)
+
+func TestChatNotifications_P0CreatedAndSyncFailed(t *testing.T) {
+	type push struct{ title, body, prio string }
+	got := make(chan push, 4)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got <- push{r.Header.Get("Title"), string(b), r.Header.Get("Priority")}
+	}))
+	defer ntfy.Close()
+	wait := func() push {
+		t.Helper()
+		select {
+		case p := <-got:
+			return p
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification received")
+		}
+		return push{}
+	}
+
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	marker := filepath.Join(dir, "added")
+	script := `if [ "$1" = "add" ]; then touch "` + marker + `"; exit 0; fi
+if [ "$1" = "sync" ]; then echo "fatal: could not read from remote" >&2; exit 1; fi
+if [ "$1" = "export" ]; then
+  if [ -f "` + marker + `" ]; then
+    echo '[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"pending","summary":"Old","priority":"P2"},{"id":2,"uuid":"22222222-2222-2222-2222-222222222222","status":"pending","summary":"Prod down","priority":"P0"}]'
+  else
+    echo '[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"pending","summary":"Old","priority":"P2"}]'
+  fi
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	s.cfg.Chat.AllowHosts = []string{"127.0.0.1"}
+	err := notify.SavePrefs(s.cfg, "admin", notify.Prefs{Targets: []notify.Target{{
+		ID: "t1", Type: notify.TargetNtfy, URL: ntfy.URL, Topic: "me", Events: []string{notify.EventP0Created, notify.EventSyncFailed},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := testPost(s, "/tasks", url.Values{"summary": {"Prod down P0"}}); rr.Code != http.StatusSeeOther {
+		t.Fatalf("create: %d %s", rr.Code, rr.Body.String())
+	}
+	if p := wait(); p.title != "P0 task created by admin" || p.body != "#2 Prod down" || p.prio != "high" {
+		t.Fatalf("unexpected P0 push: %+v", p)
+	}
+
+	s.runSync("admin", "Sync")
+	if p := wait(); p.title != "Sync failed" || !strings.Contains(p.body, "could not read from remote") {
+		t.Fatalf("unexpected sync push: %+v", p)
+	}
+}
+
+func TestChatTarget_RefusesPrivateDestinationsAndRedirects(t *testing.T) {
+	hits := 0
+	inner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_, _ = w.Write([]byte("instance-secret"))
+	}))
+	defer inner.Close()
+	s := newTestServerWithStub(t, "true", t.TempDir())
+	msg := notify.Message{Title: "t", Body: "b"}
+
+	// Loopback ohne chat.allowHosts: keine Verbindung
+	err := s.sendToTarget(notify.Target{Type: notify.TargetNtfy, URL: inner.URL, Topic: "x"}, msg)
+	if !errors.Is(err, errChatBlocked) || hits != 0 {
+		t.Fatalf("expected blocked destination, got %v (hits %d)", err, hits)
+	}
+	if txt := chatErrorText(err); strings.Contains(txt, "127.0.0.1") {
+		t.Fatalf("address leaked into flash text: %q", txt)
+	}
+
+	// freigegebener Host, der weiterleitet: die Weiterleitung wird nicht verfolgt
+	s.cfg.Chat.AllowHosts = []string{"127.0.0.1"}
+	redirect := httptest.NewServer(http.RedirectHandler(inner.URL, http.StatusFound))
+	defer redirect.Close()
+	err = s.sendToTarget(notify.Target{Type: notify.TargetNtfy, URL: redirect.URL, Topic: "x"}, msg)
+	if err == nil || hits != 0 || strings.Contains(chatErrorText(err), "instance-secret") {
+		t.Fatalf("redirect followed or body echoed: %v (hits %d)", err, hits)
+	}
+}
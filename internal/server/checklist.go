@@ -0,0 +1,132 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// checklistItemRe erkennt mögliche Checklisten-Einträge in der Quelle: "- [ ] offen", "* [x] erledigt",
+// "1. [ ] …", auch in Zitaten ("> - [ ] …"). Ob daraus eine Checkbox wird, entscheidet der Markdown-Renderer.
+var checklistItemRe = regexp.MustCompile(`^((?:[ \t]*>)*[ \t]*(?:[-*+]|\d+[.)])[ \t]+\[)([ xX])(\])`)
+
+// Jeder mögliche Eintrag bekommt vor dem Rendern hinter "]" eine Markierung mit seiner Zeilennummer
+// (Zeichen aus der Private Use Area). Nur Markierungen, die im HTML am Anfang eines <li> stehen, werden
+// zu Checkboxen; so stammen Zählung, Reihenfolge und Zeilen aus einer Quelle, dem Renderer. Markierungen
+// in Code-Blöcken o. Ä. werden wieder entfernt.
+var (
+	checklistHTMLRe   = regexp.MustCompile(`<li>(<p>)?\[([ xX])\]\x{E000}(\d+)\x{E001}`)
+	checklistMarkerRe = regexp.MustCompile(`\x{E000}\d+\x{E001}`)
+)
+
+// renderChecklist rendert notes als Markdown mit Checkboxen (data-index in Dokumentreihenfolge) und liefert
+// zu jeder Checkbox die Zeile in notes und den Status; nur interaktive Checkboxen (Edit-Seite) sind anklickbar.
+func renderChecklist(notes string, interactive bool) (html string, lines []int, checked []bool) {
+	src := strings.Split(notes, "\n")
+	for i, line := range src {
+		if loc := checklistItemRe.FindStringSubmatchIndex(line); loc != nil {
+			src[i] = line[:loc[1]] + "\uE000" + strconv.Itoa(i) + "\uE001" + line[loc[1]:]
+		}
+	}
+	html = string(markdown.ToHTML([]byte(strings.Join(src, "\n")), nil, nil))
+	html = checklistHTMLRe.ReplaceAllStringFunc(html, func(m string) string {
+		sub := checklistHTMLRe.FindStringSubmatch(m)
+		line, _ := strconv.Atoi(sub[3])
+		attrs := ` class="checklist-item" data-index="` + strconv.Itoa(len(lines)) + `"`
+		lines = append(lines, line)
+		checked = append(checked, sub[2] != " ")
+		if sub[2] != " " {
+			attrs += " checked"
+		}
+		if !interactive {
+			attrs += " disabled"
+		}
+		return "<li>" + sub[1] + `<input type="checkbox"` + attrs + ">"
+	})
+	return checklistMarkerRe.ReplaceAllString(html, ""), lines, checked
+}
+
+// checklistItems liefert Zeilennummer und Status aller Checklisten-Einträge in der Reihenfolge der
+// gerenderten Checkboxen.
+func checklistItems(notes string) (lines []int, checked []bool) {
+	_, lines, checked = renderChecklist(notes, false)
+	return lines, checked
+}
+
+// checklistProgress zählt erledigte und alle Checklisten-Einträge in notes.
+func checklistProgress(notes string) (done, total int) {
+	_, checked := checklistItems(notes)
+	for _, c := range checked {
+		if c {
+			done++
+		}
+	}
+	return done, len(checked)
+}
+
+var errChecklistIndex = errors.New("checklist item not found")
+
+// setChecklistItem setzt den index-ten Eintrag (ab 0, wie data-index) auf erledigt bzw. offen.
+func setChecklistItem(notes string, index int, checked bool) (string, error) {
+	lines, _ := checklistItems(notes)
+	if index < 0 || index >= len(lines) {
+		return "", errChecklistIndex
+	}
+	all := strings.Split(notes, "\n")
+	mark := " "
+	if checked {
+		mark = "x"
+	}
+	all[lines[index]] = checklistItemRe.ReplaceAllString(all[lines[index]], "${1}"+mark+"${3}")
+	return strings.Join(all, "\n"), nil
+}
+
+// renderChecklistMarkdown rendert Notizen wie renderMarkdown, aber mit anklickbaren Checklisten.
+func renderChecklistMarkdown(text string) template.HTML {
+	if text == "" {
+		return template.HTML("")
+	}
+	html, _, _ := renderChecklist(text, true)
+	return template.HTML(html)
+}
+
+// handleChecklistToggle hakt einen Checklisten-Eintrag in den Notizen des Tasks id ab (checked=1) oder
+// wieder auf und schreibt die Notizen über UpdateTaskNotesDirectly zurück. Antwort: neue Notizen und Fortschritt.
+func (s *Server) handleChecklistToggle(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil {
+		http.Error(w, "index required", http.StatusBadRequest)
+		return
+	}
+	key := s.repoKey(r)
+	task, ok := s.findTask(key, id)
+	if !ok {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	notes, err := setChecklistItem(trimQuotes(str(firstOf(task, "notes", "annotations", "note"))), index, r.FormValue("checked") == "1")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	target := firstNonEmptyString(str(firstOf(task, "uuid", "UUID")), id)
+	if err := s.runner.UpdateTaskNotesDirectly(key, target, notes); err != nil {
+		applog.Warnf("checklist update for task %s failed: %v", id, err)
+		http.Error(w, "saving notes failed", http.StatusBadGateway)
+		return
+	}
+	s.cmdStore.Append(key, "Tick checklist item", []string{"note", id})
+	s.autoSync(key)
+	done, total := checklistProgress(notes)
+	writeJSON(w, map[string]any{"notes": notes, "done": done, "total": total})
+}
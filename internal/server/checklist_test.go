@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChecklist_ProgressToggleAndHTML(t *testing.T) {
+	notes := "Steps:\n\n- [ ] a\n- [x] b\n  - [ ] nested\n\n```\n- [ ] not an item\n```\n\n1. [X] numbered\n"
+	if done, total := checklistProgress(notes); done != 2 || total != 4 {
+		t.Fatalf("progress = %d/%d, want 2/4", done, total)
+	}
+	got, err := setChecklistItem(notes, 2, true)
+	if err != nil || !strings.Contains(got, "  - [x] nested") || !strings.Contains(got, "- [ ] not an item") {
+		t.Fatalf("setChecklistItem: %v %q", err, got)
+	}
+	if _, err := setChecklistItem(notes, 4, true); err == nil {
+		t.Fatalf("expected error for out-of-range index")
+	}
+
+	// Checkbox-Indizes im HTML müssen zu den Einträgen in den Notizen passen
+	html := string(renderChecklistMarkdown(notes))
+	if strings.Count(html, `class="checklist-item"`) != 4 || !strings.Contains(html, `data-index="3" checked>`) {
+		t.Fatalf("unexpected html: %s", html)
+	}
+	if !strings.Contains(string(renderMarkdown(notes)), `data-index="0" disabled>`) {
+		t.Fatalf("read-only rendering should disable checkboxes")
+	}
+}
+
+func TestChecklistToggle_WritesNotes(t *testing.T) {
+	const uuid = "0f8c3e2a-1b2c-4d5e-8f90-123456789abc"
+	dir := t.TempDir()
+	home, repo := newTaskHome(t, dir, true)
+	if err := os.MkdirAll(filepath.Join(repo, "pending"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	taskFile := filepath.Join(repo, "pending", uuid+".yml")
+	if err := os.WriteFile(taskFile, []byte("summary: Release\nnotes: \"- [ ] build\\n- [ ] tag\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := `cat <<'JSON'
+[{"id":1,"uuid":"` + uuid + `","status":"pending","summary":"Release","notes":"- [ ] build\n- [ ] tag"}]
+JSON
+`
+	s := newTestServerWithStub(t, writeShellStub(t, dir, script), home)
+
+	rr := testPost(s, "/tasks/1/checklist", url.Values{"index": {"1"}, "checked": {"1"}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("toggle: %d %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Notes       string `json:"notes"`
+		Done, Total int
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Notes != "- [ ] build\n- [x] tag" || resp.Done != 1 || resp.Total != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if b, _ := os.ReadFile(taskFile); !strings.Contains(string(b), `[x] tag`) {
+		t.Fatalf("notes not written: %s", b)
+	}
+}
+
+func TestChecklist_IndicesFollowRenderedHTML(t *testing.T) {
+	cases := []struct {
+		name, notes string
+		lines       []int
+	}{
+		{"blockquote", "> - [ ] quoted\n> - [x] also quoted\n\n- [ ] plain", []int{0, 1, 3}},
+		{"code fence", "```\n- [ ] in fence\n```\n~~~\n- [x] tilde fence\n~~~\n- [ ] real", []int{6}},
+		{"indented code", "Example:\n\n    - [ ] in code\n\n- [ ] real\n- [x] done", []int{4, 5}},
+	}
+	for _, c := range cases {
+		html, lines, checked := renderChecklist(c.notes, true)
+		if strings.Count(html, `class="checklist-item"`) != len(c.lines) || len(lines) != len(c.lines) {
+			t.Fatalf("%s: %d checkboxes for lines %v, want %v: %s", c.name, strings.Count(html, `class="checklist-item"`), lines, c.lines, html)
+		}
+		for i, l := range c.lines {
+			if lines[i] != l {
+				t.Fatalf("%s: checkbox %d maps to line %d, want %d", c.name, i, lines[i], l)
+			}
+		}
+		if strings.ContainsAny(html, "\uE000\uE001") {
+			t.Fatalf("%s: line markers left in html: %q", c.name, html)
+		}
+		// letzte Checkbox umschalten trifft genau ihre Zeile
+		last := len(c.lines) - 1
+		got, err := setChecklistItem(c.notes, last, !checked[last])
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, out := strings.Split(c.notes, "\n"), strings.Split(got, "\n")
+		for i := range src {
+			if changed := src[i] != out[i]; changed != (i == c.lines[last]) {
+				t.Fatalf("%s: toggling item %d changed line %d: %q -> %q", c.name, last, i, src[i], out[i])
+			}
+		}
+	}
+}
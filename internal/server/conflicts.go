@@ -0,0 +1,163 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// handleSyncConflicts zeigt ungelöste Merge-Konflikte nach einem fehlgeschlagenen Sync
+// als feldweisen Drei-Wege-Vergleich (base, lokal/ours, remote/theirs).
+func (s *Server) handleSyncConflicts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	conflicts, err := s.runner.Conflicts(username)
+	if err != nil {
+		applog.Warnf("/sync/conflicts: %v", err)
+		http.Error(w, "failed to read conflicts", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Sync conflicts</h2>
+{{if not .Conflicts}}
+  <p>No conflicted task files.</p>
+  {{if .Merging}}
+  <form method="post" action="/sync/conflicts/finish" style="display:inline;">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+    <button type="submit">Commit merge and push</button>
+  </form>
+  {{end}}
+{{else}}
+<p>Local and remote changes to the same tasks could not be merged automatically. Pick a value per field; the resolved task is written, and once all files are resolved the merge is committed and pushed.</p>
+{{range .Conflicts}}
+<form method="post" action="/sync/conflicts/resolve" style="border:1px solid #d0d7de;border-radius:6px;padding:8px;margin-bottom:12px;">
+  <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/>
+  <input type="hidden" name="path" value="{{.Path}}"/>
+  <h3 style="margin-top:0;">{{if .Summary}}{{.Summary}}{{else}}{{.UUID}}{{end}} <code style="font-size:70%;">{{.Path}}</code></h3>
+  {{if and .HasOurs .HasTheirs}}
+  <table>
+    <thead><tr><th>Field</th><th>Base</th><th>Local (ours)</th><th>Remote (theirs)</th></tr></thead>
+    <tbody>
+    {{range .Fields}}{{if or (ne .Ours .Theirs) .Conflicting}}
+    <tr {{if .Conflicting}}style="background:#fff5f5;"{{end}}>
+      <td><strong>{{.Field}}</strong></td>
+      <td><label><input type="radio" name="f_{{.Field}}" value="base"/> <span style="white-space:pre-wrap;">{{.Base}}</span></label></td>
+      <td><label><input type="radio" name="f_{{.Field}}" value="ours" {{if or .Conflicting (ne .Ours .Base)}}checked{{end}}/> <span style="white-space:pre-wrap;">{{.Ours}}</span></label></td>
+      <td><label><input type="radio" name="f_{{.Field}}" value="theirs" {{if and (not .Conflicting) (eq .Ours .Base)}}checked{{end}}/> <span style="white-space:pre-wrap;">{{.Theirs}}</span></label></td>
+    </tr>
+    {{end}}{{end}}
+    </tbody>
+  </table>
+  <button type="submit">Save resolution</button>
+  {{else}}
+  <p>{{if .HasOurs}}The task was changed locally but removed or moved on the remote.{{else}}The task was changed on the remote but removed or moved locally.{{end}}</p>
+  {{end}}
+  <span style="margin-left:8px;">Whole file:</span>
+  {{if .HasOurs}}<button type="submit" name="whole" value="ours">keep local</button>{{end}}
+  {{if .HasTheirs}}<button type="submit" name="whole" value="theirs">keep remote</button>{{end}}
+  <button type="submit" name="whole" value="delete" onclick="return confirm('Delete this task file?');">delete</button>
+</form>
+{{end}}
+{{end}}
+{{if .Merging}}
+<form method="post" action="/sync/conflicts/abort" onsubmit="return confirm('Abort the merge and return to the local state?');">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <button type="submit" style="background:#dc3545;color:#fff;border:none;padding:6px 10px;border-radius:4px;">Abort merge</button>
+</form>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Conflicts": conflicts,
+		"Merging":   s.runner.MergeInProgress(username),
+		"CSRFToken": csrfToken,
+	}))
+}
+
+// handleSyncConflictResolve schreibt die Auflösung einer Datei und schließt den Merge ab, wenn nichts mehr offen ist.
+func (s *Server) handleSyncConflictResolve(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username := s.repoKey(r)
+	choices := map[string]string{}
+	for k, v := range r.PostForm {
+		if strings.HasPrefix(k, "f_") && len(v) > 0 {
+			choices[strings.TrimPrefix(k, "f_")] = v[0]
+		}
+	}
+	if err := s.runner.ResolveConflict(username, r.FormValue("path"), r.FormValue("whole"), choices); err != nil {
+		applog.Warnf("/sync/conflicts/resolve: %v", err)
+		s.setFlash(w, "error", "Resolving failed: "+stripANSI(err.Error()))
+		http.Redirect(w, r, "/sync/conflicts", http.StatusSeeOther)
+		return
+	}
+	s.finishMerge(w, r, username)
+}
+
+func (s *Server) handleSyncConflictFinish(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username := s.repoKey(r)
+	s.finishMerge(w, r, username)
+}
+
+func (s *Server) handleSyncConflictAbort(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username := s.repoKey(r)
+	if err := s.runner.AbortMerge(username); err != nil {
+		s.setFlash(w, "error", "Abort failed: "+stripANSI(err.Error()))
+		http.Redirect(w, r, "/sync/conflicts", http.StatusSeeOther)
+		return
+	}
+	s.setFlash(w, "success", "Merge aborted. The local state has been restored.")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// requirePostCSRF prüft Methode, Formular und CSRF-Token einfacher POST-Aktionen.
+func (s *Server) requirePostCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return false
+	}
+	if !validateCSRFToken(r, r.FormValue("csrf_token")) {
+		http.Error(w, "invalid CSRF token", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (s *Server) finishMerge(w http.ResponseWriter, r *http.Request, username string) {
+	remaining, err := s.runner.FinishMerge(username)
+	switch {
+	case err != nil:
+		applog.Warnf("finishing merge for %s failed: %v", username, err)
+		s.setFlash(w, "error", "Commit/push after resolving failed: "+stripANSI(err.Error()))
+	case remaining > 0:
+		s.setFlash(w, "info", "Resolution saved. Remaining conflicted files: "+strconv.Itoa(remaining))
+	default:
+		s.setFlash(w, "success", "All conflicts resolved; merge committed and pushed.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/sync/conflicts", http.StatusSeeOther)
+}
+
+// syncConflictsExist ist ein kleiner Helfer für den /sync-Handler.
+func (s *Server) syncConflictsExist(username string) bool {
+	c, err := s.runner.Conflicts(username)
+	return err == nil && len(c) > 0
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSyncConflictsPage_NoConflicts(t *testing.T) {
+	home, _ := newGitTaskHome(t)
+	s := newTestServerWithStub(t, "/bin/true", home)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/sync/conflicts", nil)
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "No conflicted task files") {
+		t.Fatalf("unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "Abort merge") {
+		t.Fatalf("abort button shown without merge in progress")
+	}
+}
+
+func TestSyncConflictResolve_RequiresCSRF(t *testing.T) {
+	s := newTestServer(t)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/sync/conflicts/resolve", strings.NewReader("path=x&csrf_token=a"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,475 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// blockedByRe erkennt Abhängigkeiten in Notizen, z. B. "blocked by #3" oder "Blocked by #3, #7 and #9".
+var blockedByRe = regexp.MustCompile(`(?i)\bblocked by\s+(#\d+(?:\s*(?:,|and)\s*#\d+)*)`)
+
+var taskRefRe = regexp.MustCompile(`#(\d+)`)
+
+// notesBlockerIDs liefert die Task-IDs aller "blocked by #N"-Verweise in notes.
+func notesBlockerIDs(notes string) []string {
+	var ids []string
+	for _, m := range blockedByRe.FindAllStringSubmatch(notes, -1) {
+		for _, ref := range taskRefRe.FindAllStringSubmatch(m[1], -1) {
+			ids = append(ids, ref[1])
+		}
+	}
+	return ids
+}
+
+// depGraph verbindet den Export eines Repos mit dependencies.yaml und den "blocked by"-Verweisen der Notizen.
+type depGraph struct {
+	tasks     map[string]map[string]any // UUID -> exportierter Task
+	idToUUID  map[string]string         // ID offener Tasks -> UUID
+	stored    map[string][]string       // UUID -> Blocker aus dependencies.yaml
+	fromNotes map[string][]string       // UUID -> Blocker aus den Notizen
+}
+
+func newDepGraph(tasks []map[string]any, stored map[string][]string) *depGraph {
+	g := &depGraph{
+		tasks:     map[string]map[string]any{},
+		idToUUID:  map[string]string{},
+		stored:    map[string][]string{},
+		fromNotes: map[string][]string{},
+	}
+	for _, t := range tasks {
+		uuid := strings.ToLower(str(firstOf(t, "uuid", "UUID")))
+		if uuid == "" {
+			continue
+		}
+		g.tasks[uuid] = t
+		if id := str(firstOf(t, "id", "ID", "Id")); id != "" && id != "0" && !isResolved(t) {
+			g.idToUUID[id] = uuid
+		}
+	}
+	for uuid, bs := range stored {
+		g.stored[strings.ToLower(uuid)] = bs
+	}
+	for uuid, t := range g.tasks {
+		for _, id := range notesBlockerIDs(trimQuotes(str(firstOf(t, "notes", "annotations", "note")))) {
+			if b, ok := g.idToUUID[id]; ok && b != uuid {
+				g.fromNotes[uuid] = append(g.fromNotes[uuid], b)
+			}
+		}
+	}
+	return g
+}
+
+// loadDepGraph liest Export und dependencies.yaml des Repos key.
+func (s *Server) loadDepGraph(key string) (*depGraph, error) {
+	stored, err := s.runner.Dependencies(key)
+	if err != nil {
+		return nil, err
+	}
+	res := s.runner.Run(key, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		return nil, errors.New(firstNonEmptyString(stripANSI(res.Stderr), errString(res.Err), "dstask export failed"))
+	}
+	tasks, _ := decodeTasksJSONFlexible(res.Stdout)
+	return newDepGraph(tasks, stored), nil
+}
+
+// depGraphForRows lädt den Graphen nur, wenn es überhaupt Abhängigkeiten geben kann
+// (Einträge in dependencies.yaml oder "blocked by" in den Notizen der Zeilen); sonst nil.
+func (s *Server) depGraphForRows(key string, rows []map[string]string) *depGraph {
+	stored, err := s.runner.Dependencies(key)
+	if err != nil {
+		applog.Warnf("reading %s failed: %v", key, err)
+	}
+	needed := len(stored) > 0
+	for i := 0; !needed && i < len(rows); i++ {
+		needed = blockedByRe.MatchString(rows[i]["notes"])
+	}
+	if !needed {
+		return nil
+	}
+	g, err := s.loadDepGraph(key)
+	if err != nil {
+		applog.Warnf("loading dependencies for %s failed: %v", key, err)
+		return nil
+	}
+	return g
+}
+
+// lookup liefert die UUID zu einer Task-ID oder UUID.
+func (g *depGraph) lookup(id string) string {
+	if u, ok := g.idToUUID[id]; ok {
+		return u
+	}
+	if _, ok := g.tasks[strings.ToLower(id)]; ok {
+		return strings.ToLower(id)
+	}
+	return ""
+}
+
+func (g *depGraph) isOpen(uuid string) bool {
+	t, ok := g.tasks[uuid]
+	return ok && !isResolved(t)
+}
+
+// blockers liefert alle Blocker von uuid (Sidecar und Notizen), sortiert und ohne Duplikate.
+func (g *depGraph) blockers(uuid string) []string {
+	return uniqueSorted(append(append([]string{}, g.stored[uuid]...), g.fromNotes[uuid]...))
+}
+
+// openBlockers liefert die noch nicht erledigten Blocker von uuid; nur diese blockieren.
+func (g *depGraph) openBlockers(uuid string) []string {
+	var out []string
+	for _, b := range g.blockers(uuid) {
+		if g.isOpen(b) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// openDependents liefert die offenen Tasks, die auf uuid warten.
+func (g *depGraph) openDependents(uuid string) []string {
+	var out []string
+	for t := range g.tasks {
+		if !g.isOpen(t) {
+			continue
+		}
+		for _, b := range g.blockers(t) {
+			if b == uuid {
+				out = append(out, t)
+				break
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return g.ref(out[i]) < g.ref(out[j]) })
+	return out
+}
+
+// ref formatiert einen Task als "#ID" (offene Tasks) bzw. mit gekürzter UUID.
+func (g *depGraph) ref(uuid string) string {
+	if t, ok := g.tasks[uuid]; ok && !isResolved(t) {
+		if id := str(firstOf(t, "id", "ID", "Id")); id != "" && id != "0" {
+			return "#" + id
+		}
+	}
+	if len(uuid) > 8 {
+		return uuid[:8]
+	}
+	return uuid
+}
+
+func (g *depGraph) refs(uuids []string) string {
+	out := make([]string, 0, len(uuids))
+	for _, u := range uuids {
+		out = append(out, g.ref(u))
+	}
+	return strings.Join(out, ", ")
+}
+
+func uniqueSorted(in []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// filterBlocked entfernt Zeilen mit offenen Blockern und liefert die Anzahl der ausgeblendeten.
+func filterBlocked(g *depGraph, rows []map[string]string) ([]map[string]string, int) {
+	if g == nil {
+		return rows, 0
+	}
+	out := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		if len(g.openBlockers(strings.ToLower(row["uuid"]))) > 0 {
+			continue
+		}
+		out = append(out, row)
+	}
+	return out, len(rows) - len(out)
+}
+
+// hideBlockedRows blendet in /next blockierte Tasks aus, sofern nicht ?showBlocked=1 gesetzt ist.
+// extra enthält den Hinweis samt Link zum Einblenden für renderExportTableWith.
+func (s *Server) hideBlockedRows(r *http.Request, rows []map[string]string) ([]map[string]string, map[string]any) {
+	if r.URL.Query().Get("showBlocked") == "1" {
+		return rows, nil
+	}
+	rows, hidden := filterBlocked(s.depGraphForRows(s.repoKey(r), rows), rows)
+	if hidden == 0 {
+		return rows, nil
+	}
+	q := r.URL.Query()
+	q.Set("showBlocked", "1")
+	return rows, map[string]any{"HiddenBlocked": hidden, "ShowBlockedURL": r.URL.Path + "?" + q.Encode()}
+}
+
+// dependentsWarning liefert einen Hinweis, falls einer der Tasks ids noch von anderen offenen Tasks
+// benötigt wird (vor "done" aufrufen, danach ist die ID nicht mehr auflösbar).
+func (s *Server) dependentsWarning(key string, ids []string) string {
+	g, err := s.loadDepGraph(key)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, id := range ids {
+		uuid := g.lookup(strings.TrimSpace(id))
+		if uuid == "" {
+			continue
+		}
+		if deps := g.openDependents(uuid); len(deps) > 0 {
+			parts = append(parts, g.ref(uuid)+" is blocking "+g.refs(deps))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Warning: " + strings.Join(parts, "; ") + "."
+}
+
+// parseTaskRefs wandelt Eingaben wie "#3, 7 0f8c3e2a-…" in UUIDs um.
+func (g *depGraph) parseTaskRefs(value string) ([]string, error) {
+	var out []string
+	for _, tok := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		tok = strings.TrimPrefix(strings.TrimSpace(tok), "#")
+		if tok == "" {
+			continue
+		}
+		uuid := g.lookup(tok)
+		if uuid == "" {
+			return nil, errors.New("unknown task #" + tok)
+		}
+		out = append(out, uuid)
+	}
+	return uniqueSorted(out), nil
+}
+
+// updateBlockers setzt die in dependencies.yaml gespeicherten Blocker des Tasks id aus dem Formularwert
+// ("#3, #7"). Erledigte Blocker, die nicht mehr angezeigt werden, entfallen dabei.
+func (s *Server) updateBlockers(key, id, value string) error {
+	g, err := s.loadDepGraph(key)
+	if err != nil {
+		return err
+	}
+	uuid := g.lookup(id)
+	if uuid == "" {
+		return errors.New("task " + id + " not found")
+	}
+	want, err := g.parseTaskRefs(value)
+	if err != nil {
+		return err
+	}
+	var current []string
+	for _, b := range uniqueSorted(g.stored[uuid]) {
+		if g.isOpen(b) {
+			current = append(current, b)
+		}
+	}
+	if strings.Join(current, ",") == strings.Join(want, ",") {
+		return nil
+	}
+	// SetBlockers prüft nur dependencies.yaml; Verweise aus Notizen können ebenfalls Zyklen schließen
+	all := map[string][]string{}
+	for t := range g.tasks {
+		all[t] = g.blockers(t)
+	}
+	if dstask.WouldCycle(all, uuid, want) {
+		return errors.New("dependency would create a cycle")
+	}
+	return s.runner.SetBlockers(key, uuid, want)
+}
+
+// graphNode und graphEdge beschreiben das SVG-Layout des Abhängigkeitsgraphen.
+type graphNode struct {
+	X, Y     int
+	Ref      string
+	UUID     string
+	ID       string // aktuelle ID für den Link auf die Bearbeiten-Seite (leer = kein Link)
+	Label    string
+	Title    string
+	Blocked  bool
+	External bool // Task aus einem anderen Projekt
+}
+
+type graphEdge struct {
+	X1, Y1, X2, Y2 int
+}
+
+const (
+	graphNodeW   = 190
+	graphNodeH   = 40
+	graphColGap  = 70
+	graphRowGap  = 20
+	graphPadding = 20
+)
+
+// layoutDepGraph ordnet die offenen Tasks eines Projekts (leer = alle) mit Abhängigkeiten in Spalten an:
+// Tasks ohne offene Blocker links, abhängige Tasks jeweils eine Spalte rechts ihres spätesten Blockers.
+func layoutDepGraph(g *depGraph, project string) ([]graphNode, []graphEdge, int, int) {
+	inProject := func(uuid string) bool {
+		return project == "" || strings.EqualFold(trimQuotes(str(firstOf(g.tasks[uuid], "project"))), project)
+	}
+	nodes := map[string]bool{}
+	type edge struct{ from, to string }
+	var edges []edge
+	for uuid := range g.tasks {
+		if !g.isOpen(uuid) {
+			continue
+		}
+		for _, b := range g.openBlockers(uuid) {
+			if inProject(uuid) || inProject(b) {
+				nodes[uuid], nodes[b] = true, true
+				edges = append(edges, edge{b, uuid})
+			}
+		}
+	}
+	// Spalte = längster Pfad von einem unblockierten Task; visiting schützt vor Zyklen aus Notizen
+	layer := map[string]int{}
+	visiting := map[string]bool{}
+	var depth func(string) int
+	depth = func(uuid string) int {
+		if l, ok := layer[uuid]; ok {
+			return l
+		}
+		if visiting[uuid] {
+			return 0
+		}
+		visiting[uuid] = true
+		l := 0
+		for _, b := range g.openBlockers(uuid) {
+			if nodes[b] {
+				if d := depth(b) + 1; d > l {
+					l = d
+				}
+			}
+		}
+		visiting[uuid] = false
+		layer[uuid] = l
+		return l
+	}
+	cols := map[int][]string{}
+	maxLayer, maxRows := 0, 0
+	for uuid := range nodes {
+		l := depth(uuid)
+		cols[l] = append(cols[l], uuid)
+		if l > maxLayer {
+			maxLayer = l
+		}
+	}
+	pos := map[string][2]int{}
+	var out []graphNode
+	for l := 0; l <= maxLayer; l++ {
+		col := cols[l]
+		sort.Slice(col, func(i, j int) bool { return g.ref(col[i]) < g.ref(col[j]) })
+		if len(col) > maxRows {
+			maxRows = len(col)
+		}
+		for i, uuid := range col {
+			x := graphPadding + l*(graphNodeW+graphColGap)
+			y := graphPadding + i*(graphNodeH+graphRowGap)
+			pos[uuid] = [2]int{x, y}
+			summary := trimQuotes(str(firstOf(g.tasks[uuid], "summary", "description")))
+			label := g.ref(uuid) + " " + summary
+			id := "" // Links über die aktuelle ID: der Bearbeiten-Handler löst keine UUIDs auf
+			if ref := g.ref(uuid); strings.HasPrefix(ref, "#") {
+				id = ref[1:]
+			}
+			if utf8.RuneCountInString(label) > 26 {
+				label = string([]rune(label)[:25]) + "…"
+			}
+			out = append(out, graphNode{
+				X: x, Y: y, Ref: g.ref(uuid), UUID: uuid, ID: id, Label: label, Title: g.ref(uuid) + " " + summary,
+				Blocked: len(g.openBlockers(uuid)) > 0, External: !inProject(uuid),
+			})
+		}
+	}
+	var lines []graphEdge
+	for _, e := range edges {
+		from, to := pos[e.from], pos[e.to]
+		lines = append(lines, graphEdge{X1: from[0] + graphNodeW, Y1: from[1] + graphNodeH/2, X2: to[0], Y2: to[1] + graphNodeH/2})
+	}
+	width := 2*graphPadding + (maxLayer+1)*graphNodeW + maxLayer*graphColGap
+	height := 2*graphPadding + maxRows*graphNodeH + max(maxRows-1, 0)*graphRowGap
+	return out, lines, width, height
+}
+
+// handleDependencyGraph zeigt den Abhängigkeitsgraphen eines Projekts (?project=, leer = alle Projekte) als SVG.
+func (s *Server) handleDependencyGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := s.repoKey(r)
+	s.cmdStore.Append(key, "Show dependency graph", []string{"export"})
+	g, err := s.loadDepGraph(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	project := strings.TrimSpace(r.URL.Query().Get("project"))
+	nodes, edges, width, height := layoutDepGraph(g, project)
+	projectSet := map[string]bool{}
+	for uuid := range g.tasks {
+		if p := trimQuotes(str(firstOf(g.tasks[uuid], "project"))); p != "" && g.isOpen(uuid) {
+			projectSet[p] = true
+		}
+	}
+	projects := make([]string, 0, len(projectSet))
+	for p := range projectSet {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Dependencies{{if .Project}}: {{.Project}}{{end}}</h2>
+<form method="get" action="/projects/graph" style="margin-bottom:8px;">
+  <label>Project:
+    <select name="project" onchange="this.form.submit()">
+      <option value="">(all projects)</option>
+      {{range .Projects}}<option value="{{.}}" {{if eq . $.Project}}selected{{end}}>{{.}}</option>{{end}}
+    </select>
+  </label>
+  <noscript><button type="submit">Show</button></noscript>
+</form>
+{{if .Nodes}}
+<p style="color:#57606a;">Arrows point from a blocking task to the task waiting for it. Red tasks are blocked, dashed tasks belong to another project.</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" style="max-width:100%;height:auto;font:12px sans-serif;">
+  <defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#57606a"/></marker></defs>
+  {{range .Edges}}<line x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}" stroke="#57606a" marker-end="url(#arrow)"/>
+  {{end}}
+  {{range .Nodes}}{{if .ID}}<a href="/tasks/{{.ID}}/edit">{{end}}<g>
+    <title>{{.Title}}</title>
+    <rect x="{{.X}}" y="{{.Y}}" width="{{$.NodeW}}" height="{{$.NodeH}}" rx="6" fill="{{if .Blocked}}#ffebe9{{else}}#dafbe1{{end}}" stroke="{{if .Blocked}}#cf222e{{else}}#1a7f37{{end}}"{{if .External}} stroke-dasharray="4 3"{{end}}/>
+    <text x="{{.X}}" y="{{.Y}}" dx="8" dy="24" fill="#24292f">{{.Label}}</text>
+  </g>{{if .ID}}</a>{{end}}
+  {{end}}
+</svg>
+{{else}}
+<p>No dependencies between open tasks{{if .Project}} in this project{{end}}. Set them via "Blocked by" on the task edit page or with a line like <code>blocked by #3</code> in the notes.</p>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Project":  project,
+		"Projects": projects,
+		"Nodes":    nodes,
+		"Edges":    edges,
+		"Width":    width,
+		"Height":   height,
+		"NodeW":    graphNodeW,
+		"NodeH":    graphNodeH,
+	}))
+}
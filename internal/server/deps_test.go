@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	depsUUID1 = "11111111-1111-1111-1111-111111111111"
+	depsUUID2 = "22222222-2222-2222-2222-222222222222"
+	depsUUID3 = "33333333-3333-3333-3333-333333333333"
+)
+
+func TestNotesBlockerIDs(t *testing.T) {
+	got := notesBlockerIDs("waiting.\nBlocked by #3, #7 and #9\nsee #12\nblocked by #4")
+	if strings.Join(got, ",") != "3,7,9,4" {
+		t.Fatalf("unexpected ids: %v", got)
+	}
+}
+
+// newDepsTestServer: #2 wartet laut Notiz auf #1, #3 laut dependencies.yaml auf #2.
+func newDepsTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	home, repo := newTaskHome(t, dir, true)
+	deps := "version: 1\ntasks:\n  " + depsUUID3 + ":\n    - " + depsUUID2 + "\n"
+	if err := os.WriteFile(filepath.Join(repo, "dependencies.yaml"), []byte(deps), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := `if [ "$1" = "export" ]; then
+  echo '[{"id":1,"uuid":"` + depsUUID1 + `","status":"pending","summary":"Write spec","project":"app"},
+{"id":2,"uuid":"` + depsUUID2 + `","status":"pending","summary":"Implement","project":"app","notes":"blocked by #1"},
+{"id":3,"uuid":"` + depsUUID3 + `","status":"pending","summary":"Deploy","project":"ops"}]'
+  exit 0
+fi
+if [ "$1" = "done" ] || [ "$2" = "modify" ]; then exit 0; fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	return newTestServerWithStub(t, stub, home), repo
+}
+
+func TestDependencies_NextGraphAndDoneWarning(t *testing.T) {
+	s, repo := newDepsTestServer(t)
+	get := func(path string) string {
+		rr := testGet(s, path)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, rr.Code, rr.Body.String())
+		}
+		return rr.Body.String()
+	}
+
+	body := get("/next?html=1")
+	if !strings.Contains(body, "Write spec") || strings.Contains(body, "Implement") || strings.Contains(body, "Deploy") {
+		t.Fatalf("blocked tasks not hidden: %s", body)
+	}
+	if !strings.Contains(body, "2 blocked task(s) hidden") {
+		t.Fatalf("hidden notice missing: %s", body)
+	}
+	body = get("/next?html=1&showBlocked=1")
+	if !strings.Contains(body, "Deploy") || !strings.Contains(body, `title="Blocked by #2"`) {
+		t.Fatalf("blocked badge missing: %s", body)
+	}
+
+	graph := get("/projects/graph?project=app")
+	if !strings.Contains(graph, "<svg") || !strings.Contains(graph, "#1 Write spec") || !strings.Contains(graph, "#3 Deploy") {
+		t.Fatalf("unexpected graph: %s", graph)
+	}
+	if !strings.Contains(graph, `stroke-dasharray`) {
+		t.Fatalf("task from other project not marked: %s", graph)
+	}
+	// Knoten verlinken über die aktuelle ID, die Bearbeiten-Seite findet den Task
+	if strings.Contains(graph, depsUUID3) || !strings.Contains(graph, `href="/tasks/3/edit"`) {
+		t.Fatalf("graph nodes not linked by ID: %s", graph)
+	}
+	if edit := get("/tasks/3/edit"); !strings.Contains(edit, "Deploy") {
+		t.Fatalf("node link does not open the task: %s", edit)
+	}
+
+	rr := testPost(s, "/tasks/1/done", url.Values{})
+	if c := rr.Header().Get("Set-Cookie"); !strings.Contains(c, "#1 is blocking #2") {
+		t.Fatalf("missing done warning: %q", c)
+	}
+
+	// #1 auf #3 warten zu lassen schlösse den Kreis über die Notiz von #2
+	rr = testPost(s, "/tasks/1/edit", url.Values{"summary": {"Write spec"}, "blockedBy": {"#3"}})
+	if c := rr.Header().Get("Set-Cookie"); !strings.Contains(c, "cycle") {
+		t.Fatalf("cycle not rejected: %q", c)
+	}
+	rr = testPost(s, "/tasks/3/edit", url.Values{"summary": {"Deploy"}, "blockedBy": {"#1, #2"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("edit: %d %s", rr.Code, rr.Body.String())
+	}
+	b, err := os.ReadFile(filepath.Join(repo, "dependencies.yaml"))
+	if err != nil || !strings.Contains(string(b), depsUUID1) || !strings.Contains(string(b), depsUUID2) {
+		t.Fatalf("blockers not saved: %v %s", err, b)
+	}
+}
@@ -73,6 +73,71 @@ func newTestServerWithStub(t *testing.T, stub string, home string) *Server {
 	return NewServerWithConfig(store, cfg)
 }
 
+// gitT führt git im Verzeichnis dir aus.
+func gitT(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v, out=%s", args, err, string(out))
+	}
+}
+
+// newTaskHome legt dir/home/.dstask an, mit git=true als leeres Git-Repo, und liefert home und repo.
+func newTaskHome(t *testing.T, dir string, git bool) (home, repo string) {
+	t.Helper()
+	home = filepath.Join(dir, "home")
+	repo = filepath.Join(home, ".dstask")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if git {
+		gitT(t, repo, "init", "-q")
+		gitT(t, repo, "config", "user.email", "t@example.com")
+		gitT(t, repo, "config", "user.name", "T")
+	}
+	return home, repo
+}
+
+// writeShellStub schreibt body als /bin/sh-Script nach dir/dstask und liefert den Pfad.
+func writeShellStub(t *testing.T, dir, body string) string {
+	t.Helper()
+	stub := filepath.Join(dir, "dstask")
+	if err := os.WriteFile(stub, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return stub
+}
+
+// serveAs schickt eine Anfrage als user (Passwort = Benutzername); POST-Formulare bekommen
+// das CSRF-Token samt Cookie.
+func serveAs(s *Server, user, method, path string, form url.Values) *httptest.ResponseRecorder {
+	var req *http.Request
+	if method == http.MethodPost {
+		if form == nil {
+			form = url.Values{}
+		}
+		form.Set("csrf_token", "tok")
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "tok"})
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	req.SetBasicAuth(user, user)
+	rr := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rr, req)
+	return rr
+}
+
+// testGet und testPost: Anfragen als admin.
+func testGet(s *Server, path string) *httptest.ResponseRecorder {
+	return serveAs(s, "admin", http.MethodGet, path, nil)
+}
+
+func testPost(s *Server, path string, form url.Values) *httptest.ResponseRecorder {
+	return serveAs(s, "admin", http.MethodPost, path, form)
+}
+
 func TestProjectsEndpoint_RendersTable_FromJSON(t *testing.T) {
 	tmp := t.TempDir()
 	home := filepath.Join(tmp, "home")
@@ -201,16 +266,17 @@ func TestMusicTasksHandlersPersistAndReturnState(t *testing.T) {
 	stub := createDstaskStub(t, tmp)
 	s := newTestServerWithStub(t, stub, home)
 
-	// Seed existing entry
-	m := music.Map{Version: 1, Tasks: map[string]music.TaskMusic{
-		"123": {Type: "radio", Name: "Init", URL: "https://stream", Volume: 0.42, Muted: true},
+	// Seed existing entry (Tasks sind nach UUID geschlüsselt)
+	const uuid = "0f8c3e2a-1b2c-4d5e-8f90-123456789abc"
+	m := music.Map{Version: music.CurrentVersion, Tasks: map[string]music.TaskMusic{
+		uuid: {Type: "radio", Name: "Init", URL: "https://stream", Volume: 0.42, Muted: true},
 	}}
 	if _, err := music.SaveForUser(s.cfg, "admin", &m); err != nil {
 		t.Fatalf("seed map failed: %v", err)
 	}
 
 	// GET should return persisted values
-	reqGet := httptest.NewRequest(http.MethodGet, "/music/tasks/123", nil)
+	reqGet := httptest.NewRequest(http.MethodGet, "/music/tasks/"+uuid, nil)
 	reqGet.SetBasicAuth("admin", "admin")
 	rrGet := httptest.NewRecorder()
 	s.Handler().ServeHTTP(rrGet, reqGet)
@@ -230,7 +296,7 @@ func TestMusicTasksHandlersPersistAndReturnState(t *testing.T) {
 		"muted":  false,
 	}
 	buf, _ := json.Marshal(payload)
-	reqPut := httptest.NewRequest(http.MethodPut, "/music/tasks/123", bytes.NewReader(buf))
+	reqPut := httptest.NewRequest(http.MethodPut, "/music/tasks/"+uuid, bytes.NewReader(buf))
 	reqPut.SetBasicAuth("admin", "admin")
 	reqPut.Header.Set("Content-Type", "application/json")
 	rrPut := httptest.NewRecorder()
@@ -244,11 +310,20 @@ func TestMusicTasksHandlersPersistAndReturnState(t *testing.T) {
 	if err != nil {
 		t.Fatalf("load map failed: %v", err)
 	}
-	entry, ok := loaded.Tasks["123"]
+	entry, ok := loaded.Tasks[uuid]
 	if !ok {
-		t.Fatalf("task 123 missing after PUT")
+		t.Fatalf("task %s missing after PUT", uuid)
 	}
 	if entry.Volume != 0.65 || entry.Muted {
 		t.Fatalf("unexpected entry after PUT: %+v", entry)
 	}
+
+	// eine Task-ID ohne offenen Task lässt sich keiner UUID zuordnen
+	reqPut = httptest.NewRequest(http.MethodPut, "/music/tasks/123", bytes.NewReader(buf))
+	reqPut.SetBasicAuth("admin", "admin")
+	rrPut = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rrPut, reqPut)
+	if rrPut.Code != http.StatusNotFound {
+		t.Fatalf("PUT for unknown id: status = %d", rrPut.Code)
+	}
 }
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// Ereignistypen auf dem Event-Bus.
+const (
+	EventTaskAssigned = "task.assigned"
+	EventTaskCreated  = "task.created"
+	EventTaskModified = "task.modified"
+	EventTaskStarted  = "task.started"
+	EventTaskStopped  = "task.stopped"
+	EventTaskDone     = "task.done"
+	EventTaskRemoved  = "task.removed"
+	EventRepoSynced   = "repo.synced"
+	EventSyncFailed   = "sync.failed"
+)
+
+// Event beschreibt eine Änderung, über die Nutzer oder externe Systeme benachrichtigt werden können.
+type Event struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Repo     string    `json:"repo"`   // Repo-Schlüssel des Auslösers
+	Actor    string    `json:"actor"`  // angemeldeter Nutzer, der die Änderung ausgelöst hat
+	Target   string    `json:"target"` // betroffener Nutzer (z. B. neuer Zuständiger)
+	TaskID   string    `json:"taskId,omitempty"`
+	TaskUUID string    `json:"taskUuid,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Action   string    `json:"action,omitempty"` // auslösende Aktion (start, done, edit, undo, …)
+	// Before/After: Task-Stand vor und nach der Änderung (dstask export), nur wenn ein Abonnent
+	// Snapshots braucht (siehe wantTaskSnapshots)
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after,omitempty"`
+}
+
+// eventBus verteilt Events synchron an alle Abonnenten. Abonnenten müssen schnell zurückkehren
+// und länger laufende Arbeit (Netzwerk) selbst in den Hintergrund verlagern.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs []func(Event)
+}
+
+func newEventBus() *eventBus { return &eventBus{} }
+
+// Subscribe registriert fn für alle künftigen Events.
+func (b *eventBus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, fn)
+}
+
+// Publish stellt ev allen Abonnenten zu; Panics einzelner Abonnenten werden geloggt.
+func (b *eventBus) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	b.mu.RLock()
+	subs := append([]func(Event){}, b.subs...)
+	b.mu.RUnlock()
+	for _, fn := range subs {
+		func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					applog.Errorf("event subscriber for %s panicked: %v", ev.Type, rec)
+				}
+			}()
+			fn(ev)
+		}()
+	}
+}
+
+// notification ist ein Eintrag im Posteingang eines Nutzers.
+type notification struct {
+	Event
+	Read bool `json:"read"`
+}
+
+// inboxMax begrenzt die gespeicherten Benachrichtigungen pro Nutzer.
+const inboxMax = 50
+
+// inbox hält die In-App-Benachrichtigungen pro Nutzer (nur im Speicher).
+type inbox struct {
+	mu sync.Mutex
+	m  map[string][]notification
+}
+
+func newInbox() *inbox { return &inbox{m: map[string][]notification{}} }
+
+func (in *inbox) add(user string, ev Event) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	list := append([]notification{{Event: ev}}, in.m[user]...)
+	if len(list) > inboxMax {
+		list = list[:inboxMax]
+	}
+	in.m[user] = list
+}
+
+// list liefert die Benachrichtigungen (neueste zuerst) und die Anzahl ungelesener.
+func (in *inbox) list(user string) ([]notification, int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	out := append([]notification{}, in.m[user]...)
+	unread := 0
+	for _, n := range out {
+		if !n.Read {
+			unread++
+		}
+	}
+	return out, unread
+}
+
+func (in *inbox) markRead(user string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for i := range in.m[user] {
+		in.m[user][i].Read = true
+	}
+}
+
+// notifyInbox ist der eingebaute Abonnent: Zuweisungen landen beim neuen Zuständigen,
+// sofern er sie sich nicht selbst gegeben hat.
+func (s *Server) notifyInbox(ev Event) {
+	if ev.Type == EventTaskAssigned && ev.Target != "" && ev.Target != ev.Actor {
+		s.inbox.add(ev.Target, ev)
+	}
+}
+
+// handleNotifications liefert den Posteingang als JSON (?format=json, für das Nav-Badge) oder als Seite.
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	items, unread := s.inbox.list(user)
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, map[string]any{"unread": unread, "items": items})
+		return
+	}
+	csrfToken := s.ensureCSRFToken(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Notifications</h2>
+<p><a href="/notifications/settings">Notification settings</a> (reminder emails) · <a href="/webhooks">Webhook deliveries</a></p>
+{{if .Items}}
+<table>
+  <thead><tr><th>When</th><th>From</th><th>Task</th><th>Repository</th></tr></thead>
+  <tbody>
+  {{range .Items}}<tr{{if not .Read}} style="font-weight:bold;"{{end}}>
+    <td>{{.Time.Format "2006-01-02 15:04"}}</td>
+    <td>{{.Actor}}</td>
+    <td>{{if eq .Type "task.assigned"}}assigned you {{end}}{{if .TaskUUID}}<a href="/tasks/{{.TaskUUID}}/history">{{if .TaskID}}#{{.TaskID}} {{end}}{{.Summary}}</a>{{else}}{{.Summary}}{{end}}</td>
+    <td><code>{{.Repo}}</code></td>
+  </tr>{{end}}
+  </tbody>
+</table>
+{{if .Unread}}
+<form method="post" action="/notifications/read" style="margin-top:8px;">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <button type="submit">Mark all as read</button>
+</form>
+{{end}}
+{{else}}
+<p>No notifications.</p>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Items":     items,
+		"Unread":    unread,
+		"CSRFToken": csrfToken,
+	}))
+}
+
+func (s *Server) handleNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	s.inbox.markRead(user)
+	http.Redirect(w, r, "/notifications", http.StatusSeeOther)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/gitauth"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// handleHTTPSCredentials zeigt (GET) bzw. speichert/löscht (POST) HTTPS-Zugangsdaten für das Git-Remote.
+// Das Token wird nie wieder angezeigt.
+func (s *Server) handleHTTPSCredentials(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.handleHTTPSCredentialsPost(w, r)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	host, user, updated, ok := gitauth.HTTPSCredentialInfo(s.cfg, username)
+	remoteURL, _ := s.runner.GitRemoteURL(s.repoKey(r))
+	remoteHost := ""
+	if u, err := neturl.Parse(remoteURL); err == nil && (u.Scheme == "https" || u.Scheme == "http") {
+		remoteHost = u.Hostname()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>HTTPS credentials</h2>
+<p>For HTTPS remotes, git gets the username and personal access token from a built-in credential helper. The token is stored encrypted and is never written into the remote URL or shown again.</p>
+{{if .OK}}
+<p>Stored: <strong>{{.User}}</strong> for {{if .Host}}<code>{{.Host}}</code>{{else}}any HTTPS host{{end}}{{if not .Updated.IsZero}} (updated {{.Updated.Format "2006-01-02 15:04"}}){{end}}.</p>
+<form method="post" action="/repo/https" onsubmit="return confirm('Delete the stored credentials?');">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <input type="hidden" name="action" value="delete"/>
+  <button type="submit">Delete</button>
+</form>
+{{else}}
+<p>No credentials stored.</p>
+{{end}}
+<h3>{{if .OK}}Replace{{else}}Add{{end}} credentials</h3>
+<form method="post" action="/repo/https" autocomplete="off">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <input type="hidden" name="action" value="save"/>
+  <div><label>Host: <input name="host" value="{{if .Host}}{{.Host}}{{else}}{{.RemoteHost}}{{end}}" placeholder="github.com (empty = any)"/></label></div>
+  <div style="margin-top:6px;"><label>Username: <input name="username" value="{{.User}}" required/></label></div>
+  <div style="margin-top:6px;"><label>Token: <input type="password" name="token" required autocomplete="new-password"/></label></div>
+  <div style="margin-top:8px;"><button type="submit">Save</button></div>
+</form>
+<p><a href="/repo/ssh">SSH deploy key</a> · <a href="/repo">Back to repository</a></p>`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"OK":         ok,
+		"Host":       host,
+		"User":       user,
+		"Updated":    updated,
+		"RemoteHost": remoteHost,
+		"CSRFToken":  csrfToken,
+	}))
+}
+
+func (s *Server) handleHTTPSCredentialsPost(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	switch r.FormValue("action") {
+	case "save":
+		err := gitauth.SaveHTTPSCredential(s.cfg, username, gitauth.HTTPSCredential{
+			Host:     r.FormValue("host"),
+			Username: r.FormValue("username"),
+			Token:    r.FormValue("token"),
+		})
+		if err != nil {
+			applog.Warnf("/repo/https: saving credentials for %s failed: %v", username, err)
+			s.setFlash(w, "error", "Saving credentials failed: "+err.Error())
+		} else {
+			applog.Infof("stored HTTPS credentials for %s", username)
+			s.setFlash(w, "success", "Credentials saved.")
+		}
+	case "delete":
+		if err := gitauth.DeleteHTTPSCredential(s.cfg, username); err != nil {
+			s.setFlash(w, "error", "Deleting credentials failed: "+err.Error())
+		} else {
+			s.setFlash(w, "success", "Credentials deleted.")
+		}
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, "/repo/https", http.StatusSeeOther)
+}
+
+// extractURLCredentials entfernt Nutzer/Token aus einer eingegebenen HTTPS-Remote-URL und speichert
+// sie im Credential-Store, damit sie nicht in .git/config landen (auch https://<token>@host). Liefert die
+// bereinigte URL.
+// key ist der Repo-Schlüssel; Zugangsdaten werden beim Nutzer abgelegt.
+func (s *Server) extractURLCredentials(key, rawURL string) string {
+	username, _ := config.SplitRepoKey(key)
+	clean, user, token := gitauth.SplitURLCredentials(rawURL)
+	if token == "" {
+		return rawURL
+	}
+	host := ""
+	if u, err := neturl.Parse(clean); err == nil {
+		host = strings.ToLower(u.Host)
+	}
+	if err := gitauth.SaveHTTPSCredential(s.cfg, username, gitauth.HTTPSCredential{Host: host, Username: user, Token: token}); err != nil {
+		applog.Warnf("storing credentials from remote URL for %s failed: %v", username, err)
+		return rawURL
+	}
+	applog.Infof("moved credentials from remote URL into the credential store for %s", username)
+	return clean
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// handleTaskHistory zeigt alle Commits, die die YAML-Datei eines Tasks verändert haben.
+// id darf eine Task-ID oder eine UUID sein (für resolved/entfernte Tasks).
+func (s *Server) handleTaskHistory(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	username := s.repoKey(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	uuid, err := s.runner.ResolveTaskUUID(username, id)
+	if err != nil {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	revs, err := s.runner.TaskHistory(username, uuid)
+	if err != nil {
+		applog.Warnf("/tasks/%s/history: %v", id, err)
+		http.Error(w, "failed to read task history", http.StatusBadGateway)
+		return
+	}
+	summary := ""
+	for _, rev := range revs {
+		if v := rev.Fields["summary"]; v != "" {
+			summary = v
+			break
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>History of task {{if .Summary}}“{{.Summary}}”{{else}}{{.ID}}{{end}}</h2>
+<p><code>{{.UUID}}</code> · <a href="/tasks/removed">Removed tasks</a></p>
+{{if not .Revisions}}<p>No commits found for this task.</p>{{end}}
+{{if .Revisions}}
+<table>
+  <thead><tr><th>Date</th><th>Author</th><th>Commit</th><th>Changes</th></tr></thead>
+  <tbody>
+  {{range .Revisions}}
+  <tr>
+    <td style="white-space:nowrap;">{{.Date.Format "2006-01-02 15:04"}}</td>
+    <td title="{{.Email}}">{{.Author}}</td>
+    <td>
+      <code>{{.ShortCommit}}</code> {{.Message}}
+      {{if not .Deleted}}
+      <form method="post" action="/tasks/{{$.UUID}}/history/restore" style="margin-top:4px;" onsubmit="return confirm('Restore the whole task to this version?');">
+        <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/>
+        <input type="hidden" name="commit" value="{{.Commit}}"/>
+        <button type="submit" title="Restore the whole task as of this commit">restore this version</button>
+      </form>
+      {{end}}
+    </td>
+    <td>
+      {{if .Deleted}}<em>task removed</em>{{end}}
+      {{$rev := .}}
+      {{range .Changes}}
+        {{if eq .Field "notes"}}
+        <details><summary><strong>notes</strong> changed</summary>
+          <div style="display:flex;gap:8px;">
+            <pre style="white-space:pre-wrap;flex:1;background:#fff5f5;">{{.Old}}</pre>
+            <pre style="white-space:pre-wrap;flex:1;background:#f0fff4;">{{.New}}</pre>
+          </div>
+        </details>
+        {{else}}
+        <div><strong>{{.Field}}</strong>: {{if .Old}}<del>{{.Old}}</del>{{else}}<em>(empty)</em>{{end}} → {{if .New}}{{.New}}{{else}}<em>(empty)</em>{{end}}</div>
+        {{end}}
+        {{if not $rev.Deleted}}
+        <form method="post" action="/tasks/{{$.UUID}}/history/restore" style="display:inline;">
+          <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/>
+          <input type="hidden" name="commit" value="{{$rev.Commit}}"/>
+          <input type="hidden" name="field" value="{{.Field}}"/>
+          <button type="submit" title="Set {{.Field}} back to the value after this commit">restore {{.Field}}</button>
+        </form>
+        {{end}}
+      {{end}}
+    </td>
+  </tr>
+  {{end}}
+  </tbody>
+</table>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"ID":        id,
+		"UUID":      uuid,
+		"Summary":   summary,
+		"Revisions": revs,
+		"CSRFToken": csrfToken,
+	}))
+}
+
+// handleTaskRestore stellt einen Task (oder ein einzelnes Feld) aus einer früheren Revision wieder her.
+func (s *Server) handleTaskRestore(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	if !validateCSRFToken(r, r.FormValue("csrf_token")) {
+		http.Error(w, "invalid CSRF token", http.StatusForbidden)
+		return
+	}
+	username := s.repoKey(r)
+	uuid, err := s.runner.ResolveTaskUUID(username, id)
+	if err != nil {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	field := strings.TrimSpace(r.FormValue("field"))
+	commit := strings.TrimSpace(r.FormValue("commit"))
+	if err := s.runner.RestoreTaskRevision(username, uuid, commit, field); err != nil {
+		applog.Warnf("/tasks/%s/history/restore failed: %v", id, err)
+		s.setFlash(w, "error", "Restore failed: "+err.Error())
+	} else {
+		what := "Task"
+		if field != "" {
+			what = "Field " + field
+		}
+		s.setFlash(w, "success", what+" restored")
+		s.autoSync(username)
+	}
+	http.Redirect(w, r, "/tasks/"+uuid+"/history", http.StatusSeeOther)
+}
+
+// handleRemovedTasks listet entfernte Tasks, die aus der Git-Historie zurückgeholt werden können.
+func (s *Server) handleRemovedTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	removed, err := s.runner.RemovedTasks(username, 100)
+	if err != nil {
+		applog.Warnf("/tasks/removed: %v", err)
+		http.Error(w, "failed to read git history", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Removed tasks</h2>
+{{if not .Removed}}<p>No removed tasks found in the repository history.</p>{{end}}
+{{if .Removed}}
+<table>
+  <thead><tr><th>Removed</th><th>By</th><th>Summary</th><th></th></tr></thead>
+  <tbody>
+  {{range .Removed}}
+  <tr>
+    <td style="white-space:nowrap;">{{.Date.Format "2006-01-02 15:04"}}</td>
+    <td>{{.Author}}</td>
+    <td>{{.Summary}} <code>{{.UUID}}</code></td>
+    <td>
+      <a href="/tasks/{{.UUID}}/history">history</a>
+      · <form method="post" action="/tasks/{{.UUID}}/history/restore" style="display:inline;">
+        <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/>
+        <button type="submit" title="Restore the last version before removal">restore</button>
+      </form>
+    </td>
+  </tr>
+  {{end}}
+  </tbody>
+</table>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Removed":   removed,
+		"CSRFToken": csrfToken,
+	}))
+}
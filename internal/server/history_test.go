@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTaskUUID = "0f8c3e2a-1b2c-4d5e-8f90-123456789abc"
+
+// newGitTaskHome legt ein .dstask-Repo mit einem Task an und committet es.
+func newGitTaskHome(t *testing.T) (home, repo string) {
+	t.Helper()
+	home, repo = newTaskHome(t, t.TempDir(), true)
+	gitT(t, repo, "config", "user.email", "alice@example.com")
+	gitT(t, repo, "config", "user.name", "Alice")
+	if err := os.MkdirAll(filepath.Join(repo, "pending"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(repo, "pending", testTaskUUID+".yml")
+	if err := os.WriteFile(p, []byte("summary: Fix VPN\nnotes: first notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitT(t, repo, "add", "-A")
+	gitT(t, repo, "commit", "-m", "Added: Fix VPN")
+	if err := os.WriteFile(p, []byte("summary: Fix VPN\nnotes: \"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitT(t, repo, "commit", "-am", "Modified: Fix VPN")
+	return home, repo
+}
+
+func TestTaskHistoryPage(t *testing.T) {
+	home, _ := newGitTaskHome(t)
+	s := newTestServerWithStub(t, "/bin/true", home)
+	rr := testGet(s, "/tasks/"+testTaskUUID+"/history")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"Fix VPN", "Alice", "Modified: Fix VPN", "first notes"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("history page missing %q: %s", want, body)
+		}
+	}
+}
+
+func TestTaskRestoreField(t *testing.T) {
+	home, repo := newGitTaskHome(t)
+	s := newTestServerWithStub(t, "/bin/true", home)
+	out, err := exec.Command("git", "-C", repo, "rev-list", "--max-parents=0", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := testPost(s, "/tasks/"+testTaskUUID+"/history/restore", url.Values{"commit": {strings.TrimSpace(string(out))}, "field": {"notes"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	b, _ := os.ReadFile(filepath.Join(repo, "pending", testTaskUUID+".yml"))
+	if !strings.Contains(string(b), "first notes") {
+		t.Fatalf("notes not restored: %s", string(b))
+	}
+}
+
+func TestActivityPage(t *testing.T) {
+	home, _ := newGitTaskHome(t)
+	s := newTestServerWithStub(t, "/bin/true", home)
+	rr := testGet(s, "/activity?author=Alice")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "Alice added") || !strings.Contains(body, "edited notes") {
+		t.Fatalf("activity feed missing entries: %s", body)
+	}
+}
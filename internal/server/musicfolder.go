@@ -0,0 +1,162 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+// musicRoot liefert den freigegebenen Musikordner des angemeldeten Nutzers (musicRoots).
+func (s *Server) musicRoot(r *http.Request) (string, bool) {
+	user, _ := auth.UsernameFromRequest(r)
+	return config.ResolveMusicRoot(s.cfg, user)
+}
+
+// musicPathError übersetzt Fehler beim Auflösen eines Pfads im Musikordner in einen HTTP-Status.
+func musicPathError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, music.ErrOutsideRoot):
+		http.Error(w, "path outside music folder", http.StatusForbidden)
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, music.ErrHidden):
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// m3uText ersetzt Steuerzeichen (v. a. CR/LF aus Dateinamen) durch Leerzeichen, damit ein Titel
+// keine eigenen Zeilen in die Playlist schreiben kann.
+func m3uText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+type playlistTrack struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+	URL   string `json:"url"`
+}
+
+// handleMusicPlaylist liefert die Audiodateien eines Ordners im Musikordner als M3U-Playlist bzw. mit
+// format=json für den Player. Der Ordner kommt aus einer Musik-Regel (key, z. B. UUID oder "tag:…"),
+// aus der für den Task id aufgelösten Regel oder aus path; shuffle=1 (oder Shuffle in der Regel) mischt
+// die Reihenfolge.
+func (s *Server) handleMusicPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	root, ok := s.musicRoot(r)
+	if !ok {
+		http.Error(w, "no music folder configured for this user (musicRoots)", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	dir, name, shuffle := q.Get("path"), "", q.Get("shuffle") == "1"
+	if key, id := strings.TrimSpace(q.Get("key")), strings.TrimSpace(q.Get("id")); key != "" || id != "" {
+		var tm music.TaskMusic
+		found := false
+		if key != "" {
+			m, err := s.musicMap(s.repoKey(r), nil)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			tm, found = m.Entry(key)
+		} else {
+			tm, key, found = s.taskMusic(s.repoKey(r), id)
+		}
+		if !found || tm.Type != "folder" {
+			http.Error(w, "no music folder assigned to "+firstNonEmptyString(key, id), http.StatusNotFound)
+			return
+		}
+		dir, name, shuffle = tm.Path, tm.Name, shuffle || tm.Shuffle
+	}
+	tracks, err := music.Tracks(root, dir)
+	if err != nil {
+		applog.Warnf("/music/playlist %q: %v", dir, err)
+		musicPathError(w, err)
+		return
+	}
+	if shuffle {
+		music.Shuffle(tracks)
+	}
+	list := make([]playlistTrack, 0, len(tracks))
+	for _, t := range tracks {
+		list = append(list, playlistTrack{Title: music.TrackTitle(t), Path: t, URL: "/music/file?path=" + url.QueryEscape(t)})
+	}
+	if name == "" {
+		name = firstNonEmptyString(filepath.Base(filepath.FromSlash(strings.Trim(dir, "/"))), "Music")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if q.Get("format") == "json" {
+		writeJSON(w, map[string]any{"name": name, "path": dir, "shuffle": shuffle, "tracks": list})
+		return
+	}
+	// externe Player brauchen absolute URLs
+	base := "http://" + r.Host
+	if r.TLS != nil {
+		base = "https://" + r.Host
+	}
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#PLAYLIST:" + m3uText(name) + "\n")
+	for _, t := range list {
+		b.WriteString("#EXTINF:-1," + m3uText(t.Title) + "\n" + base + t.URL + "\n")
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\"playlist.m3u\"")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// handleMusicFile liefert eine Audiodatei aus dem Musikordner; Range-Anfragen (Spulen) übernimmt
+// http.ServeContent.
+func (s *Server) handleMusicFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	root, ok := s.musicRoot(r)
+	if !ok {
+		http.Error(w, "no music folder configured for this user (musicRoots)", http.StatusNotFound)
+		return
+	}
+	rel := r.URL.Query().Get("path")
+	ct := music.AudioContentType(rel)
+	if ct == "" {
+		http.Error(w, "not an audio file", http.StatusForbidden)
+		return
+	}
+	p, err := music.Resolve(root, rel)
+	if err != nil {
+		applog.Warnf("/music/file %q: %v", rel, err)
+		musicPathError(w, err)
+		return
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		musicPathError(w, err)
+		return
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, st.Name(), st.ModTime(), f)
+}
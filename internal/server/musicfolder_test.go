@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+func TestMusicFolder_PlaylistFileAndScoping(t *testing.T) {
+	tmp := t.TempDir()
+	home, _ := newTaskHome(t, tmp, false)
+	root := filepath.Join(tmp, "music")
+	for name, body := range map[string]string{"focus/01 Intro.mp3": "0123456789", "focus/02 Deep.ogg": "abc", "focus/notes.txt": "x",
+		"focus/.private.mp3": "x", "focus/03 Evil\n#EXTM3U.mp3": "x"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "secret.mp3"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Task 7 ist offen, damit der alte ID-Eintrag auf seine UUID umgestellt wird
+	script := `if [ "$1" = "export" ]; then
+  echo '[{"id":7,"uuid":"77777777-7777-7777-7777-777777777777","status":"pending","summary":"Focus work"}]'
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, tmp, script)
+	s := newTestServerWithStub(t, stub, home)
+	get := func(path string, hdr map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetBasicAuth("admin", "admin")
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rr, req)
+		return rr
+	}
+
+	// ohne freigegebenen Musikordner nichts ausliefern
+	if rr := get("/music/playlist?path=focus", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("playlist without music root: %d", rr.Code)
+	}
+	s.cfg.MusicRoots = map[string]string{"admin": root}
+
+	m := music.Map{Version: 1, Tasks: map[string]music.TaskMusic{"7": {Type: "folder", Name: "Focus", Path: "focus", Shuffle: true}}}
+	if _, err := music.SaveForUser(s.cfg, "admin", &m); err != nil {
+		t.Fatal(err)
+	}
+	rr := get("/music/playlist?id=7&format=json", nil)
+	var pl struct {
+		Name    string          `json:"name"`
+		Shuffle bool            `json:"shuffle"`
+		Tracks  []playlistTrack `json:"tracks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &pl); err != nil {
+		t.Fatalf("%d %s: %v", rr.Code, rr.Body.String(), err)
+	}
+	if pl.Name != "Focus" || !pl.Shuffle || len(pl.Tracks) != 3 {
+		t.Fatalf("unexpected playlist: %+v", pl)
+	}
+	m3u := get("/music/playlist?path=focus", nil)
+	if ct := m3u.Header().Get("Content-Type"); !strings.HasPrefix(ct, "audio/x-mpegurl") ||
+		!strings.HasPrefix(m3u.Body.String(), "#EXTM3U\n") ||
+		!strings.Contains(m3u.Body.String(), "#EXTINF:-1,01 Intro\nhttp://example.com/music/file?path=focus%2F01+Intro.mp3\n") {
+		t.Fatalf("unexpected M3U (%s):\n%s", ct, m3u.Body.String())
+	}
+	// Zeilenumbrüche im Dateinamen schreiben keine eigenen Playlist-Zeilen
+	if body := m3u.Body.String(); !strings.Contains(body, "#EXTINF:-1,03 Evil #EXTM3U\n") || strings.Count(body, "#EXTM3U") != 2 {
+		t.Fatalf("title not sanitized in M3U:\n%s", body)
+	}
+
+	// Range-Anfrage zum Spulen
+	rr = get("/music/file?path=focus%2F01+Intro.mp3", map[string]string{"Range": "bytes=2-5"})
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "2345" || rr.Header().Get("Content-Type") != "audio/mpeg" {
+		t.Fatalf("range request: %d %q %s", rr.Code, rr.Body.String(), rr.Header().Get("Content-Type"))
+	}
+	for path, want := range map[string]int{
+		"/music/file?path=..%2Fsecret.mp3":              http.StatusForbidden,
+		"/music/file?path=focus%2F..%2F..%2Fsecret.mp3": http.StatusForbidden,
+		"/music/file?path=focus%2Fnotes.txt":            http.StatusForbidden,
+		"/music/file?path=focus%2Fmissing.mp3":          http.StatusNotFound,
+		"/music/file?path=focus%2F.private.mp3":         http.StatusNotFound,
+		"/music/playlist?path=..":                       http.StatusForbidden,
+	} {
+		if rr := get(path, nil); rr.Code != want {
+			t.Fatalf("%s: got %d, want %d", path, rr.Code, want)
+		}
+	}
+
+	// Flash-Token beim Start verweist auf die Ordner-Playlist der Regel
+	if tok := s.musicToken("admin", "start", "7"); !strings.HasPrefix(tok, "__MUSIC_START__Focus|/music/playlist?key=77777777-7777-7777-7777-777777777777|id=77777777-7777-7777-7777-777777777777|") || !strings.HasSuffix(tok, "|type=folder") {
+		t.Fatalf("unexpected music token %q", tok)
+	}
+}
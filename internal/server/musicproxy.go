@@ -0,0 +1,392 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+// proxyMaxRedirects begrenzt die Weiterleitungen eines Streams (jede wird erneut geprüft).
+const proxyMaxRedirects = 5
+
+// errProxyBlocked: Ziel-Host oder -Adresse ist für den Proxy gesperrt (musicProxy).
+var errProxyBlocked = errors.New("music proxy: destination not allowed")
+
+// proxyBlockedNets ergänzt Loopback, private und Link-Local-Adressen um weitere nicht öffentliche Netze.
+var proxyBlockedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // "dieses" Netz
+		"100.64.0.0/10", // Carrier-Grade NAT
+		"198.18.0.0/15", // Benchmark-Netze
+		"240.0.0.0/4",   // reserviert inkl. Broadcast
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// proxyIPBlocked meldet Adressen, die der Proxy ohne allowPrivate/allowHosts nicht abruft.
+func proxyIPBlocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range proxyBlockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyDial löst den Host selbst auf und verbindet sich nur mit erlaubten Adressen. Die Prüfung
+// geschieht beim Verbindungsaufbau, also auch für Weiterleitungen und ohne DNS-Rebinding-Lücke.
+func (s *Server) proxyDial(ctx context.Context, network, addr string) (net.Conn, error) {
+	pc := s.cfg.MusicProxy
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if pc.HostDenied(host) {
+		return nil, fmt.Errorf("%w: %s", errProxyBlocked, host)
+	}
+	return dialPublic(ctx, network, addr, pc.AllowPrivate || pc.HostAllowed(host), "/music/proxy", errProxyBlocked)
+}
+
+// dialPublic löst den Host von addr auf und verbindet sich nur mit öffentlichen Adressen (außer bei
+// anyAddr). Gesperrte Adressen werden unter label geloggt; sind alle gesperrt, kommt blocked zurück.
+func dialPublic(ctx context.Context, network, addr string, anyAddr bool, label string, blocked error) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var d net.Dialer
+	lastErr := fmt.Errorf("%w: %s resolves to a non-public address", blocked, host)
+	for _, ip := range ips {
+		if !anyAddr && proxyIPBlocked(ip.IP) {
+			applog.Warnf("%s blocked %s (%s)", label, host, ip.IP)
+			continue
+		}
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// checkProxyURL prüft Schema und denyHosts; die Adresse prüft proxyDial.
+func (s *Server) checkProxyURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+		return errors.New("invalid url")
+	}
+	if s.cfg.MusicProxy.HostDenied(u.Hostname()) {
+		return fmt.Errorf("%w: %s", errProxyBlocked, u.Hostname())
+	}
+	return nil
+}
+
+// proxyClient liefert den HTTP-Client für einen Stream: eigene Auflösung (proxyDial), kein
+// Umgebungs-Proxy und erneute Prüfung jeder Weiterleitung.
+func (s *Server) proxyClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           s.proxyDial,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 15 * time.Second,
+			DisableKeepAlives:     true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= proxyMaxRedirects {
+				return errors.New("too many redirects")
+			}
+			return s.checkProxyURL(req.URL)
+		},
+	}
+}
+
+// streamLimiter zählt die laufenden Streams pro Nutzer.
+type streamLimiter struct {
+	mu sync.Mutex
+	n  map[string]int
+}
+
+func newStreamLimiter() *streamLimiter {
+	return &streamLimiter{n: map[string]int{}}
+}
+
+// acquire belegt einen Stream für user, sofern weniger als max laufen.
+func (l *streamLimiter) acquire(user string, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.n[user] >= max {
+		return false
+	}
+	l.n[user]++
+	return true
+}
+
+func (l *streamLimiter) release(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.n[user]--; l.n[user] <= 0 {
+		delete(l.n, user)
+	}
+}
+
+// musicURLKnown meldet, ob raw in einer music-map.yaml des Nutzers (alle seine Repos) als Radio-Stream steht.
+func (s *Server) musicURLKnown(r *http.Request, raw string) bool {
+	username, _ := auth.UsernameFromRequest(r)
+	keys := []string{s.repoKey(r)}
+	for _, name := range config.RepoNames(s.cfg, username) {
+		if k := config.RepoKey(username, name); k != keys[0] {
+			keys = append(keys, k)
+		}
+	}
+	for _, key := range keys {
+		if m, _, err := music.LoadForUser(s.cfg, key); err == nil && m.HasStreamURL(raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// throttle wartet, bis total Bytes seit start bei rate Bytes/s erlaubt sind; false, wenn ctx endet.
+func throttle(ctx context.Context, start time.Time, total, rate int64) bool {
+	if rate <= 0 {
+		return true
+	}
+	wait := time.Duration(total*int64(time.Second)/rate) - time.Since(start)
+	if wait <= 0 {
+		return true
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// handleMusicProxy reicht einen Radio-Stream durch (Referer/CORS/ICY). Abgerufen werden nur Stream-URLs
+// aus der music-map.yaml des Nutzers (außer musicProxy.anyURL) und nur öffentliche Adressen; Anzahl,
+// Bandbreite und Dauer der Streams sind begrenzt.
+func (s *Server) handleMusicProxy(w http.ResponseWriter, r *http.Request) {
+	// Robust extraction of the upstream URL: prefer full RawQuery tail after 'url='
+	// This handles unencoded '&' inside the upstream URL parameters (token/sid/etc.).
+	rq := r.URL.RawQuery
+	raw := strings.TrimSpace(r.URL.Query().Get("url"))
+	// If RawQuery tail was used previously, it may have included '&referer=' or other proxy params.
+	// Prefer the explicit query param value; only fall back if empty.
+	if raw == "" {
+		if i := strings.Index(rq, "url="); i >= 0 {
+			cand := rq[i+4:]
+			// Trim at next '&' to avoid appending proxy params like '&referer=' or '&_ts='
+			if j := strings.IndexByte(cand, '&'); j >= 0 {
+				cand = cand[:j]
+			}
+			if d, err := url.QueryUnescape(cand); err == nil && d != "" {
+				cand = d
+			}
+			raw = strings.TrimSpace(cand)
+		}
+	}
+	if raw == "" {
+		http.Error(w, "missing url", http.StatusBadRequest)
+		return
+	}
+	// sanitize accidental token tailings (e.g., "|id=1" appended) from client flash parsing
+	if k := strings.Index(raw, "|"); k >= 0 {
+		raw = raw[:k]
+	}
+	if k := strings.Index(raw, "/id="); k >= 0 {
+		raw = raw[:k]
+	}
+	u, err := url.Parse(raw)
+	if err == nil {
+		err = s.checkProxyURL(u)
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errProxyBlocked) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	pc := s.cfg.MusicProxy
+	if !pc.AnyURL && !s.musicURLKnown(r, raw) {
+		applog.Warnf("/music/proxy refused %s: not in music map", raw)
+		http.Error(w, "stream URL is not in your music map", http.StatusForbidden)
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	if !s.streams.acquire(username, pc.Streams()) {
+		http.Error(w, fmt.Sprintf("too many streams (max %d)", pc.Streams()), http.StatusTooManyRequests)
+		return
+	}
+	defer s.streams.release(username)
+	ctx, cancel := context.WithTimeout(r.Context(), pc.Duration())
+	defer cancel()
+
+	applog.Infof("/music/proxy fetch %s", raw)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Generic UA
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; dstask-web)")
+	req.Header.Set("Accept", "audio/*, */*;q=0.5")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Pragma", "no-cache")
+	req.Header.Set("Accept-Encoding", "identity")
+	// ICY-Metadaten anfordern; sie werden unten aus dem Audio entfernt und als "now playing" veröffentlicht
+	req.Header.Set("Icy-MetaData", "1")
+	if al := r.Header.Get("Accept-Language"); al != "" {
+		req.Header.Set("Accept-Language", al)
+	}
+	if ua := r.Header.Get("User-Agent"); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	// Client-IP für Geo-/Token-Backends; ein vom Client mitgeschicktes X-Forwarded-For wird nicht
+	// weitergereicht, da es beliebig gesetzt werden kann
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		req.Header.Set("X-Forwarded-For", host)
+	}
+	// Optional Referer passthrough for token-gated streams
+	referer := strings.TrimSpace(r.URL.Query().Get("referer"))
+	if referer == "" {
+		// Also try to extract from RawQuery if provided without encoding
+		if j := strings.Index(rq, "referer="); j >= 0 {
+			ref := rq[j+8:]
+			if d, err := url.QueryUnescape(ref); err == nil && d != "" {
+				referer = d
+			} else {
+				referer = ref
+			}
+		}
+	}
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
+	resp, err := s.proxyClient().Do(req)
+	if err != nil {
+		if errors.Is(err, errProxyBlocked) {
+			applog.Warnf("/music/proxy refused %s: %v", raw, err)
+			http.Error(w, "stream destination not allowed", http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Determine content type; fallback anhand Dateiendung
+	ct := resp.Header.Get("Content-Type")
+	if ct == "" {
+		lp := strings.ToLower(u.Path)
+		switch {
+		case strings.Contains(lp, ".aac"):
+			ct = "audio/aac"
+		case strings.Contains(lp, ".m4a") || strings.Contains(lp, ".mp4"):
+			ct = "audio/mp4"
+		default:
+			ct = "audio/mpeg"
+		}
+	}
+
+	// Response-Header setzen; Content-Length entfernen, damit Chunked-Streaming genutzt wird
+	w.Header().Set("Content-Type", ct)
+	if v := resp.Header.Get("Ice-Audio-Info"); v != "" {
+		w.Header().Set("Ice-Audio-Info", v)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(resp.StatusCode)
+
+	var body io.Reader = resp.Body
+	if mi, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Icy-Metaint"))); err == nil && mi > 0 && resp.StatusCode == http.StatusOK {
+		task := strings.TrimSpace(r.URL.Query().Get("task"))
+		onTitle, done := s.watchStreamTitles(s.repoKey(r), strings.TrimSpace(resp.Header.Get("Icy-Name")), task)
+		defer done()
+		body = music.NewICYReader(resp.Body, mi, onTitle)
+	}
+
+	// Chunked stream mit periodischem Flush, gedrosselt auf musicProxy.maxKbps
+	var total int64
+	rate, start := pc.BytesPerSecond(), time.Now()
+	f, canFlush := w.(http.Flusher)
+	if canFlush {
+		f.Flush() // Header sofort senden, auch wenn der Stream erst verzögert Daten liefert
+	}
+	buf := make([]byte, 32*1024)
+	bw := bufio.NewWriterSize(w, 64*1024)
+	lastFlush := time.Now()
+	bytesSinceFlush := 0
+	for {
+		n, er := body.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			if !throttle(ctx, start, total, rate) {
+				break
+			}
+			if _, ew := bw.Write(buf[:n]); ew != nil {
+				applog.Warnf("/music/proxy write error: %v (bytes_sent=%d)", ew, total)
+				return
+			}
+			bytesSinceFlush += n
+			if bytesSinceFlush >= 128*1024 || time.Since(lastFlush) >= 300*time.Millisecond {
+				if err := bw.Flush(); err != nil {
+					applog.Warnf("/music/proxy buffer flush error: %v (bytes_sent=%d)", err, total)
+					return
+				}
+				if canFlush {
+					f.Flush()
+				}
+				bytesSinceFlush = 0
+				lastFlush = time.Now()
+			}
+		}
+		if er != nil {
+			if er != io.EOF && !errors.Is(er, context.DeadlineExceeded) {
+				applog.Warnf("/music/proxy stream error: %v (bytes_sent=%d)", er, total)
+			}
+			break
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		applog.Warnf("/music/proxy final flush error: %v (bytes_sent=%d)", err, total)
+	} else if canFlush {
+		f.Flush()
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		applog.Infof("/music/proxy %s reached maxDuration %s", raw, pc.Duration())
+	}
+	applog.Infof("/music/proxy done %s status=%d bytes_sent=%d", raw, resp.StatusCode, total)
+}
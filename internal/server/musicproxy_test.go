@@ -0,0 +1,155 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+func TestProxyIPBlocked(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.16.0.1":      true,
+		"192.168.178.1":   true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fe80::1":         true,
+		"fd00::1":         true,
+		"::ffff:10.0.0.1": true,
+		"93.184.216.34":   false,
+		"2a00:1450::1":    false,
+	} {
+		if got := proxyIPBlocked(net.ParseIP(addr)); got != want {
+			t.Errorf("%s: blocked=%v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestMusicProxy_SSRFGuardsAndLimits(t *testing.T) {
+	tmp := t.TempDir()
+	home, _ := newTaskHome(t, tmp, false)
+	s := newTestServerWithStub(t, createDstaskStub(t, tmp), home)
+
+	hold := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect-internal":
+			// Weiterleitung auf einen nicht freigegebenen internen Namen
+			http.Redirect(w, r, "http://localhost:"+r.Host[strings.LastIndex(r.Host, ":")+1:]+"/live", http.StatusFound)
+		case "/hold":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = w.Write([]byte("x"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-hold:
+			case <-r.Context().Done():
+			}
+		case "/endless":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			for r.Context().Err() == nil {
+				if _, err := w.Write(make([]byte, 1024)); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				time.Sleep(10 * time.Millisecond)
+			}
+		default:
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = w.Write([]byte("audio"))
+		}
+	}))
+	defer upstream.Close()
+	defer close(hold)
+
+	m := music.Map{Version: music.CurrentVersion, Tags: map[string]music.TaskMusic{}}
+	for _, p := range []string{"/live", "/redirect-internal", "/hold", "/endless"} {
+		m.Tags[strings.TrimPrefix(p, "/")] = music.TaskMusic{Type: "radio", URL: upstream.URL + p}
+	}
+	if _, err := music.SaveForUser(s.cfg, "admin", &m); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	get := func(stream string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/music/proxy?url="+url.QueryEscape(stream), nil)
+		req.SetBasicAuth("admin", "admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	status := func(stream string) int {
+		t.Helper()
+		resp := get(stream)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// nicht in der music map: abgelehnt, bevor irgendetwas abgerufen wird
+	if code := status("http://169.254.169.254/latest/meta-data/"); code != http.StatusForbidden {
+		t.Fatalf("unmapped metadata URL: %d", code)
+	}
+	// eingetragen, aber Loopback-Adresse
+	if code := status(upstream.URL + "/live"); code != http.StatusForbidden {
+		t.Fatalf("loopback stream without allowHosts: %d", code)
+	}
+	s.cfg.MusicProxy.AllowHosts = []string{"127.0.0.1"}
+	if code := status(upstream.URL + "/live"); code != http.StatusOK {
+		t.Fatalf("allowed host: %d", code)
+	}
+	// Weiterleitungen werden erneut geprüft ("localhost" ist nicht freigegeben)
+	if code := status(upstream.URL + "/redirect-internal"); code != http.StatusForbidden {
+		t.Fatalf("redirect to internal host: %d", code)
+	}
+	s.cfg.MusicProxy.DenyHosts = []string{"127.0.0.1"}
+	if code := status(upstream.URL + "/live"); code != http.StatusForbidden {
+		t.Fatalf("denyHosts must win over allowHosts: %d", code)
+	}
+	s.cfg.MusicProxy.DenyHosts = nil
+
+	// gleichzeitige Streams pro Nutzer
+	s.cfg.MusicProxy.MaxStreams = 1
+	first := get(upstream.URL + "/hold")
+	defer first.Body.Close()
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("first stream: %d", first.StatusCode)
+	}
+	if code := status(upstream.URL + "/live"); code != http.StatusTooManyRequests {
+		t.Fatalf("second stream: %d", code)
+	}
+	first.Body.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for status(upstream.URL+"/live") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("stream slot not released")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Höchstdauer und Bandbreite
+	s.cfg.MusicProxy.MaxDuration = "300ms"
+	s.cfg.MusicProxy.MaxKbps = 64 // 8000 Bytes/s
+	started := time.Now()
+	resp := get(upstream.URL + "/endless")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if d := time.Since(started); d > 3*time.Second {
+		t.Fatalf("maxDuration not enforced: stream ran %s", d)
+	}
+	if len(body) > 8000 {
+		t.Fatalf("bandwidth cap not enforced: %d bytes in 300ms", len(body))
+	}
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+// taskRefs liefert die offenen Tasks (ID -> Referenz für die Musik-Regeln); nil, wenn der Export fehlschlägt.
+func (s *Server) taskRefs(key string) map[string]music.TaskRef {
+	res := s.runner.Run(key, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		applog.Warnf("music: export for %s failed: %v", key, res.Err)
+		return nil
+	}
+	tasks, _ := decodeTasksJSONFlexible(res.Stdout)
+	refs := map[string]music.TaskRef{}
+	for _, row := range buildRowsFromTasks(tasks, "") {
+		if row["id"] != "" && row["id"] != "0" {
+			refs[row["id"]] = rowMusicRef(row)
+		}
+	}
+	return refs
+}
+
+// rowMusicRef baut die Referenz aus einer Tabellenzeile (Tags als "a, b").
+func rowMusicRef(row map[string]string) music.TaskRef {
+	ref := music.TaskRef{ID: row["id"], UUID: row["uuid"], Project: row["project"]}
+	for _, t := range strings.Split(row["tags"], ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			ref.Tags = append(ref.Tags, t)
+		}
+	}
+	return ref
+}
+
+// musicMap lädt music-map.yaml des Repos. Eine Map der Version 1 (Tasks nach Task-ID) wird einmalig auf
+// UUIDs umgestellt und gespeichert; IDs ohne offenen Task kommen nach Unresolved und gelten nicht mehr.
+// refs darf nil sein (dann wird bei Bedarf exportiert).
+func (s *Server) musicMap(key string, refs map[string]music.TaskRef) (*music.Map, error) {
+	m, _, err := music.LoadForUser(s.cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	if m.Version >= music.CurrentVersion && len(m.LegacyIDs()) == 0 {
+		return m, nil
+	}
+	if refs == nil {
+		if refs = s.taskRefs(key); refs == nil {
+			return m, nil // ohne Export keine Umstellung; Einträge zu Task-IDs gelten bis dahin nicht
+		}
+	}
+	byID := make(map[string]string, len(refs))
+	for id, ref := range refs {
+		byID[id] = ref.UUID
+	}
+	migrated, unresolved := m.MigrateIDs(byID)
+	if _, err := music.SaveForUser(s.cfg, key, m); err != nil {
+		applog.Warnf("music: saving migrated map for %s failed: %v", key, err)
+	} else {
+		applog.Infof("music: migrated %d task id mapping(s) to uuids for %s, %d without open task set aside", migrated, key, unresolved)
+	}
+	return m, nil
+}
+
+// taskMusic löst die Musik für den Task id über die Regeln auf (Task, Tag, Projekt) und liefert auch
+// den Regel-Schlüssel, unter dem der Player Lautstärke und Stummschaltung speichert.
+func (s *Server) taskMusic(key, id string) (music.TaskMusic, string, bool) {
+	tm, rule, _, ok := s.taskMusicRef(key, id)
+	return tm, rule, ok
+}
+
+// taskMusicRef löst wie taskMusic auf und liefert zusätzlich die Referenz des Tasks (mit UUID, sofern
+// der Task offen ist).
+func (s *Server) taskMusicRef(key, id string) (music.TaskMusic, string, music.TaskRef, bool) {
+	refs := s.taskRefs(key)
+	ref, found := refs[id]
+	if !found {
+		ref = music.TaskRef{ID: id}
+	}
+	m, err := s.musicMap(key, refs)
+	if err != nil {
+		applog.Warnf("loading music map failed for %s: %v", key, err)
+		return music.TaskMusic{}, "", ref, false
+	}
+	tm, rule, ok := m.Lookup(ref)
+	return tm, rule, ref, ok
+}
+
+// musicRuleKey übersetzt den Schlüssel aus /music/tasks/{key}: eine Task-ID wird, wenn der Task in refs
+// offen ist, zur UUID; UUIDs und "project:…"/"tag:…" bleiben unverändert.
+func musicRuleKey(refs map[string]music.TaskRef, k string) string {
+	if _, err := strconv.Atoi(k); err != nil {
+		return k
+	}
+	if ref, ok := refs[k]; ok && ref.UUID != "" {
+		return strings.ToLower(ref.UUID)
+	}
+	return k
+}
+
+// musicRule ist eine Zeile der Regelübersicht.
+type musicRule struct {
+	Key, Label string
+	music.TaskMusic
+}
+
+// musicRuleSection ist eine Tabelle der Regelübersicht (Tags, Projekte, Tasks).
+type musicRuleSection struct {
+	Title, Column string
+	Rules         []musicRule
+}
+
+// handleMusicRules zeigt die Musik-Regeln für Projekte und Tags und legt sie an bzw. löscht sie
+// (POST action=save|delete).
+func (s *Server) handleMusicRules(w http.ResponseWriter, r *http.Request) {
+	key := s.repoKey(r)
+	if r.Method == http.MethodPost {
+		if !s.requirePostCSRF(w, r) {
+			return
+		}
+		m, err := s.musicMap(key, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		switch r.FormValue("action") {
+		case "delete":
+			m.Delete(r.FormValue("key"))
+			s.setFlash(w, "success", "Music rule removed")
+		case "save":
+			value := strings.TrimSpace(r.FormValue("value"))
+			rule := ""
+			switch r.FormValue("scope") {
+			case "project":
+				rule = music.ProjectKey(value)
+			case "tag":
+				rule = music.TagKey(value)
+			}
+			tm := music.TaskMusic{Type: r.FormValue("type"), Name: strings.TrimSpace(r.FormValue("name"))}
+			switch tm.Type {
+			case "radio":
+				tm.URL = strings.TrimSpace(r.FormValue("url"))
+			case "folder":
+				tm.Path = strings.TrimSpace(r.FormValue("path"))
+				tm.Shuffle = r.FormValue("shuffle") == "1"
+			}
+			if rule == "" || strings.TrimPrefix(value, "+") == "" || (tm.Type == "radio" && tm.URL == "") || (tm.Type != "radio" && tm.Type != "folder") {
+				s.setFlash(w, "error", "Scope, project/tag, type and stream URL (for radio) are required")
+				http.Redirect(w, r, "/music/rules", http.StatusSeeOther)
+				return
+			}
+			// Lautstärke einer bestehenden Regel beibehalten
+			if prev, ok := m.Entry(rule); ok {
+				tm.Volume, tm.Muted = prev.Volume, prev.Muted
+			}
+			m.Set(rule, tm)
+			s.setFlash(w, "success", "Music rule saved for "+rule)
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		if _, err := music.SaveForUser(s.cfg, key, m); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/music/rules", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	refs := s.taskRefs(key)
+	m, err := s.musicMap(key, refs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byUUID := map[string]music.TaskRef{}
+	for _, ref := range refs {
+		byUUID[strings.ToLower(ref.UUID)] = ref
+	}
+	list := func(table map[string]music.TaskMusic, label func(k string) (string, string)) []musicRule {
+		out := make([]musicRule, 0, len(table))
+		for k, tm := range table {
+			rk, l := label(k)
+			out = append(out, musicRule{Key: rk, Label: l, TaskMusic: tm})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Label < out[j].Label })
+		return out
+	}
+	tasks := list(m.Tasks, func(k string) (string, string) {
+		if ref, ok := byUUID[k]; ok {
+			return k, "#" + ref.ID
+		}
+		return k, k
+	})
+	unresolved := list(m.Unresolved, func(k string) (string, string) { return music.UnresolvedKey(k), "#" + k })
+	tags := list(m.Tags, func(k string) (string, string) { return music.TagKey(k), "+" + k })
+	projects := list(m.Projects, func(k string) (string, string) { return music.ProjectKey(k), k })
+	_, hasRoot := s.musicRoot(r)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Music rules</h2>
+<p>A task plays its own station or folder (set in the edit form). Without one, the first matching tag rule applies (tags in alphabetical order), then the project rule.</p>
+{{range .Sections}}
+<h3>{{.Title}}</h3>
+<table>
+  <thead><tr><th>{{.Column}}</th><th>Type</th><th>Name</th><th>Source</th><th></th></tr></thead>
+  <tbody>{{range .Rules}}<tr>
+    <td>{{.Label}}</td><td>{{.Type}}</td><td>{{.Name}}</td><td><code>{{if eq .Type "folder"}}{{.Path}}{{if .Shuffle}} (shuffle){{end}}{{else}}{{.URL}}{{end}}</code></td>
+    <td><form method="post" action="/music/rules" style="display:inline"><input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/><input type="hidden" name="action" value="delete"/><input type="hidden" name="key" value="{{.Key}}"/><button type="submit">remove</button></form></td>
+  </tr>{{else}}<tr><td colspan="5">none</td></tr>{{end}}</tbody>
+</table>
+{{end}}
+<h3>Add or replace a rule</h3>
+<form method="post" action="/music/rules">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <input type="hidden" name="action" value="save"/>
+  <select name="scope"><option value="tag">tag</option><option value="project">project</option></select>
+  <input name="value" placeholder="deepwork / acme" required/>
+  <select name="type"><option value="radio">radio</option>{{if .HasMusicRoot}}<option value="folder">folder</option>{{end}}</select>
+  <input name="name" placeholder="Label"/>
+  <input name="url" placeholder="https://… (radio)" style="width:240px"/>
+  {{if .HasMusicRoot}}<input name="path" placeholder="focus/lofi (folder)"/> <label><input type="checkbox" name="shuffle" value="1"/> shuffle</label>{{end}}
+  <button type="submit">Save rule</button>
+</form>
+{{if .Unresolved}}
+<h3>Old task IDs</h3>
+<p style="color:#57606a;">These entries were keyed by a task ID whose task was not open when the map was converted to UUIDs. dstask reuses IDs, so they are never applied. Set the music on the task again, then remove them here.</p>
+<table>
+  <thead><tr><th>Task ID</th><th>Type</th><th>Name</th><th>Source</th><th></th></tr></thead>
+  <tbody>{{range .Unresolved}}<tr>
+    <td>{{.Label}}</td><td>{{.Type}}</td><td>{{.Name}}</td><td><code>{{if eq .Type "folder"}}{{.Path}}{{else}}{{.URL}}{{end}}</code></td>
+    <td><form method="post" action="/music/rules" style="display:inline"><input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/><input type="hidden" name="action" value="delete"/><input type="hidden" name="key" value="{{.Key}}"/><button type="submit">remove</button></form></td>
+  </tr>{{end}}</tbody>
+</table>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Sections": []musicRuleSection{
+			{Title: "Tags", Column: "Tag", Rules: tags},
+			{Title: "Projects", Column: "Project", Rules: projects},
+			{Title: "Tasks", Column: "Task", Rules: tasks},
+		},
+		"Unresolved":   unresolved,
+		"HasMusicRoot": hasRoot,
+		"CSRFToken":    s.ensureCSRFToken(w, r),
+	}))
+}
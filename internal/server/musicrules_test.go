@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+func TestMusicRules_UUIDKeysTagAndProjectRules(t *testing.T) {
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	script := `if [ "$1" = "export" ]; then
+  echo '[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"pending","summary":"Invoice","project":"acme","tags":["deepwork","admin"]},
+{"id":2,"uuid":"22222222-2222-2222-2222-222222222222","status":"pending","summary":"Report","project":"acme","tags":[]},
+{"id":3,"uuid":"33333333-3333-3333-3333-333333333333","status":"pending","summary":"Write","project":"","tags":["deepwork"]}]'
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	// Version 1: nach Task-ID geschlüsselt; "9" gehört zu keinem offenen Task
+	m := music.Map{Version: 1, Tasks: map[string]music.TaskMusic{
+		"1": {Type: "radio", Name: "Own", URL: "https://radio.example.org/own"},
+		"9": {Type: "radio", Name: "Gone", URL: "https://radio.example.org/gone"},
+	}}
+	if _, err := music.SaveForUser(s.cfg, "admin", &m); err != nil {
+		t.Fatal(err)
+	}
+	post := func(form url.Values) *httptest.ResponseRecorder { return testPost(s, "/music/rules", form) }
+	for _, form := range []url.Values{
+		{"action": {"save"}, "scope": {"tag"}, "value": {"+deepwork"}, "type": {"radio"}, "name": {"Deep"}, "url": {"https://radio.example.org/deep"}},
+		{"action": {"save"}, "scope": {"project"}, "value": {"acme"}, "type": {"radio"}, "name": {"Acme"}, "url": {"https://radio.example.org/acme"}},
+	} {
+		if rr := post(form); rr.Code != http.StatusSeeOther {
+			t.Fatalf("save rule: %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	loaded, _, err := music.LoadForUser(s.cfg, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Tasks["11111111-1111-1111-1111-111111111111"]; !ok || loaded.Version != music.CurrentVersion {
+		t.Fatalf("id 1 should be migrated to its uuid (version %d): %+v", music.CurrentVersion, loaded)
+	}
+	if _, ok := loaded.Tasks["9"]; ok || loaded.Unresolved["9"].Name != "Gone" {
+		t.Fatalf("legacy entry without open task must be set aside, not kept as task entry: %+v %+v", loaded.Tasks, loaded.Unresolved)
+	}
+	if loaded.Tags["deepwork"].Name != "Deep" || loaded.Projects["acme"].Name != "Acme" {
+		t.Fatalf("rules not saved: %+v %+v", loaded.Tags, loaded.Projects)
+	}
+
+	// eigene Zuordnung vor Tag vor Projekt
+	for id, want := range map[string]string{
+		"1": "__MUSIC_START__Own|https://radio.example.org/own|id=11111111-1111-1111-1111-111111111111|",
+		"3": "__MUSIC_START__Deep|https://radio.example.org/deep|id=tag:deepwork|",
+		"2": "__MUSIC_START__Acme|https://radio.example.org/acme|id=project:acme|",
+	} {
+		if tok := s.musicToken("admin", "start", id); !strings.HasPrefix(tok, want) {
+			t.Fatalf("task %s: token %q, want prefix %q", id, tok, want)
+		}
+	}
+
+	// Lautstärke aus dem Player landet bei der Regel
+	req := httptest.NewRequest(http.MethodPut, "/music/tasks/"+url.PathEscape("tag:deepwork"), strings.NewReader(`{"type":"radio","name":"Deep","url":"https://radio.example.org/deep","volume":0.3}`))
+	req.SetBasicAuth("admin", "admin")
+	rr := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("put rule volume: %d %s", rr.Code, rr.Body.String())
+	}
+	if loaded, _, _ = music.LoadForUser(s.cfg, "admin"); loaded.Tags["deepwork"].Volume != 0.3 {
+		t.Fatalf("volume not stored on tag rule: %+v", loaded.Tags)
+	}
+
+	rr = testGet(s, "/music/rules")
+	if body := rr.Body.String(); rr.Code != http.StatusOK || !strings.Contains(body, "deepwork</td>") || !strings.Contains(body, "#1") || !strings.Contains(body, "Old task IDs") || !strings.Contains(body, "unresolved:9") {
+		t.Fatalf("rules page: %d\n%s", rr.Code, body)
+	}
+
+	if rr := post(url.Values{"action": {"delete"}, "key": {"tag:deepwork"}}); rr.Code != http.StatusSeeOther {
+		t.Fatalf("delete rule: %d", rr.Code)
+	}
+	if rr := post(url.Values{"action": {"delete"}, "key": {"unresolved:9"}}); rr.Code != http.StatusSeeOther {
+		t.Fatalf("delete unresolved entry: %d", rr.Code)
+	}
+	if loaded, _, _ = music.LoadForUser(s.cfg, "admin"); len(loaded.Unresolved) != 0 {
+		t.Fatalf("unresolved entry not removed: %+v", loaded.Unresolved)
+	}
+	if tok := s.musicToken("admin", "start", "3"); tok != "" {
+		t.Fatalf("no rule should match task 3 any more: %q", tok)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+// nowPlayingPing hält die SSE-Verbindung über Proxies hinweg offen.
+const nowPlayingPing = 25 * time.Second
+
+// nowPlaying ist der aktuelle Titel des Radio-Streams eines Repos (ICY StreamTitle).
+type nowPlaying struct {
+	Station string    `json:"station,omitempty"`
+	Title   string    `json:"title"`
+	Task    string    `json:"task,omitempty"` // UUID des Tasks, zu dem der Stream läuft
+	Since   time.Time `json:"since"`
+	Playing bool      `json:"playing"`
+}
+
+// nowPlayingHub verteilt die Titel der über /music/proxy laufenden Streams an die SSE-Clients
+// (/music/nowplaying) und führt das Protokoll der gespielten Titel pro Task.
+type nowPlayingHub struct {
+	cfg   *config.Config
+	mu    sync.Mutex
+	cur   map[string]nowPlaying
+	gen   map[string]int // jüngster Stream pro Repo; ältere Verbindungen melden nichts mehr
+	subs  map[string]map[chan nowPlaying]struct{}
+	dir   string
+	store *music.PlayedStore
+}
+
+func newNowPlayingHub(cfg *config.Config) *nowPlayingHub {
+	return &nowPlayingHub{cfg: cfg, cur: map[string]nowPlaying{}, gen: map[string]int{}, subs: map[string]map[chan nowPlaying]struct{}{}}
+}
+
+// played liefert den Store der gespielten Titel (<dataDir>/music-played.json).
+func (h *nowPlayingHub) played() *music.PlayedStore {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if dir := config.ResolveDataDir(h.cfg); h.store == nil || h.dir != dir {
+		h.dir, h.store = dir, music.NewPlayedStore(filepath.Join(dir, "music-played.json"))
+	}
+	return h.store
+}
+
+// begin meldet einen neuen Stream für key an und liefert seine Generation.
+func (h *nowPlayingHub) begin(key, station, task string) int {
+	h.mu.Lock()
+	h.gen[key]++
+	g := h.gen[key]
+	h.mu.Unlock()
+	h.publish(key, g, nowPlaying{Station: station, Task: task, Since: time.Now(), Playing: true})
+	return g
+}
+
+// title setzt den Titel des Streams g; ist inzwischen ein anderer Stream aktiv, passiert nichts.
+func (h *nowPlayingHub) title(key string, g int, title string) bool {
+	h.mu.Lock()
+	np := h.cur[key]
+	h.mu.Unlock()
+	np.Title, np.Since, np.Playing = title, time.Now(), true
+	return h.publish(key, g, np)
+}
+
+// end meldet das Ende des Streams g.
+func (h *nowPlayingHub) end(key string, g int) {
+	h.mu.Lock()
+	np := h.cur[key]
+	h.mu.Unlock()
+	np.Playing = false
+	h.publish(key, g, np)
+}
+
+func (h *nowPlayingHub) publish(key string, g int, np nowPlaying) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.gen[key] != g {
+		return false
+	}
+	h.cur[key] = np
+	for ch := range h.subs[key] {
+		// langsame Clients verpassen einen Zwischenstand, bekommen aber den nächsten
+		select {
+		case ch <- np:
+		default:
+		}
+	}
+	return true
+}
+
+// subscribe liefert den aktuellen Stand und einen Kanal für Änderungen; cancel meldet ab.
+func (h *nowPlayingHub) subscribe(key string) (nowPlaying, <-chan nowPlaying, func()) {
+	ch := make(chan nowPlaying, 4)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[key] == nil {
+		h.subs[key] = map[chan nowPlaying]struct{}{}
+	}
+	h.subs[key][ch] = struct{}{}
+	return h.cur[key], ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[key], ch)
+	}
+}
+
+// watchStreamTitles verbindet einen Stream von /music/proxy mit dem Hub: liefert den Callback für
+// music.ICYReader (Titel an die SSE-Clients und ins Protokoll des Tasks) und die Funktion fürs Ende.
+func (s *Server) watchStreamTitles(key, station, task string) (func(string), func()) {
+	g := s.playing.begin(key, station, task)
+	onTitle := func(title string) {
+		if !s.playing.title(key, g, title) {
+			return
+		}
+		applog.Debugf("/music/proxy now playing for %s: %q", key, title)
+		if task == "" || title == "" {
+			return
+		}
+		if err := s.playing.played().Add(key, task, music.Played{Time: time.Now().UTC(), Title: title, Station: station}); err != nil {
+			applog.Warnf("music: logging played title failed: %v", err)
+		}
+	}
+	return onTitle, func() { s.playing.end(key, g) }
+}
+
+// handleNowPlaying sendet den aktuellen Titel als Server-Sent Events (event "title", JSON), zuerst den
+// aktuellen Stand, danach jede Änderung.
+func (s *Server) handleNowPlaying(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	cur, ch, cancel := s.playing.subscribe(s.repoKey(r))
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	send := func(np nowPlaying) bool {
+		b, _ := json.Marshal(np)
+		if _, err := fmt.Fprintf(w, "event: title\ndata: %s\n\n", b); err != nil {
+			return false
+		}
+		f.Flush()
+		return true
+	}
+	if !send(cur) {
+		return
+	}
+	ping := time.NewTicker(nowPlayingPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case np := <-ch:
+			if !send(np) {
+				return
+			}
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			f.Flush()
+		}
+	}
+}
+
+// handleMusicPlayed liefert die im Radio gespielten Titel eines Tasks (task = ID oder UUID), neueste zuerst.
+func (s *Server) handleMusicPlayed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := s.repoKey(r)
+	task := strings.TrimSpace(r.URL.Query().Get("task"))
+	if task == "" {
+		http.Error(w, "missing task", http.StatusBadRequest)
+		return
+	}
+	uuid := musicRuleKey(s.taskRefs(key), task)
+	list, err := s.playing.played().List(key, uuid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{"task": uuid, "played": list})
+}
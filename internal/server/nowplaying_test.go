@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+func TestMusicProxy_ICYTitlesOverSSE(t *testing.T) {
+	tmp := t.TempDir()
+	home, _ := newTaskHome(t, tmp, false)
+	s := newTestServerWithStub(t, createDstaskStub(t, tmp), home)
+	s.cfg.DataDir = filepath.Join(tmp, "data")
+
+	// Icecast-Stream mit Metadaten alle 4 Bytes
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Icy-MetaData") != "1" {
+			t.Errorf("proxy did not request ICY metadata")
+		}
+		meta := "StreamTitle='Miles Davis - So What';"
+		block := make([]byte, 1+48)
+		block[0] = 3
+		copy(block[1:], meta)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("Icy-Metaint", "4")
+		w.Header().Set("Icy-Name", "Jazz FM")
+		_, _ = w.Write(append(append([]byte("aaaa"), block...), "bbbb"...))
+	}))
+	defer upstream.Close()
+	// lokaler Test-Stream: Host freigeben und als Station eintragen
+	s.cfg.MusicProxy.AllowHosts = []string{"127.0.0.1"}
+	m := music.Map{Version: music.CurrentVersion, Tags: map[string]music.TaskMusic{"jazz": {Type: "radio", Name: "Jazz", URL: upstream.URL + "/live"}}}
+	if _, err := music.SaveForUser(s.cfg, "admin", &m); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	get := func(ctx context.Context, path string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		req.SetBasicAuth("admin", "admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	events := get(ctx, "/music/nowplaying")
+	defer events.Body.Close()
+	if ct := events.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	lines := bufio.NewScanner(events.Body)
+	next := func() nowPlaying {
+		t.Helper()
+		for lines.Scan() {
+			if data, ok := strings.CutPrefix(lines.Text(), "data: "); ok {
+				var np nowPlaying
+				if err := json.Unmarshal([]byte(data), &np); err != nil {
+					t.Fatal(err)
+				}
+				return np
+			}
+		}
+		t.Fatalf("event stream ended: %v", lines.Err())
+		return nowPlaying{}
+	}
+	if np := next(); np.Playing {
+		t.Fatalf("nothing should be playing yet: %+v", np)
+	}
+
+	const uuid = "11111111-1111-1111-1111-111111111111"
+	resp := get(ctx, "/music/proxy?url="+url.QueryEscape(upstream.URL+"/live")+"&task="+uuid)
+	audio, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(audio) != "aaaabbbb" {
+		t.Fatalf("metadata not stripped from audio: %q", audio)
+	}
+	var titled bool
+	for !titled {
+		np := next()
+		titled = np.Title == "Miles Davis - So What" && np.Station == "Jazz FM" && np.Task == uuid
+	}
+
+	resp = get(ctx, "/music/played?task="+uuid)
+	var played struct {
+		Played []struct{ Title, Station string } `json:"played"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&played)
+	resp.Body.Close()
+	if len(played.Played) != 1 || played.Played[0].Title != "Miles Davis - So What" {
+		t.Fatalf("played log: %+v", played)
+	}
+}
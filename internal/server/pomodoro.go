@@ -0,0 +1,376 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// Phasen einer Pomodoro-Sitzung.
+const (
+	pomodoroFocus = "focus"
+	pomodoroBreak = "break"
+	pomodoroIdle  = "idle" // Pause vorbei, nächste Einheit startet erst auf Klick
+)
+
+// pomodoroSession ist die laufende Sitzung eines Repos. Der Countdown läuft im Browser; maßgeblich
+// ist aber Ends, damit die Sitzung Seitenwechsel und geschlossene Tabs übersteht.
+type pomodoroSession struct {
+	TaskID, UUID, Summary string
+	Actor                 string // wer die Sitzung gestartet hat
+	Phase                 string
+	Started, Ends         time.Time
+	StopOnBreak           bool
+	Completed             int // abgeschlossene Einheiten dieser Sitzung
+}
+
+// pomodoroTracker hält die laufenden Sitzungen (nur im Speicher), Schlüssel ist der Repo-Schlüssel.
+// Pro Sitzung läuft ein Timer, der die Fokus-Einheit zu ihrem Ende abschließt (finishPomodoro).
+type pomodoroTracker struct {
+	mu     sync.Mutex
+	m      map[string]*pomodoroSession
+	timers map[string]*time.Timer
+}
+
+func newPomodoroTracker() *pomodoroTracker {
+	return &pomodoroTracker{m: map[string]*pomodoroSession{}, timers: map[string]*time.Timer{}}
+}
+
+func (t *pomodoroTracker) set(key string, p pomodoroSession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.m[key] = &p
+}
+
+func (t *pomodoroTracker) clear(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.m, key)
+	if tm := t.timers[key]; tm != nil {
+		tm.Stop()
+		delete(t.timers, key)
+	}
+}
+
+// get liefert eine Kopie der Sitzung, ohne sie zu verändern.
+func (t *pomodoroTracker) get(key string) (pomodoroSession, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.m[key]
+	if !ok {
+		return pomodoroSession{}, false
+	}
+	return *p, true
+}
+
+// schedule ruft fn zum Zeitpunkt at auf und ersetzt einen vorher geplanten Aufruf für key.
+func (t *pomodoroTracker) schedule(key string, at time.Time, fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tm := t.timers[key]; tm != nil {
+		tm.Stop()
+	}
+	t.timers[key] = time.AfterFunc(time.Until(at), fn)
+}
+
+// update wendet fn unter der Sperre auf die Sitzung an und liefert eine Kopie des neuen Stands.
+func (t *pomodoroTracker) update(key string, fn func(p *pomodoroSession)) (pomodoroSession, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.m[key]
+	if !ok {
+		return pomodoroSession{}, false
+	}
+	fn(p)
+	return *p, true
+}
+
+// schedulePomodoro plant den Abschluss der laufenden Fokus-Einheit von key zu ihrem Ende.
+func (s *Server) schedulePomodoro(key string, ends time.Time) {
+	s.pomodoros.schedule(key, ends, func() { s.finishPomodoro(key, time.Now()) })
+}
+
+// finishPomodoro schaltet die Sitzung des Repos weiter, wenn ihre Phase abgelaufen ist: eine beendete
+// Fokus-Einheit wird in pomodoros.yaml erfasst und die Pause beginnt (dabei wird der Task auf Wunsch
+// gestoppt, mit dem Ende der Einheit als Zeitpunkt für die Zeiterfassung). Aufgerufen vom Timer der
+// Sitzung und von POST /pomodoro/next; der Phasenwechsel geschieht unter der Sperre des Trackers, so
+// wird jede Einheit genau einmal erfasst.
+func (s *Server) finishPomodoro(key string, now time.Time) (pomodoroSession, bool) {
+	var finished *pomodoroSession
+	sess, ok := s.pomodoros.update(key, func(p *pomodoroSession) {
+		if p.Phase == pomodoroFocus && !now.Before(p.Ends) {
+			f := *p
+			finished = &f
+			p.Completed++
+			p.Phase = pomodoroBreak
+			p.Started = p.Ends
+			p.Ends = p.Ends.Add(s.cfg.Pomodoro.BreakDuration(p.Completed))
+		}
+		if p.Phase == pomodoroBreak && !now.Before(p.Ends) {
+			p.Phase = pomodoroIdle
+		}
+	})
+	if finished == nil {
+		return sess, ok
+	}
+	if err := s.runner.AddPomodoro(key, finished.UUID, dstask.Pomodoro{Start: finished.Started, End: finished.Ends, User: finished.Actor}); err != nil {
+		applog.Warnf("recording pomodoro for %s failed: %v", key, err)
+	}
+	if finished.StopOnBreak {
+		// die ID vom Start der Einheit kann inzwischen zu einem anderen Task gehören
+		id, status := s.pomodoroTask(key, finished.UUID)
+		if !strings.EqualFold(status, "active") {
+			applog.Infof("pomodoro: task %s is no longer active, not stopping it", finished.UUID)
+			return sess, ok
+		}
+		change := s.beginTaskChangeAs(key, finished.Actor)
+		change.at = finished.Ends
+		res := s.runner.Run(key, 10*time.Second, "stop", id)
+		if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+			applog.Warnf("pomodoro: stopping task %s failed: code=%d err=%v", id, res.ExitCode, res.Err)
+		} else {
+			change.publish("stop", id)
+		}
+	}
+	return sess, ok
+}
+
+// pomodoroTask sucht den Task der Sitzung über seine UUID im Export und liefert aktuelle ID und Status
+// (beide leer, wenn der Task nicht mehr exportiert wird).
+func (s *Server) pomodoroTask(key, uuid string) (id, status string) {
+	res := s.runner.Run(key, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		return "", ""
+	}
+	decoded, _ := decodeTasksJSONFlexible(res.Stdout)
+	for _, row := range buildRowsFromTasks(decoded, "") {
+		if strings.EqualFold(row["uuid"], uuid) {
+			return row["id"], row["status"]
+		}
+	}
+	return "", ""
+}
+
+// ensureTaskActive startet den Task, falls er nicht schon aktiv ist.
+func (s *Server) ensureTaskActive(r *http.Request, id, status string) bool {
+	if strings.EqualFold(status, "active") {
+		return true
+	}
+	change := s.beginTaskChange(r)
+	res := s.runner.Run(s.repoKey(r), 10*time.Second, "start", id)
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		applog.Warnf("pomodoro: starting task %s failed: code=%d err=%v", id, res.ExitCode, res.Err)
+		return false
+	}
+	change.publish("start", id)
+	return true
+}
+
+// pomodoroMusic ist die Musik des Tasks (music-map.yaml) für den Player: Radio-Stream oder Ordner-Playlist.
+type pomodoroMusic struct {
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	URL    string  `json:"url"`
+	ID     string  `json:"id"`
+	Task   string  `json:"task,omitempty"` // UUID für das Protokoll der gespielten Titel
+	Volume float64 `json:"volume"`
+	Muted  bool    `json:"muted"`
+}
+
+// pomodoroState ist die JSON-Antwort von /pomodoro für den Countdown im Player.
+type pomodoroState struct {
+	Active      bool           `json:"active"`
+	Phase       string         `json:"phase,omitempty"`
+	TaskID      string         `json:"taskId,omitempty"`
+	UUID        string         `json:"uuid,omitempty"`
+	Summary     string         `json:"summary,omitempty"`
+	Ends        *time.Time     `json:"ends,omitempty"`
+	Remaining   int            `json:"remaining"` // Sekunden bis zum Ende der Phase
+	Completed   int            `json:"completed"` // Einheiten dieser Sitzung
+	Total       int            `json:"total"`     // alle erfassten Einheiten des Tasks
+	StopOnBreak bool           `json:"stopOnBreak,omitempty"`
+	Music       *pomodoroMusic `json:"music,omitempty"`
+	CSRFToken   string         `json:"csrfToken"`
+}
+
+func (s *Server) pomodoroState(w http.ResponseWriter, r *http.Request, sess pomodoroSession, ok bool, now time.Time) pomodoroState {
+	st := pomodoroState{CSRFToken: s.ensureCSRFToken(w, r)}
+	if !ok {
+		return st
+	}
+	key := s.repoKey(r)
+	if sess.Phase == pomodoroBreak && !now.Before(sess.Ends) {
+		sess.Phase = pomodoroIdle
+	}
+	ends := sess.Ends
+	st.Active, st.Phase, st.Ends = true, sess.Phase, &ends
+	st.TaskID, st.UUID, st.Summary = sess.TaskID, sess.UUID, sess.Summary
+	st.Completed, st.StopOnBreak = sess.Completed, sess.StopOnBreak
+	if sess.Phase != pomodoroIdle {
+		st.Remaining = int(sess.Ends.Sub(now).Round(time.Second).Seconds())
+		if st.Remaining < 0 {
+			st.Remaining = 0
+		}
+	}
+	if all, err := s.runner.Pomodoros(key); err == nil {
+		st.Total = len(all[sess.UUID])
+	}
+	if tm, rule, ok := s.taskMusic(key, sess.TaskID); ok && taskMusicSource(rule, tm) != "" {
+		st.Music = &pomodoroMusic{Type: tm.Type, Name: tm.Name, URL: taskMusicSource(rule, tm), ID: rule, Task: sess.UUID, Volume: tm.Volume, Muted: tm.Muted}
+	}
+	return st
+}
+
+// handlePomodoro liefert den Stand der Sitzung (GET /pomodoro). Nur lesend: abgelaufene Einheiten
+// schließt der Timer der Sitzung ab.
+func (s *Server) handlePomodoro(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	now := time.Now()
+	sess, ok := s.pomodoros.get(s.repoKey(r))
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, s.pomodoroState(w, r, sess, ok, now))
+}
+
+// handlePomodoroStart startet eine Fokus-Einheit am Task id (Formular in der Taskliste): der Task wird
+// gestartet und der Stream des Tasks über das Flash-Token im Player abgespielt.
+func (s *Server) handlePomodoroStart(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	id := strings.TrimSpace(r.FormValue("id"))
+	key := s.repoKey(r)
+	actor, _ := auth.UsernameFromRequest(r)
+	var task map[string]string
+	if res := s.runner.Run(key, 5*time.Second, "export"); res.Err == nil && res.ExitCode == 0 && !res.TimedOut {
+		decoded, _ := decodeTasksJSONFlexible(res.Stdout)
+		for _, row := range buildRowsFromTasks(decoded, "") {
+			if id != "" && row["id"] == id {
+				task = row
+				break
+			}
+		}
+	}
+	if task == nil || task["uuid"] == "" {
+		s.setFlash(w, "error", "Task "+id+" not found")
+		http.Redirect(w, r, "/open?html=1", http.StatusSeeOther)
+		return
+	}
+	if !s.ensureTaskActive(r, id, task["status"]) {
+		s.setFlash(w, "error", "Task action failed")
+		http.Redirect(w, r, "/open?html=1", http.StatusSeeOther)
+		return
+	}
+	now := time.Now()
+	focus := s.cfg.Pomodoro.FocusDuration()
+	s.pomodoros.set(key, pomodoroSession{
+		TaskID: id, UUID: strings.ToLower(task["uuid"]), Summary: task["summary"], Actor: actor,
+		Phase: pomodoroFocus, Started: now, Ends: now.Add(focus), StopOnBreak: r.FormValue("stop") == "1",
+	})
+	s.schedulePomodoro(key, now.Add(focus))
+	msg := fmt.Sprintf("Focus session started: %s on #%s", formatTracked(focus), id)
+	if token := s.musicToken(key, "start", id); token != "" {
+		msg = token + "\n" + msg
+	}
+	s.setFlash(w, "success", msg)
+	s.autoSync(key)
+	http.Redirect(w, r, "/open?html=1", http.StatusSeeOther)
+}
+
+// handlePomodoroNext beginnt die nächste Fokus-Einheit der Sitzung (auch vorzeitig aus der Pause).
+func (s *Server) handlePomodoroNext(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	now := time.Now()
+	key := s.repoKey(r)
+	sess, ok := s.finishPomodoro(key, now)
+	if !ok {
+		http.Error(w, "no pomodoro session", http.StatusConflict)
+		return
+	}
+	id, status := s.pomodoroTask(key, sess.UUID)
+	if id != "" {
+		sess.TaskID = id
+	}
+	if status == "" || !s.ensureTaskActive(r, sess.TaskID, status) {
+		http.Error(w, "starting task failed", http.StatusBadGateway)
+		return
+	}
+	sess, ok = s.pomodoros.update(key, func(p *pomodoroSession) {
+		p.TaskID = sess.TaskID
+		p.Phase = pomodoroFocus
+		p.Started = now
+		p.Ends = now.Add(s.cfg.Pomodoro.FocusDuration())
+	})
+	if ok {
+		s.schedulePomodoro(key, sess.Ends)
+	}
+	writeJSON(w, s.pomodoroState(w, r, sess, ok, now))
+}
+
+// handlePomodoroCancel beendet die Sitzung; eine laufende Einheit wird nicht erfasst, der Task bleibt wie er ist.
+func (s *Server) handlePomodoroCancel(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	s.pomodoros.clear(s.repoKey(r))
+	writeJSON(w, s.pomodoroState(w, r, pomodoroSession{}, false, time.Now()))
+}
+
+// pomodoroTotal fasst die Einheiten eines Tasks im Berichtszeitraum zusammen.
+type pomodoroTotal struct {
+	ID, UUID, Summary string
+	Count             int
+	Duration          time.Duration
+}
+
+func (t pomodoroTotal) Label() string { return formatTracked(t.Duration) }
+
+func (t pomodoroTotal) Hours() string { return strconv.FormatFloat(t.Duration.Hours(), 'f', 2, 64) }
+
+// pomodoroTotals zählt die Einheiten, die in [from, to) begonnen haben, je Task (user leer = alle).
+func pomodoroTotals(all map[string][]dstask.Pomodoro, tasks map[string]map[string]string, from, to time.Time, user string) []pomodoroTotal {
+	var out []pomodoroTotal
+	for uuid, ps := range all {
+		t := pomodoroTotal{UUID: uuid, ID: tasks[uuid]["id"], Summary: firstNonEmptyString(tasks[uuid]["summary"], "(unknown task)")}
+		for _, p := range ps {
+			if p.Start.Before(from) || !p.Start.Before(to) || (user != "" && p.User != user) {
+				continue
+			}
+			t.Count++
+			t.Duration += p.Duration()
+		}
+		if t.Count > 0 {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Summary < out[j].Summary
+	})
+	return out
+}
+
+func writePomodoroCSV(w http.ResponseWriter, totals []pomodoroTotal, period string) {
+	w.Header().Set("Content-Disposition", "attachment; filename=\"pomodoros-"+period+".csv\"")
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"id", "uuid", "summary", "pomodoros", "hours"})
+	for _, t := range totals {
+		_ = cw.Write(csvCells(t.ID, t.UUID, t.Summary, strconv.Itoa(t.Count), t.Hours()))
+	}
+	cw.Flush()
+}
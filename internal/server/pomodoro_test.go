@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/music"
+)
+
+func TestPomodoro_FocusBreakAndReport(t *testing.T) {
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	marker := filepath.Join(dir, "active")
+	script := `if [ "$1" = "start" ]; then touch "` + marker + `"; exit 0; fi
+if [ "$1" = "stop" ]; then rm -f "` + marker + `"; exit 0; fi
+if [ "$1" = "export" ]; then
+  status=pending
+  if [ -f "` + marker + `" ]; then status=active; fi
+  echo '[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"'$status'","summary":"Invoice ACME","project":"acme"}]'
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	m := music.Map{Version: 1, Tasks: map[string]music.TaskMusic{"1": {Type: "radio", Name: "Lofi", URL: "https://radio.example.org/lofi", Volume: 0.5}}}
+	if _, err := music.SaveForUser(s.cfg, "admin", &m); err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path string, form url.Values) *httptest.ResponseRecorder {
+		return serveAs(s, "admin", method, path, form)
+	}
+	state := func(rr *httptest.ResponseRecorder) pomodoroState {
+		t.Helper()
+		var st pomodoroState
+		if err := json.Unmarshal(rr.Body.Bytes(), &st); err != nil {
+			t.Fatalf("%d %s: %v", rr.Code, rr.Body.String(), err)
+		}
+		return st
+	}
+
+	rr := do(http.MethodPost, "/pomodoro/start", url.Values{"id": {"1"}, "stop": {"1"}})
+	if rr.Code != http.StatusSeeOther || !strings.Contains(rr.Header().Get("Set-Cookie"), "__MUSIC_START__Lofi") {
+		t.Fatalf("start: %d, flash %q", rr.Code, rr.Header().Get("Set-Cookie"))
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("task not started: %v", err)
+	}
+	st := state(do(http.MethodGet, "/pomodoro", nil))
+	if !st.Active || st.Phase != "focus" || st.Remaining < 24*60 || st.Music == nil || st.Music.URL != "https://radio.example.org/lofi" {
+		t.Fatalf("unexpected focus state: %+v", st)
+	}
+
+	// Fokus-Einheit abgelaufen: erfasst, Pause beginnt, Task wird zum Ende der Einheit gestoppt
+	focusEnd := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s.pomodoros.update("admin", func(p *pomodoroSession) { p.Started, p.Ends = focusEnd.Add(-25*time.Minute), focusEnd })
+	// GET ist nur lesend: auch nach Ablauf wird nichts erfasst oder gestoppt
+	for i := 0; i < 2; i++ {
+		if st = state(do(http.MethodGet, "/pomodoro", nil)); st.Phase != "focus" || st.Completed != 0 || st.Total != 0 || st.Remaining != 0 {
+			t.Fatalf("GET changed the session: %+v", st)
+		}
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("GET stopped the task: %v", err)
+	}
+	// der Timer der Sitzung schließt die Einheit ab, ein zweiter Aufruf erfasst sie nicht noch einmal
+	s.schedulePomodoro("admin", focusEnd)
+	deadline := time.Now().Add(5 * time.Second)
+	closed := func() bool {
+		s.timeQueue.wait()
+		log, _ := s.runner.TimeLog("admin")
+		ivs := log["11111111-1111-1111-1111-111111111111"]
+		return len(ivs) == 1 && !ivs[0].Running()
+	}
+	for !closed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond) // das Ende der Zeiterfassung kommt als Letztes
+	}
+	s.finishPomodoro("admin", time.Now())
+	st = state(do(http.MethodGet, "/pomodoro", nil))
+	if st.Phase != "break" || st.Completed != 1 || st.Total != 1 || st.Remaining <= 0 {
+		t.Fatalf("unexpected break state: %+v", st)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("task not stopped for the break")
+	}
+	log, _ := s.runner.TimeLog("admin")
+	if ivs := log["11111111-1111-1111-1111-111111111111"]; len(ivs) != 1 || !ivs[0].End.Equal(focusEnd) {
+		t.Fatalf("time log not closed at the end of the focus session: %+v", ivs)
+	}
+
+	st = state(do(http.MethodPost, "/pomodoro/next", url.Values{}))
+	if st.Phase != "focus" || st.Completed != 1 {
+		t.Fatalf("unexpected state after next: %+v", st)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("task not restarted: %v", err)
+	}
+	if body := do(http.MethodGet, "/open?html=1", nil).Body.String(); !strings.Contains(body, "🍅1") || !strings.Contains(body, `action="/pomodoro/start"`) {
+		t.Fatalf("pomodoro count or button missing on rows")
+	}
+
+	day := focusEnd.Add(-25 * time.Minute).Format("2006-01-02")
+	if got := do(http.MethodGet, "/reports/time?from="+day+"&to="+day+"&format=csv&group=pomodoro", nil).Body.String(); got != "id,uuid,summary,pomodoros,hours\n1,11111111-1111-1111-1111-111111111111,Invoice ACME,1,0.42\n" {
+		t.Fatalf("unexpected pomodoro CSV: %q", got)
+	}
+
+	if st = state(do(http.MethodPost, "/pomodoro/cancel", url.Values{})); st.Active {
+		t.Fatalf("session not cancelled: %+v", st)
+	}
+	if rr := do(http.MethodPost, "/pomodoro/next", url.Values{}); rr.Code != http.StatusConflict {
+		t.Fatalf("next without session: %d", rr.Code)
+	}
+}
+
+func TestPomodoro_TimerDoesNotStopReusedID(t *testing.T) {
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	// der Task der Sitzung wurde erledigt, ID 1 gehört jetzt einem anderen aktiven Task; #4 ist der alte Task
+	stopLog := filepath.Join(dir, "stop.log")
+	script := `if [ "$1" = "stop" ]; then echo "$@" >> ` + stopLog + `; exit 0; fi
+if [ "$1" = "export" ]; then
+  echo '[{"id":1,"uuid":"22222222-2222-2222-2222-222222222222","status":"active","summary":"Other"},
+{"id":4,"uuid":"33333333-3333-3333-3333-333333333333","status":"active","summary":"Moved"}]'
+  exit 0
+fi
+echo '[]'
+`
+	s := newTestServerWithStub(t, writeShellStub(t, dir, script), home)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	ends := time.Now().Add(-time.Minute)
+	session := pomodoroSession{TaskID: "1", UUID: "11111111-1111-1111-1111-111111111111", Actor: "admin",
+		Phase: pomodoroFocus, Started: ends.Add(-25 * time.Minute), Ends: ends, StopOnBreak: true}
+	s.pomodoros.set("admin", session)
+	if st, _ := s.finishPomodoro("admin", time.Now()); st.Phase != pomodoroBreak {
+		t.Fatalf("focus session not finished: %+v", st)
+	}
+	if b, err := os.ReadFile(stopLog); err == nil {
+		t.Fatalf("stopped a task that is not the session's: %q", b)
+	}
+
+	// hat der Task inzwischen eine neue ID, wird er unter dieser gestoppt
+	session.UUID = "33333333-3333-3333-3333-333333333333"
+	s.pomodoros.set("admin", session)
+	s.finishPomodoro("admin", time.Now())
+	s.timeQueue.wait()
+	if b, _ := os.ReadFile(stopLog); string(b) != "stop 4\n" {
+		t.Fatalf("unexpected stop call: %q", b)
+	}
+}
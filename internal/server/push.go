@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/notify"
+	"github.com/elpatron68/dstask-ui/internal/webpush"
+)
+
+// pushTimeout begrenzt eine Zustellung an einen Push-Dienst.
+const pushTimeout = 10 * time.Second
+
+// errPushBlocked: der Endpoint eines Abonnements zeigt auf eine private, Loopback- oder Link-Local-Adresse
+// (push.allowHosts).
+var errPushBlocked = errors.New("push: destination not allowed")
+
+// pushService hält VAPID-Schlüssel und Abonnement-Store des aktuellen Datenverzeichnisses; der
+// Schlüssel wird beim ersten Gebrauch geladen bzw. erzeugt.
+type pushService struct {
+	cfg   *config.Config
+	mu    sync.Mutex
+	dir   string
+	vapid *webpush.VAPID
+	store *webpush.Store
+	tls   *tls.Config // nur für Tests (Zertifikat des lokalen Stand-ins); nil = System-CAs
+}
+
+// client liefert den HTTP-Client für die Push-Dienste: eigene Auflösung wie beim Musik-Proxy (die
+// Endpoints stammen von den Nutzern), kein Umgebungs-Proxy und keine Weiterleitungen.
+func (p *pushService) client() *http.Client {
+	pc := p.cfg.Push
+	return &http.Client{
+		Timeout: pushTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				return dialPublic(ctx, network, addr, pc.AllowPrivate || pc.HostAllowed(host), "push", errPushBlocked)
+			},
+			TLSClientConfig:     p.tls,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return errors.New("redirects are not followed")
+		},
+	}
+}
+
+func newPushService(cfg *config.Config) *pushService {
+	return &pushService{cfg: cfg}
+}
+
+// subs liefert den Abonnement-Store (<dataDir>/push-subscriptions.json).
+func (p *pushService) subs() *webpush.Store {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.switchDirLocked()
+	return p.store
+}
+
+// key liefert das VAPID-Schlüsselpaar (<dataDir>/vapid.json).
+func (p *pushService) key() (*webpush.VAPID, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.switchDirLocked()
+	if p.vapid == nil {
+		v, err := webpush.LoadOrCreateVAPID(filepath.Join(p.dir, "vapid.json"))
+		if err != nil {
+			return nil, err
+		}
+		p.vapid = v
+	}
+	return p.vapid, nil
+}
+
+func (p *pushService) switchDirLocked() {
+	if dir := config.ResolveDataDir(p.cfg); p.store == nil || p.dir != dir {
+		p.dir, p.vapid, p.store = dir, nil, webpush.NewStore(filepath.Join(dir, "push-subscriptions.json"))
+	}
+}
+
+// subscribed meldet die Nutzer mit mindestens einem Abonnement.
+func (p *pushService) subscribed() map[string]bool {
+	users, err := p.subs().Users()
+	if err != nil {
+		applog.Warnf("push: reading subscriptions failed: %v", err)
+	}
+	m := make(map[string]bool, len(users))
+	for _, u := range users {
+		m[u] = true
+	}
+	return m
+}
+
+// pushMessage ist die Nutzlast, die der Service Worker (/push/sw.js) als Benachrichtigung anzeigt.
+type pushMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body,omitempty"`
+	URL   string `json:"url,omitempty"` // Ziel beim Anklicken
+	Tag   string `json:"tag,omitempty"` // ersetzt eine angezeigte Benachrichtigung mit gleichem Tag
+}
+
+// send stellt m an alle Browser des Nutzers zu und liefert die Anzahl erfolgreicher Zustellungen.
+// Vom Push-Dienst verworfene Abonnements (404/410) werden entfernt.
+func (p *pushService) send(user string, m pushMessage, urgency string) int {
+	store := p.subs()
+	subs, err := store.List(user)
+	if err != nil {
+		applog.Warnf("push: reading subscriptions failed: %v", err)
+		return 0
+	}
+	if len(subs) == 0 {
+		return 0
+	}
+	v, err := p.key()
+	if err != nil {
+		applog.Warnf("push: loading VAPID key failed: %v", err)
+		return 0
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return 0
+	}
+	opts := webpush.Options{Subject: p.cfg.Push.SubjectOrDefault(), TTL: 24 * time.Hour, Urgency: urgency}
+	sent := 0
+	client := p.client()
+	for _, sub := range subs {
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		err := webpush.Send(ctx, client, v, sub, payload, opts)
+		cancel()
+		switch {
+		case errors.Is(err, webpush.ErrGone):
+			applog.Infof("push: subscription of %s expired, removing it", user)
+			_, _ = store.Remove(user, sub.Endpoint)
+		case err != nil:
+			applog.Warnf("push: delivery for %s failed: %v", user, err)
+		default:
+			sent++
+		}
+	}
+	return sent
+}
+
+// pushDue meldet Tasks per Push, sobald sie fällig bzw. überfällig werden (je Zustand einmal pro Fälligkeit).
+func (s *Server) pushDue(user string, now time.Time, st *notify.ReminderState, reminderRows func() ([]map[string]string, bool)) (sent int, changed bool) {
+	rows, ok := reminderRows()
+	if !ok {
+		return 0, false
+	}
+	if st.PushSent == nil {
+		st.PushSent = map[string]string{}
+	}
+	var due, overdue []map[string]string
+	open := map[string]bool{}
+	for _, row := range rows {
+		id := row["uuid"]
+		open[id] = true
+		start := parseDueDate(row["due"])
+		if start.IsZero() || start.After(now) {
+			continue
+		}
+		state := "due:" + row["due"]
+		if dueDeadline(row["due"]).Before(now) {
+			state = "overdue:" + row["due"]
+		}
+		if st.PushSent[id] == state {
+			continue
+		}
+		if strings.HasPrefix(state, "overdue:") {
+			overdue = append(overdue, row)
+		} else {
+			due = append(due, row)
+		}
+		st.PushSent[id] = state
+		changed = true
+	}
+	for id := range st.PushSent {
+		if !open[id] {
+			delete(st.PushSent, id)
+			changed = true
+		}
+	}
+	if len(due) > 0 {
+		sent += s.push.send(user, duePushMessage("due", due, "/open?html=1&dueFilterType=on&dueFilterDate="+now.Format("2006-01-02")), "normal")
+	}
+	if len(overdue) > 0 {
+		sent += s.push.send(user, duePushMessage("overdue", overdue, "/open?html=1&dueFilterType=overdue"), "high")
+	}
+	return sent, changed
+}
+
+// duePushMessage fasst fällige bzw. überfällige Tasks zu einer Benachrichtigung zusammen; listURL ist
+// die gefilterte Liste (einzelne Tasks im Standard-Repo verlinken direkt auf die Bearbeitung).
+func duePushMessage(kind string, rows []map[string]string, listURL string) pushMessage {
+	sortRowsByDue(rows)
+	if len(rows) == 1 {
+		row := rows[0]
+		m := pushMessage{Title: "Task " + kind, Body: strings.TrimSpace(reminderLine(row)), URL: listURL, Tag: "due-" + row["uuid"]}
+		if row["repo"] == "" {
+			m.URL = "/tasks/" + row["id"] + "/edit"
+		}
+		return m
+	}
+	lines := make([]string, 0, 3)
+	for i, row := range rows {
+		if i == 3 {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(rows)-3))
+			break
+		}
+		lines = append(lines, strings.TrimSpace(reminderLine(row)))
+	}
+	return pushMessage{Title: fmt.Sprintf("%d tasks %s", len(rows), kind), Body: strings.Join(lines, "\n"), URL: listURL, Tag: kind}
+}
+
+// pushSyncFinished meldet das Ende eines langen manuellen Syncs.
+func (s *Server) pushSyncFinished(user, key string, ok bool, took time.Duration, errMsg string) {
+	m := pushMessage{Title: "Sync finished" + repoSuffix(key), Body: "dstask sync took " + took.Round(time.Second).String() + ".", URL: "/", Tag: "sync"}
+	if !ok {
+		m.Title = "Sync failed" + repoSuffix(key)
+		m.Body = firstNonEmptyString(errMsg, "dstask sync failed")
+	}
+	s.push.send(user, m, "normal")
+}
+
+// handlePushKey liefert den öffentlichen VAPID-Schlüssel (applicationServerKey).
+func (s *Server) handlePushKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	v, err := s.push.key()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]string{"publicKey": v.PublicKey()})
+}
+
+// handlePushSubscribe speichert das Abonnement des Browsers (Formularfelder endpoint, p256dh, auth).
+func (s *Server) handlePushSubscribe(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	var sub webpush.Subscription
+	sub.Endpoint = strings.TrimSpace(r.FormValue("endpoint"))
+	sub.Keys.P256dh = strings.TrimSpace(r.FormValue("p256dh"))
+	sub.Keys.Auth = strings.TrimSpace(r.FormValue("auth"))
+	sub.UserAgent = r.UserAgent()
+	if err := s.push.subs().Add(user, sub); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	applog.Infof("push: %s subscribed a browser", user)
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+// handlePushUnsubscribe entfernt das Abonnement mit endpoint.
+func (s *Server) handlePushUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	removed, err := s.push.subs().Remove(user, r.FormValue("endpoint"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]bool{"ok": removed})
+}
+
+// handlePushTest schickt eine Testbenachrichtigung an alle Browser des Nutzers.
+func (s *Server) handlePushTest(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	if n := s.push.send(user, pushMessage{Title: "dstask-ui test", Body: "Browser notifications for " + user + " arrive here.", URL: "/notifications/settings", Tag: "test"}, "normal"); n > 0 {
+		s.setFlash(w, "success", fmt.Sprintf("Test notification sent to %d browser(s).", n))
+	} else {
+		s.setFlash(w, "error", "No browser received the test notification.")
+	}
+	http.Redirect(w, r, "/notifications/settings", http.StatusSeeOther)
+}
+
+// handlePushServiceWorker liefert den Service Worker, der Push-Nachrichten als Benachrichtigung anzeigt.
+func (s *Server) handlePushServiceWorker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Service-Worker-Allowed", "/")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write([]byte(pushServiceWorkerJS))
+}
+
+const pushServiceWorkerJS = `self.addEventListener('push', function (e) {
+  var d = {};
+  try { d = e.data ? e.data.json() : {}; } catch (_) { d = { body: e.data ? e.data.text() : '' }; }
+  e.waitUntil(self.registration.showNotification(d.title || 'dstask', {
+    body: d.body || '', tag: d.tag || undefined, icon: '/favicon.svg', data: { url: d.url || '/' }
+  }));
+});
+self.addEventListener('notificationclick', function (e) {
+  e.notification.close();
+  e.waitUntil(clients.openWindow((e.notification.data && e.notification.data.url) || '/'));
+});
+`
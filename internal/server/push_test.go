@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/webpush/webpushtest"
+)
+
+func TestWebPush_SubscribeDueTasksAndLongSync(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	day := func(d int) string { return now.AddDate(0, 0, d).Format("2006-01-02") }
+	script := `if [ "$1" = "sync" ]; then exit 0; fi
+cat <<'JSON'
+[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"pending","summary":"File taxes","due":"` + day(-3) + `"},
+{"id":2,"uuid":"22222222-2222-2222-2222-222222222222","status":"pending","summary":"Book flights","due":"` + day(0) + `"},
+{"id":3,"uuid":"33333333-3333-3333-3333-333333333333","status":"pending","summary":"Renew passport","due":"` + day(30) + `"}]
+JSON
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	s.cfg.Push.Subject = "mailto:admin@example.org"
+	svc := webpushtest.NewService(t)
+	sub := svc.Subscribe(t)
+	s.push.tls = svc.TLSConfig()
+
+	if rr := testPost(s, "/push/subscribe", url.Values{"endpoint": {"http://push.example.org/x"}, "p256dh": {sub.Keys.P256dh}, "auth": {sub.Keys.Auth}}); rr.Code != http.StatusBadRequest {
+		t.Fatalf("non-https endpoint accepted: %d", rr.Code)
+	}
+	if rr := testPost(s, "/push/subscribe", url.Values{"endpoint": {sub.Endpoint}, "p256dh": {sub.Keys.P256dh}, "auth": {sub.Keys.Auth}}); rr.Code != http.StatusOK {
+		t.Fatalf("subscribe: %d %s", rr.Code, rr.Body.String())
+	}
+
+	// der Stand-in läuft auf 127.0.0.1: ohne push.allowHosts wird nichts zugestellt (und das Abo bleibt)
+	if n := s.push.send("admin", pushMessage{Title: "blocked"}, ""); n != 0 || len(svc.Messages()) != 0 {
+		t.Fatalf("push delivered to loopback endpoint: %d", n)
+	}
+	if subs, _ := s.push.subs().List("admin"); len(subs) != 1 {
+		t.Fatalf("blocked delivery removed the subscription: %+v", subs)
+	}
+	s.cfg.Push.AllowHosts = []string{"127.0.0.1"}
+
+	// fälliger und überfälliger Task: je eine Benachrichtigung, beim zweiten Lauf keine mehr
+	if n := s.runReminders(now); n != 2 {
+		t.Fatalf("expected 2 push messages, got %d", n)
+	}
+	var got []pushMessage
+	for _, m := range svc.Messages() {
+		var pm pushMessage
+		if err := json.Unmarshal(m.Payload, &pm); err != nil {
+			t.Fatal(err)
+		}
+		if m.Subject != "mailto:admin@example.org" {
+			t.Fatalf("unexpected VAPID subject %q", m.Subject)
+		}
+		got = append(got, pm)
+	}
+	if got[0].Title != "Task due" || !strings.Contains(got[0].Body, "#2 Book flights") || got[0].URL != "/tasks/2/edit" ||
+		got[1].Title != "Task overdue" || !strings.Contains(got[1].Body, "#1 File taxes") {
+		t.Fatalf("unexpected messages: %+v", got)
+	}
+	if n := s.runReminders(now.Add(time.Minute)); n != 0 {
+		t.Fatalf("push sent twice: %d", n)
+	}
+	// am nächsten Tag ist der heute fällige Task überfällig
+	if n := s.runReminders(now.AddDate(0, 0, 1)); n != 1 {
+		t.Fatalf("expected overdue push for task 2, got %d", n)
+	}
+
+	s.cfg.Push.LongSync = "1ns"
+	s.runSync("admin", "Sync")
+	m := svc.Wait(t, 5*time.Second)
+	for !strings.Contains(string(m.Payload), "Sync") {
+		m = svc.Wait(t, 5*time.Second)
+	}
+	if !strings.Contains(string(m.Payload), `"title":"Sync finished"`) {
+		t.Fatalf("unexpected sync push: %s", m.Payload)
+	}
+
+	// vom Dienst verworfene Abonnements werden entfernt
+	svc.Expire(sub.Endpoint)
+	if rr := testPost(s, "/push/test", url.Values{}); rr.Code != http.StatusSeeOther {
+		t.Fatalf("test: %d", rr.Code)
+	}
+	if subs, _ := s.push.subs().List("admin"); len(subs) != 0 {
+		t.Fatalf("expired subscription kept: %+v", subs)
+	}
+}
+
+func TestPushClientBlocksPrivateAndRedirects(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer target.Close()
+	redirect := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(target.Certificate())
+	pool.AddCert(redirect.Certificate())
+	p := &pushService{cfg: &config.Config{}, tls: &tls.Config{RootCAs: pool}}
+	if _, err := p.client().Post(target.URL, "text/plain", nil); !errors.Is(err, errPushBlocked) {
+		t.Fatalf("loopback endpoint not blocked: %v", err)
+	}
+	p.cfg.Push.AllowHosts = []string{"127.0.0.1"}
+	resp, err := p.client().Post(target.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("allowed host: %v", err)
+	}
+	resp.Body.Close()
+	if _, err := p.client().Post(redirect.URL, "text/plain", nil); err == nil || !strings.Contains(err.Error(), "redirects are not followed") {
+		t.Fatalf("redirect followed: %v", err)
+	}
+}
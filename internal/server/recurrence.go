@@ -0,0 +1,474 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// recurrenceTick ist das Prüfintervall des Wiederholungs-Schedulers.
+const recurrenceTick = time.Minute
+
+// recurrencePreview ist die Anzahl der angezeigten künftigen Termine.
+const recurrencePreview = 5
+
+// recurrenceLoop prüft regelmäßig alle Repos auf fällige Wiederholungen. Die Repos werden nacheinander
+// abgearbeitet, damit geteilte Repos (mehrere Schlüssel, ein Verzeichnis) keine doppelten Instanzen erzeugen.
+func (s *Server) recurrenceLoop(ctx context.Context, keys []string) {
+	ticker := time.NewTicker(recurrenceTick)
+	defer ticker.Stop()
+	for {
+		for _, key := range keys {
+			if n := s.runRecurrence(key, time.Now()); n > 0 {
+				applog.Infof("created %d recurring task(s) for %s", n, key)
+				s.autoSync(key)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recurrenceRun hält den Export eines Laufs, damit er nur bei Bedarf und einmal geladen wird.
+type recurrenceRun struct {
+	s     *Server
+	key   string
+	tasks map[string]map[string]any // UUID -> Task
+}
+
+func (rr *recurrenceRun) load() bool {
+	if rr.tasks != nil {
+		return true
+	}
+	res := rr.s.runner.Run(rr.key, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		applog.Warnf("recurrence: export for %s failed: %v", rr.key, res.Err)
+		return false
+	}
+	tasks, _ := decodeTasksJSONFlexible(res.Stdout)
+	rr.tasks = map[string]map[string]any{}
+	for _, t := range tasks {
+		if u := strings.ToLower(str(firstOf(t, "uuid", "UUID"))); u != "" {
+			rr.tasks[u] = t
+		}
+	}
+	return true
+}
+
+// runRecurrence legt alle am Tag now fälligen Instanzen an und liefert deren Anzahl.
+func (s *Server) runRecurrence(key string, now time.Time) int {
+	rules, err := s.runner.RecurrenceRules(key)
+	if err != nil {
+		applog.Warnf("recurrence: reading rules for %s failed: %v", key, err)
+		return 0
+	}
+	if len(rules) == 0 {
+		return 0
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	run := &recurrenceRun{s: s, key: key}
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	created := map[string]dstask.RecurrenceRule{}
+	for _, id := range ids {
+		rule := rules[id]
+		due, ok := s.recurrenceDue(run, rule, today)
+		if !ok {
+			continue
+		}
+		uuid, err := s.createRecurrence(run, rule, due)
+		if err != nil {
+			applog.Warnf("recurrence %s for %s: %v", id, key, err)
+			continue
+		}
+		rule.Last = due.Format(dstask.DateLayout)
+		rule.LastUUID = uuid
+		created[id] = rule
+	}
+	if len(created) == 0 {
+		return 0
+	}
+	err = s.runner.UpdateRecurrenceRules(key, "dstask-ui: recurring tasks created", func(cur map[string]dstask.RecurrenceRule) bool {
+		for id, rule := range created {
+			if c, ok := cur[id]; ok {
+				c.Last, c.LastUUID = rule.Last, rule.LastUUID
+				cur[id] = c
+			}
+		}
+		return true
+	})
+	if err != nil {
+		applog.Warnf("recurrence: saving state for %s failed: %v", key, err)
+	}
+	return len(created)
+}
+
+// recurrenceDue liefert die Fälligkeit der anzulegenden Instanz. Kalenderregeln werden am Fälligkeitstag
+// angelegt; "after"-Regeln, sobald die vorige Instanz erledigt ist (fällig Every Tage nach Erledigung).
+func (s *Server) recurrenceDue(run *recurrenceRun, rule dstask.RecurrenceRule, today time.Time) (time.Time, bool) {
+	if rule.Calendar() {
+		return rule.DueOn(today)
+	}
+	if rule.Last != "" && rule.LastUUID == "" {
+		// vorige Instanz nicht ermittelbar; nicht blind erneut anlegen
+		return time.Time{}, false
+	}
+	prev := firstNonEmptyString(rule.LastUUID, strings.ToLower(rule.Task))
+	if prev == "" {
+		// Template ohne bisherige Instanz: erste Instanz ab Start
+		start, err := time.ParseInLocation(dstask.DateLayout, rule.Start, time.Local)
+		return today, err == nil && !start.After(today)
+	}
+	if !run.load() {
+		return time.Time{}, false
+	}
+	t, ok := run.tasks[prev]
+	if !ok || !isResolved(t) {
+		return time.Time{}, false
+	}
+	resolved := parseTimeOrZero(trimQuotes(str(firstOf(t, "resolved", "Resolved"))))
+	if resolved.IsZero() {
+		resolved = today
+	}
+	resolved = resolved.In(time.Local)
+	due := time.Date(resolved.Year(), resolved.Month(), resolved.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, rule.Every)
+	// erst anlegen, wenn die neue Instanz fällig wird
+	return due, !due.After(today)
+}
+
+// createRecurrence legt eine Instanz an (add template:<id> bzw. Kopie des Tasks) und liefert deren UUID.
+func (s *Server) createRecurrence(run *recurrenceRun, rule dstask.RecurrenceRule, due time.Time) (string, error) {
+	if !run.load() {
+		return "", errors.New("export failed")
+	}
+	args := []string{"add"}
+	if rule.Template != "" {
+		args = append(args, "template:"+rule.Template)
+	} else {
+		src, ok := run.tasks[strings.ToLower(rule.Task)]
+		if !ok {
+			return "", errors.New("task " + rule.Task + " not found")
+		}
+		args = append(args, summaryTokens(trimQuotes(str(firstOf(src, "summary", "Summary"))))...)
+		if p := trimQuotes(str(firstOf(src, "project", "Project"))); p != "" {
+			args = append(args, "project:"+quoteIfNeeded(p))
+		}
+		if p := str(firstOf(src, "priority", "Priority")); p != "" {
+			args = append(args, p)
+		}
+		for _, tg := range strings.Split(joinTags(firstOf(src, "tags", "Tags")), ", ") {
+			if tg = strings.TrimPrefix(strings.TrimSpace(tg), "+"); tg != "" {
+				args = append(args, "+"+tg)
+			}
+		}
+	}
+	args = append(args, "due:"+due.Format(dstask.DateLayout))
+	res := s.runner.Run(run.key, 10*time.Second, args...)
+	s.cmdStore.Append(run.key, "Create recurring task", args)
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		return "", errors.New(firstNonEmptyString(stripANSI(res.Stderr), errString(res.Err), "dstask add failed"))
+	}
+	// neue Instanz = UUID, die vorher nicht im Export war
+	before := run.tasks
+	run.tasks = nil
+	if !run.load() {
+		return "", nil
+	}
+	for u := range run.tasks {
+		if _, ok := before[u]; !ok {
+			return u, nil
+		}
+	}
+	return "", nil
+}
+
+// recurrenceRow ist ein Eintrag der Übersicht /recurrence.
+type recurrenceRow struct {
+	Key      string
+	Source   string
+	EditURL  string
+	Rule     string
+	Last     string
+	Next     string
+	Upcoming []string
+}
+
+// recurrenceNext beschreibt die nächste Fälligkeit für die Anzeige.
+func recurrenceNext(rule dstask.RecurrenceRule) (string, []string) {
+	if !rule.Calendar() {
+		if rule.LastUUID == "" && rule.Template != "" {
+			return "from " + rule.Start, nil
+		}
+		return strconv.Itoa(rule.Every) + " day(s) after the current instance is done", nil
+	}
+	var upcoming []string
+	for _, d := range rule.Upcoming(recurrencePreview) {
+		upcoming = append(upcoming, d.Format("Mon 2006-01-02"))
+	}
+	if len(upcoming) == 0 {
+		return "", nil
+	}
+	return upcoming[0], upcoming
+}
+
+// recurrenceSources liefert Beschriftungen für Templates (über show-templates) und Tasks (über export).
+func (s *Server) recurrenceSources(key string) (templates map[string]string, tasks map[string]string) {
+	templates, tasks = map[string]string{}, map[string]string{}
+	res := s.runner.Run(key, 5*time.Second, "show-templates")
+	for _, t := range parseTemplatesFromOutput(res.Stdout) {
+		templates[t["id"]] = t["summary"]
+	}
+	run := &recurrenceRun{s: s, key: key}
+	if run.load() {
+		for u, t := range run.tasks {
+			tasks[u] = trimQuotes(str(firstOf(t, "summary", "Summary")))
+		}
+	}
+	return templates, tasks
+}
+
+func sourceLabel(rule dstask.RecurrenceRule, templates, tasks map[string]string) string {
+	if rule.Template != "" {
+		return "template " + rule.Template + " " + templates[rule.Template]
+	}
+	u := strings.ToLower(rule.Task)
+	return "task " + u[:8] + " " + tasks[u]
+}
+
+func recurrenceEditURL(rule dstask.RecurrenceRule) string {
+	if rule.Template != "" {
+		return "/recurrence/edit?template=" + rule.Template
+	}
+	return "/recurrence/edit?task=" + rule.Task
+}
+
+// handleRecurrence listet alle Wiederholungsregeln des Repos mit Vorschau der nächsten Termine.
+func (s *Server) handleRecurrence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := s.repoKey(r)
+	rules, err := s.runner.RecurrenceRules(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates, tasks := s.recurrenceSources(key)
+	rows := make([]recurrenceRow, 0, len(rules))
+	for k, rule := range rules {
+		next, upcoming := recurrenceNext(rule)
+		rows = append(rows, recurrenceRow{
+			Key: k, Source: sourceLabel(rule, templates, tasks), EditURL: recurrenceEditURL(rule),
+			Rule: rule.Describe(), Last: rule.Last, Next: next, Upcoming: upcoming,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+	csrfToken := s.ensureCSRFToken(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Recurring tasks</h2>
+<p>New instances are created automatically when they are due. Add a rule via "repeat" on the <a href="/templates">Templates</a> page or "Repeat…" on a task's edit page.</p>
+{{if .Rows}}
+<table border="1" cellpadding="4" cellspacing="0">
+  <thead><tr><th>Source</th><th>Rule</th><th>Last created</th><th>Next</th><th>Upcoming</th><th>Actions</th></tr></thead>
+  <tbody>
+  {{range .Rows}}<tr>
+    <td>{{.Source}}</td>
+    <td>{{.Rule}}</td>
+    <td>{{.Last}}</td>
+    <td>{{.Next}}</td>
+    <td>{{range $i, $d := .Upcoming}}{{if $i}}, {{end}}{{$d}}{{end}}</td>
+    <td>
+      <a href="{{.EditURL}}">edit</a>
+      · <form method="post" action="/recurrence/delete" style="display:inline" onsubmit="return confirm('Delete this rule?');">
+        <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/>
+        <input type="hidden" name="key" value="{{.Key}}"/>
+        <button type="submit">delete</button>
+      </form>
+    </td>
+  </tr>{{end}}
+  </tbody>
+</table>
+{{else}}
+<p>No recurring tasks yet.</p>
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{"Rows": rows, "CSRFToken": csrfToken}))
+}
+
+// ruleFromForm liest eine Regel aus dem Formular von /recurrence/edit.
+func ruleFromForm(r *http.Request) dstask.RecurrenceRule {
+	rule := dstask.RecurrenceRule{
+		Template: strings.TrimSpace(r.FormValue("template")),
+		Task:     strings.ToLower(strings.TrimSpace(r.FormValue("task"))),
+		Freq:     strings.TrimSpace(r.FormValue("freq")),
+		Start:    strings.TrimSpace(r.FormValue("start")),
+	}
+	switch rule.Freq {
+	case dstask.RecurWeekly:
+		rule.Weekdays = r.Form["weekdays"]
+	case dstask.RecurMonthly:
+		rule.Day, _ = strconv.Atoi(r.FormValue("day"))
+	case dstask.RecurAfterDone:
+		rule.Every, _ = strconv.Atoi(r.FormValue("every"))
+	}
+	return rule
+}
+
+// handleRecurrenceEdit zeigt (GET) bzw. speichert (POST) die Regel für ?template=<id> oder ?task=<id|uuid>.
+// POST mit preview=1 zeigt nur die Vorschau der nächsten Termine, ohne zu speichern.
+func (s *Server) handleRecurrenceEdit(w http.ResponseWriter, r *http.Request) {
+	key := s.repoKey(r)
+	rules, err := s.runner.RecurrenceRules(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var rule dstask.RecurrenceRule
+	var formErr string
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		rule.Template = strings.TrimSpace(q.Get("template"))
+		if id := strings.TrimSpace(q.Get("task")); id != "" && rule.Template == "" {
+			task, ok := s.findTask(key, id)
+			if !ok {
+				http.Error(w, "task not found", http.StatusNotFound)
+				return
+			}
+			rule.Task = strings.ToLower(str(firstOf(task, "uuid", "UUID")))
+		}
+		if existing, ok := rules[dstask.RecurrenceKey(rule.Template, rule.Task)]; ok {
+			rule = existing
+		} else {
+			rule.Freq = dstask.RecurWeekly
+			rule.Weekdays = []string{strings.ToLower(time.Now().Weekday().String()[:3])}
+			rule.Day = time.Now().Day()
+			rule.Every = 7
+			rule.Start = time.Now().Format(dstask.DateLayout)
+		}
+	case http.MethodPost:
+		if !s.requirePostCSRF(w, r) {
+			return
+		}
+		rule = ruleFromForm(r)
+		if err := rule.Validate(); err != nil {
+			formErr = err.Error()
+			break
+		}
+		if r.FormValue("preview") == "1" {
+			break
+		}
+		k := dstask.RecurrenceKey(rule.Template, rule.Task)
+		err := s.runner.UpdateRecurrenceRules(key, "dstask-ui: recurrence "+k, func(cur map[string]dstask.RecurrenceRule) bool {
+			// Stand der letzten Instanz bleibt erhalten, sonst würde sie erneut angelegt
+			if old, ok := cur[k]; ok {
+				rule.Last, rule.LastUUID = old.Last, old.LastUUID
+			}
+			cur[k] = rule
+			return true
+		})
+		if err != nil {
+			formErr = err.Error()
+			break
+		}
+		s.setFlash(w, "success", "Recurrence saved: "+rule.Describe())
+		s.autoSync(key)
+		http.Redirect(w, r, "/recurrence", http.StatusSeeOther)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if rule.Template == "" && rule.Task == "" {
+		http.Error(w, "template or task required", http.StatusBadRequest)
+		return
+	}
+	templates, tasks := s.recurrenceSources(key)
+	next, upcoming := "", []string(nil)
+	if formErr == "" {
+		next, upcoming = recurrenceNext(rule)
+	}
+	selected := map[string]bool{}
+	for _, d := range rule.Weekdays {
+		selected[strings.ToLower(d)] = true
+	}
+	_, exists := rules[dstask.RecurrenceKey(rule.Template, rule.Task)]
+	csrfToken := s.ensureCSRFToken(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Repeat {{.Source}}</h2>
+{{if .Error}}<div class="flash error" style="margin:10px 0;padding:8px;border:1px solid #d0d7de;border-left:4px solid #cf222e;">{{.Error}}</div>{{end}}
+<form method="post" action="/recurrence/edit">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <input type="hidden" name="template" value="{{.Rule.Template}}"/>
+  <input type="hidden" name="task" value="{{.Rule.Task}}"/>
+  <div><label><input type="radio" name="freq" value="daily" {{if eq .Rule.Freq "daily"}}checked{{end}}/> daily</label></div>
+  <div><label><input type="radio" name="freq" value="weekly" {{if eq .Rule.Freq "weekly"}}checked{{end}}/> weekly on</label>
+    {{range .Weekdays}}<label style="margin-left:6px;"><input type="checkbox" name="weekdays" value="{{.}}" {{if index $.Selected .}}checked{{end}}/> {{.}}</label>{{end}}
+  </div>
+  <div><label><input type="radio" name="freq" value="monthly" {{if eq .Rule.Freq "monthly"}}checked{{end}}/> monthly on day</label>
+    <input type="number" name="day" min="1" max="31" value="{{.Rule.Day}}" style="width:60px;"/></div>
+  <div><label><input type="radio" name="freq" value="after" {{if eq .Rule.Freq "after"}}checked{{end}}/> every</label>
+    <input type="number" name="every" min="1" value="{{.Rule.Every}}" style="width:60px;"/> day(s) after the previous instance is completed</div>
+  <div style="margin-top:6px;"><label>Starting: <input type="date" name="start" value="{{.Rule.Start}}"/></label></div>
+  <div style="margin-top:8px;">
+    <button type="submit">Save</button>
+    <button type="submit" name="preview" value="1" style="margin-left:8px;">Preview</button>
+    <a href="/recurrence" style="margin-left:8px;">cancel</a>
+  </div>
+</form>
+{{if .Next}}
+<h3>Upcoming</h3>
+{{if .Upcoming}}<ul>{{range .Upcoming}}<li>{{.}}</li>{{end}}</ul>{{else}}<p>{{.Next}}</p>{{end}}
+{{end}}
+{{if .Exists}}<p style="color:#57606a;">Last instance created for {{or .Rule.Last "–"}}.</p>{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Source":    sourceLabel(rule, templates, tasks),
+		"Rule":      rule,
+		"Weekdays":  dstask.WeekdayNames(),
+		"Selected":  selected,
+		"Next":      next,
+		"Upcoming":  upcoming,
+		"Exists":    exists,
+		"Error":     formErr,
+		"CSRFToken": csrfToken,
+	}))
+}
+
+// handleRecurrenceDelete entfernt eine Regel (Formularfeld key).
+func (s *Server) handleRecurrenceDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	key := s.repoKey(r)
+	k := strings.TrimSpace(r.FormValue("key"))
+	err := s.runner.UpdateRecurrenceRules(key, "dstask-ui: remove recurrence "+k, func(cur map[string]dstask.RecurrenceRule) bool {
+		if _, ok := cur[k]; !ok {
+			return false
+		}
+		delete(cur, k)
+		return true
+	})
+	if err != nil {
+		s.setFlash(w, "error", "Deleting the rule failed: "+err.Error())
+	} else {
+		s.setFlash(w, "success", "Recurrence removed")
+		s.autoSync(key)
+	}
+	http.Redirect(w, r, "/recurrence", http.StatusSeeOther)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+)
+
+const recurTaskUUID = "0f8c3e2a-1b2c-4d5e-8f90-123456789abc"
+
+func TestRunRecurrence_CreatesDueInstancesOnce(t *testing.T) {
+	dir := t.TempDir()
+	home, repo := newTaskHome(t, dir, true)
+	now := time.Now()
+	today := now.Format(dstask.DateLayout)
+	rules := "version: 1\nrules:\n" +
+		"  template:3:\n    template: \"3\"\n    freq: daily\n    start: " + now.AddDate(0, 0, -2).Format(dstask.DateLayout) + "\n" +
+		"  task:" + recurTaskUUID + ":\n    task: " + recurTaskUUID + "\n    freq: after\n    every: 2\n    start: " + today + "\n"
+	if err := os.WriteFile(filepath.Join(repo, dstask.RecurrenceFile), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Jeder "add"-Aufruf erscheint danach als neuer Task im Export
+	addLog := filepath.Join(dir, "add.log")
+	script := `if [ "$1" = "add" ]; then echo "$@" >> ` + addLog + `; exit 0; fi
+if [ "$1" = "export" ]; then
+  printf '[{"id":0,"uuid":"` + recurTaskUUID + `","status":"resolved","summary":"Water plants","project":"home","priority":"P2","tags":["garden"],"resolved":"` + now.AddDate(0, 0, -3).Format(time.RFC3339) + `"}'
+  n=0
+  if [ -f ` + addLog + ` ]; then n=$(wc -l < ` + addLog + `); fi
+  i=1
+  while [ $i -le $n ]; do printf ',{"id":%d,"uuid":"aaaaaaaa-0000-0000-0000-00000000000%d","status":"pending","summary":"instance"}' $i $i; i=$((i+1)); done
+  echo ']'
+  exit 0
+fi
+echo '[]'
+`
+	s := newTestServerWithStub(t, writeShellStub(t, dir, script), home)
+
+	if n := s.runRecurrence("admin", now); n != 2 {
+		t.Fatalf("created %d instances, want 2", n)
+	}
+	b, _ := os.ReadFile(addLog)
+	log := string(b)
+	if !strings.Contains(log, "add template:3 due:"+today) {
+		t.Fatalf("template instance not created: %q", log)
+	}
+	if !strings.Contains(log, "add Water plants project:home P2 +garden due:"+now.AddDate(0, 0, -1).Format(dstask.DateLayout)) {
+		t.Fatalf("task copy not created: %q", log)
+	}
+	saved, err := s.runner.RecurrenceRules("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := saved["template:3"]; r.Last != today || !strings.HasPrefix(r.LastUUID, "aaaaaaaa-") {
+		t.Fatalf("template rule state not saved: %+v", r)
+	}
+	if n := s.runRecurrence("admin", now); n != 0 {
+		t.Fatalf("second run created %d instances", n)
+	}
+
+	// Vorschau und Speichern über die Seiten
+	rr := testPost(s, "/recurrence/edit", url.Values{"template": {"5"}, "freq": {"weekly"}, "weekdays": {"mon", "fri"}, "start": {today}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("save rule: %d %s", rr.Code, rr.Body.String())
+	}
+	if body := testGet(s, "/recurrence").Body.String(); !strings.Contains(body, "weekly on mon, fri") || !strings.Contains(body, "2 day(s) after completion") {
+		t.Fatalf("unexpected overview: %s", body)
+	}
+}
@@ -0,0 +1,438 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/notify"
+)
+
+// reminderTick ist das Prüfintervall der Erinnerungen; nach einer Ruhezeit wird spätestens nach einem Tick nachgeholt.
+const reminderTick = 5 * time.Minute
+
+// reminderLoop verschickt Fälligkeits-Erinnerungen (E-Mail und Chat), solange ctx läuft.
+func (s *Server) reminderLoop(ctx context.Context) {
+	ticker := time.NewTicker(reminderTick)
+	defer ticker.Stop()
+	for {
+		if n := s.runReminders(time.Now()); n > 0 {
+			applog.Infof("sent %d reminder(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runReminders prüft für alle Nutzer mit Opt-in, ob die Tagesübersicht, Einzel-Erinnerungen, die
+// Chat-Meldung "morgen fällig" oder Push-Meldungen zu fälligen Tasks anstehen, und liefert die Anzahl
+// verschickter Nachrichten.
+func (s *Server) runReminders(now time.Time) int {
+	all, err := notify.AllPrefs(s.cfg)
+	if err != nil {
+		applog.Warnf("reminders: reading preferences failed: %v", err)
+		return 0
+	}
+	states, err := notify.LoadReminderState(s.cfg)
+	if err != nil {
+		applog.Warnf("reminders: reading state failed: %v", err)
+		return 0
+	}
+	push := s.push.subscribed()
+	users := make([]string, 0, len(all)+len(push))
+	for u, p := range all {
+		if p.Email.Enabled || p.Wants(notify.EventDueTomorrow) || push[u] {
+			users = append(users, u)
+		}
+	}
+	for u := range push {
+		if _, ok := all[u]; !ok {
+			users = append(users, u)
+		}
+	}
+	sort.Strings(users)
+	sent := 0
+	for _, user := range users {
+		p := all[user]
+		if notify.InQuietHours(p.QuietHours, now) {
+			continue
+		}
+		st := states[user]
+		rows := lazyRows(func() ([]map[string]string, bool) { return s.reminderRows(user) })
+		changed := false
+		if to := notify.EmailAddress(s.cfg, user, p); p.Email.Enabled && to != "" && s.cfg.SMTP.Host != "" {
+			n, c := s.remindUser(user, to, now, &st, rows)
+			sent, changed = sent+n, changed || c
+		}
+		if p.Wants(notify.EventDueTomorrow) {
+			n, c := s.chatDueTomorrow(user, p, now, &st, rows)
+			sent, changed = sent+n, changed || c
+		}
+		if push[user] {
+			n, c := s.pushDue(user, now, &st, rows)
+			sent, changed = sent+n, changed || c
+		}
+		if changed {
+			if err := notify.SaveReminderState(s.cfg, user, st); err != nil {
+				applog.Warnf("reminders: saving state for %s failed: %v", user, err)
+			}
+		}
+	}
+	return sent
+}
+
+// remindUser verschickt die fälligen E-Mails eines Nutzers und aktualisiert st.
+func (s *Server) remindUser(user, to string, now time.Time, st *notify.ReminderState, reminderRows func() ([]map[string]string, bool)) (sent int, changed bool) {
+	today := now.Format("2006-01-02")
+	h, m := s.cfg.Reminders.DailyTime()
+	digestDue := st.LastDigest != today && !now.Before(time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, now.Location()))
+	lead := s.cfg.Reminders.BeforeDueDuration()
+	if !digestDue && lead <= 0 {
+		return 0, false
+	}
+	rows, ok := reminderRows()
+	if !ok {
+		return 0, false
+	}
+	if digestDue {
+		overdue := applyDueFilter(rows, "due:overdue")
+		upcoming := upcomingRows(rows, now, s.cfg.Reminders.Horizon())
+		if len(overdue)+len(upcoming) > 0 {
+			mail := notify.Mail{To: to, Subject: digestSubject(len(overdue), len(upcoming)), Body: digestBody(overdue, upcoming, s.cfg.Reminders.Horizon())}
+			if err := notify.SendMail(s.cfg.SMTP, mail); err != nil {
+				applog.Warnf("reminders: digest for %s failed: %v", user, err)
+				return sent, changed
+			}
+			sent++
+		}
+		st.LastDigest = today
+		changed = true
+	}
+	if lead > 0 {
+		if st.Sent == nil {
+			st.Sent = map[string]string{}
+		}
+		open := map[string]bool{}
+		for _, row := range rows {
+			open[row["uuid"]] = true
+			deadline := dueDeadline(row["due"])
+			if deadline.IsZero() || !deadline.After(now) || deadline.Sub(now) > lead || st.Sent[row["uuid"]] == row["due"] {
+				continue
+			}
+			mail := notify.Mail{
+				To:      to,
+				Subject: "Due soon: " + row["summary"],
+				Body:    fmt.Sprintf("This task is due %s:\n\n%s\n", deadline.Format("2006-01-02 15:04"), reminderLine(row)),
+			}
+			if err := notify.SendMail(s.cfg.SMTP, mail); err != nil {
+				applog.Warnf("reminders: reminder for %s failed: %v", user, err)
+				continue
+			}
+			sent++
+			st.Sent[row["uuid"]] = row["due"]
+			changed = true
+		}
+		// erledigte Tasks vergessen, damit die Datei nicht wächst
+		for id := range st.Sent {
+			if !open[id] {
+				delete(st.Sent, id)
+				changed = true
+			}
+		}
+	}
+	return sent, changed
+}
+
+// reminderRows liefert die offenen Tasks aller Repos des Nutzers; in geteilten Repos nur die ihm zugewiesenen.
+func (s *Server) reminderRows(user string) ([]map[string]string, bool) {
+	keys := []string{user}
+	if names := config.RepoNames(s.cfg, user); len(names) > 0 {
+		keys = keys[:0]
+		for _, n := range names {
+			keys = append(keys, config.RepoKey(user, n))
+		}
+	}
+	var rows []map[string]string
+	ok := false
+	for _, key := range keys {
+		exp := s.runner.Run(key, 5*time.Second, "export")
+		if exp.Err != nil || exp.ExitCode != 0 || exp.TimedOut {
+			applog.Warnf("reminders: export for %s failed: %v", key, exp.Err)
+			continue
+		}
+		ok = true
+		tasks, _ := decodeTasksJSONFlexible(exp.Stdout)
+		keyRows := buildRowsFromTasks(tasks, "")
+		if len(config.RepoUsers(s.cfg, key)) > 1 {
+			keyRows = filterByAssignee(keyRows, user)
+		}
+		if _, repo := config.SplitRepoKey(key); repo != "" {
+			for _, row := range keyRows {
+				row["repo"] = repo
+			}
+		}
+		rows = append(rows, keyRows...)
+	}
+	return rows, ok
+}
+
+// lazyRows lädt die Tasks beim ersten Aufruf und liefert danach dasselbe Ergebnis.
+func lazyRows(load func() ([]map[string]string, bool)) func() ([]map[string]string, bool) {
+	var rows []map[string]string
+	var ok, done bool
+	return func() ([]map[string]string, bool) {
+		if !done {
+			rows, ok = load()
+			done = true
+		}
+		return rows, ok
+	}
+}
+
+// upcomingRows liefert Tasks, die heute oder in den nächsten days Tagen fällig sind.
+func upcomingRows(rows []map[string]string, now time.Time, days int) []map[string]string {
+	rows = applyDueFilter(rows, "due.after:"+now.AddDate(0, 0, -1).Format("2006-01-02"))
+	return applyDueFilter(rows, "due.before:"+now.AddDate(0, 0, days+1).Format("2006-01-02"))
+}
+
+// dueDeadline liefert den Fälligkeitszeitpunkt; reine Datumsangaben (Mitternacht) gelten bis Tagesende.
+func dueDeadline(due string) time.Time {
+	t := parseDueDate(due)
+	if t.IsZero() {
+		return t
+	}
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		t = t.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return t
+}
+
+func digestSubject(overdue, upcoming int) string {
+	var parts []string
+	if overdue > 0 {
+		parts = append(parts, fmt.Sprintf("%d overdue", overdue))
+	}
+	if upcoming > 0 {
+		parts = append(parts, fmt.Sprintf("%d due soon", upcoming))
+	}
+	return "dstask: " + strings.Join(parts, ", ")
+}
+
+func digestBody(overdue, upcoming []map[string]string, days int) string {
+	var b strings.Builder
+	section := func(title string, rows []map[string]string) {
+		if len(rows) == 0 {
+			return
+		}
+		sortRowsByDue(rows)
+		fmt.Fprintf(&b, "%s (%d):\n", title, len(rows))
+		for _, row := range rows {
+			b.WriteString(reminderLine(row))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	section("Overdue", overdue)
+	section(fmt.Sprintf("Due in the next %d days", days), upcoming)
+	return b.String()
+}
+
+func sortRowsByDue(rows []map[string]string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return parseDueDate(rows[i]["due"]).Before(parseDueDate(rows[j]["due"]))
+	})
+}
+
+// reminderLine: "  #12 Summary (due 2024-05-01) [project] @repo".
+func reminderLine(row map[string]string) string {
+	line := "  #" + row["id"] + " " + row["summary"]
+	if d := parseDueDate(row["due"]); !d.IsZero() {
+		line += " (due " + d.Format("2006-01-02") + ")"
+	}
+	if p := row["project"]; p != "" {
+		line += " [" + p + "]"
+	}
+	if repo := row["repo"]; repo != "" {
+		line += " @" + repo
+	}
+	return line
+}
+
+// handleNotificationSettings zeigt (GET) bzw. speichert (POST) die Benachrichtigungs-Einstellungen des Nutzers.
+func (s *Server) handleNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	username, _ := auth.UsernameFromRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !s.requirePostCSRF(w, r) {
+			return
+		}
+		p, err := notify.LoadPrefs(s.cfg, username)
+		if err != nil {
+			s.setFlash(w, "error", "Reading settings failed: "+err.Error())
+			http.Redirect(w, r, "/notifications/settings", http.StatusSeeOther)
+			return
+		}
+		switch r.FormValue("action") {
+		case "addTarget", "deleteTarget", "testTarget":
+			s.handleChatTargetAction(w, r, username, p)
+			return
+		}
+		p.Email.Enabled = r.FormValue("email") == "1"
+		p.Email.Address = r.FormValue("address")
+		p.QuietHours = r.FormValue("quietHours")
+		if err := notify.SavePrefs(s.cfg, username, p); err != nil {
+			s.setFlash(w, "error", "Saving settings failed: "+err.Error())
+		} else {
+			s.setFlash(w, "success", "Notification settings saved.")
+		}
+		http.Redirect(w, r, "/notifications/settings", http.StatusSeeOther)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p, err := notify.LoadPrefs(s.cfg, username)
+	if err != nil {
+		applog.Warnf("/notifications/settings: %v", err)
+	}
+	pushKey := ""
+	if v, err := s.push.key(); err != nil {
+		applog.Warnf("/notifications/settings: VAPID key: %v", err)
+	} else {
+		pushKey = v.PublicKey()
+	}
+	devices, _ := s.push.subs().List(username)
+	csrfToken := s.ensureCSRFToken(w, r)
+	h, m := s.cfg.Reminders.DailyTime()
+	beforeDue := ""
+	if s.cfg.Reminders.BeforeDueDuration() > 0 {
+		beforeDue = strings.TrimSpace(s.cfg.Reminders.BeforeDue)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Notification settings</h2>
+<h3>Reminder emails</h3>
+{{if not .MailEnabled}}<p style="color:#9a6700;">Email is not configured on this server (<code>smtp.host</code>); settings are saved but no emails are sent.</p>{{end}}
+<p>A daily summary of overdue tasks and tasks due in the next {{.Horizon}} days is sent at {{.DailyAt}}{{if .BeforeDue}}, plus a reminder {{.BeforeDue}} before each task is due{{end}}. In shared repositories only tasks assigned to you are included.</p>
+<form method="post" action="/notifications/settings">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <div><label><input type="checkbox" name="email" value="1" {{if .Prefs.Email.Enabled}}checked{{end}}/> Send reminder emails</label></div>
+  <div style="margin-top:6px;"><label>Address: <input type="email" name="address" value="{{.Prefs.Email.Address}}" placeholder="{{.DefaultAddress}}"/></label></div>
+  <div style="margin-top:6px;"><label>Quiet hours: <input name="quietHours" value="{{.Prefs.QuietHours}}" placeholder="22:00-07:00" pattern="\d{1,2}:\d{2}\s*-\s*\d{1,2}:\d{2}"/></label> <small>nothing is sent in this time; reminders follow afterwards</small></div>
+  <div style="margin-top:8px;"><button type="submit">Save</button></div>
+</form>
+<h3>Chat notifications</h3>
+<p>Send messages to ntfy topics, Gotify or Matrix rooms (quiet hours apply as well).</p>
+{{if .Prefs.Targets}}
+<table>
+  <thead><tr><th>Target</th><th>Events</th><th></th></tr></thead>
+  <tbody>
+  {{range .Prefs.Targets}}<tr>
+    <td><code>{{.Describe}}</code></td>
+    <td>{{range $i, $e := .Events}}{{if $i}}, {{end}}{{index $.EventLabels $e}}{{end}}</td>
+    <td>
+      <form method="post" action="/notifications/settings" style="display:inline;">
+        <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/><input type="hidden" name="id" value="{{.ID}}"/>
+        <button type="submit" name="action" value="testTarget">Send test</button>
+        <button type="submit" name="action" value="deleteTarget" onclick="return confirm('Remove this target?');">Remove</button>
+      </form>
+    </td>
+  </tr>{{end}}
+  </tbody>
+</table>
+{{end}}
+<form method="post" action="/notifications/settings" autocomplete="off" style="margin-top:8px;">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <input type="hidden" name="action" value="addTarget"/>
+  <div><label>Type: <select name="type">
+    <option value="ntfy">ntfy (topic POST)</option>
+    <option value="gotify">Gotify</option>
+    <option value="matrix">Matrix room</option>
+  </select></label></div>
+  <div style="margin-top:6px;"><label>Server: <input name="url" placeholder="https://ntfy.sh" required/></label></div>
+  <div style="margin-top:6px;"><label>Topic (ntfy): <input name="topic"/></label> <label>Room ID (Matrix): <input name="room" placeholder="!abc:example.org"/></label></div>
+  <div style="margin-top:6px;"><label>Token: <input type="password" name="token" autocomplete="new-password"/></label> <small>ntfy access token (optional), Gotify app token or Matrix access token</small></div>
+  <div style="margin-top:6px;">Events:
+    {{range .Events}}<label style="margin-right:8px;"><input type="checkbox" name="events" value="{{.}}" checked/> {{index $.EventLabels .}}</label>{{end}}
+  </div>
+  <div style="margin-top:8px;"><button type="submit">Add target</button></div>
+</form>
+<h3>Browser notifications</h3>
+<p>Native notifications via Web Push when a task becomes due or overdue and when a manual sync taking {{.LongSync}} or longer finishes. Quiet hours apply to due tasks. Registered browsers: {{.PushDevices}}.</p>
+{{if .PushKey}}
+<p id="push-status"></p>
+<button type="button" id="push-enable" hidden>Enable in this browser</button>
+<button type="button" id="push-disable" hidden>Disable in this browser</button>
+{{if .PushDevices}}<form method="post" action="/push/test" style="display:inline;">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <button type="submit">Send test</button>
+</form>{{end}}
+<script>
+(function(){
+  var status = document.getElementById('push-status');
+  var enable = document.getElementById('push-enable'), disable = document.getElementById('push-disable');
+  if (!('serviceWorker' in navigator) || !('PushManager' in window)) {
+    status.textContent = 'This browser does not support push notifications (HTTPS is required except on localhost).';
+    return;
+  }
+  var csrf = {{.CSRFToken}}, key = {{.PushKey}};
+  function keyBytes(s){ s = s.replace(/-/g, '+').replace(/_/g, '/'); var raw = atob(s + '==='.slice((s.length + 3) % 4)); return Uint8Array.from(raw, function(c){ return c.charCodeAt(0); }); }
+  function post(url, sub){
+    var f = new URLSearchParams({csrf_token: csrf});
+    var j = sub.toJSON(); f.set('endpoint', j.endpoint);
+    if (j.keys) { f.set('p256dh', j.keys.p256dh); f.set('auth', j.keys.auth); }
+    return fetch(url, {method: 'POST', body: f, credentials: 'same-origin'}).then(function(r){ if (!r.ok) return r.text().then(function(t){ throw new Error(t); }); });
+  }
+  function ready(){ return navigator.serviceWorker.register('/push/sw.js', {scope: '/'}).then(function(){ return navigator.serviceWorker.ready; }); }
+  function refresh(){
+    return ready().then(function(reg){ return reg.pushManager.getSubscription(); }).then(function(sub){
+      enable.hidden = !!sub; disable.hidden = !sub;
+      status.textContent = sub ? 'Notifications are enabled in this browser.' : 'Notifications are not enabled in this browser.';
+    });
+  }
+  function fail(e){ status.textContent = 'Failed: ' + e.message; }
+  enable.onclick = function(){
+    Notification.requestPermission().then(function(perm){
+      if (perm !== 'granted') throw new Error('permission ' + perm);
+      return ready();
+    }).then(function(reg){
+      return reg.pushManager.subscribe({userVisibleOnly: true, applicationServerKey: keyBytes(key)});
+    }).then(function(sub){ return post('/push/subscribe', sub); }).then(function(){ location.reload(); }).catch(fail);
+  };
+  disable.onclick = function(){
+    ready().then(function(reg){ return reg.pushManager.getSubscription(); }).then(function(sub){
+      if (!sub) return;
+      return post('/push/unsubscribe', sub).then(function(){ return sub.unsubscribe(); });
+    }).then(function(){ location.reload(); }).catch(fail);
+  };
+  refresh().catch(fail);
+})();
+</script>
+{{else}}<p style="color:#9a6700;">Push is unavailable: the VAPID key could not be loaded (see server log).</p>{{end}}
+<p><a href="/notifications">Back to notifications</a></p>`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Events":         notify.ChatEvents,
+		"EventLabels":    chatEventLabels,
+		"Prefs":          p,
+		"DefaultAddress": notify.EmailAddress(s.cfg, username, notify.Prefs{}),
+		"MailEnabled":    strings.TrimSpace(s.cfg.SMTP.Host) != "",
+		"Horizon":        s.cfg.Reminders.Horizon(),
+		"DailyAt":        fmt.Sprintf("%02d:%02d", h, m),
+		"BeforeDue":      beforeDue,
+		"PushKey":        pushKey,
+		"PushDevices":    len(devices),
+		"LongSync":       s.cfg.Push.LongSyncDuration().String(),
+		"CSRFToken":      csrfToken,
+	}))
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/notify"
+	"github.com/elpatron68/dstask-ui/internal/notify/notifytest"
+)
+
+func TestRunReminders_DigestBeforeDueAndQuietHours(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	day := func(d int) string { return now.AddDate(0, 0, d).Format("2006-01-02") }
+	script := `cat <<'JSON'
+[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"pending","summary":"File taxes","due":"` + day(-3) + `"},
+{"id":2,"uuid":"22222222-2222-2222-2222-222222222222","status":"pending","summary":"Book flights","due":"` + day(2) + `","project":"trip"},
+{"id":3,"uuid":"33333333-3333-3333-3333-333333333333","status":"pending","summary":"Renew passport","due":"` + day(30) + `"},
+{"id":4,"uuid":"44444444-4444-4444-4444-444444444444","status":"pending","summary":"Call dentist","due":"` + now.Add(time.Hour).Format(time.RFC3339) + `"},
+{"id":5,"uuid":"55555555-5555-5555-5555-555555555555","status":"resolved","summary":"Old","due":"` + day(-10) + `"}]
+JSON
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	srv := notifytest.NewSMTPServer(t)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	s.cfg.SMTP.Host = srv.Host
+	s.cfg.SMTP.Port, _ = strconv.Atoi(srv.Port)
+	s.cfg.Reminders.DailyAt = "00:00"
+	s.cfg.Reminders.BeforeDue = "2h"
+
+	// Ruhezeit um now herum: nichts wird verschickt
+	quiet := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	if err := notify.SavePrefs(s.cfg, "admin", notify.Prefs{Email: notify.EmailPrefs{Enabled: true, Address: "admin@example.org"}, QuietHours: quiet}); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.runReminders(now); n != 0 || len(srv.Messages()) != 0 {
+		t.Fatalf("sent %d mail(s) during quiet hours", n)
+	}
+
+	if err := notify.SavePrefs(s.cfg, "admin", notify.Prefs{Email: notify.EmailPrefs{Enabled: true, Address: "admin@example.org"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.runReminders(now); n != 2 {
+		t.Fatalf("expected digest and one before-due reminder, got %d", n)
+	}
+	msgs := srv.Messages()
+	digest := msgs[0].Data
+	if !strings.Contains(digest, "Subject: dstask: 1 overdue, 2 due soon") || !strings.Contains(digest, "#1 File taxes") ||
+		!strings.Contains(digest, "#2 Book flights (due "+day(2)+") [trip]") || strings.Contains(digest, "Renew passport") || strings.Contains(digest, "Old") {
+		t.Fatalf("unexpected digest: %s", digest)
+	}
+	if msgs[1].To[0] != "admin@example.org" || !strings.Contains(msgs[1].Data, "Subject: Due soon: Call dentist") {
+		t.Fatalf("unexpected reminder: %+v", msgs[1])
+	}
+
+	// zweiter Lauf am selben Tag: alles schon verschickt
+	if n := s.runReminders(now.Add(time.Minute)); n != 0 {
+		t.Fatalf("reminders sent twice: %d", n)
+	}
+}
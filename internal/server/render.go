@@ -7,8 +7,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
 	"github.com/elpatron68/dstask-ui/internal/music"
 )
 
@@ -130,7 +133,7 @@ func (s *Server) renderListHTML(w http.ResponseWriter, r *http.Request, title st
 		q.Set("html", "1")
 		return r.URL.Path + "?" + q.Encode()
 	}
-	uname, _ := auth.UsernameFromRequest(r)
+	uname := s.repoKey(r)
 	show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 	_ = t.Execute(w, map[string]any{
 		"Title":       title,
@@ -151,15 +154,38 @@ func (s *Server) renderListHTML(w http.ResponseWriter, r *http.Request, title st
 // renderExportTable rendert Tasks aus `dstask export` als Tabelle.
 // `rows` erwartet bereits gefilterte/aufbereitete Zeilen.
 func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title string, rows []map[string]string) {
+	s.renderExportTableWith(w, r, title, rows, nil)
+}
+
+// renderExportTableWith rendert wie renderExportTable; extra ergänzt die Template-Daten
+// (z. B. Team für die Zuständigen-Auswahl, Assignee für den aktiven Filter).
+func (s *Server) renderExportTableWith(w http.ResponseWriter, r *http.Request, title string, rows []map[string]string, extra map[string]any) {
 	t := template.Must(s.layoutTpl.Clone())
-	// load per-user music map to flag tasks with stream linkage (used later when building rows)
-	username, _ := auth.UsernameFromRequest(r)
-	musicSet := map[string]struct{}{}
-	if m, _, err := music.LoadForUser(s.cfg, username); err == nil && m != nil && m.Tasks != nil {
-		for tid := range m.Tasks {
-			musicSet[tid] = struct{}{}
+	// load per-user music map to flag tasks with stream linkage (task, tag or project rule)
+	username := s.repoKey(r)
+	musicRefs := map[string]music.TaskRef{}
+	for _, row := range rows {
+		if row["id"] != "" && row["id"] != "0" {
+			musicRefs[row["id"]] = rowMusicRef(row)
 		}
 	}
+	musicMap, err := s.musicMap(username, musicRefs)
+	if err != nil {
+		applog.Warnf("loading music map failed for %s: %v", username, err)
+	}
+	// Abhängigkeiten (dependencies.yaml, "blocked by #N" in Notizen) für Badge und Done-Warnung
+	deps := s.depGraphForRows(username, rows)
+	// erfasste Zeiten (timelog.yaml)
+	timeLog, err := s.runner.TimeLog(username)
+	if err != nil {
+		applog.Warnf("reading time log for %s failed: %v", username, err)
+	}
+	// abgeschlossene Pomodoros (pomodoros.yaml)
+	pomodoros, err := s.runner.Pomodoros(username)
+	if err != nil {
+		applog.Warnf("reading pomodoros for %s failed: %v", username, err)
+	}
+	now := time.Now()
 	// sorting
 	sortKey := r.URL.Query().Get("sort")
 	sortDir := r.URL.Query().Get("dir")
@@ -183,8 +209,11 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
 	}
 	_, _ = t.New("content").Parse(`
 <h2>{{.Title}}</h2>
+{{if .HiddenBlocked}}<p style="color:#57606a;">{{.HiddenBlocked}} blocked task(s) hidden · <a href="{{.ShowBlockedURL}}">show blocked</a></p>{{end}}
+{{if .Team}}<div style="margin-bottom:8px;">Assignee:{{range .Team}} <a href="{{.URL}}"{{if .Selected}} style="font-weight:bold;"{{end}}>{{.Label}}</a> ({{.Count}}){{end}}</div>{{end}}
 <form method="get" style="margin-bottom:8px">
   <input type="hidden" name="html" value="1"/>
+  {{if .Assignee}}<input type="hidden" name="assignee" value="{{.Assignee}}"/>{{end}}
   <input name="q" value="{{.Q}}" placeholder="Filter: +tag project:foo text" style="width:50%" />
   <label style="margin-left:8px;">Due filter:
     <select name="dueFilterType" style="margin-left:4px;">
@@ -211,19 +240,20 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
     <th style="width:160px;"><a href="{{.Sort.Created}}">Created</a></th>
     <th style="width:160px;"><a href="{{.Sort.Resolved}}">Resolved</a></th>
     <th style="width:90px;"><a href="{{.Sort.Age}}">Age</a></th>
+    <th style="width:80px;">Time</th>
     <th style="width:220px;">Aktionen</th>
   </tr></thead>
   <tbody>
   {{range .Rows}}
     <tr>
       <td><input type="checkbox" name="ids" value="{{index . "id"}}" form="batchForm"/></td>
-      <td>{{index . "id"}}{{if .hasMusic}} <span title="Music assigned">🎵</span>{{end}}{{if .hasNotes}} 
+      <td>{{index . "id"}}{{if .hasMusic}} <span title="{{.musicTitle}}">🎵</span>{{end}}{{if .blockedBy}} <span class="pill" style="background:#ffebe9;color:#cf222e;" title="Blocked by {{.blockedBy}}">blocked</span>{{end}}{{if .hasNotes}} 
         <span class="hovercard"><span class="label" title="Show notes">📝</span>
           <div class="card"><div class="notes-content">{{renderMarkdown (index . "notes")}}</div></div>
         </span>
       {{end}}</td>
       <td><span class="badge status {{index . "status"}}" title="{{index . "status"}}">{{index . "status"}}</span></td>
-      <td style="white-space:pre-wrap;">{{linkifyURLs (index . "summary")}}</td>
+      <td style="white-space:pre-wrap;">{{linkifyURLs (index . "summary")}}{{if .checklistTotal}} <span title="Checklist: {{.checklistDone}} of {{.checklistTotal}} done" style="white-space:nowrap;"><progress value="{{.checklistDone}}" max="{{.checklistTotal}}" style="width:60px;height:10px;"></progress> {{.checklistDone}}/{{.checklistTotal}}</span>{{end}}</td>
       <td>{{index . "project"}}</td>
       <td><span class="badge prio {{index . "priority"}}" title="{{index . "priority"}}">{{index . "priority"}}</span></td>
       <td class="due {{if .overdue}}overdue{{end}}">{{index . "due"}}</td>
@@ -236,12 +266,15 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
       <td><code>{{index . "created"}}</code></td>
       <td><code>{{index . "resolved"}}</code></td>
       <td>{{index . "age"}}</td>
+      <td style="white-space:nowrap;">{{if .tracked}}<span title="Tracked time{{if .timerRunning}} (running){{end}}">{{if .timerRunning}}⏱ {{end}}{{.tracked}}</span>{{end}}{{if .pomodoros}} <span title="Completed pomodoros">🍅{{.pomodoros}}</span>{{end}}</td>
       <td>
         <form method="get" action="/tasks/{{index . "id"}}/edit" style="display:inline"><button type="submit" title="Edit task details">edit</button></form>
          · <form method="post" action="/tasks/{{index . "id"}}/start" style="display:inline"><button type="submit" {{if not .canStart}}disabled{{end}} title="Mark task as active">start</button></form>
-         · <form method="post" action="/tasks/{{index . "id"}}/done" style="display:inline"><button type="submit" {{if not .canDone}}disabled{{end}} title="Mark task as completed/resolved">done</button></form>
+         · <form method="post" action="/pomodoro/start" style="display:inline"><input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/><input type="hidden" name="id" value="{{index . "id"}}"/>{{if $.PomodoroStopOnBreak}}<input type="hidden" name="stop" value="1"/>{{end}}<button type="submit" {{if not .canDone}}disabled{{end}} title="Start a Pomodoro focus session (starts the task and its music)">🍅</button></form>
+         · <form method="post" action="/tasks/{{index . "id"}}/done" style="display:inline"{{if .blocks}} onsubmit="return confirm('Task {{index . "id"}} is blocking {{.blocks}}. Mark it done anyway?');"{{end}}><button type="submit" {{if not .canDone}}disabled{{end}} title="Mark task as completed/resolved">done</button></form>
          · <form method="post" action="/tasks/{{index . "id"}}/stop" style="display:inline"><button type="submit" {{if not .canStop}}disabled{{end}} title="Pause/stop the task">stop</button></form>
          · <form method="post" action="/tasks/{{index . "id"}}/remove" style="display:inline" onsubmit="return confirm('Are you sure you want to delete this task?');"><input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/><button type="submit" title="Delete the task">remove</button></form>
+         · <a href="/tasks/{{or (index . "uuid") (index . "id")}}/history" title="Show change history of this task">history</a>
          {{if .hasURLs}} · <a href="/tasks/{{index . "id"}}/open" title="View and open URLs from this task">open</a>{{end}}
       </td>
     </tr>
@@ -258,9 +291,16 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
       <option value="done">done</option>
       <option value="remove">remove</option>
       <option value="note">note</option>
+      <option value="assign">assign</option>
     </select>
   </label>
   <label style="margin-left:8px;">Note: <input name="note" placeholder="for action 'note'"/></label>
+  <label style="margin-left:8px;">Assignee:
+    <select name="assignee">
+      <option value="">(unassigned)</option>
+      {{range .Members}}<option value="{{.}}">@{{.}}</option>{{end}}
+    </select>
+  </label>
   <button type="submit" style="margin-left:8px;">Apply</button>
 </form>
 {{if .Pagination}}
@@ -277,7 +317,7 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
 </div>
 {{end}}
 `)
-	uname, _ := auth.UsernameFromRequest(r)
+	uname := s.repoKey(r)
 	show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 	q := r.URL.Query()
 	// Pagination
@@ -336,12 +376,29 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
 		// Check for notes
 		notes := m["notes"]
 		mm["hasNotes"] = notes != "" && strings.TrimSpace(notes) != ""
+		if done, total := checklistProgress(notes); total > 0 {
+			mm["checklistDone"] = done
+			mm["checklistTotal"] = total
+		}
 		if due, ok := m["due"]; ok {
 			mm["overdue"] = isOverdue(due)
 		}
-		if id, ok := m["id"]; ok {
-			if _, ok2 := musicSet[id]; ok2 {
+		if deps != nil {
+			uuid := strings.ToLower(m["uuid"])
+			mm["blockedBy"] = deps.refs(deps.openBlockers(uuid))
+			mm["blocks"] = deps.refs(deps.openDependents(uuid))
+		}
+		if ivs := timeLog[strings.ToLower(m["uuid"])]; len(ivs) > 0 {
+			mm["tracked"] = formatTracked(dstask.TrackedTime(ivs, now))
+			mm["timerRunning"] = ivs[len(ivs)-1].Running()
+		}
+		if n := len(pomodoros[strings.ToLower(m["uuid"])]); n > 0 {
+			mm["pomodoros"] = n
+		}
+		if musicMap != nil {
+			if tm, rule, ok := musicMap.Lookup(rowMusicRef(m)); ok {
 				mm["hasMusic"] = true
+				mm["musicTitle"] = firstNonEmptyString(tm.Name, "Music") + " (" + rule + ")"
 			}
 		}
 		rowsAny = append(rowsAny, mm)
@@ -377,16 +434,21 @@ func (s *Server) renderExportTable(w http.ResponseWriter, r *http.Request, title
 	dueFilterType := q.Get("dueFilterType")
 	dueFilterDate := q.Get("dueFilterDate")
 	csrfToken := s.ensureCSRFToken(w, r)
-	_ = t.Execute(w, map[string]any{"Title": title, "Rows": rowsAny, "Q": q.Get("q"), "Active": activeFromPath(r.URL.Path),
+	data := map[string]any{"Title": title, "Rows": rowsAny, "Q": q.Get("q"), "Active": activeFromPath(r.URL.Path),
 		"Flash":      s.getFlash(r),
 		"ShowCmdLog": show, "CmdEntries": entries, "MoreURL": moreURL, "CanShowMore": canMore, "ReturnURL": ret,
-		"DueFilterType": dueFilterType, "DueFilterDate": dueFilterDate, "CSRFToken": csrfToken,
+		"DueFilterType": dueFilterType, "DueFilterDate": dueFilterDate, "CSRFToken": csrfToken, "PomodoroStopOnBreak": s.cfg.Pomodoro.StopOnBreak,
 		"Pagination": pagination,
+		"Members":    config.RepoUsers(s.cfg, uname),
 		"Sort": map[string]string{
 			"ID": mk("id"), "Status": mk("status"), "Summary": mk("summary"), "Project": mk("project"), "Priority": mk("priority"), "Due": mk("due"), "Tags": mk("tags"),
 			"Created": mk("created"), "Resolved": mk("resolved"), "Age": mk("age"),
 		},
-	})
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	_ = t.Execute(w, data)
 }
 
 // renderProjectsTable rendert eine Tabelle für Projekte
@@ -422,7 +484,7 @@ func (s *Server) renderProjectsTable(w http.ResponseWriter, r *http.Request, tit
   <tbody>
   {{range .Rows}}
     <tr>
-      <td>{{index . "name"}}</td>
+      <td>{{index . "name"}} <a href="/projects/graph?project={{index . "name"}}" title="Show dependency graph" style="font-size:90%;">graph</a></td>
       <td>{{index . "taskCount"}}</td>
       <td>{{index . "resolvedCount"}}</td>
       <td>{{index . "active"}}</td>
@@ -432,7 +494,7 @@ func (s *Server) renderProjectsTable(w http.ResponseWriter, r *http.Request, tit
   </tbody>
 </table>
 `)
-	uname, _ := auth.UsernameFromRequest(r)
+	uname := s.repoKey(r)
 	show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 	_ = t.Execute(w, map[string]any{"Title": title, "Rows": rows, "Active": activeFromPath(r.URL.Path),
 		"ShowCmdLog": show, "CmdEntries": entries, "MoreURL": moreURL, "CanShowMore": canMore, "ReturnURL": ret,
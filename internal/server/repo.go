@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// handleRepo zeigt den Zustand des .dstask-Git-Repos samt geführter Korrekturen.
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	h, err := s.runner.RepoHealth(username)
+	if err != nil {
+		applog.Warnf("/repo: %v", err)
+		http.Error(w, "failed to inspect repository", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Repository</h2>
+<p>Directory: <code>{{.H.Dir}}</code> · <a href="/repo/ssh">SSH deploy key</a> · <a href="/repo/https">HTTPS credentials</a></p>
+{{if not .H.IsRepo}}
+  <div style="margin:8px 0;background:#fff3cd;border:1px solid #ffeeba;padding:8px;">No Git repository in this directory. Clone a remote from the <a href="/">home page</a>.</div>
+{{else}}
+{{if .H.Detached}}
+  <div style="margin:8px 0;background:#fff5f5;border:1px solid #f5c2c7;padding:8px;">HEAD is detached (commit <code>{{.H.ShortCommit}}</code>). dstask commits made now are not on any branch; check out a branch in the repository before syncing.</div>
+{{end}}
+<table>
+  <tbody>
+  <tr><th style="text-align:left;">Remote</th><td>{{if .H.RemoteURL}}<code>{{.H.RemoteURL}}</code>{{else}}<em>none</em>{{end}}</td></tr>
+  <tr><th style="text-align:left;">Branch</th><td>{{if .H.Branch}}<code>{{.H.Branch}}</code>{{else}}<em>detached</em>{{end}}</td></tr>
+  <tr><th style="text-align:left;">Upstream</th><td>
+    {{if .H.Upstream}}<code>{{.H.Upstream}}</code>{{else}}<em>not set</em>
+      {{if and .H.RemoteURL .H.Branch}}
+      <form method="post" action="/repo/fix" style="display:inline;margin-left:8px;">
+        <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+        <input type="hidden" name="action" value="upstream"/>
+        <button type="submit">Set upstream</button>
+      </form>
+      {{end}}
+    {{end}}</td></tr>
+  {{if .H.Upstream}}
+  <tr><th style="text-align:left;">Ahead / behind</th><td>{{.H.Ahead}} / {{.H.Behind}}{{if or .H.Ahead .H.Behind}} – <form method="post" action="/sync" style="display:inline;"><button type="submit">Sync</button></form>{{end}}</td></tr>
+  {{end}}
+  <tr><th style="text-align:left;">Last commit</th><td>{{if .H.LastCommit}}<code>{{.H.ShortCommit}}</code> {{.H.LastCommitSubject}} – {{.H.LastCommitAuthor}}, {{.H.LastCommitDate.Format "2006-01-02 15:04"}}{{else}}<em>no commits</em>{{end}}</td></tr>
+  <tr><th style="text-align:left;">Size</th><td>{{.Size}} (of which .git: {{.GitSize}})
+      <form method="post" action="/repo/fix" style="display:inline;margin-left:8px;">
+        <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+        <input type="hidden" name="action" value="gc"/>
+        <button type="submit">Run git gc</button>
+      </form></td></tr>
+  </tbody>
+</table>
+<h3>Uncommitted files</h3>
+{{if .H.Dirty}}
+<p>These files are not committed and will not be synced.</p>
+<table>
+  <thead><tr><th>Status</th><th>Path</th></tr></thead>
+  <tbody>
+  {{range .H.Dirty}}<tr><td><code>{{.Status}}</code></td><td><code>{{.Path}}</code></td></tr>{{end}}
+  </tbody>
+</table>
+<form method="post" action="/repo/fix" style="margin-top:8px;" onsubmit="return confirm('Commit all uncommitted files?');">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <input type="hidden" name="action" value="commit"/>
+  <button type="submit">Commit stray files</button>
+</form>
+{{else}}
+<p>Working tree is clean.</p>
+{{end}}
+{{end}}`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"H":         h,
+		"Size":      formatBytes(h.SizeBytes),
+		"GitSize":   formatBytes(h.GitSizeBytes),
+		"CSRFToken": csrfToken,
+	}))
+}
+
+// handleRepoFix führt eine der geführten Korrekturen aus: commit, upstream oder gc.
+func (s *Server) handleRepoFix(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username := s.repoKey(r)
+	switch r.FormValue("action") {
+	case "commit":
+		n, err := s.runner.CommitStrayFiles(username)
+		if err != nil {
+			s.setFlash(w, "error", "Commit failed: "+stripANSI(err.Error()))
+		} else {
+			s.cmdStore.Append(username, "Repo: commit stray files", []string{"git", "commit"})
+			s.setFlash(w, "success", fmt.Sprintf("Committed %d file(s).", n))
+		}
+	case "upstream":
+		branch, err := s.runner.GitSetUpstreamIfMissing(username)
+		if err != nil {
+			s.setFlash(w, "error", "Setting upstream failed: "+stripANSI(err.Error()))
+		} else {
+			s.setFlash(w, "success", "Upstream set for branch "+branch+".")
+		}
+	case "gc":
+		before, after, err := s.runner.GitGC(username)
+		if err != nil {
+			s.setFlash(w, "error", "git gc failed: "+stripANSI(err.Error()))
+		} else {
+			s.cmdStore.Append(username, "Repo: git gc", []string{"git", "gc"})
+			s.setFlash(w, "success", fmt.Sprintf("git gc done: .git %s → %s.", formatBytes(before), formatBytes(after)))
+		}
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, "/repo", http.StatusSeeOther)
+}
+
+// formatBytes formatiert eine Größe in B, KiB, MiB oder GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 2; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMG"[exp])
+}
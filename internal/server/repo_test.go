@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elpatron68/dstask-ui/internal/gitauth"
+)
+
+func TestRepoPage_ShowsDirtyFilesAndFixes(t *testing.T) {
+	home, repo := newGitTaskHome(t)
+	if err := os.WriteFile(filepath.Join(repo, "stray.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServerWithStub(t, "/bin/true", home)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/repo", nil)
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"stray.txt", "Commit stray files", "Modified: Fix VPN", "Run git gc", "not set"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("repo page missing %q: %s", want, body)
+		}
+	}
+}
+
+func TestRepoFix_RequiresCSRF(t *testing.T) {
+	s := newTestServer(t)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/repo/fix", strings.NewReader("action=gc&csrf_token=a"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{512: "512 B", 2048: "2.0 KiB", 5 << 20: "5.0 MiB", 3 << 30: "3.0 GiB"}
+	for n, want := range cases {
+		if got := formatBytes(n); got != want {
+			t.Fatalf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSSHKeyPage_GenerateAndShowPublicKey(t *testing.T) {
+	home, _ := newGitTaskHome(t)
+	s := newTestServerWithStub(t, "/bin/true", home)
+	s.cfg.DataDir = t.TempDir()
+	if _, err := gitauth.GenerateKey(s.cfg, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/repo/ssh", nil)
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"ssh-ed25519 ", "dstask-ui admin", "SHA256:", "accept-new"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("ssh page missing %q: %s", want, body)
+		}
+	}
+}
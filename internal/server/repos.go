@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+// repoCookie speichert das im Repo-Umschalter gewählte Repo.
+const repoCookie = "dstask_repo"
+
+// repoKey liefert den Repo-Schlüssel (config.RepoKey) für das gewählte Repo des angemeldeten Nutzers.
+// Alle Runner-Aufrufe, Music-Map, Command-Log und Sync-Status laufen über diesen Schlüssel.
+func (s *Server) repoKey(r *http.Request) string {
+	username, _ := auth.UsernameFromRequest(r)
+	return config.RepoKey(username, s.currentRepoName(r))
+}
+
+// currentRepoName liefert den Namen des gewählten Repos; ohne (gültige) Auswahl das erste konfigurierte.
+func (s *Server) currentRepoName(r *http.Request) string {
+	username, _ := auth.UsernameFromRequest(r)
+	names := config.RepoNames(s.cfg, username)
+	if c, err := r.Cookie(repoCookie); err == nil {
+		for _, n := range names {
+			if n == c.Value {
+				return n
+			}
+		}
+	}
+	if len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// handleRepos liefert die Repos des Nutzers als JSON für den Umschalter in der Navigation.
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	names := config.RepoNames(s.cfg, username)
+	repos := make([]map[string]any, 0, len(names))
+	for _, n := range names {
+		home, _ := config.ResolveHomeForUsername(s.cfg, config.RepoKey(username, n))
+		repos = append(repos, map[string]any{"name": n, "home": home})
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, map[string]any{
+		"current":   s.currentRepoName(r),
+		"repos":     repos,
+		"csrfToken": s.ensureCSRFToken(w, r),
+	})
+}
+
+// handleRepoSelect wechselt das Repo (Cookie) und kehrt zur aufrufenden Seite zurück.
+func (s *Server) handleRepoSelect(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	name := r.FormValue("repo")
+	found := false
+	for _, n := range config.RepoNames(s.cfg, username) {
+		if n == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "unknown repository", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: repoCookie, Value: name, Path: "/", MaxAge: 86400 * 365, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	s.setFlash(w, "info", "Switched to repository "+name+".")
+	ret := r.FormValue("return")
+	// nur lokale Pfade, keine protokoll-relativen URLs
+	if !strings.HasPrefix(ret, "/") || strings.HasPrefix(ret, "//") || strings.HasPrefix(ret, "/\\") {
+		ret = "/"
+	}
+	http.Redirect(w, r, ret, http.StatusSeeOther)
+}
+
+// repoLabel liefert den Namen des gewählten Repos, wenn der Nutzer mehrere hat (sonst leer).
+func (s *Server) repoLabel(r *http.Request) string {
+	username, _ := auth.UsernameFromRequest(r)
+	if len(config.RepoNames(s.cfg, username)) < 2 {
+		return ""
+	}
+	return s.currentRepoName(r)
+}
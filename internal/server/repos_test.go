@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTwoRepoServer(t *testing.T) (*Server, string, string) {
+	t.Helper()
+	home, _ := newGitTaskHome(t)
+	work := filepath.Join(t.TempDir(), "work")
+	s := newTestServerWithStub(t, "/bin/true", home)
+	s.cfg.NamedRepos = map[string]map[string]string{"admin": {"work": work}}
+	return s, home, work
+}
+
+func TestReposJSONAndSelect(t *testing.T) {
+	s, _, work := newTwoRepoServer(t)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/repos", nil)
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	var out struct {
+		Current   string `json:"current"`
+		CSRFToken string `json:"csrfToken"`
+		Repos     []struct {
+			Name string `json:"name"`
+		} `json:"repos"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+		t.Fatalf("invalid json: %v: %s", err, rr.Body.String())
+	}
+	if out.Current != "default" || len(out.Repos) != 2 || out.Repos[1].Name != "work" {
+		t.Fatalf("unexpected repos: %+v", out)
+	}
+
+	// Auswahl per Formular; CSRF-Cookie aus der ersten Antwort mitschicken
+	rr2 := httptest.NewRecorder()
+	req2 := httptest.NewRequest(http.MethodPost, "/repos/select", strings.NewReader("repo=work&return=/repo&csrf_token="+out.CSRFToken))
+	req2.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req2.SetBasicAuth("admin", "admin")
+	for _, c := range rr.Result().Cookies() {
+		req2.AddCookie(c)
+	}
+	s.Handler().ServeHTTP(rr2, req2)
+	if rr2.Code != http.StatusSeeOther || rr2.Header().Get("Location") != "/repo" {
+		t.Fatalf("unexpected select response %d %q", rr2.Code, rr2.Header().Get("Location"))
+	}
+	var repoCookieValue string
+	for _, c := range rr2.Result().Cookies() {
+		if c.Name == repoCookie {
+			repoCookieValue = c.Value
+		}
+	}
+	if repoCookieValue != "work" {
+		t.Fatalf("repo cookie not set: %q", repoCookieValue)
+	}
+
+	// Folgeseiten arbeiten auf dem gewählten Repo
+	rr3 := httptest.NewRecorder()
+	req3 := httptest.NewRequest(http.MethodGet, "/repo", nil)
+	req3.SetBasicAuth("admin", "admin")
+	req3.AddCookie(&http.Cookie{Name: repoCookie, Value: "work"})
+	s.Handler().ServeHTTP(rr3, req3)
+	if !strings.Contains(rr3.Body.String(), filepath.Join(work, ".dstask")) {
+		t.Fatalf("repo page does not use selected repo: %s", rr3.Body.String())
+	}
+}
+
+func TestRepoSelect_RejectsUnknownRepoAndForeignReturn(t *testing.T) {
+	s, _, _ := newTwoRepoServer(t)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/repos", nil)
+	req.SetBasicAuth("admin", "admin")
+	// unbekanntes Repo im Cookie fällt auf den Standard zurück
+	req.AddCookie(&http.Cookie{Name: repoCookie, Value: "nope"})
+	s.Handler().ServeHTTP(rr, req)
+	var out struct {
+		Current   string `json:"current"`
+		CSRFToken string `json:"csrfToken"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &out)
+	if out.Current != "default" {
+		t.Fatalf("unknown repo cookie accepted: %q", out.Current)
+	}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rr2 := httptest.NewRecorder()
+		req2 := httptest.NewRequest(http.MethodPost, "/repos/select", strings.NewReader(body+"&csrf_token="+out.CSRFToken))
+		req2.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req2.SetBasicAuth("admin", "admin")
+		for _, c := range rr.Result().Cookies() {
+			req2.AddCookie(c)
+		}
+		s.Handler().ServeHTTP(rr2, req2)
+		return rr2
+	}
+	if rr2 := post("repo=nope"); rr2.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown repo, got %d", rr2.Code)
+	}
+	if rr2 := post("repo=work&return=//evil.example/"); rr2.Header().Get("Location") != "/" {
+		t.Fatalf("foreign return URL accepted: %q", rr2.Header().Get("Location"))
+	}
+}
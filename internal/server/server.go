@@ -1,7 +1,7 @@
 package server
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -18,6 +18,7 @@ import (
 	applog "github.com/elpatron68/dstask-ui/internal/log"
 	"github.com/elpatron68/dstask-ui/internal/music"
 	"github.com/elpatron68/dstask-ui/internal/ui"
+	"github.com/elpatron68/dstask-ui/internal/webhook"
 )
 
 type Server struct {
@@ -28,6 +29,15 @@ type Server struct {
 	runner    *dstask.Runner
 	cmdStore  *ui.CommandLogStore
 	uiCfg     config.UIConfig
+	syncs     *syncTracker
+	events    *eventBus
+	inbox     *inbox
+	webhooks  *webhook.Dispatcher
+	push      *pushService
+	pomodoros *pomodoroTracker
+	timeQueue *timeQueue
+	playing   *nowPlayingHub // aktueller Radio-Titel (ICY) pro Repo
+	streams   *streamLimiter // laufende Streams von /music/proxy pro Nutzer
 }
 
 const faviconSVG = `<?xml version="1.0" encoding="UTF-8"?>
@@ -46,12 +56,27 @@ func NewServerWithConfig(userStore auth.UserStore, cfg *config.Config) *Server {
 	s.runner = dstask.NewRunner(cfg)
 	s.mux = http.NewServeMux()
 	s.cmdStore = ui.NewCommandLogStore(cfg.UI.CommandLogMax)
+	s.syncs = newSyncTracker()
+	s.events = newEventBus()
+	s.inbox = newInbox()
+	s.webhooks = webhook.New(cfg)
+	s.push = newPushService(cfg)
+	s.pomodoros = newPomodoroTracker()
+	s.timeQueue = newTimeQueue()
+	s.playing = newNowPlayingHub(cfg)
+	s.streams = newStreamLimiter()
+	s.events.Subscribe(s.notifyInbox)
+	s.events.Subscribe(s.enqueueWebhooks)
+	s.events.Subscribe(s.notifyChat)
+	s.events.Subscribe(s.trackTime)
 
 	// Templates: register helpers (e.g., split, linkifyURLs, renderMarkdown)
 	baseTpl := template.New("layout").Funcs(template.FuncMap{
 		"split":          func(s, sep string) []string { return strings.Split(s, sep) },
 		"linkifyURLs":    linkifyURLs,
 		"renderMarkdown": renderMarkdown,
+		// Notizen mit anklickbaren Checklisten (Edit-Seite)
+		"renderChecklistMarkdown": renderChecklistMarkdown,
 	})
 	s.layoutTpl = template.Must(baseTpl.Parse(`<!doctype html><html><head><meta charset="utf-8"><title>dstask</title><link rel="icon" href="/favicon.svg" type="image/svg+xml">
 <style>
@@ -112,13 +137,23 @@ table, th, td, table pre {font-size:13px}
   <a href="/active?html=1" class="{{if eq .Active "active"}}active{{end}}">Active</a>
   <a href="/paused?html=1" class="{{if eq .Active "paused"}}active{{end}}">Paused</a>
   <a href="/resolved?html=1" class="{{if eq .Active "resolved"}}active{{end}}">Resolved</a>
+  <a href="/my" class="{{if eq .Active "my"}}active{{end}}">My tasks</a>
+  <a href="/team" class="{{if eq .Active "team"}}active{{end}}">Team</a>
+  <a href="/delegated" class="{{if eq .Active "delegated"}}active{{end}}">Delegated</a>
   <a href="/tags" class="{{if eq .Active "tags"}}active{{end}}">Tags</a>
   <a href="/projects" class="{{if eq .Active "projects"}}active{{end}}">Projects</a>
   <a href="/templates" class="{{if eq .Active "templates"}}active{{end}}">Templates</a>
   <a href="/context" class="{{if eq .Active "context"}}active{{end}}">Context</a>
   <a href="/tasks/new" class="{{if eq .Active "new"}}active{{end}}">New task</a>
   <a href="/tasks/action" class="{{if eq .Active "action"}}active{{end}}">Actions</a>
+  <a href="/activity" class="{{if eq .Active "activity"}}active{{end}}">Activity</a>
+  <a href="/reports/time" class="{{if eq .Active "reports"}}active{{end}}">Time</a>
+  <a href="/backup" class="{{if eq .Active "backup"}}active{{end}}">Backup</a>
+  <a href="/repo" class="{{if eq .Active "repo"}}active{{end}}">Repo</a>
   <a href="/version" class="{{if eq .Active "version"}}active{{end}}">Version</a>
+  <span id="repo-switcher"></span>
+  <a href="/notifications" id="notif-badge" class="{{if eq .Active "notifications"}}active{{end}}" title="Notifications">🔔</a>
+  <a href="/sync" id="sync-badge" class="{{if eq .Active "sync"}}active{{end}}" title="Sync status">Sync</a>
   <form method="post" action="/undo" style="display:inline;margin-left:8px;">
     <button type="submit" style="background:#f59e0b;color:#fff;border:none;padding:6px 10px;border-radius:4px;cursor:pointer;">Undo</button>
   </form>
@@ -127,10 +162,16 @@ table, th, td, table pre {font-size:13px}
   <strong>Music</strong>
   <div style="margin-top:4px;display:flex;gap:6px;align-items:center;">
     <button id="mp-play" title="Play/Pause">▶️/⏸</button>
+    <button id="mp-next" title="Next track" style="display:none;">⏭</button>
     <button id="mp-mute" title="Mute">🔇</button>
     <input id="mp-vol" type="range" min="0" max="1" step="0.01" value="0.8"/>
     <span id="mp-label" style="font-size:12px;color:#6a737d;max-width:220px;white-space:nowrap;overflow:hidden;text-overflow:ellipsis;">—</span>
   </div>
+  <div id="pomo" style="display:none;margin-top:6px;gap:6px;align-items:center;font-size:13px;">
+    <span id="pomo-label" style="max-width:260px;white-space:nowrap;overflow:hidden;text-overflow:ellipsis;"></span>
+    <button id="pomo-next" style="display:none;"></button>
+    <button id="pomo-cancel" title="End the Pomodoro session">✕</button>
+  </div>
 </div>
 <script>
 (function(){
@@ -140,6 +181,7 @@ table, th, td, table pre {font-size:13px}
   const log = (...a)=>{ try{ console.log('[music]', ...a); } catch(e){} };
   const elPlay  = document.getElementById('mp-play');
   const elMute  = document.getElementById('mp-mute');
+  const elNext  = document.getElementById('mp-next');
   const elVol   = document.getElementById('mp-vol');
   const elLabel = document.getElementById('mp-label');
   let persistCurrent = function(){ /* noop until stream active */ };
@@ -206,20 +248,29 @@ table, th, td, table pre {font-size:13px}
 
   function setSrcAndPlay(name, url, opts){
     opts = opts || {};
-    current = {type:'radio', name, url, id: opts.id||'', volume: opts.vol, muted: opts.muted};
+    current = {type: opts.type||'radio', name, url, id: opts.id||'', volume: opts.vol, muted: opts.muted,
+      path: opts.path||'', shuffle: !!opts.shuffle, tracks: opts.tracks||null, index: 0};
     if (elLabel) elLabel.textContent = name || url;
-    // Immer über Proxy (mit Referer & Cache-Buster), da Quelle sonst 401/CORS liefern kann
-    try {
-      const orig = url;
-      // best-guess Referer je nach Stream-Host
-      let upstream;
-      try { upstream = new URL(orig); } catch(_) {}
-      let refererStr = window.location.href;
-      if (upstream && /(^|\.)rndfnk\.com$/i.test(upstream.hostname)) {
-        refererStr = 'https://www.deutschlandfunk.de/';
-      }
-      url = '/music/proxy?url=' + encodeURIComponent(orig) + '&referer=' + encodeURIComponent(refererStr) + '&_ts=' + Date.now();
-    } catch(e) { /* ignore */ }
+    if (elNext) elNext.style.display = current.tracks ? '' : 'none';
+    if (current.tracks) {
+      // lokaler Ordner: Dateien kommen vom eigenen Server, kein Proxy nötig
+      url = current.tracks[0].url;
+      if (elLabel) elLabel.textContent = (name ? name + ' – ' : '') + current.tracks[0].title;
+    } else {
+      // Immer über Proxy (mit Referer & Cache-Buster), da Quelle sonst 401/CORS liefern kann
+      try {
+        const orig = url;
+        // best-guess Referer je nach Stream-Host
+        let upstream;
+        try { upstream = new URL(orig); } catch(_) {}
+        let refererStr = window.location.href;
+        if (upstream && /(^|\.)rndfnk\.com$/i.test(upstream.hostname)) {
+          refererStr = 'https://www.deutschlandfunk.de/';
+        }
+        url = '/music/proxy?url=' + encodeURIComponent(orig) + '&referer=' + encodeURIComponent(refererStr) + (opts.task ? '&task=' + encodeURIComponent(opts.task) : '') + '&_ts=' + Date.now();
+      } catch(e) { /* ignore */ }
+    }
+    watchTitles(!current.tracks);
     log('set src', url);
     try { audio.pause(); } catch(e){}
     audio.src = url;
@@ -229,7 +280,9 @@ table, th, td, table pre {font-size:13px}
         log('persist skip', reason, 'no current id');
         return;
       }
-      const payload = { type: 'radio', name: current.name||'', url: current.url||'', volume: (typeof audio.volume==='number'?audio.volume:undefined), muted: !!audio.muted };
+      const payload = current.type === 'folder'
+        ? { type: 'folder', name: current.name||'', path: current.path||'', shuffle: !!current.shuffle, volume: (typeof audio.volume==='number'?audio.volume:undefined), muted: !!audio.muted }
+        : { type: 'radio', name: current.name||'', url: current.url||'', volume: (typeof audio.volume==='number'?audio.volume:undefined), muted: !!audio.muted };
       log('persist', reason, 'id=', current.id, payload);
       fetch('/music/tasks/' + encodeURIComponent(current.id), { method: 'PUT', headers: { 'Content-Type': 'application/json' }, credentials: 'same-origin', body: JSON.stringify(payload) })
         .then(()=>{
@@ -317,7 +370,48 @@ table, th, td, table pre {font-size:13px}
     }, 500);
   }
 
+  // Radio: aktueller Titel (ICY StreamTitle) kommt per SSE vom Proxy
+  let titles = null;
+  function watchTitles(on){
+    if (!on) { if (titles) { titles.close(); titles = null; } return; }
+    if (titles || !window.EventSource) return;
+    titles = new EventSource('/music/nowplaying');
+    titles.addEventListener('title', (ev)=>{
+      let np = null;
+      try { np = JSON.parse(ev.data); } catch(_){ return; }
+      if (!(current && !current.tracks && elLabel)) return;
+      const base = current.name || current.url;
+      elLabel.textContent = np.playing && np.title ? base + ' – ' + np.title : base;
+      elLabel.title = np.playing && np.title ? np.title + (np.station ? ' (' + np.station + ')' : '') : '';
+      log('now playing', np.title);
+    });
+  }
+
+  // Ordner-Playlist: nächster Titel (am Ende wieder von vorn)
+  function nextTrack(){
+    if (!(current && current.tracks && current.tracks.length)) return;
+    current.index = (current.index + 1) % current.tracks.length;
+    const t = current.tracks[current.index];
+    log('next track', t.path);
+    if (elLabel) elLabel.textContent = (current.name ? current.name + ' – ' : '') + t.title;
+    audio.src = t.url;
+    audio.play().catch(e=>log('play rejected', e));
+  }
+
+  function playFolder(name, playlistURL, opts){
+    opts = opts || {};
+    const u = playlistURL + (playlistURL.indexOf('?') >= 0 ? '&' : '?') + 'format=json';
+    fetch(u, { credentials: 'same-origin' })
+      .then(res=>{ if (!res.ok) throw new Error('status '+res.status); return res.json(); })
+      .then(pl=>{
+        if (!pl.tracks || !pl.tracks.length) { if (elLabel) elLabel.textContent = (name||pl.name) + ' (no audio files)'; return; }
+        setSrcAndPlay(name || pl.name, playlistURL, Object.assign({}, opts, { type: 'folder', path: pl.path, shuffle: pl.shuffle, tracks: pl.tracks }));
+      })
+      .catch(err=>{ log('playlist failed', err); if (elLabel) elLabel.textContent = (name||'') + ' (playlist unavailable)'; });
+  }
+
   // Controls
+  elNext && elNext.addEventListener('click', nextTrack);
   elPlay && elPlay.addEventListener('click', ()=>{
     if (audio.paused) { log('play click'); audio.play().catch(e=>log('play rejected', e)); }
     else { log('pause click'); audio.pause(); }
@@ -340,6 +434,7 @@ table, th, td, table pre {font-size:13px}
   audio.addEventListener('playing', ()=>log('event: playing'));
   audio.addEventListener('waiting', ()=>{ log('event: waiting'); /* try nudging play */ if (audio.src) { audio.play().catch(()=>{}); } });
   audio.addEventListener('stalled', ()=>log('event: stalled'));
+  audio.addEventListener('ended',   ()=>{ log('event: ended'); nextTrack(); });
   audio.addEventListener('error',   ()=>{ const e=audio.error; log('event: error', e && (e.code+':'+e.message)); });
   audio.addEventListener('volumechange', ()=>{
     if (!current) return;
@@ -350,7 +445,9 @@ table, th, td, table pre {font-size:13px}
   // API for server-driven start/stop
   window.dstaskMusic = {
     playRadio: (name, url, opts)=> setSrcAndPlay(name, url, opts),
-    stop: ()=>{ log('stop'); try{ audio.pause(); }catch(e){}; audio.currentTime = 0; current = null; persistCurrent = function(){}; },
+    playFolder: (name, playlistURL, opts)=> playFolder(name, playlistURL, opts),
+    stop: ()=>{ log('stop'); try{ audio.pause(); }catch(e){}; audio.currentTime = 0; current = null; watchTitles(false); if (elLabel) elLabel.title = ''; persistCurrent = function(){}; if (elNext) elNext.style.display = 'none'; },
+    playing: ()=> !!current && !audio.paused,
     setVolume: (v)=>{ applyVolume(parseFloat(v), 'api'); log('set volume', v); }
   };
 })();
@@ -396,6 +493,8 @@ table, th, td, table pre {font-size:13px}
       var id = '';
       var vol = undefined;
       var muted = undefined;
+      var type = '';
+      var task = '';
       if(i >= 0){
         name = p.slice(0,i).trim();
         var rest = p.slice(i+1).trim();
@@ -410,6 +509,8 @@ table, th, td, table pre {font-size:13px}
           if (k === 'id') id = v;
           else if (k === 'vol') { var f = parseFloat(v); if (!isNaN(f)) vol = f; }
           else if (k === 'muted') { muted = (v === '1' || v === 'true'); }
+          else if (k === 'type') { type = v; }
+          else if (k === 'task') { task = v; }
         });
       } else {
         // Fallback: finde URL-Beginn über http(s)://
@@ -429,8 +530,13 @@ table, th, td, table pre {font-size:13px}
       }
       log('parsed name:', name, 'url:', url.substring(0, 100), 'id:', id, 'vol:', vol, 'muted:', muted);
       if(window.dstaskMusic && url){
+        if(type === 'folder'){
+          log('calling playFolder');
+          window.dstaskMusic.playFolder(name, url, { id: id, vol: vol, muted: muted });
+          return true;
+        }
         log('calling playRadio');
-        window.dstaskMusic.playRadio(name, url, { id: id, vol: vol, muted: muted });
+        window.dstaskMusic.playRadio(name, url, { id: id, task: task, vol: vol, muted: muted });
         return true;
       } else {
         log('dstaskMusic not available yet');
@@ -462,6 +568,147 @@ table, th, td, table pre {font-size:13px}
   }
 })();
 </script>
+<script>
+// Pomodoro: Countdown im Player; in der Pause wird der Stream gestoppt, zur nächsten Einheit wieder gestartet
+(function(){
+  var box = document.getElementById('pomo');
+  if(!box || !window.fetch) return;
+  var label = document.getElementById('pomo-label');
+  var next = document.getElementById('pomo-next');
+  var cancel = document.getElementById('pomo-cancel');
+  var st = null, deadline = 0, loading = false;
+  function fmt(sec){ sec = Math.max(0, Math.round(sec)); var m = Math.floor(sec/60), s = sec%60; return m + ':' + (s < 10 ? '0' : '') + s; }
+  function flashStartsMusic(){ var f = document.querySelector('.flash'); return !!(f && (f.textContent||'').indexOf('__MUSIC_START__') >= 0); }
+  function render(){
+    if(!st || !st.active){ box.style.display = 'none'; return; }
+    box.style.display = 'flex';
+    var task = '#' + st.taskId + ' ' + st.summary;
+    var left = (deadline - Date.now()) / 1000;
+    if(st.phase === 'focus'){
+      label.textContent = '🍅 ' + fmt(left) + ' · ' + task;
+      next.style.display = 'none';
+    } else if(st.phase === 'break'){
+      label.textContent = '☕ ' + fmt(left) + ' break · 🍅×' + st.completed + (st.stopOnBreak ? ' · task stopped' : '');
+      next.textContent = 'Skip break'; next.style.display = '';
+    } else {
+      label.textContent = '☕ Break over · ' + task;
+      next.textContent = 'Next 🍅'; next.style.display = '';
+    }
+    label.title = task + ' · ' + st.total + ' pomodoro(s) in total';
+    if(st.phase !== 'idle' && left <= 0) load();
+  }
+  function apply(d, initial){
+    var prev = st ? st.phase : '';
+    st = d; deadline = Date.now() + (d.remaining || 0) * 1000;
+    var m = window.dstaskMusic;
+    if(m && d.active && d.phase === 'focus' && prev !== 'focus' && d.music && !m.playing() && !(initial && flashStartsMusic())){
+      var play = d.music.type === 'folder' ? m.playFolder : m.playRadio;
+      play(d.music.name, d.music.url, { id: d.music.id, task: d.music.task, vol: d.music.volume, muted: d.music.muted });
+    }
+    if(m && prev === 'focus' && d.phase !== 'focus') m.stop();
+    render();
+  }
+  function load(initial){
+    if(loading) return;
+    loading = true;
+    fetch('/pomodoro', {credentials:'same-origin'}).then(function(r){ return r.ok ? r.json() : null; })
+      .then(function(d){ loading = false; if(d) apply(d, initial); })
+      .catch(function(){ loading = false; });
+  }
+  function post(path){
+    if(!st) return;
+    fetch(path, {method:'POST', credentials:'same-origin', body: new URLSearchParams({csrf_token: st.csrfToken})})
+      .then(function(r){ return r.ok ? r.json() : null; }).then(function(d){ if(d) apply(d); }).catch(function(){});
+  }
+  next.addEventListener('click', function(){ post('/pomodoro/next'); });
+  cancel.addEventListener('click', function(){ post('/pomodoro/cancel'); });
+  setInterval(function(){ if(st && st.active) render(); }, 1000);
+  load(true);
+})();
+</script>
+<script>
+// Repo-Umschalter: nur sichtbar, wenn der Nutzer mehrere Repos hat
+(function(){
+  var el = document.getElementById('repo-switcher');
+  if(!el || !window.fetch) return;
+  fetch('/repos', {credentials:'same-origin'}).then(function(r){ return r.ok ? r.json() : null; }).then(function(d){
+    if(!d || !d.repos || d.repos.length < 2) return;
+    var f = document.createElement('form');
+    f.method = 'post'; f.action = '/repos/select'; f.style.display = 'inline';
+    var add = function(n, v){ var i = document.createElement('input'); i.type = 'hidden'; i.name = n; i.value = v; f.appendChild(i); };
+    add('csrf_token', d.csrfToken);
+    add('return', location.pathname + location.search);
+    var sel = document.createElement('select');
+    sel.name = 'repo'; sel.title = 'Repository';
+    d.repos.forEach(function(r){
+      var o = document.createElement('option');
+      o.value = r.name; o.textContent = r.name; o.title = r.home || '';
+      if(r.name === d.current) o.selected = true;
+      sel.appendChild(o);
+    });
+    sel.addEventListener('change', function(){ f.submit(); });
+    f.appendChild(sel);
+    el.appendChild(f);
+  }).catch(function(){});
+})();
+</script>
+<script>
+// Sync-Badge in der Navigation: Status per /sync/status abfragen
+(function(){
+  var el = document.getElementById('sync-badge');
+  if(!el || !window.fetch) return;
+  function ago(iso){
+    if(!iso) return '';
+    var s = Math.max(0, Math.round((Date.now() - new Date(iso).getTime())/1000));
+    if(s < 60) return s + 's';
+    if(s < 3600) return Math.round(s/60) + 'm';
+    if(s < 86400) return Math.round(s/3600) + 'h';
+    return Math.round(s/86400) + 'd';
+  }
+  function refresh(){
+    fetch('/sync/status', {credentials:'same-origin'}).then(function(r){ return r.ok ? r.json() : null; }).then(function(st){
+      if(!st) return;
+      var txt = 'Sync';
+      var title = [];
+      var failed = st.lastErrorAt && (!st.lastSuccess || st.lastErrorAt > st.lastSuccess);
+      if(st.running){ txt += ' ⟳'; }
+      else if(st.paused){ txt += ' ⏸'; }
+      else if(failed){ txt += ' ⚠'; }
+      else if(st.lastSuccess){ txt += ' ✓ ' + ago(st.lastSuccess); }
+      if(st.ahead != null && st.behind != null){
+        if(st.ahead > 0) txt += ' ↑' + st.ahead;
+        if(st.behind > 0) txt += ' ↓' + st.behind;
+        title.push('ahead ' + st.ahead + ', behind ' + st.behind);
+      }
+      if(st.lastSuccess) title.push('last success: ' + new Date(st.lastSuccess).toLocaleString());
+      if(st.lastErrorAt) title.push('last error: ' + new Date(st.lastErrorAt).toLocaleString() + (st.lastError ? ' – ' + st.lastError : ''));
+      if(st.paused) title.push('scheduled sync paused: ' + st.paused);
+      if(st.nextRun) title.push('next run: ' + new Date(st.nextRun).toLocaleString());
+      el.textContent = txt;
+      el.title = title.join('\n') || 'Sync status';
+      el.style.color = failed ? '#991b1b' : '';
+    }).catch(function(){});
+  }
+  refresh();
+  setInterval(refresh, 30000);
+})();
+</script>
+<script>
+// Benachrichtigungs-Badge: Anzahl ungelesener Einträge per /notifications?format=json
+(function(){
+  var el = document.getElementById('notif-badge');
+  if(!el || !window.fetch) return;
+  function refresh(){
+    fetch('/notifications?format=json', {credentials:'same-origin'}).then(function(r){ return r.ok ? r.json() : null; }).then(function(st){
+      if(!st) return;
+      el.textContent = st.unread > 0 ? '🔔 ' + st.unread : '🔔';
+      el.style.fontWeight = st.unread > 0 ? 'bold' : '';
+    }).catch(function(){});
+  }
+  refresh();
+  setInterval(refresh, 30000);
+})();
+</script>
 </body></html>`))
 
 	s.routes()
@@ -504,180 +751,15 @@ func (s *Server) routes() {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		_, _ = io.Copy(w, resp.Body)
 	})
-	// TODO(music): Lokale MP3-Wiedergabe später reaktivieren. Routen vorübergehend deaktiviert.
-	/*
-		// Music: playlist (M3U) for folder under user's HOME/.dstask scope
-		s.mux.HandleFunc("/music/playlist", func(w http.ResponseWriter, r *http.Request) {
-			...
-		})
-		// Minimal file serving for audio under HOME/.dstask
-		s.mux.HandleFunc("/music/file", func(w http.ResponseWriter, r *http.Request) {
-			...
-		})
-	*/
-
-	// Simple streaming proxy to improve compatibility (e.g., AAC/MP3/ICY/CORS)
-	s.mux.HandleFunc("/music/proxy", func(w http.ResponseWriter, r *http.Request) {
-		// Robust extraction of the upstream URL: prefer full RawQuery tail after 'url='
-		// This handles unencoded '&' inside the upstream URL parameters (token/sid/etc.).
-		rq := r.URL.RawQuery
-		raw := strings.TrimSpace(r.URL.Query().Get("url"))
-		// If RawQuery tail was used previously, it may have included '&referer=' or other proxy params.
-		// Prefer the explicit query param value; only fall back if empty.
-		if raw == "" {
-			if i := strings.Index(rq, "url="); i >= 0 {
-				cand := rq[i+4:]
-				// Trim at next '&' to avoid appending proxy params like '&referer=' or '&_ts='
-				if j := strings.IndexByte(cand, '&'); j >= 0 {
-					cand = cand[:j]
-				}
-				if d, err := url.QueryUnescape(cand); err == nil && d != "" {
-					cand = d
-				}
-				raw = strings.TrimSpace(cand)
-			}
-		}
-		if raw == "" {
-			http.Error(w, "missing url", http.StatusBadRequest)
-			return
-		}
-		// sanitize accidental token tailings (e.g., "|id=1" appended) from client flash parsing
-		if k := strings.Index(raw, "|"); k >= 0 {
-			raw = raw[:k]
-		}
-		if k := strings.Index(raw, "/id="); k >= 0 {
-			raw = raw[:k]
-		}
-		u, err := url.Parse(raw)
-		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
-			http.Error(w, "invalid url", http.StatusBadRequest)
-			return
-		}
-		applog.Infof("/music/proxy fetch %s", raw)
-		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, raw, nil)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		// Generic UA
-		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; dstask-web)")
-		req.Header.Set("Accept", "audio/*, */*;q=0.5")
-		req.Header.Set("Cache-Control", "no-cache")
-		req.Header.Set("Pragma", "no-cache")
-		req.Header.Set("Accept-Encoding", "identity")
-		if al := r.Header.Get("Accept-Language"); al != "" {
-			req.Header.Set("Accept-Language", al)
-		}
-		if ua := r.Header.Get("User-Agent"); ua != "" {
-			req.Header.Set("User-Agent", ua)
-		}
-		// try to preserve client IP for geo/token backends
-		if xffVal := r.Header.Get("X-Forwarded-For"); xffVal != "" {
-			req.Header.Set("X-Forwarded-For", xffVal)
-		} else {
-			host := r.RemoteAddr
-			if i := strings.LastIndex(host, ":"); i > 0 {
-				host = host[:i]
-			}
-			req.Header.Set("X-Forwarded-For", host)
-		}
-		// Optional Referer passthrough for token-gated streams
-		referer := strings.TrimSpace(r.URL.Query().Get("referer"))
-		if referer == "" {
-			// Also try to extract from RawQuery if provided without encoding
-			rq := r.URL.RawQuery
-			if j := strings.Index(rq, "referer="); j >= 0 {
-				ref := rq[j+8:]
-				if d, err := url.QueryUnescape(ref); err == nil && d != "" {
-					referer = d
-				} else {
-					referer = ref
-				}
-			}
-		}
-		if referer != "" {
-			req.Header.Set("Referer", referer)
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Determine content type; fallback anhand Dateiendung
-		ct := resp.Header.Get("Content-Type")
-		if ct == "" {
-			lp := strings.ToLower(u.Path)
-			switch {
-			case strings.Contains(lp, ".aac"):
-				ct = "audio/aac"
-			case strings.Contains(lp, ".m4a") || strings.Contains(lp, ".mp4"):
-				ct = "audio/mp4"
-			default:
-				ct = "audio/mpeg"
-			}
-		}
-
-		// Response-Header setzen; Content-Length entfernen, damit Chunked-Streaming genutzt wird
-		w.Header().Set("Content-Type", ct)
-		if v := resp.Header.Get("Ice-Audio-Info"); v != "" {
-			w.Header().Set("Ice-Audio-Info", v)
-		}
-		w.Header().Set("Cache-Control", "no-store")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Del("Content-Length")
-		w.WriteHeader(resp.StatusCode)
-
-		// Chunked stream mit periodischem Flush
-		var total int64
-		if f, ok := w.(http.Flusher); ok {
-			buf := make([]byte, 32*1024)
-			bw := bufio.NewWriterSize(w, 64*1024)
-			lastFlush := time.Now()
-			bytesSinceFlush := 0
-			for {
-				n, er := resp.Body.Read(buf)
-				if n > 0 {
-					total += int64(n)
-					if _, ew := bw.Write(buf[:n]); ew != nil {
-						applog.Warnf("/music/proxy write error: %v (bytes_sent=%d)", ew, total)
-						return
-					}
-					bytesSinceFlush += n
-					if bytesSinceFlush >= 128*1024 || time.Since(lastFlush) >= 300*time.Millisecond {
-						if err := bw.Flush(); err != nil {
-							applog.Warnf("/music/proxy buffer flush error: %v (bytes_sent=%d)", err, total)
-							return
-						}
-						f.Flush()
-						bytesSinceFlush = 0
-						lastFlush = time.Now()
-					}
-				}
-				if er != nil {
-					if er != io.EOF {
-						applog.Warnf("/music/proxy stream error: %v (bytes_sent=%d)", er, total)
-					}
-					break
-				}
-			}
-			if err := bw.Flush(); err != nil {
-				applog.Warnf("/music/proxy final flush error: %v (bytes_sent=%d)", err, total)
-			} else {
-				f.Flush()
-			}
-		} else {
-			n, err := io.Copy(w, resp.Body)
-			total = n
-			if err != nil {
-				applog.Warnf("/music/proxy stream error: %v (bytes_sent=%d)", err, total)
-			}
-		}
-
-		applog.Infof("/music/proxy done %s status=%d bytes_sent=%d", raw, resp.StatusCode, total)
-	})
+	// Music: lokale Ordner (M3U-Playlist und Audiodateien) aus dem freigegebenen Musikordner des Nutzers
+	s.mux.HandleFunc("/music/playlist", s.handleMusicPlaylist)
+	s.mux.HandleFunc("/music/file", s.handleMusicFile)
+	// Music: aktueller Titel des Radio-Streams (SSE) und gespielte Titel pro Task
+	s.mux.HandleFunc("/music/nowplaying", s.handleNowPlaying)
+	s.mux.HandleFunc("/music/played", s.handleMusicPlayed)
+
+	// Streaming-Proxy für Radio-Streams (Referer/CORS/ICY), mit SSRF-Schutz und Limits (musicProxy)
+	s.mux.HandleFunc("/music/proxy", s.handleMusicProxy)
 	// Batch actions
 	s.mux.HandleFunc("/tasks/batch", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -702,8 +784,17 @@ func (s *Server) routes() {
 			http.Error(w, "ids/action required", http.StatusBadRequest)
 			return
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		actor, _ := auth.UsernameFromRequest(r)
+		assignee := strings.TrimSpace(r.FormValue("assignee"))
+		warning := ""
+		if action == "done" {
+			warning = s.dependentsWarning(username, ids)
+		}
+		change := s.beginTaskChange(r)
+		var applied []string
 		var ok, skipped, failed int
+		assignErr := ""
 		for _, id := range ids {
 			id = strings.TrimSpace(id)
 			if id == "" {
@@ -719,6 +810,21 @@ func (s *Server) routes() {
 					continue
 				}
 				res = s.runner.Run(username, 10*time.Second, "note", id, note)
+			case "assign":
+				changed, err := s.assignTask(username, actor, id, assignee)
+				switch {
+				case errors.Is(err, errAssignmentNotRecorded):
+					ok++
+					assignErr = err.Error()
+				case err != nil:
+					applog.Warnf("batch assign %s: %v", id, err)
+					failed++
+				case changed:
+					ok++
+				default:
+					skipped++
+				}
+				continue
 			default:
 				skipped++
 				continue
@@ -727,10 +833,24 @@ func (s *Server) routes() {
 				failed++
 			} else {
 				ok++
+				applied = append(applied, id)
+			}
+		}
+		if action == "log" && len(applied) > 0 {
+			change.publishCreated(action)
+		} else {
+			for _, id := range applied {
+				change.publish(action, id)
 			}
 		}
 		msg := fmt.Sprintf("Batch %s: %d ok, %d skipped, %d failed", action, ok, skipped, failed)
-		s.setFlash(w, "info", msg)
+		if assignErr != "" {
+			s.setFlash(w, "error", msg+". Tags were changed, but saving the assignments failed: "+assignErr)
+		} else if warning != "" && ok > 0 {
+			s.setFlash(w, "warning", msg+". "+warning)
+		} else {
+			s.setFlash(w, "info", msg)
+		}
 		if ok > 0 {
 			s.autoSync(username)
 		}
@@ -756,7 +876,7 @@ func (s *Server) routes() {
 	})
 	// Music map CRUD
 	s.mux.HandleFunc("/music/map", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		switch r.Method {
 		case http.MethodGet:
 			m, _, err := music.LoadForUser(s.cfg, username)
@@ -787,36 +907,37 @@ func (s *Server) routes() {
 		}
 	})
 	s.mux.HandleFunc("/music/tasks/", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		id := strings.TrimPrefix(r.URL.Path, "/music/tasks/")
 		if id == "" {
 			http.Error(w, "missing id", http.StatusBadRequest)
 			return
 		}
-		m, path, err := music.LoadForUser(s.cfg, username)
+		// Task-ID, UUID oder Regel-Schlüssel ("tag:…", "project:…")
+		refs := s.taskRefs(username)
+		m, err := s.musicMap(username, refs)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		key := musicRuleKey(refs, id)
 		switch r.Method {
 		case http.MethodGet:
-			if m.Tasks == nil {
-				http.Error(w, "not found", http.StatusNotFound)
-				return
-			}
-			tm, ok := m.Tasks[id]
+			tm, ok := m.Entry(key)
 			if !ok {
 				http.Error(w, "not found", http.StatusNotFound)
 				return
 			}
 			writeJSON(w, map[string]any{
-				"id":     id,
-				"type":   tm.Type,
-				"name":   tm.Name,
-				"url":    tm.URL,
-				"path":   tm.Path,
-				"volume": tm.Volume,
-				"muted":  tm.Muted,
+				"id":      id,
+				"key":     key,
+				"type":    tm.Type,
+				"name":    tm.Name,
+				"url":     tm.URL,
+				"path":    tm.Path,
+				"shuffle": tm.Shuffle,
+				"volume":  tm.Volume,
+				"muted":   tm.Muted,
 			})
 		case http.MethodPut:
 			var tm music.TaskMusic
@@ -824,20 +945,20 @@ func (s *Server) routes() {
 				http.Error(w, "invalid json", http.StatusBadRequest)
 				return
 			}
-			if m.Tasks == nil {
-				m.Tasks = map[string]music.TaskMusic{}
+			if !m.Set(key, tm) {
+				http.Error(w, "task "+id+" is not open; music is stored per task uuid", http.StatusNotFound)
+				return
 			}
-			m.Tasks[id] = tm
-			if _, err := music.SaveForUser(s.cfg, username, m); err != nil {
+			path, err := music.SaveForUser(s.cfg, username, m)
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 			writeJSON(w, map[string]any{"ok": true, "path": path})
 		case http.MethodDelete:
-			if m.Tasks != nil {
-				delete(m.Tasks, id)
-			}
-			if _, err := music.SaveForUser(s.cfg, username, m); err != nil {
+			m.Delete(key)
+			path, err := music.SaveForUser(s.cfg, username, m)
+			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -846,15 +967,17 @@ func (s *Server) routes() {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	s.mux.HandleFunc("/music/rules", s.handleMusicRules)
 
 	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		t := template.Must(s.layoutTpl.Clone())
-		_, _ = t.New("content").Parse(`<h1>dstask Web UI</h1><p>Signed in as: {{.User}}</p>
+		_, _ = t.New("content").Parse(`<h1>dstask Web UI</h1><p>Signed in as: {{.User}}{{if .RepoName}} · Repository: <strong>{{.RepoName}}</strong>{{end}}</p>
 {{if .IsGitRepo}}
   {{if .RemoteURL}}
     <div style="margin:8px 0;">Remote: <code>{{.RemoteURL}}</code></div>
     <form method="post" action="/sync" style="margin-top:8px"><button type="submit">Sync</button></form>
+    <div style="margin-top:8px;"><a href="/repo">Repository status</a></div>
   {{else}}
     <div style="margin:8px 0;background:#fff3cd;border:1px solid #ffeeba;padding:8px;">Kein Git-Remote konfiguriert. Sync erfordert ein Remote-Repository.</div>
     <form method="post" action="/sync" style="margin-top:8px"><button type="submit">Sync…</button></form>
@@ -863,6 +986,7 @@ func (s *Server) routes() {
       <button type="submit">Remote speichern</button>
       <a href="/" style="margin-left:8px;">abbrechen</a>
     </form>
+    <div style="margin-top:6px;"><small>Zugangsdaten für das Remote: <a href="/repo/ssh">SSH-Deploy-Key</a> oder <a href="/repo/https">HTTPS-Token</a>.</small></div>
   {{end}}
 {{else}}
   <div style="margin:8px 0;background:#fff3cd;border:1px solid #ffeeba;padding:8px;">Kein Git-Repository im .dstask-Verzeichnis. Du kannst ein Remote hier klonen.<br/><small>Verwendetes lokales Verzeichnis: <code>{{.RepoDir}}</code></small></div>
@@ -871,8 +995,10 @@ func (s *Server) routes() {
     <button type="submit">Remote klonen</button>
     <a href="/" style="margin-left:8px;">abbrechen</a>
   </form>
+  <div style="margin-top:6px;"><small>Zugangsdaten für das Remote: <a href="/repo/ssh">SSH-Deploy-Key</a> oder <a href="/repo/https">HTTPS-Token</a>.</small></div>
 {{end}}`) // placeholder
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		authUser, _ := auth.UsernameFromRequest(r)
 		remoteURL, _ := s.runner.GitRemoteURL(username)
 		// Prüfe Git-Repo vorhanden
 		isRepo := false
@@ -902,7 +1028,8 @@ func (s *Server) routes() {
 		}
 		show, entries, moreURL, canMore, ret := s.footerData(r, username)
 		_ = t.Execute(w, map[string]any{
-			"User":        username,
+			"User":        authUser,
+			"RepoName":    s.repoLabel(r),
 			"RemoteURL":   remoteURL,
 			"IsGitRepo":   isRepo,
 			"RepoDir":     repoDir,
@@ -917,7 +1044,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/next", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		s.cmdStore.Append(username, "List next tasks", []string{"next"})
 		if r.URL.Query().Get("html") == "1" {
 			exp := s.runner.Run(username, 5_000_000_000, "export")
@@ -928,8 +1055,9 @@ func (s *Server) routes() {
 					dueFilter := buildDueFilterToken(r.URL.Query())
 					rows = applyDueFilter(rows, dueFilter)
 					if len(rows) > 0 {
+						rows, extra := s.hideBlockedRows(r, rows)
 						w.Header().Set("Content-Type", "text/html; charset=utf-8")
-						s.renderExportTable(w, r, "Next", rows)
+						s.renderExportTableWith(w, r, "Next", rows, extra)
 						return
 					}
 				}
@@ -946,8 +1074,9 @@ func (s *Server) routes() {
 				dueFilter := buildDueFilterToken(r.URL.Query())
 				rows = applyDueFilter(rows, dueFilter)
 				if len(rows) > 0 {
+					rows, extra := s.hideBlockedRows(r, rows)
 					w.Header().Set("Content-Type", "text/html; charset=utf-8")
-					s.renderExportTable(w, r, "Next", rows)
+					s.renderExportTableWith(w, r, "Next", rows, extra)
 					return
 				}
 			}
@@ -965,7 +1094,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/open", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		s.cmdStore.Append(username, "List open tasks", []string{"show-open"})
 		if r.URL.Query().Get("html") == "1" {
 			// Primär: export rohen JSON-Text holen und parsen (robuster, da wir Json sehen)
@@ -981,6 +1110,7 @@ func (s *Server) routes() {
 						}
 						rows = append(rows, map[string]string{
 							"id":       id,
+							"uuid":     str(firstOf(t, "uuid", "UUID")),
 							"status":   str(firstOf(t, "status", "state")),
 							"summary":  trimQuotes(str(firstOf(t, "summary", "Summary", "description", "Description"))),
 							"project":  trimQuotes(str(firstOf(t, "project", "Project"))),
@@ -1029,6 +1159,7 @@ func (s *Server) routes() {
 					}
 					rows = append(rows, map[string]string{
 						"id":       id,
+						"uuid":     str(firstOf(t, "uuid", "UUID")),
 						"status":   str(firstOf(t, "status", "state")),
 						"summary":  trimQuotes(str(firstOf(t, "summary", "description"))),
 						"project":  trimQuotes(str(firstOf(t, "project"))),
@@ -1078,7 +1209,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/active", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		s.cmdStore.Append(username, "List active tasks", []string{"show-active"})
 		if r.URL.Query().Get("html") == "1" {
 			exp := s.runner.Run(username, 5_000_000_000, "export")
@@ -1126,7 +1257,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/paused", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		s.cmdStore.Append(username, "List paused tasks", []string{"show-paused"})
 		if r.URL.Query().Get("html") == "1" {
 			exp := s.runner.Run(username, 5_000_000_000, "export")
@@ -1174,7 +1305,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/resolved", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		s.cmdStore.Append(username, "List resolved tasks", []string{"show-resolved"})
 		if r.URL.Query().Get("html") == "1" {
 			exp := s.runner.Run(username, 5_000_000_000, "export")
@@ -1218,7 +1349,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/tags", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		res := s.runner.Run(username, 5_000_000_000, "show-tags")
 		s.cmdStore.Append(username, "List tags", []string{"show-tags"})
 		if res.Err != nil && !res.TimedOut {
@@ -1230,7 +1361,7 @@ func (s *Server) routes() {
 			t := template.Must(s.layoutTpl.Clone())
 			_, _ = t.New("content").Parse(`<h2>Tags</h2>
 <pre style="white-space:pre-wrap;">{{.Out}}</pre>`)
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 			_ = t.Execute(w, map[string]any{
 				"Out":         strings.TrimSpace(res.Stdout),
@@ -1253,7 +1384,7 @@ func (s *Server) routes() {
 	})
 
 	s.mux.HandleFunc("/projects", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		res := s.runner.Run(username, 5_000_000_000, "show-projects")
 		s.cmdStore.Append(username, "List projects", []string{"show-projects"})
 		if res.Err != nil && !res.TimedOut {
@@ -1288,7 +1419,7 @@ func (s *Server) routes() {
 			t := template.Must(s.layoutTpl.Clone())
 			_, _ = t.New("content").Parse(`<h2>Projects</h2>
 <pre style="white-space:pre-wrap;">{{.Out}}</pre>`)
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 			_ = t.Execute(w, map[string]any{
 				"Out":         strings.TrimSpace(res.Stdout),
@@ -1323,7 +1454,7 @@ func (s *Server) routes() {
 				http.Error(w, "summary required", http.StatusBadRequest)
 				return
 			}
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 			tags := strings.TrimSpace(r.FormValue("tags"))
 			project := strings.TrimSpace(r.FormValue("project"))
 			// Prefer selected existing project if provided
@@ -1389,7 +1520,7 @@ func (s *Server) routes() {
 		}
 
 		// GET: Templates anzeigen
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		res := s.runner.Run(username, 5_000_000_000, "show-templates")
 		s.cmdStore.Append(username, "List templates", []string{"show-templates"})
 		if res.Err != nil && !res.TimedOut {
@@ -1400,7 +1531,7 @@ func (s *Server) routes() {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		t := template.Must(s.layoutTpl.Clone())
 		_, _ = t.New("content").Parse(`
-<h2>Templates <a href="/templates/new" style="font-size:14px;font-weight:normal;margin-left:8px;">(New template)</a></h2>
+<h2>Templates <a href="/templates/new" style="font-size:14px;font-weight:normal;margin-left:8px;">(New template)</a> <a href="/recurrence" style="font-size:14px;font-weight:normal;margin-left:8px;">(Recurring)</a></h2>
 {{if .Templates}}
 <table border="1" cellpadding="4" cellspacing="0">
   <thead><tr>
@@ -1408,7 +1539,8 @@ func (s *Server) routes() {
     <th>Summary</th>
     <th>Project</th>
     <th>Tags</th>
-    <th style="width:200px;">Actions</th>
+    <th>Repeats</th>
+    <th style="width:240px;">Actions</th>
   </tr></thead>
   <tbody>
   {{range .Templates}}
@@ -1417,6 +1549,7 @@ func (s *Server) routes() {
       <td><pre style="margin:0;white-space:pre-wrap;">{{index . "summary"}}</pre></td>
       <td>{{index . "project"}}</td>
       <td>{{index . "tags"}}</td>
+      <td>{{index $.Repeats (index . "id")}}</td>
       <td>
         <form method="get" action="/tasks/new" style="display:inline">
           <input type="hidden" name="template" value="{{index . "id"}}" />
@@ -1428,6 +1561,7 @@ func (s *Server) routes() {
          · <form method="post" action="/templates/{{index . "id"}}/delete" style="display:inline" onsubmit="return confirm('Delete this template?');">
            <button type="submit">delete</button>
          </form>
+         · <a href="/recurrence/edit?template={{index . "id"}}" title="Create this template as a task on a schedule">repeat</a>
       </td>
     </tr>
   {{end}}
@@ -1437,10 +1571,20 @@ func (s *Server) routes() {
 <p>No templates found.</p>
 {{end}}
 `)
-		uname, _ := auth.UsernameFromRequest(r)
+		// Wiederholungsregeln je Template-ID
+		repeats := map[string]string{}
+		if rules, err := s.runner.RecurrenceRules(username); err == nil {
+			for _, rule := range rules {
+				if rule.Template != "" {
+					repeats[rule.Template] = rule.Describe()
+				}
+			}
+		}
+		uname := s.repoKey(r)
 		show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 		_ = t.Execute(w, map[string]any{
 			"Templates":   templates,
+			"Repeats":     repeats,
 			"Active":      activeFromPath(r.URL.Path),
 			"Flash":       s.getFlash(r),
 			"ShowCmdLog":  show,
@@ -1453,7 +1597,7 @@ func (s *Server) routes() {
 
 	// Template erstellen (Form)
 	s.mux.HandleFunc("/templates/new", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		// Fetch existing projects and tags
 		projRes := s.runner.Run(username, 5_000_000_000, "show-projects")
 		tagRes := s.runner.Run(username, 5_000_000_000, "show-tags")
@@ -1499,7 +1643,7 @@ func (s *Server) routes() {
   </div>
  </form>
         `)
-		uname, _ := auth.UsernameFromRequest(r)
+		uname := s.repoKey(r)
 		show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 		_ = t.Execute(w, map[string]any{
 			"Active":      activeFromPath(r.URL.Path),
@@ -1523,7 +1667,7 @@ func (s *Server) routes() {
 		templateID := parts[1]
 		action := parts[2]
 
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 
 		// GET /templates/{id}/edit - Bearbeitungsformular anzeigen
 		if action == "edit" && r.Method == http.MethodGet {
@@ -1608,7 +1752,7 @@ func (s *Server) routes() {
   </div>
 </form>
 `)
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 
 			// Parse due date to YYYY-MM-DD format if it's a date
@@ -1749,7 +1893,7 @@ func (s *Server) routes() {
 	s.mux.HandleFunc("/context", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 			res := s.runner.Run(username, 5_000_000_000, "context")
 			s.cmdStore.Append(username, "Show context", []string{"context"})
 			if res.Err != nil && !res.TimedOut {
@@ -1767,7 +1911,7 @@ func (s *Server) routes() {
     <button type="submit" name="clear" value="1">Clear</button>
   </div>
 </form>`)
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 			_ = t.Execute(w, map[string]any{
 				"Out":         strings.TrimSpace(res.Stdout),
@@ -1784,7 +1928,7 @@ func (s *Server) routes() {
 				http.Error(w, "invalid form", http.StatusBadRequest)
 				return
 			}
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 			if r.FormValue("clear") == "1" {
 				res := s.runner.Run(username, 5_000_000_000, "context", "none")
 				s.cmdStore.Append(username, "Clear context", []string{"context", "none"})
@@ -1818,7 +1962,7 @@ func (s *Server) routes() {
 
 	// Task erstellen (Form)
 	s.mux.HandleFunc("/tasks/new", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		// Fetch existing projects and tags
 		projRes := s.runner.Run(username, 5_000_000_000, "show-projects")
 		tagRes := s.runner.Run(username, 5_000_000_000, "show-tags")
@@ -1914,7 +2058,7 @@ func (s *Server) routes() {
   <div style="margin-top:8px;"><button type="submit">Create</button></div>
  </form>
         `)
-		uname, _ := auth.UsernameFromRequest(r)
+		uname := s.repoKey(r)
 		show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 		_ = t.Execute(w, map[string]any{
 			"Active": activeFromPath(r.URL.Path), "Projects": projects, "Tags": tags, "Templates": templates, "SelectedTemplate": selectedTemplate,
@@ -1990,7 +2134,8 @@ func (s *Server) routes() {
 		if templateID := strings.TrimSpace(r.FormValue("template")); templateID != "" {
 			args = append(args, "template:"+templateID)
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		change := s.beginTaskChange(r)
 		res := s.runner.Run(username, 10_000_000_000, args...) // 10s
 		s.cmdStore.Append(username, "New task", append([]string{"add"}, args[1:]...))
 		if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
@@ -1998,6 +2143,7 @@ func (s *Server) routes() {
 			http.Redirect(w, r, "/tasks/new", http.StatusSeeOther)
 			return
 		}
+		change.publishCreated("add")
 		s.setFlash(w, "success", "Task created")
 		s.autoSync(username)
 		http.Redirect(w, r, "/open?html=1", http.StatusSeeOther)
@@ -2005,10 +2151,20 @@ func (s *Server) routes() {
 
 	// Task Aktionen: /tasks/{id}/start|stop|done|remove|log|note
 	// Open URLs: /tasks/{id}/open
+	s.mux.HandleFunc("/tasks/removed", s.handleRemovedTasks)
 	s.mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
 		applog.Infof("/tasks: %s %s", r.Method, r.URL.Path)
 		// Parse path parts
 		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		// Task-Historie aus dem Git-Log
+		if len(parts) == 3 && parts[0] == "tasks" && parts[2] == "history" {
+			s.handleTaskHistory(w, r, strings.TrimSpace(parts[1]))
+			return
+		}
+		if len(parts) == 4 && parts[0] == "tasks" && parts[2] == "history" && parts[3] == "restore" {
+			s.handleTaskRestore(w, r, strings.TrimSpace(parts[1]))
+			return
+		}
 		// Handle task actions via GET or POST first (start|stop|done|remove|log)
 		if len(parts) == 3 && parts[0] == "tasks" {
 			act := strings.TrimSpace(parts[2])
@@ -2018,38 +2174,32 @@ func (s *Server) routes() {
 					http.NotFound(w, r)
 					return
 				}
-				username, _ := auth.UsernameFromRequest(r)
+				username := s.repoKey(r)
+				warning := ""
+				if act == "done" {
+					warning = s.dependentsWarning(username, []string{id})
+				}
+				change := s.beginTaskChange(r)
 				res := s.runner.Run(username, 10*time.Second, act, id)
 				if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
 					applog.Warnf("/tasks action failed: %s %s code=%d timeout=%v err=%v", act, id, res.ExitCode, res.TimedOut, res.Err)
 					s.setFlash(w, "error", "Task action failed")
 				} else {
 					applog.Infof("/tasks action ok: %s %s", act, id)
+					if act == "log" {
+						change.publishCreated(act)
+					} else {
+						change.publish(act, id)
+					}
 					var token string
 					if act == "start" || act == "stop" {
-						if m, _, err := music.LoadForUser(s.cfg, username); err == nil && m.Tasks != nil {
-							if tm, ok := m.Tasks[id]; ok && tm.Type == "radio" && tm.URL != "" {
-								if act == "start" {
-									// include id and persisted volume/muted in token (always send volume to restore exact state)
-									token = "__MUSIC_START__" + tm.Name + "|" + tm.URL + "|id=" + id + "|vol=" + strconv.FormatFloat(float64(tm.Volume), 'f', 2, 32)
-									if tm.Muted {
-										token += "|muted=1"
-									}
-									applog.Infof("music token set for task %s start: %s", id, tm.URL)
-								} else {
-									token = "__MUSIC_STOP__"
-									applog.Infof("music token set for task %s stop", id)
-								}
-							} else {
-								applog.Debugf("no music mapping for task %s or URL empty", id)
-							}
-						} else if err != nil {
-							applog.Warnf("loading music map failed for %s: %v", username, err)
-						}
+						token = s.musicToken(username, act, id)
 					}
 					msg := "Task action applied"
 					if token != "" {
 						s.setFlash(w, "success", token+"\n"+msg)
+					} else if warning != "" {
+						s.setFlash(w, "warning", msg+". "+warning)
 					} else {
 						s.setFlash(w, "success", msg)
 					}
@@ -2067,7 +2217,7 @@ func (s *Server) routes() {
 				http.NotFound(w, r)
 				return
 			}
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 			res := s.runner.Run(username, 5*time.Second, "export")
 
 			if res.Err != nil || res.ExitCode != 0 {
@@ -2118,7 +2268,7 @@ func (s *Server) routes() {
 <p><a href="/open?html=1">Back to tasks</a></p>
 `)
 
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 			_ = tpl.Execute(w, map[string]any{
 				"ID":          id,
@@ -2141,7 +2291,7 @@ func (s *Server) routes() {
 				http.NotFound(w, r)
 				return
 			}
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 
 			var task map[string]any
 
@@ -2201,6 +2351,35 @@ func (s *Server) routes() {
 			tagRes := s.runner.Run(username, 5_000_000_000, "show-tags")
 			projects := parseProjectsFromOutput(projRes.Stdout)
 			tags := parseTagsFromOutput(tagRes.Stdout)
+			// Zuständigkeits-Tags (+@user) werden über die Assignee-Auswahl gesetzt, nicht als Checkbox
+			plainTags := tags[:0]
+			for _, tg := range tags {
+				if !strings.HasPrefix(strings.TrimPrefix(tg, "+"), assigneePrefix) {
+					plainTags = append(plainTags, tg)
+				}
+			}
+			tags = plainTags
+			assignee := ""
+			if as := taskAssignees(task); len(as) > 0 {
+				assignee = as[0]
+			}
+			// Abhängigkeiten: gespeicherte Blocker sind editierbar, Verweise aus Notizen werden nur angezeigt
+			var blockedBy, notesBlockers, blocks string
+			if g, err := s.loadDepGraph(username); err == nil {
+				if uuid := g.lookup(strings.ToLower(str(firstOf(task, "uuid", "UUID")))); uuid != "" {
+					var stored []string
+					for _, b := range uniqueSorted(g.stored[uuid]) {
+						if g.isOpen(b) {
+							stored = append(stored, b)
+						}
+					}
+					blockedBy = g.refs(stored)
+					notesBlockers = g.refs(uniqueSorted(g.fromNotes[uuid]))
+					blocks = g.refs(g.openDependents(uuid))
+				}
+			} else {
+				applog.Warnf("task %s: loading dependencies failed: %v", id, err)
+			}
 
 			// Parse task data
 			summary := trimQuotes(str(firstOf(task, "summary", "Summary", "description", "Description")))
@@ -2246,16 +2425,31 @@ func (s *Server) routes() {
 
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			t := template.Must(s.layoutTpl.Clone())
-			// Load existing music mapping for this task
-			mm, _, _ := music.LoadForUser(s.cfg, username)
-			var mtype, mname, murl, mpath string
-			if mm != nil {
-				if tm, ok := mm.Tasks[id]; ok {
-					mtype, mname, murl, mpath = tm.Type, tm.Name, tm.URL, tm.Path
+			// Load existing music mapping for this task (own entry; otherwise show the inherited tag/project rule)
+			var mtype, mname, murl, mpath, mrule string
+			var mshuffle bool
+			ref := music.TaskRef{ID: id, UUID: str(firstOf(task, "uuid", "UUID")), Project: project}
+			for tag := range existingTags {
+				ref.Tags = append(ref.Tags, tag)
+			}
+			if mm, err := s.musicMap(username, nil); err == nil {
+				if tm, rule, ok := mm.Lookup(ref); ok {
+					if strings.HasPrefix(rule, "tag:") || strings.HasPrefix(rule, "project:") {
+						mrule = rule + " → " + firstNonEmptyString(tm.Name, tm.Type)
+					} else {
+						mtype, mname, murl, mpath, mshuffle = tm.Type, tm.Name, tm.URL, tm.Path, tm.Shuffle
+					}
 				}
 			}
+			_, hasMusicRoot := s.musicRoot(r)
+			// zuletzt im Radio gespielte Titel (ICY)
+			played, _ := s.playing.played().List(username, ref.UUID)
+			if len(played) > 10 {
+				played = played[:10]
+			}
 			_, _ = t.New("content").Parse(`
 <h2>Edit task #{{.TaskID}}</h2>
+<p><a href="/tasks/{{.TaskID}}/history">History</a></p>
 <form method="post" action="/tasks/{{.TaskID}}/edit">
   <input type="hidden" name="return_to" value="{{.Referer}}"/>
   <div><label>Summary: <input name="summary" value="{{.Summary}}" required style="width:60%"></label></div>
@@ -2293,6 +2487,21 @@ func (s *Server) routes() {
       <label>Add tags (comma-separated): <input name="tags" placeholder="additional tags"></label>
     </div>
   </div>
+  <div>
+    <label>Assignee:
+      <select name="assignee">
+        <option value="">(unassigned)</option>
+        {{range .Members}}<option value="{{.}}" {{if eq $.Assignee .}}selected{{end}}>@{{.}}</option>{{end}}
+        {{if and .Assignee (not .AssigneeIsMember)}}<option value="{{.Assignee}}" selected>@{{.Assignee}}</option>{{end}}
+      </select>
+    </label>
+  </div>
+  <div>
+    <label>Blocked by: <input name="blockedBy" value="{{.BlockedBy}}" placeholder="#3, #7"></label>
+    {{if .NotesBlockers}}<span style="margin-left:8px;color:#57606a;">from notes: {{.NotesBlockers}}</span>{{end}}
+    {{if .Blocks}}<span style="margin-left:8px;color:#57606a;">blocking: {{.Blocks}}</span>{{end}}
+    <a href="/recurrence/edit?task={{.TaskID}}" style="margin-left:12px;" title="Create copies of this task on a schedule">Repeat…</a>
+  </div>
   <div>
     <label>Due:</label>
     <input type="date" name="dueDate" value="{{.DueDate}}" />
@@ -2305,11 +2514,39 @@ func (s *Server) routes() {
     </label>
     {{if .Notes}}
     <div style="margin-top:8px;">
-      <strong>Preview:</strong>
-      <div class="notes-content">{{renderMarkdown .Notes}}</div>
+      <strong>Preview:</strong>{{if .ChecklistTotal}} <span id="checklist-progress">{{.ChecklistDone}}/{{.ChecklistTotal}} done</span>{{end}}
+      <div class="notes-content" id="notes-preview">{{renderChecklistMarkdown .Notes}}</div>
     </div>
     {{end}}
   </div>
+  <script>
+  (function(){
+    // Checklisten-Einträge direkt abhaken; gespeichert wird sofort, das Notizfeld zieht nach
+    var preview = document.getElementById('notes-preview');
+    var ta = document.querySelector('textarea[name="notes"]');
+    var progress = document.getElementById('checklist-progress');
+    if(!preview || !ta) return;
+    preview.addEventListener('change', function(ev){
+      var cb = ev.target;
+      if(!cb.classList || !cb.classList.contains('checklist-item')) return;
+      var body = new URLSearchParams();
+      body.set('csrf_token', '{{.CSRFToken}}');
+      body.set('index', cb.getAttribute('data-index'));
+      body.set('checked', cb.checked ? '1' : '0');
+      cb.disabled = true;
+      fetch('/tasks/{{.TaskID}}/checklist', {method:'POST', body: body, headers: {'Content-Type':'application/x-www-form-urlencoded'}})
+        .then(function(r){ if(!r.ok){ throw new Error(r.status); } return r.json(); })
+        .then(function(d){
+          // ungespeicherte Änderungen im Notizfeld nicht überschreiben
+          if(ta.value === ta.defaultValue){ ta.value = d.notes; }
+          ta.defaultValue = d.notes;
+          if(progress){ progress.textContent = d.done + '/' + d.total + ' done'; }
+        })
+        .catch(function(){ cb.checked = !cb.checked; alert('Saving the checklist failed'); })
+        .then(function(){ cb.disabled = false; });
+    });
+  })();
+  </script>
   <fieldset style="margin-top:12px;">
     <legend>Music (radio station or local folder)</legend>
     <div>
@@ -2317,11 +2554,17 @@ func (s *Server) routes() {
         <select name="music_type">
           <option value="">(none)</option>
           <option value="radio" {{if eq .MusicType "radio"}}selected{{end}}>radio</option>
+          <option value="folder" {{if eq .MusicType "folder"}}selected{{end}} {{if not .HasMusicRoot}}disabled title="No music folder configured (musicRoots)"{{end}}>folder</option>
         </select>
       </label>
     </div>
     <div style="margin-top:6px;"><label>Name: <input name="music_name" value="{{.MusicName}}" placeholder="Station or folder label"><button type="button" id="music_name_search" style="margin-left:6px;">Search</button></label></div>
     <div style="margin-top:6px;"><label>Stream URL: <input name="music_url" value="{{.MusicURL}}" style="width:60%" placeholder="https://… (for type=radio)"></label></div>
+    <div style="margin-top:6px;"><label>Folder: <input name="music_path" value="{{.MusicPath}}" style="width:40%" placeholder="focus/lofi (for type=folder, relative to your music folder)"></label>
+      <label style="margin-left:8px;"><input type="checkbox" name="music_shuffle" value="1" {{if .MusicShuffle}}checked{{end}}/> shuffle</label>
+      {{if and .HasMusicRoot .MusicPath}} <a href="/music/playlist?path={{.MusicPath}}" title="M3U playlist for external players">playlist.m3u</a>{{end}}</div>
+    <div style="margin-top:6px;color:#57606a;">{{if .MusicRule}}Without an own entry this task plays <code>{{.MusicRule}}</code>. {{end}}<a href="/music/rules">Music rules for tags and projects</a></div>
+    {{if .MusicPlayed}}<details style="margin-top:6px;"><summary>Recently played</summary><ul>{{range .MusicPlayed}}<li><span style="color:#57606a;">{{.Time.Local.Format "2006-01-02 15:04"}}</span> {{.Title}}{{if .Station}} <span style="color:#57606a;">({{.Station}})</span>{{end}}</li>{{end}}</ul></details>{{end}}
     <ul id="music_search_results" style="max-height:160px; overflow:auto; border:1px solid #ddd; padding:6px; margin-top:6px;"></ul>
   </fieldset>
   <script>
@@ -2367,8 +2610,10 @@ func (s *Server) routes() {
 			if referer == "" {
 				referer = "/open?html=1"
 			}
+			csrfToken := s.ensureCSRFToken(w, r)
+			checklistDone, checklistTotal := checklistProgress(notes)
 
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 			_ = t.Execute(w, map[string]any{
 				"TaskID":             id,
@@ -2382,11 +2627,24 @@ func (s *Server) routes() {
 				"Projects":           projects,
 				"Tags":               tags,
 				"ExistingTags":       existingTags,
+				"Assignee":           assignee,
+				"AssigneeIsMember":   s.isRepoMember(username, assignee),
+				"Members":            config.RepoUsers(s.cfg, username),
+				"BlockedBy":          blockedBy,
+				"NotesBlockers":      notesBlockers,
+				"Blocks":             blocks,
+				"ChecklistDone":      checklistDone,
+				"ChecklistTotal":     checklistTotal,
+				"CSRFToken":          csrfToken,
 				"Referer":            referer,
 				"MusicType":          mtype,
 				"MusicName":          mname,
 				"MusicURL":           murl,
 				"MusicPath":          mpath,
+				"MusicShuffle":       mshuffle,
+				"MusicRule":          mrule,
+				"MusicPlayed":        played,
+				"HasMusicRoot":       hasMusicRoot,
 				"Active":             activeFromPath(r.URL.Path),
 				"ShowCmdLog":         show,
 				"CmdEntries":         entries,
@@ -2397,6 +2655,12 @@ func (s *Server) routes() {
 			return
 		}
 
+		// POST /tasks/{id}/checklist - Checklisten-Eintrag in den Notizen abhaken
+		if len(parts) == 3 && parts[0] == "tasks" && parts[2] == "checklist" {
+			s.handleChecklistToggle(w, r, parts[1])
+			return
+		}
+
 		// POST /tasks/{id}/edit - Task aktualisieren
 		if len(parts) == 3 && parts[0] == "tasks" && parts[2] == "edit" && r.Method == http.MethodPost {
 			id := parts[1]
@@ -2408,7 +2672,7 @@ func (s *Server) routes() {
 				http.Error(w, "invalid form", http.StatusBadRequest)
 				return
 			}
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 
 			summary := strings.TrimSpace(r.FormValue("summary"))
 			if summary == "" {
@@ -2432,6 +2696,7 @@ func (s *Server) routes() {
 			mname := strings.TrimSpace(r.FormValue("music_name"))
 			murl := strings.TrimSpace(r.FormValue("music_url"))
 			mpath := strings.TrimSpace(r.FormValue("music_path"))
+			mshuffle := r.FormValue("music_shuffle") == "1"
 
 			// Build modify args: dstask <id> modify <summary> project: priority due: +tags
 			args := []string{id, "modify"}
@@ -2478,6 +2743,7 @@ func (s *Server) routes() {
 				}
 			}
 
+			change := s.beginTaskChange(r)
 			res := s.runner.Run(username, 10*time.Second, args...)
 			s.cmdStore.Append(username, "Edit task", args)
 			if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
@@ -2492,6 +2758,23 @@ func (s *Server) routes() {
 				http.Redirect(w, r, "/tasks/"+id+"/edit", http.StatusSeeOther)
 				return
 			}
+			// Zuständigen ändern (eigenes Event, Delegation wird vermerkt)
+			assignErr := ""
+			if _, has := r.Form["assignee"]; has {
+				actor, _ := auth.UsernameFromRequest(r)
+				if _, err := s.assignTask(username, actor, id, strings.TrimSpace(r.FormValue("assignee"))); err != nil {
+					applog.Warnf("task %s: assign failed: %v", id, err)
+					assignErr = err.Error()
+				}
+			}
+			// Blocker in dependencies.yaml übernehmen (z. B. Zyklen werden abgelehnt)
+			depsErr := ""
+			if _, has := r.Form["blockedBy"]; has {
+				if err := s.updateBlockers(username, id, r.FormValue("blockedBy")); err != nil {
+					applog.Warnf("task %s: updating dependencies failed: %v", id, err)
+					depsErr = err.Error()
+				}
+			}
 			// Update notes if provided
 			notesUpdateSuccess := true
 			if strings.TrimSpace(notes) != "" {
@@ -2508,26 +2791,35 @@ func (s *Server) routes() {
 			}
 			// Save music mapping based on form
 			if mtype != "" {
-				if mm, _, err := music.LoadForUser(s.cfg, username); err == nil {
-					if mm.Tasks == nil {
-						mm.Tasks = map[string]music.TaskMusic{}
-					}
-					tm := music.TaskMusic{Type: mtype, Name: mname}
+				// unter der UUID speichern, damit die Zuordnung eine neue Task-ID übersteht
+				refs := s.taskRefs(username)
+				if mm, err := s.musicMap(username, refs); err == nil {
+					key := musicRuleKey(refs, id)
+					// Lautstärke und Stummschaltung aus dem Player beibehalten
+					prev, _ := mm.Entry(key)
+					tm := music.TaskMusic{Type: mtype, Name: mname, Volume: prev.Volume, Muted: prev.Muted}
 					if mtype == "radio" {
 						tm.URL = murl
 					} else if mtype == "folder" {
 						tm.Path = mpath
+						tm.Shuffle = mshuffle
 					}
-					mm.Tasks[id] = tm
-					if _, err := music.SaveForUser(s.cfg, username, mm); err != nil {
+					if !mm.Set(key, tm) {
+						applog.Warnf("music mapping for task %s not saved: no uuid for this id", id)
+					} else if _, err := music.SaveForUser(s.cfg, username, mm); err != nil {
 						applog.Warnf("failed to save music mapping: %v", err)
 					}
 				} else {
 					applog.Warnf("failed to load music map: %v", err)
 				}
 			}
+			change.publish("edit", id)
 			s.autoSync(username)
-			if notesUpdateSuccess {
+			if assignErr != "" {
+				s.setFlash(w, "error", "Task updated, but the assignment was not saved: "+assignErr)
+			} else if depsErr != "" {
+				s.setFlash(w, "warning", "Task updated, but dependencies were not saved: "+depsErr)
+			} else if notesUpdateSuccess {
 				s.setFlash(w, "success", "Task updated successfully")
 			} else {
 				s.setFlash(w, "warning", "Task updated, but notes update may have failed. Check the task to verify.")
@@ -2571,7 +2863,7 @@ func (s *Server) routes() {
   <div><label>Note (for action "note"):<br><textarea name="note" rows="3" cols="40"></textarea></label></div>
   <div><button type="submit">Execute</button></div>
 </form>`)
-			uname, _ := auth.UsernameFromRequest(r)
+			uname := s.repoKey(r)
 			show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 			_ = t.Execute(w, map[string]any{
 				"Active":     activeFromPath(r.URL.Path),
@@ -2601,7 +2893,8 @@ func (s *Server) routes() {
 			http.Error(w, "id/action required", http.StatusBadRequest)
 			return
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		change := s.beginTaskChange(r)
 		timeout := 10 * time.Second
 		var res dstask.Result
 		switch action {
@@ -2621,6 +2914,11 @@ func (s *Server) routes() {
 			http.Error(w, res.Stderr, http.StatusBadRequest)
 			return
 		}
+		if action == "log" {
+			change.publishCreated(action)
+		} else {
+			change.publish(action, id)
+		}
 		s.setFlash(w, "success", "Task action applied")
 		s.autoSync(username)
 		http.Redirect(w, r, "/open?html=1", http.StatusSeeOther)
@@ -2683,14 +2981,16 @@ func (s *Server) routes() {
 				args = append(args, t)
 			}
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		// dstask modify erwartet Syntax: dstask <id> modify ... (laut usage)
 		full := append([]string{id}, args...)
+		change := s.beginTaskChange(r)
 		res := s.runner.Run(username, 10*time.Second, full...)
 		if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
 			http.Error(w, res.Stderr, http.StatusBadRequest)
 			return
 		}
+		change.publish("modify", id)
 		s.setFlash(w, "success", "Task modified")
 		s.autoSync(username)
 		http.Redirect(w, r, "/open?html=1", http.StatusSeeOther)
@@ -2698,7 +2998,7 @@ func (s *Server) routes() {
 
 	// Version anzeigen
 	s.mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
 		res := s.runner.Run(username, 5_000_000_000, "version")
 		s.cmdStore.Append(username, "Show version", []string{"version"})
 		if res.Err != nil && !res.TimedOut {
@@ -2718,7 +3018,7 @@ func (s *Server) routes() {
 		t := template.Must(s.layoutTpl.Clone())
 		_, _ = t.New("content").Parse(`<h2>dstask version</h2>
 <pre style="white-space:pre-wrap;">{{.Out}}</pre>`)
-		uname, _ := auth.UsernameFromRequest(r)
+		uname := s.repoKey(r)
 		show, entries, moreURL, canMore, ret := s.footerData(r, uname)
 		_ = t.Execute(w, map[string]any{
 			"Out":         out,
@@ -2743,7 +3043,7 @@ func (s *Server) routes() {
 <form method="post" action="/sync"><button type="submit">Sync now</button></form>`)
 			_ = t.Execute(w, nil)
 		case http.MethodPost:
-			username, _ := auth.UsernameFromRequest(r)
+			username := s.repoKey(r)
 			applog.Infof("/sync POST from %s", username)
 			if dirty, err := dstask.IsRepoDirty(s.cfg, username); err == nil && dirty {
 				s.setFlash(w, "warning", "Local .dstask repository has uncommitted changes. Please commit or pull before syncing again.")
@@ -2792,9 +3092,14 @@ func (s *Server) routes() {
 				applog.Warnf("/sync: upstream missing; automatic setup failed: %v", err)
 			}
 			applog.Infof("/sync: starting dstask sync for %s", username)
-			res := s.runner.Run(username, 30_000_000_000, "sync") // 30s
+			res := s.runSync(username, "Sync")
 			applog.Infof("/sync: finished for %s: code=%d timeout=%v err=%v", username, res.ExitCode, res.TimedOut, res.Err)
-			s.cmdStore.Append(username, "Sync", []string{"sync"})
+			// Merge-Konflikte nach fehlgeschlagenem Pull: Auflösungs-Dialog anbieten
+			if (res.Err != nil || res.ExitCode != 0) && s.syncConflictsExist(username) {
+				s.setFlash(w, "warning", "Sync stopped because of merge conflicts. Please resolve them below.")
+				http.Redirect(w, r, "/sync/conflicts", http.StatusSeeOther)
+				return
+			}
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			t := template.Must(s.layoutTpl.Clone())
 			// Erkennung gängiger Git-Fehler für hilfreiche Hinweise
@@ -2825,6 +3130,15 @@ git push -u origin master</pre>`
 		}
 	})
 
+	// Sync-Status (Scheduler, letzter Erfolg/Fehler, ahead/behind) als JSON
+	s.mux.HandleFunc("/sync/status", s.handleSyncStatus)
+
+	// Merge-Konflikte nach Sync auflösen
+	s.mux.HandleFunc("/sync/conflicts", s.handleSyncConflicts)
+	s.mux.HandleFunc("/sync/conflicts/resolve", s.handleSyncConflictResolve)
+	s.mux.HandleFunc("/sync/conflicts/finish", s.handleSyncConflictFinish)
+	s.mux.HandleFunc("/sync/conflicts/abort", s.handleSyncConflictAbort)
+
 	// Remote setzen
 	s.mux.HandleFunc("/sync/set-remote", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -2843,7 +3157,8 @@ git push -u origin master</pre>`
 			http.Error(w, "url required", http.StatusBadRequest)
 			return
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		url = s.extractURLCredentials(username, url)
 		applog.Infof("/sync/set-remote from %s: url=%s", username, url)
 		if err := s.runner.GitSetRemoteOrigin(username, url); err != nil {
 			s.setFlash(w, "error", "Remote konnte nicht gesetzt werden: "+stripANSI(err.Error()))
@@ -2877,7 +3192,8 @@ git push -u origin master</pre>`
 			http.Error(w, "url required", http.StatusBadRequest)
 			return
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		url = s.extractURLCredentials(username, url)
 		applog.Infof("/sync/clone-remote from %s: url=%s", username, url)
 		if err := s.runner.GitCloneRemote(username, url); err != nil {
 			s.setFlash(w, "error", "Klonen fehlgeschlagen: "+stripANSI(err.Error()))
@@ -2894,19 +3210,73 @@ git push -u origin master</pre>`
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 	})
 
+	// Aktivitäts-Feed aus dem Git-Log
+	s.mux.HandleFunc("/activity", s.handleActivity)
+
+	// Backup/Restore des .dstask-Repositories
+	s.mux.HandleFunc("/backup", s.handleBackupPage)
+	s.mux.HandleFunc("/backup/download", s.handleBackupDownload)
+	s.mux.HandleFunc("/backup/restore", s.handleBackupRestore)
+	s.mux.HandleFunc("/backup/rollback", s.handleBackupRollback)
+
+	// Geteilte Repos: eigene und Team-Aufgaben nach Zuständigkeit (+@user)
+	s.mux.HandleFunc("/my", s.handleMyTasks)
+	s.mux.HandleFunc("/team", s.handleTeamTasks)
+	s.mux.HandleFunc("/delegated", s.handleDelegatedTasks)
+	s.mux.HandleFunc("/notifications", s.handleNotifications)
+	s.mux.HandleFunc("/notifications/read", s.handleNotificationsRead)
+	s.mux.HandleFunc("/notifications/settings", s.handleNotificationSettings)
+	s.mux.HandleFunc("/webhooks", s.handleWebhooks)
+	s.mux.HandleFunc("/webhooks/retry", s.handleWebhookRetry)
+	// Web Push (VAPID-Schlüssel und Abonnements im Datenverzeichnis)
+	s.mux.HandleFunc("/push/key", s.handlePushKey)
+	s.mux.HandleFunc("/push/subscribe", s.handlePushSubscribe)
+	s.mux.HandleFunc("/push/unsubscribe", s.handlePushUnsubscribe)
+	s.mux.HandleFunc("/push/test", s.handlePushTest)
+	s.mux.HandleFunc("/push/sw.js", s.handlePushServiceWorker)
+
+	// Zeiterfassung (timelog.yaml, Abgleich bei start/stop über den Event-Bus)
+	s.mux.HandleFunc("/reports/time", s.handleTimeReport)
+	s.mux.HandleFunc("/pomodoro", s.handlePomodoro)
+	s.mux.HandleFunc("/pomodoro/start", s.handlePomodoroStart)
+	s.mux.HandleFunc("/pomodoro/next", s.handlePomodoroNext)
+	s.mux.HandleFunc("/pomodoro/cancel", s.handlePomodoroCancel)
+
+	// Abhängigkeiten zwischen Tasks (dependencies.yaml)
+	s.mux.HandleFunc("/projects/graph", s.handleDependencyGraph)
+
+	// Wiederkehrende Aufgaben (recurrence.yaml, Scheduler in StartBackgroundJobs)
+	s.mux.HandleFunc("/recurrence", s.handleRecurrence)
+	s.mux.HandleFunc("/recurrence/edit", s.handleRecurrenceEdit)
+	s.mux.HandleFunc("/recurrence/delete", s.handleRecurrenceDelete)
+
+	// Repo-Umschalter (mehrere benannte Repos pro Nutzer)
+	s.mux.HandleFunc("/repos", s.handleRepos)
+	s.mux.HandleFunc("/repos/select", s.handleRepoSelect)
+
+	// Repository-Dashboard
+	s.mux.HandleFunc("/repo", s.handleRepo)
+	s.mux.HandleFunc("/repo/fix", s.handleRepoFix)
+	s.mux.HandleFunc("/repo/ssh", s.handleSSHKeys)
+	s.mux.HandleFunc("/repo/ssh/generate", s.handleSSHKeyGenerate)
+	s.mux.HandleFunc("/repo/ssh/known-hosts", s.handleSSHKnownHosts)
+	s.mux.HandleFunc("/repo/https", s.handleHTTPSCredentials)
+
 	// Undo last action
 	s.mux.HandleFunc("/undo", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		username, _ := auth.UsernameFromRequest(r)
+		username := s.repoKey(r)
+		change := s.beginTaskChange(r)
 		res := s.runner.Run(username, 10*time.Second, "undo")
 		s.cmdStore.Append(username, "Undo last action", []string{"undo"})
 
 		if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
 			s.setFlash(w, "error", "Undo failed: "+res.Stderr)
 		} else {
+			change.publishChanged("undo")
 			s.setFlash(w, "success", "Last action undone")
 		}
 
@@ -2984,6 +3354,63 @@ func (s *Server) footerData(r *http.Request, username string) (show bool, entrie
 	return
 }
 
+// pageData ergänzt data um die Felder, die das Layout erwartet (Navigation, Flash, Footer).
+func (s *Server) pageData(r *http.Request, data map[string]any) map[string]any {
+	if data == nil {
+		data = map[string]any{}
+	}
+	uname := s.repoKey(r)
+	show, entries, moreURL, canMore, ret := s.footerData(r, uname)
+	data["Active"] = activeFromPath(r.URL.Path)
+	data["Flash"] = s.getFlash(r)
+	data["ShowCmdLog"] = show
+	data["CmdEntries"] = entries
+	data["MoreURL"] = moreURL
+	data["CanShowMore"] = canMore
+	data["ReturnURL"] = ret
+	return data
+}
+
+// musicToken liefert das Flash-Token, mit dem das Layout den Radio-Stream eines Tasks startet
+// (act "start") bzw. stoppt; leer, wenn dem Task kein Stream zugeordnet ist.
+func (s *Server) musicToken(username, act, id string) string {
+	tm, rule, ref, ok := s.taskMusicRef(username, id)
+	src := taskMusicSource(rule, tm)
+	if !ok || src == "" {
+		applog.Debugf("no music mapping for task %s or URL empty", id)
+		return ""
+	}
+	if act != "start" {
+		applog.Infof("music token set for task %s stop", id)
+		return "__MUSIC_STOP__"
+	}
+	// include rule key and persisted volume/muted in token (always send volume to restore exact state)
+	token := "__MUSIC_START__" + tm.Name + "|" + src + "|id=" + rule + "|vol=" + strconv.FormatFloat(float64(tm.Volume), 'f', 2, 32)
+	if tm.Muted {
+		token += "|muted=1"
+	}
+	if tm.Type == "folder" {
+		token += "|type=folder"
+	} else if ref.UUID != "" {
+		// für das Protokoll der gespielten Titel (/music/played)
+		token += "|task=" + strings.ToLower(ref.UUID)
+	}
+	applog.Infof("music token set for task %s start (%s): %s", id, rule, src)
+	return token
+}
+
+// taskMusicSource liefert, was der Player für die Regel abspielt: die Stream-URL (radio) bzw. die
+// Playlist des Ordners (folder); leer ohne abspielbare Zuordnung.
+func taskMusicSource(rule string, tm music.TaskMusic) string {
+	switch tm.Type {
+	case "radio":
+		return tm.URL
+	case "folder":
+		return "/music/playlist?key=" + url.QueryEscape(rule)
+	}
+	return ""
+}
+
 // flash support
 type flash struct{ Type, Text string }
 
@@ -3023,8 +3450,7 @@ func (s *Server) autoSync(username string) {
 		return
 	}
 	go func() {
-		res := s.runner.Run(username, 30*time.Second, "sync")
-		s.cmdStore.Append(username, "Auto sync", []string{"sync"})
+		res := s.runSync(username, "Auto sync")
 		if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
 			applog.Warnf("auto git sync failed for %s: code=%d timeout=%v err=%v stderr=%q", username, res.ExitCode, res.TimedOut, res.Err, truncate(res.Stderr, 200))
 		} else {
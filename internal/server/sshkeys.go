@@ -0,0 +1,125 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/gitauth"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// handleSSHKeys zeigt den SSH-Deploy-Key des Nutzers und die gepinnten Host-Keys.
+func (s *Server) handleSSHKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	csrfToken := s.ensureCSRFToken(w, r)
+	pub, err := gitauth.PublicKey(s.cfg, username)
+	if err != nil && !errors.Is(err, gitauth.ErrNoKey) {
+		applog.Warnf("/repo/ssh: reading public key for %s failed: %v", username, err)
+	}
+	hosts, err := gitauth.KnownHosts(s.cfg, username)
+	if err != nil {
+		applog.Warnf("/repo/ssh: reading known_hosts of %s failed: %v", username, err)
+	}
+	shared, err := gitauth.SharedKnownHosts(s.cfg)
+	if err != nil {
+		applog.Warnf("/repo/ssh: reading shared known_hosts failed: %v", err)
+	}
+	knownHostsPath, _ := gitauth.KnownHostsPath(s.cfg, username)
+	remoteURL, _ := s.runner.GitRemoteURL(s.repoKey(r))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>SSH deploy key</h2>
+<p>Git uses this key for SSH remotes (<code>git@host:owner/repo.git</code>, <code>ssh://…</code>) when cloning, syncing and pushing. Add the public key as a deploy key with write access on your git host.</p>
+{{if .PublicKey}}
+<p>Public key (<code>{{.Fingerprint}}</code>):</p>
+<textarea readonly rows="3" style="width:100%;font-family:monospace;" onclick="this.select();">{{.PublicKey}}</textarea>
+<form method="post" action="/repo/ssh/generate" style="margin-top:8px;" onsubmit="return confirm('Replace the current key? The old key stops working for this app.');">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <button type="submit">Generate new key</button>
+</form>
+{{else}}
+<p>No key has been generated yet.</p>
+<form method="post" action="/repo/ssh/generate">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <button type="submit">Generate ed25519 key</button>
+</form>
+{{end}}
+{{if .RemoteURL}}<p>Current remote: <code>{{.RemoteURL}}</code>{{if not .RemoteHost}} – not an SSH remote, the key is not used.{{end}}</p>{{end}}
+
+<h3>Known hosts</h3>
+<p>Host key checking: <code>{{.StrictMode}}</code>{{if eq .StrictMode "accept-new"}} – the host key is pinned on first contact{{else if eq .StrictMode "yes"}} – only hosts listed here are accepted{{else}} – host keys are not verified{{end}}. Your pinned keys apply to your git calls only. File: <code>{{.KnownHostsPath}}</code></p>
+{{if .KnownHosts}}
+<table>
+  <thead><tr><th>Hosts</th><th>Type</th><th>Fingerprint</th></tr></thead>
+  <tbody>
+  {{range .KnownHosts}}<tr><td>{{range $i, $h := .Hosts}}{{if $i}}, {{end}}<code>{{$h}}</code>{{end}}</td><td>{{.Type}}</td><td><code>{{.Fingerprint}}</code></td></tr>{{end}}
+  </tbody>
+</table>
+{{else}}
+<p>No host keys pinned yet.</p>
+{{end}}
+{{if .SharedKnownHosts}}
+<p>Trusted for all users (<code>{{.SharedKnownHostsPath}}</code>, maintained by the administrator):</p>
+<table>
+  <thead><tr><th>Hosts</th><th>Type</th><th>Fingerprint</th></tr></thead>
+  <tbody>
+  {{range .SharedKnownHosts}}<tr><td>{{range $i, $h := .Hosts}}{{if $i}}, {{end}}<code>{{$h}}</code>{{end}}</td><td>{{.Type}}</td><td><code>{{.Fingerprint}}</code></td></tr>{{end}}
+  </tbody>
+</table>
+{{end}}
+<form method="post" action="/repo/ssh/known-hosts" style="margin-top:8px;">
+  <input type="hidden" name="csrf_token" value="{{.CSRFToken}}"/>
+  <label>Pin host keys (known_hosts lines{{if .RemoteHost}}, e.g. output of <code>ssh-keyscan {{.RemoteHost}}</code>{{end}}):</label><br/>
+  <textarea name="lines" rows="4" style="width:100%;font-family:monospace;" required></textarea><br/>
+  <button type="submit">Add</button>
+</form>
+<p><a href="/repo/https">HTTPS credentials</a> · <a href="/repo">Back to repository</a></p>`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"PublicKey":            pub,
+		"Fingerprint":          gitauth.Fingerprint(pub),
+		"RemoteURL":            remoteURL,
+		"RemoteHost":           gitauth.HostFromRemote(remoteURL),
+		"StrictMode":           s.cfg.SSH.StrictMode(),
+		"KnownHostsPath":       knownHostsPath,
+		"KnownHosts":           hosts,
+		"CSRFToken":            csrfToken,
+		"SharedKnownHosts":     shared,
+		"SharedKnownHostsPath": gitauth.SharedKnownHostsPath(s.cfg),
+	}))
+}
+
+func (s *Server) handleSSHKeyGenerate(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	if _, err := gitauth.GenerateKey(s.cfg, username); err != nil {
+		applog.Warnf("/repo/ssh/generate: %v", err)
+		s.setFlash(w, "error", "Key generation failed: "+err.Error())
+	} else {
+		applog.Infof("generated SSH deploy key for %s", username)
+		s.setFlash(w, "success", "New SSH key generated. Add the public key to your git host.")
+	}
+	http.Redirect(w, r, "/repo/ssh", http.StatusSeeOther)
+}
+
+func (s *Server) handleSSHKnownHosts(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	username, _ := auth.UsernameFromRequest(r)
+	n, err := gitauth.AddKnownHosts(s.cfg, username, r.FormValue("lines"))
+	if err != nil {
+		s.setFlash(w, "error", err.Error())
+	} else {
+		s.setFlash(w, "success", "Pinned "+strconv.Itoa(n)+" host key(s).")
+	}
+	http.Redirect(w, r, "/repo/ssh", http.StatusSeeOther)
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// syncStatus hält das Ergebnis der letzten Syncs eines Nutzers (manuell, Auto-Sync oder Scheduler).
+type syncStatus struct {
+	LastRun     time.Time
+	LastSuccess time.Time
+	LastErrorAt time.Time
+	LastError   string
+	Failures    int
+	Running     bool
+	NextRun     time.Time
+	Paused      string // warum der Scheduler nicht synct (offener Merge, uncommittete Änderungen); leer = aktiv
+}
+
+var errSyncRunning = errors.New("sync already running")
+
+type syncTracker struct {
+	mu sync.Mutex
+	m  map[string]*syncStatus
+}
+
+func newSyncTracker() *syncTracker {
+	return &syncTracker{m: map[string]*syncStatus{}}
+}
+
+// begin markiert einen laufenden Sync; false, wenn für den Nutzer bereits einer läuft.
+func (t *syncTracker) begin(username string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.get(username)
+	if st.Running {
+		return false
+	}
+	st.Running = true
+	st.LastRun = time.Now()
+	return true
+}
+
+func (t *syncTracker) finish(username string, res dstask.Result) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.get(username)
+	st.Running = false
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		st.Failures++
+		st.LastErrorAt = time.Now()
+		st.LastError = truncate(stripANSI(firstNonEmptyString(res.Stderr, res.Stdout, errString(res.Err))), 300)
+		if res.TimedOut {
+			st.LastError = "timeout"
+		}
+		return
+	}
+	st.Failures = 0
+	st.LastSuccess = time.Now()
+	st.LastError = ""
+}
+
+// setPaused hält fest, warum der Scheduler aussetzt (leer = läuft wieder); true, wenn sich das geändert hat.
+func (t *syncTracker) setPaused(username, reason string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.get(username)
+	changed := st.Paused != reason
+	st.Paused = reason
+	return changed
+}
+
+func (t *syncTracker) setNext(username string, next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(username).NextRun = next
+}
+
+func (t *syncTracker) snapshot(username string) syncStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return *t.get(username)
+}
+
+func (t *syncTracker) get(username string) *syncStatus {
+	st, ok := t.m[username]
+	if !ok {
+		st = &syncStatus{}
+		t.m[username] = st
+	}
+	return st
+}
+
+// runSync führt `dstask sync` aus und hält das Ergebnis im Tracker fest.
+func (s *Server) runSync(username, context string) dstask.Result {
+	if !s.syncs.begin(username) {
+		applog.Debugf("sync for %s skipped (%s): already running", username, context)
+		return dstask.Result{Err: errSyncRunning, Stderr: errSyncRunning.Error(), ExitCode: -1}
+	}
+	started := time.Now()
+	res := s.runner.Run(username, 30*time.Second, "sync")
+	took := time.Since(started)
+	s.syncs.finish(username, res)
+	actor, _ := config.SplitRepoKey(username)
+	ok := res.Err == nil && res.ExitCode == 0 && !res.TimedOut
+	lastError := s.syncs.snapshot(username).LastError
+	if ok {
+		s.events.Publish(Event{Type: EventRepoSynced, Repo: username, Actor: actor, Action: "sync"})
+	} else {
+		s.events.Publish(Event{Type: EventSyncFailed, Repo: username, Actor: actor, Action: "sync", Summary: lastError})
+	}
+	// lange manuelle Syncs per Web Push melden (der Nutzer hat den Tab womöglich verlassen)
+	if context == "Sync" && took >= s.cfg.Push.LongSyncDuration() {
+		go s.pushSyncFinished(actor, username, ok, took, lastError)
+	}
+	s.cmdStore.Append(username, context, []string{"sync"})
+	return res
+}
+
+// StartBackgroundJobs startet periodische Jobs (Wiederholungen, Sync) für die angegebenen Nutzer.
+// Die Jobs enden, wenn ctx abgebrochen wird.
+func (s *Server) StartBackgroundJobs(ctx context.Context, usernames []string) {
+	go s.recurrenceLoop(ctx, usernames)
+	if s.webhooks.Enabled() {
+		go s.webhooks.Run(ctx)
+	}
+	go s.reminderLoop(ctx)
+	interval := s.cfg.Sync.IntervalDuration()
+	if interval <= 0 {
+		applog.Infof("background sync disabled (sync.interval not set)")
+		return
+	}
+	for _, u := range usernames {
+		go s.syncLoop(ctx, u, interval)
+	}
+	applog.Infof("background sync every %s for %d user(s)", interval, len(usernames))
+}
+
+func (s *Server) syncLoop(ctx context.Context, username string, interval time.Duration) {
+	for {
+		st := s.syncs.snapshot(username)
+		wait := nextSyncDelay(interval, s.cfg.Sync.JitterDuration(), s.cfg.Sync.MaxBackoffDuration(), st.Failures, rand.Int63)
+		s.syncs.setNext(username, time.Now().Add(wait))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		// ein offener Merge oder fremde Änderungen lassen jeden Sync scheitern: aussetzen und den Zustand
+		// melden, statt bei jedem Tick zu scheitern und sync.failed zu benachrichtigen
+		if reason := s.syncPauseReason(username); reason != "" {
+			if s.syncs.setPaused(username, reason) {
+				applog.Warnf("scheduled sync for %s paused: %s", username, reason)
+			}
+			continue
+		}
+		if s.syncs.setPaused(username, "") {
+			applog.Infof("scheduled sync for %s resumed", username)
+		}
+		res := s.runSync(username, "Scheduled sync")
+		if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+			applog.Warnf("scheduled sync failed for %s: code=%d timeout=%v err=%v", username, res.ExitCode, res.TimedOut, res.Err)
+		} else {
+			applog.Debugf("scheduled sync completed for %s", username)
+		}
+	}
+}
+
+// syncPauseReason liefert den Grund, aus dem ein geplanter Sync nicht laufen sollte (leer = kein Grund).
+func (s *Server) syncPauseReason(username string) string {
+	if s.runner.MergeInProgress(username) {
+		return "merge in progress, resolve the conflicts first"
+	}
+	if dirty, err := dstask.IsRepoDirty(s.cfg, username); err == nil && dirty {
+		return "uncommitted changes in the repository, commit or discard them first"
+	}
+	return ""
+}
+
+// nextSyncDelay berechnet die Wartezeit bis zum nächsten Sync: Intervall, nach Fehlern
+// exponentiell verlängert (höchstens maxBackoff), plus zufälliger Jitter.
+func nextSyncDelay(interval, jitter, maxBackoff time.Duration, failures int, rnd func() int64) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if failures > 0 && d > maxBackoff {
+		d = maxBackoff
+	}
+	if jitter > 0 {
+		d += time.Duration(rnd() % int64(jitter))
+	}
+	return d
+}
+
+// handleSyncStatus liefert den Sync-Status des angemeldeten Nutzers als JSON (für das Nav-Badge).
+func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := s.repoKey(r)
+	st := s.syncs.snapshot(username)
+	out := map[string]any{
+		"enabled":         s.cfg.Sync.IntervalDuration() > 0,
+		"intervalSeconds": int(s.cfg.Sync.IntervalDuration().Seconds()),
+		"running":         st.Running,
+		"failures":        st.Failures,
+		"lastRun":         timeOrNil(st.LastRun),
+		"lastSuccess":     timeOrNil(st.LastSuccess),
+		"lastErrorAt":     timeOrNil(st.LastErrorAt),
+		"lastError":       st.LastError,
+		"nextRun":         timeOrNil(st.NextRun),
+		"paused":          st.Paused,
+		"ahead":           nil,
+		"behind":          nil,
+	}
+	if ahead, behind, err := s.runner.GitAheadBehind(username); err == nil {
+		out["ahead"] = ahead
+		out["behind"] = behind
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, out)
+}
+
+func timeOrNil(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
+
+func firstNonEmptyString(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextSyncDelay_BackoffAndCap(t *testing.T) {
+	zero := func() int64 { return 0 }
+	if d := nextSyncDelay(10*time.Minute, 0, time.Hour, 0, zero); d != 10*time.Minute {
+		t.Fatalf("no failures: got %s", d)
+	}
+	if d := nextSyncDelay(10*time.Minute, 0, time.Hour, 2, zero); d != 40*time.Minute {
+		t.Fatalf("two failures: got %s", d)
+	}
+	if d := nextSyncDelay(10*time.Minute, 0, time.Hour, 5, zero); d != time.Hour {
+		t.Fatalf("expected cap at 1h, got %s", d)
+	}
+	if d := nextSyncDelay(10*time.Minute, 30*time.Second, time.Hour, 0, func() int64 { return int64(45 * time.Second) }); d != 10*time.Minute+15*time.Second {
+		t.Fatalf("jitter not applied modulo: got %s", d)
+	}
+}
+
+func TestSyncStatus_RecordsFailure(t *testing.T) {
+	home, _ := newGitTaskHome(t)
+	s := newTestServerWithStub(t, "/bin/false", home)
+	if res := s.runSync("admin", "Sync"); res.ExitCode == 0 {
+		t.Fatalf("expected failing sync from stub")
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/sync/status", nil)
+	req.SetBasicAuth("admin", "admin")
+	s.Handler().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	var st map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &st); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if st["enabled"] != false || st["running"] != false {
+		t.Fatalf("unexpected flags: %v", st)
+	}
+	if st["failures"] != float64(1) || st["lastErrorAt"] == nil || st["lastSuccess"] != nil {
+		t.Fatalf("failure not recorded: %v", st)
+	}
+}
+
+func TestRunSync_SkipsWhileRunning(t *testing.T) {
+	s := newTestServer(t)
+	if !s.syncs.begin("admin") {
+		t.Fatalf("begin should succeed")
+	}
+	res := s.runSync("admin", "Sync")
+	if res.Err != errSyncRunning {
+		t.Fatalf("expected errSyncRunning, got %v", res.Err)
+	}
+}
+
+func TestSyncLoop_PausesOnMergeOrDirtyTree(t *testing.T) {
+	home, repo := newGitTaskHome(t)
+	syncLog := filepath.Join(t.TempDir(), "sync.log")
+	s := newTestServerWithStub(t, writeShellStub(t, t.TempDir(), `echo "$@" >> `+syncLog+"\n"), home)
+	s.cfg.Sync.Jitter = "0s"
+	mergeHead := filepath.Join(repo, ".git", "MERGE_HEAD")
+	if err := os.WriteFile(mergeHead, []byte("0000000000000000000000000000000000000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(what string, ok func(syncStatus) bool) syncStatus {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			st := s.syncs.snapshot("admin")
+			if ok(st) {
+				return st
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: %+v", what, st)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.syncLoop(ctx, "admin", 5*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// offener Merge: kein Sync, keine Fehler, Zustand im Status
+	st := waitFor("not paused for merge", func(st syncStatus) bool { return strings.Contains(st.Paused, "merge") })
+	if _, err := os.Stat(syncLog); err == nil || st.Failures != 0 || !st.LastRun.IsZero() {
+		t.Fatalf("sync ran during a merge: %+v", st)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(testGet(s, "/sync/status").Body.Bytes(), &out); err != nil || !strings.Contains(out["paused"].(string), "merge") {
+		t.Fatalf("pause not reported: %v %v", out, err)
+	}
+
+	// fremde Datei im Repo
+	if err := os.Remove(mergeHead); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "stray.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("not paused for stray file", func(st syncStatus) bool { return strings.Contains(st.Paused, "uncommitted") })
+	if _, err := os.Stat(syncLog); err == nil {
+		t.Fatal("sync ran with a dirty tree")
+	}
+
+	// aufgeräumt: der Scheduler synct wieder
+	if err := os.Remove(filepath.Join(repo, "stray.txt")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("sync not resumed", func(st syncStatus) bool { return st.Paused == "" && !st.LastSuccess.IsZero() })
+}
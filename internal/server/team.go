@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+// assigneePrefix kennzeichnet Zuständigkeits-Tags: +@alice heißt "alice zugewiesen".
+const assigneePrefix = "@"
+
+// unassignedFilter ist der Wert von ?assignee= für Aufgaben ohne Zuständigen.
+const unassignedFilter = "none"
+
+// rowAssignees liefert die Nutzer aus den @-Tags einer Tabellenzeile.
+func rowAssignees(row map[string]string) []string {
+	var out []string
+	for _, tag := range strings.Split(row["tags"], ", ") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "+")
+		if u, ok := strings.CutPrefix(tag, assigneePrefix); ok && u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// filterByAssignee behält Zeilen, die assignee zugewiesen sind; unassignedFilter wählt Zeilen ohne Zuständigen.
+func filterByAssignee(rows []map[string]string, assignee string) []map[string]string {
+	if assignee == "" {
+		return rows
+	}
+	out := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		as := rowAssignees(row)
+		if assignee == unassignedFilter {
+			if len(as) == 0 {
+				out = append(out, row)
+			}
+			continue
+		}
+		for _, a := range as {
+			if strings.EqualFold(a, assignee) {
+				out = append(out, row)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// openTaskRows liefert die nicht erledigten Tasks des gewählten Repos als Tabellenzeilen (q- und Due-Filter angewandt).
+func (s *Server) openTaskRows(r *http.Request) ([]map[string]string, bool) {
+	username := s.repoKey(r)
+	exp := s.runner.Run(username, 5_000_000_000, "export")
+	if exp.Err != nil || exp.ExitCode != 0 || exp.TimedOut {
+		return nil, false
+	}
+	tasks, _ := decodeTasksJSONFlexible(exp.Stdout)
+	rows := buildRowsFromTasks(tasks, "")
+	rows = applyQueryFilter(rows, r.URL.Query().Get("q"))
+	return applyDueFilter(rows, buildDueFilterToken(r.URL.Query())), true
+}
+
+// handleMyTasks zeigt die offenen Aufgaben, die dem angemeldeten Nutzer zugewiesen sind (+@user).
+func (s *Server) handleMyTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	s.cmdStore.Append(s.repoKey(r), "List my tasks", []string{"export"})
+	rows, ok := s.openTaskRows(r)
+	if !ok {
+		http.Error(w, "failed to export tasks", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	s.renderExportTableWith(w, r, "My tasks (+"+assigneePrefix+user+")", filterByAssignee(rows, user), nil)
+}
+
+// teamFilter ist ein Eintrag der Zuständigen-Auswahl in der Team-Ansicht.
+type teamFilter struct {
+	Label    string
+	URL      string
+	Count    int
+	Selected bool
+}
+
+// handleTeamTasks zeigt alle offenen Aufgaben des (geteilten) Repos, filterbar nach Zuständigem.
+func (s *Server) handleTeamTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.cmdStore.Append(s.repoKey(r), "List team tasks", []string{"export"})
+	rows, ok := s.openTaskRows(r)
+	if !ok {
+		http.Error(w, "failed to export tasks", http.StatusBadGateway)
+		return
+	}
+	assignee := strings.TrimSpace(r.URL.Query().Get("assignee"))
+
+	// Zähler pro Zuständigem; Mitglieder des Repos erscheinen auch ohne Aufgaben
+	counts := map[string]int{}
+	for _, u := range config.RepoUsers(s.cfg, s.repoKey(r)) {
+		counts[u] = 0
+	}
+	unassigned := 0
+	for _, row := range rows {
+		as := rowAssignees(row)
+		if len(as) == 0 {
+			unassigned++
+		}
+		for _, a := range as {
+			counts[a]++
+		}
+	}
+	names := make([]string, 0, len(counts))
+	for n := range counts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	link := func(value string) string {
+		q := url.Values{}
+		if value != "" {
+			q.Set("assignee", value)
+		}
+		if v := r.URL.Query().Get("q"); v != "" {
+			q.Set("q", v)
+		}
+		if len(q) == 0 {
+			return "/team"
+		}
+		return "/team?" + q.Encode()
+	}
+	team := []teamFilter{{Label: "all", URL: link(""), Count: len(rows), Selected: assignee == ""}}
+	for _, n := range names {
+		team = append(team, teamFilter{Label: assigneePrefix + n, URL: link(n), Count: counts[n], Selected: strings.EqualFold(assignee, n)})
+	}
+	team = append(team, teamFilter{Label: "unassigned", URL: link(unassignedFilter), Count: unassigned, Selected: assignee == unassignedFilter})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	s.renderExportTableWith(w, r, "Team", filterByAssignee(rows, assignee), map[string]any{
+		"Team":     team,
+		"Assignee": assignee,
+	})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFilterByAssignee(t *testing.T) {
+	rows := []map[string]string{
+		{"id": "1", "tags": "net, @alice"},
+		{"id": "2", "tags": "@bob"},
+		{"id": "3", "tags": "net"},
+		{"id": "4", "tags": ""},
+	}
+	ids := func(rs []map[string]string) string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r["id"])
+		}
+		return strings.Join(out, ",")
+	}
+	if got := ids(filterByAssignee(rows, "alice")); got != "1" {
+		t.Fatalf("alice: %s", got)
+	}
+	if got := ids(filterByAssignee(rows, unassignedFilter)); got != "3,4" {
+		t.Fatalf("unassigned: %s", got)
+	}
+	if got := ids(filterByAssignee(rows, "")); got != "1,2,3,4" {
+		t.Fatalf("all: %s", got)
+	}
+}
+
+func TestMyAndTeamViews(t *testing.T) {
+	dir := t.TempDir()
+	script := `echo '[{"id":1,"uuid":"a","status":"pending","summary":"Mine","tags":["@admin"]},{"id":2,"uuid":"b","status":"pending","summary":"Bobs","tags":["@bob"]},{"id":3,"uuid":"c","status":"pending","summary":"Nobody","tags":[]}]'
+`
+	stub := writeShellStub(t, dir, script)
+	home, _ := newTaskHome(t, dir, false)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.Repos["bob"] = home
+
+	get := func(path string) string {
+		rr := testGet(s, path)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rr.Code)
+		}
+		return rr.Body.String()
+	}
+	body := get("/my")
+	if !strings.Contains(body, "Mine") || strings.Contains(body, "Bobs") || strings.Contains(body, "Nobody") {
+		t.Fatalf("my view shows wrong tasks: %s", body)
+	}
+	body = get("/team")
+	if !strings.Contains(body, "Mine") || !strings.Contains(body, "Bobs") || !strings.Contains(body, "Nobody") {
+		t.Fatalf("team view incomplete: %s", body)
+	}
+	if !strings.Contains(body, `href="/team?assignee=bob"`) || !strings.Contains(body, "unassigned</a> (1)") {
+		t.Fatalf("assignee filter links missing: %s", body)
+	}
+	body = get("/team?assignee=none")
+	if strings.Contains(body, "Mine") || !strings.Contains(body, "Nobody") {
+		t.Fatalf("unassigned filter wrong: %s", body)
+	}
+}
@@ -0,0 +1,403 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/dstask"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// trackTime ist der Abonnent für die Zeiterfassung: nach start, stop, done, remove und undo werden die
+// offenen Intervalle in timelog.yaml mit den aktiven Tasks des Repos abgeglichen. Export und Commit
+// laufen im Hintergrund (timeQueue), damit Publish und damit die Anfrage nicht auf git warten.
+func (s *Server) trackTime(ev Event) {
+	switch ev.Type {
+	case EventTaskStarted, EventTaskStopped, EventTaskDone, EventTaskRemoved:
+	default:
+		if ev.Action != "undo" {
+			return
+		}
+	}
+	s.timeQueue.enqueue(ev, s.syncTimers)
+}
+
+// syncTimers gleicht timelog.yaml mit den aktiven Tasks ab (Zeitpunkt des Ereignisses ev).
+func (s *Server) syncTimers(ev Event) {
+	res := s.runner.Run(ev.Repo, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		applog.Warnf("time tracking: export for %s failed: %v", ev.Repo, res.Err)
+		return
+	}
+	tasks, _ := decodeTasksJSONFlexible(res.Stdout)
+	var active []string
+	for _, t := range tasks {
+		if strings.EqualFold(str(firstOf(t, "status", "Status")), "active") {
+			active = append(active, str(firstOf(t, "uuid", "UUID")))
+		}
+	}
+	started, stopped, err := s.runner.SyncTimers(ev.Repo, ev.Actor, active, ev.Time)
+	if err != nil {
+		applog.Warnf("time tracking for %s: %v", ev.Repo, err)
+		return
+	}
+	if started+stopped > 0 {
+		applog.Debugf("time tracking for %s: %d started, %d stopped", ev.Repo, started, stopped)
+	}
+}
+
+// timeQueue arbeitet die Ereignisse der Zeiterfassung je Repo der Reihe nach in einer eigenen
+// Goroutine ab; verschiedene Repos laufen parallel.
+type timeQueue struct {
+	mu      sync.Mutex
+	idle    *sync.Cond // signalisiert, wenn keine Ereignisse mehr anstehen
+	pending map[string][]Event
+}
+
+func newTimeQueue() *timeQueue {
+	q := &timeQueue{pending: map[string][]Event{}}
+	q.idle = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *timeQueue) enqueue(ev Event, run func(Event)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	busy := len(q.pending[ev.Repo]) > 0
+	q.pending[ev.Repo] = append(q.pending[ev.Repo], ev)
+	if !busy {
+		go q.drain(ev.Repo, run)
+	}
+}
+
+func (q *timeQueue) drain(repo string, run func(Event)) {
+	for {
+		q.mu.Lock()
+		if len(q.pending[repo]) == 0 {
+			delete(q.pending, repo)
+			if len(q.pending) == 0 {
+				q.idle.Broadcast()
+			}
+			q.mu.Unlock()
+			return
+		}
+		ev := q.pending[repo][0]
+		q.mu.Unlock()
+		func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					applog.Errorf("time tracking for %s panicked: %v", repo, rec)
+				}
+			}()
+			run(ev)
+		}()
+		q.mu.Lock()
+		q.pending[repo] = q.pending[repo][1:]
+		q.mu.Unlock()
+	}
+}
+
+// csvCells schützt Textzellen vor Formel-Injektion in Tabellenkalkulationen: Zellen, die mit =, +, -,
+// @, Tab oder CR beginnen, bekommen ein vorangestelltes '.
+func csvCells(cells ...string) []string {
+	for i, c := range cells {
+		if c != "" && strings.ContainsRune("=+-@\t\r", rune(c[0])) {
+			cells[i] = "'" + c
+		}
+	}
+	return cells
+}
+
+// formatTracked formatiert eine erfasste Dauer kompakt, z. B. "2h 05m" oder "12m".
+func formatTracked(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// timeEntry ist der Anteil eines Intervalls an einem Kalendertag (Intervalle über Mitternacht werden geteilt).
+type timeEntry struct {
+	Day        string
+	Start, End time.Time
+	Duration   time.Duration
+	UUID, ID   string
+	Summary    string
+	Project    string
+	Tags       []string
+	User       string
+}
+
+// timeEntries zerlegt die Intervalle in Tagesanteile innerhalb [from, to) (Ortszeit von loc).
+func timeEntries(log map[string][]dstask.TimeInterval, tasks map[string]map[string]string, from, to, now time.Time, loc *time.Location) []timeEntry {
+	var out []timeEntry
+	for uuid, ivs := range log {
+		row := tasks[uuid]
+		var tags []string
+		for _, tag := range strings.Split(row["tags"], ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		for _, iv := range ivs {
+			start, end := iv.Start.In(loc), iv.End.In(loc)
+			if iv.Running() {
+				end = now.In(loc)
+			}
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			for start.Before(end) {
+				midnight := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, loc)
+				pieceEnd := end
+				if midnight.Before(end) {
+					pieceEnd = midnight
+				}
+				out = append(out, timeEntry{
+					Day: start.Format("2006-01-02"), Start: start, End: pieceEnd, Duration: pieceEnd.Sub(start),
+					UUID: uuid, ID: row["id"], Summary: firstNonEmptyString(row["summary"], "(unknown task)"),
+					Project: row["project"], Tags: tags, User: iv.User,
+				})
+				start = pieceEnd
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// timeTotal ist eine Summenzeile des Berichts.
+type timeTotal struct {
+	Key      string
+	Duration time.Duration
+}
+
+func (t timeTotal) Hours() string { return strconv.FormatFloat(t.Duration.Hours(), 'f', 2, 64) }
+
+func (t timeTotal) Label() string { return formatTracked(t.Duration) }
+
+// sumBy summiert die Einträge nach keys (ein Eintrag kann zu mehreren Schlüsseln zählen, z. B. Tags).
+func sumBy(entries []timeEntry, keys func(timeEntry) []string, byKey bool) []timeTotal {
+	m := map[string]time.Duration{}
+	for _, e := range entries {
+		for _, k := range keys(e) {
+			m[k] += e.Duration
+		}
+	}
+	out := make([]timeTotal, 0, len(m))
+	for k, d := range m {
+		out = append(out, timeTotal{Key: k, Duration: d})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if byKey || out[i].Duration == out[j].Duration {
+			return out[i].Key < out[j].Key
+		}
+		return out[i].Duration > out[j].Duration
+	})
+	return out
+}
+
+func entryDay(e timeEntry) []string { return []string{e.Day} }
+
+func entryProject(e timeEntry) []string {
+	return []string{firstNonEmptyString(e.Project, "(no project)")}
+}
+
+func entryTags(e timeEntry) []string {
+	if len(e.Tags) == 0 {
+		return []string{"(no tags)"}
+	}
+	return e.Tags
+}
+
+// handleTimeReport zeigt die erfassten Zeiten des aktuellen Repos je Tag, Projekt und Tag (Schlagwort)
+// für einen Zeitraum; format=csv liefert die Einzeleinträge bzw. mit group=day|project|tag die Summen.
+func (s *Server) handleTimeReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := s.repoKey(r)
+	q := r.URL.Query()
+	now := time.Now()
+	loc := now.Location()
+	fromDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	toDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if v := strings.TrimSpace(q.Get("from")); v != "" {
+		if d, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+			fromDay = d
+		}
+	}
+	if v := strings.TrimSpace(q.Get("to")); v != "" {
+		if d, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+			toDay = d
+		}
+	}
+	if toDay.Before(fromDay) {
+		fromDay, toDay = toDay, fromDay
+	}
+	log, err := s.runner.TimeLog(key)
+	if err != nil {
+		http.Error(w, "reading time log failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tasks := map[string]map[string]string{}
+	if res := s.runner.Run(key, 5*time.Second, "export"); res.Err == nil && res.ExitCode == 0 && !res.TimedOut {
+		decoded, _ := decodeTasksJSONFlexible(res.Stdout)
+		for _, row := range buildRowsFromTasks(decoded, "") {
+			tasks[strings.ToLower(row["uuid"])] = row
+		}
+	}
+	entries := timeEntries(log, tasks, fromDay, toDay.AddDate(0, 0, 1), now, loc)
+	users := map[string]bool{}
+	for _, e := range entries {
+		if e.User != "" {
+			users[e.User] = true
+		}
+	}
+	if user := strings.TrimSpace(q.Get("user")); user != "" {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.User == user {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+	byDay := sumBy(entries, entryDay, true)
+	byProject := sumBy(entries, entryProject, false)
+	byTag := sumBy(entries, entryTags, false)
+	var total time.Duration
+	for _, e := range entries {
+		total += e.Duration
+	}
+	period := fromDay.Format("2006-01-02") + "_" + toDay.Format("2006-01-02")
+	allPomodoros, err := s.runner.Pomodoros(key)
+	if err != nil {
+		applog.Warnf("reading pomodoros for %s failed: %v", key, err)
+	}
+	pomodoros := pomodoroTotals(allPomodoros, tasks, fromDay, toDay.AddDate(0, 0, 1), strings.TrimSpace(q.Get("user")))
+
+	if q.Get("format") == "csv" && q.Get("group") == "pomodoro" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writePomodoroCSV(w, pomodoros, period)
+		return
+	}
+	if q.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		switch group := q.Get("group"); group {
+		case "day", "project", "tag":
+			totals := map[string][]timeTotal{"day": byDay, "project": byProject, "tag": byTag}[group]
+			w.Header().Set("Content-Disposition", "attachment; filename=\"time-"+group+"-"+period+".csv\"")
+			_ = cw.Write([]string{group, "hours"})
+			for _, t := range totals {
+				_ = cw.Write(csvCells(t.Key, t.Hours()))
+			}
+		default:
+			w.Header().Set("Content-Disposition", "attachment; filename=\"time-"+period+".csv\"")
+			_ = cw.Write([]string{"date", "start", "end", "hours", "id", "uuid", "summary", "project", "tags", "user"})
+			for _, e := range entries {
+				_ = cw.Write(csvCells(
+					e.Day, e.Start.Format("15:04"), e.End.Format("15:04"), timeTotal{Duration: e.Duration}.Hours(),
+					e.ID, e.UUID, e.Summary, e.Project, strings.Join(e.Tags, " "), e.User,
+				))
+			}
+		}
+		cw.Flush()
+		return
+	}
+
+	userList := make([]string, 0, len(users))
+	for u := range users {
+		userList = append(userList, u)
+	}
+	sort.Strings(userList)
+	csvURL := func(group string) string {
+		qc := r.URL.Query()
+		qc.Set("from", fromDay.Format("2006-01-02"))
+		qc.Set("to", toDay.Format("2006-01-02"))
+		qc.Set("format", "csv")
+		if group != "" {
+			qc.Set("group", group)
+		}
+		return "/reports/time?" + qc.Encode()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Time report</h2>
+<p>Time between <em>start</em> and <em>stop</em> (or done) of each task, stored in <code>timelog.yaml</code> in the repository. Running timers count up to now.</p>
+<form method="get" action="/reports/time" style="margin-bottom:8px;">
+  <label>From <input type="date" name="from" value="{{.From}}"/></label>
+  <label style="margin-left:8px;">To <input type="date" name="to" value="{{.To}}"/></label>
+  {{if gt (len .Users) 1}}<label style="margin-left:8px;">User <select name="user"><option value="">(all)</option>{{range .Users}}<option value="{{.}}" {{if eq . $.User}}selected{{end}}>{{.}}</option>{{end}}</select></label>{{end}}
+  <button type="submit" style="margin-left:8px;">Show</button>
+</form>
+<p><strong>Total: {{.Total}}</strong> · CSV: <a href="{{.CSV.entries}}">entries</a> · <a href="{{.CSV.day}}">per day</a> · <a href="{{.CSV.project}}">per project</a> · <a href="{{.CSV.tag}}">per tag</a> · <a href="{{.CSV.pomodoro}}">pomodoros</a></p>
+{{if .Entries}}
+<div style="display:flex;gap:24px;flex-wrap:wrap;align-items:flex-start;">
+{{range .Groups}}
+  <table>
+    <thead><tr><th>{{.Title}}</th><th>Time</th><th>Hours</th></tr></thead>
+    <tbody>{{range .Totals}}<tr><td>{{.Key}}</td><td>{{.Label}}</td><td style="text-align:right;">{{.Hours}}</td></tr>{{end}}</tbody>
+  </table>
+{{end}}
+</div>
+<p style="color:#57606a;">A task with several tags counts towards each of its tags.</p>
+{{end}}
+{{if .Pomodoros}}
+<h3>Pomodoros</h3>
+<table>
+  <thead><tr><th>Task</th><th>🍅</th><th>Focus time</th></tr></thead>
+  <tbody>{{range .Pomodoros}}<tr><td>{{if .ID}}#{{.ID}} {{end}}{{.Summary}}</td><td style="text-align:right;">{{.Count}}</td><td>{{.Label}}</td></tr>{{end}}</tbody>
+</table>
+{{end}}
+{{if .Entries}}
+<h3>Entries</h3>
+<table>
+  <thead><tr><th>Date</th><th>From</th><th>To</th><th>Time</th><th>Task</th><th>Project</th><th>Tags</th><th>User</th></tr></thead>
+  <tbody>
+  {{range .Entries}}<tr>
+    <td>{{.Day}}</td><td>{{.Start.Format "15:04"}}</td><td>{{.End.Format "15:04"}}</td><td>{{.Label}}</td>
+    <td>{{if .ID}}#{{.ID}} {{end}}{{.Summary}}</td><td>{{.Project}}</td><td>{{range .Tags}}<span class="pill">{{.}}</span>{{end}}</td><td>{{.User}}</td>
+  </tr>{{end}}
+  </tbody>
+</table>
+{{else}}
+<p>No tracked time in this period. Start a task to begin tracking.</p>
+{{end}}`)
+	type entryView struct {
+		timeEntry
+		Label string
+	}
+	views := make([]entryView, 0, len(entries))
+	for _, e := range entries {
+		views = append(views, entryView{timeEntry: e, Label: formatTracked(e.Duration)})
+	}
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"From":      fromDay.Format("2006-01-02"),
+		"To":        toDay.Format("2006-01-02"),
+		"User":      q.Get("user"),
+		"Users":     userList,
+		"Total":     formatTracked(total),
+		"Entries":   views,
+		"Pomodoros": pomodoros,
+		"Groups": []map[string]any{
+			{"Title": "Day", "Totals": byDay},
+			{"Title": "Project", "Totals": byProject},
+			{"Title": "Tag", "Totals": byTag},
+		},
+		"CSV": map[string]string{"entries": csvURL(""), "day": csvURL("day"), "project": csvURL("project"), "tag": csvURL("tag"), "pomodoro": csvURL("pomodoro")},
+	}))
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wait blockiert, bis alle eingereihten Ereignisse verarbeitet sind.
+func (q *timeQueue) wait() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) > 0 {
+		q.idle.Wait()
+	}
+}
+
+func TestTimeTracking_StartStopAndReport(t *testing.T) {
+	dir := t.TempDir()
+	home, repo := newTaskHome(t, dir, false)
+	// "start" setzt eine Markierung, "stop" entfernt sie; der Export zeigt Task 1 entsprechend als aktiv
+	marker := filepath.Join(dir, "active")
+	script := `if [ "$1" = "start" ]; then touch "` + marker + `"; exit 0; fi
+if [ "$1" = "stop" ]; then rm -f "` + marker + `"; exit 0; fi
+if [ "$1" = "export" ]; then
+  status=pending
+  if [ -f "` + marker + `" ]; then status=active; fi
+  echo '[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"'$status'","summary":"Invoice ACME","project":"acme","tags":["billing","mail"]},
+{"id":2,"uuid":"22222222-2222-2222-2222-222222222222","status":"pending","summary":"Refactor","project":"internal"}]'
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	post := func(path string, form url.Values) {
+		t.Helper()
+		if rr := testPost(s, path, form); rr.Code != http.StatusSeeOther {
+			t.Fatalf("%s: %d %s", path, rr.Code, rr.Body.String())
+		}
+	}
+
+	post("/tasks/1/start", url.Values{})
+	s.timeQueue.wait()
+	log, err := s.runner.TimeLog("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ivs := log["11111111-1111-1111-1111-111111111111"]
+	if len(ivs) != 1 || !ivs[0].Running() || ivs[0].User != "admin" {
+		t.Fatalf("expected a running interval after start: %+v", ivs)
+	}
+	if body := testGet(s, "/open?html=1").Body.String(); !strings.Contains(body, "⏱ 0m") {
+		t.Fatalf("running timer not shown on rows")
+	}
+	post("/tasks/batch", url.Values{"ids": {"1"}, "action": {"stop"}})
+	s.timeQueue.wait()
+	log, _ = s.runner.TimeLog("admin")
+	if ivs := log["11111111-1111-1111-1111-111111111111"]; len(ivs) != 1 || ivs[0].Running() {
+		t.Fatalf("expected the interval to be closed by the batch stop: %+v", ivs)
+	}
+
+	// Bericht aus festen Intervallen; eines geht über Mitternacht
+	day := time.Date(2025, 3, 3, 0, 0, 0, 0, time.Local)
+	at := func(d, h, m int) string {
+		return day.AddDate(0, 0, d).Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute).Format(time.RFC3339)
+	}
+	timelog := `version: 1
+tasks:
+  11111111-1111-1111-1111-111111111111:
+    - {start: "` + at(0, 9, 0) + `", end: "` + at(0, 10, 30) + `", user: admin}
+    - {start: "` + at(0, 23, 0) + `", end: "` + at(1, 1, 0) + `", user: admin}
+  22222222-2222-2222-2222-222222222222:
+    - {start: "` + at(1, 14, 0) + `", end: "` + at(1, 14, 45) + `", user: admin}
+    - {start: "` + at(9, 8, 0) + `", end: "` + at(9, 9, 0) + `", user: admin}
+`
+	if err := os.WriteFile(filepath.Join(repo, "timelog.yaml"), []byte(timelog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rr := testGet(s, "/reports/time?from=2025-03-03&to=2025-03-04&format=csv&group=project")
+	if got := rr.Body.String(); got != "project,hours\nacme,3.50\ninternal,0.75\n" || !strings.Contains(rr.Header().Get("Content-Disposition"), "time-project-2025-03-03_2025-03-04.csv") {
+		t.Fatalf("unexpected project CSV: %q", got)
+	}
+	if got := testGet(s, "/reports/time?from=2025-03-03&to=2025-03-04&format=csv&group=day").Body.String(); got != "day,hours\n2025-03-03,2.50\n2025-03-04,1.75\n" {
+		t.Fatalf("unexpected day CSV: %q", got)
+	}
+	if got := testGet(s, "/reports/time?from=2025-03-03&to=2025-03-04&format=csv&group=tag").Body.String(); got != "tag,hours\nbilling,3.50\nmail,3.50\n(no tags),0.75\n" {
+		t.Fatalf("unexpected tag CSV: %q", got)
+	}
+	entries := testGet(s, "/reports/time?from=2025-03-03&to=2025-03-04&format=csv").Body.String()
+	if lines := strings.Split(strings.TrimSpace(entries), "\n"); len(lines) != 5 ||
+		lines[2] != "2025-03-03,23:00,00:00,1.00,1,11111111-1111-1111-1111-111111111111,Invoice ACME,acme,billing mail,admin" {
+		t.Fatalf("unexpected entries CSV:\n%s", entries)
+	}
+	if body := testGet(s, "/reports/time?from=2025-03-03&to=2025-03-04").Body.String(); !strings.Contains(body, "Total: 4h 15m") || !strings.Contains(body, "#2 Refactor") {
+		t.Fatalf("unexpected report page")
+	}
+}
+
+func TestCSVCells_EscapesFormulas(t *testing.T) {
+	var b strings.Builder
+	cw := csv.NewWriter(&b)
+	_ = cw.Write(csvCells("2025-03-03", "1.50", `=HYPERLINK("http://evil","x")`, "+1", "-2+3", "@SUM(A1)", "plain", ""))
+	cw.Flush()
+	want := `2025-03-03,1.50,"'=HYPERLINK(""http://evil"",""x"")",'+1,'-2+3,'@SUM(A1),plain,` + "\n"
+	if b.String() != want {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+}
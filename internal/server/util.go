@@ -13,8 +13,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/gomarkdown/markdown"
 )
 
 func str(v any) string {
@@ -138,6 +136,7 @@ func buildRowsFromTasks(tasks []map[string]any, statusFilter string) []map[strin
 		notes := trimQuotes(str(firstOf(t, "notes", "annotations", "note")))
 		rows = append(rows, map[string]string{
 			"id":       id,
+			"uuid":     str(firstOf(t, "uuid", "UUID")),
 			"status":   st,
 			"summary":  trimQuotes(str(firstOf(t, "summary", "description"))),
 			"project":  trimQuotes(str(firstOf(t, "project"))),
@@ -211,9 +210,17 @@ func activeFromPath(path string) string {
 		return "paused"
 	case strings.HasPrefix(path, "/resolved"):
 		return "resolved"
+	case path == "/my":
+		return "my"
+	case path == "/team":
+		return "team"
+	case path == "/delegated":
+		return "delegated"
+	case strings.HasPrefix(path, "/notifications"):
+		return "notifications"
 	case strings.HasPrefix(path, "/projects"):
 		return "projects"
-	case strings.HasPrefix(path, "/templates"):
+	case strings.HasPrefix(path, "/templates"), strings.HasPrefix(path, "/recurrence"):
 		return "templates"
 	case strings.HasPrefix(path, "/context"):
 		return "context"
@@ -225,6 +232,14 @@ func activeFromPath(path string) string {
 		return "version"
 	case strings.HasPrefix(path, "/sync"):
 		return "sync"
+	case strings.HasPrefix(path, "/activity"):
+		return "activity"
+	case strings.HasPrefix(path, "/reports"):
+		return "reports"
+	case strings.HasPrefix(path, "/backup"):
+		return "backup"
+	case strings.HasPrefix(path, "/repo"):
+		return "repo"
 	case strings.HasPrefix(path, "/undo"):
 		return "undo"
 	default:
@@ -1006,7 +1021,7 @@ func renderMarkdown(text string) template.HTML {
 	if text == "" {
 		return template.HTML("")
 	}
-	// Konvertiere Markdown zu HTML
-	html := markdown.ToHTML([]byte(text), nil, nil)
+	// Konvertiere Markdown zu HTML; Checklisten erscheinen als (nicht anklickbare) Checkboxen
+	html, _, _ := renderChecklist(text, false)
 	return template.HTML(html)
 }
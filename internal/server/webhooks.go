@@ -0,0 +1,318 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/auth"
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+	"github.com/elpatron68/dstask-ui/internal/webhook"
+)
+
+// actionEvents ordnet dstask-Aktionen den Ereignistypen zu.
+var actionEvents = map[string]string{
+	"add":    EventTaskCreated,
+	"log":    EventTaskCreated,
+	"start":  EventTaskStarted,
+	"stop":   EventTaskStopped,
+	"done":   EventTaskDone,
+	"remove": EventTaskRemoved,
+	"note":   EventTaskModified,
+	"edit":   EventTaskModified,
+	"modify": EventTaskModified,
+}
+
+// wantTaskSnapshots meldet, ob ein Abonnent Vorher/Nachher-Stände der Tasks braucht; sonst spart
+// sich taskChange die zusätzlichen Exporte.
+func (s *Server) wantTaskSnapshots() bool {
+	return s.webhooks.Enabled() || s.chatWantsSnapshots()
+}
+
+// taskChange begleitet eine Änderung durch einen Handler: beginTaskChange vor dem dstask-Aufruf,
+// publish danach. Mit Snapshots werden Vorher/Nachher-Stand in die Events übernommen.
+type taskChange struct {
+	s             *Server
+	key, actor    string
+	before, after map[string]map[string]any // UUID -> Task; nil ohne Snapshots
+	at            time.Time                 // Zeitpunkt für die Events; Nullwert = jetzt
+}
+
+func (s *Server) beginTaskChange(r *http.Request) *taskChange {
+	actor, _ := auth.UsernameFromRequest(r)
+	return s.beginTaskChangeAs(s.repoKey(r), actor)
+}
+
+// beginTaskChangeAs ist beginTaskChange ohne Anfrage (z. B. für Timer im Hintergrund).
+func (s *Server) beginTaskChangeAs(key, actor string) *taskChange {
+	c := &taskChange{s: s, key: key, actor: actor}
+	if s.wantTaskSnapshots() {
+		c.before = c.export()
+	}
+	return c
+}
+
+func (c *taskChange) export() map[string]map[string]any {
+	res := c.s.runner.Run(c.key, 5*time.Second, "export")
+	if res.Err != nil || res.ExitCode != 0 || res.TimedOut {
+		applog.Warnf("events: export for %s failed: %v", c.key, res.Err)
+		return nil
+	}
+	tasks, _ := decodeTasksJSONFlexible(res.Stdout)
+	m := make(map[string]map[string]any, len(tasks))
+	for _, t := range tasks {
+		if u := strings.ToLower(str(firstOf(t, "uuid", "UUID"))); u != "" {
+			m[u] = t
+		}
+	}
+	return m
+}
+
+// loadAfter exportiert den Stand nach der Änderung (einmal pro Handler-Aufruf).
+func (c *taskChange) loadAfter() {
+	if c.before != nil && c.after == nil {
+		c.after = c.export()
+	}
+}
+
+// lookup sucht einen Task per ID (nur offene Tasks haben eine) oder UUID.
+func lookupTask(tasks map[string]map[string]any, id string) (string, map[string]any) {
+	if t, ok := tasks[strings.ToLower(id)]; ok {
+		return strings.ToLower(id), t
+	}
+	for u, t := range tasks {
+		if str(firstOf(t, "id", "ID")) == id && !isResolved(t) {
+			return u, t
+		}
+	}
+	return "", nil
+}
+
+// publish meldet die Aktion action am Task id.
+func (c *taskChange) publish(action, id string) {
+	c.loadAfter()
+	uuid, before := lookupTask(c.before, id)
+	var after map[string]any
+	if uuid != "" {
+		after = c.after[uuid]
+	}
+	c.emit(actionEvents[action], action, id, uuid, before, after)
+}
+
+// publishCreated meldet alle Tasks, die seit beginTaskChange neu hinzugekommen sind; ohne Snapshots
+// ein Event ohne Task-Bezug.
+func (c *taskChange) publishCreated(action string) {
+	c.loadAfter()
+	if c.before == nil {
+		c.emit(EventTaskCreated, action, "", "", nil, nil)
+		return
+	}
+	for _, uuid := range c.changed() {
+		if _, existed := c.before[uuid]; !existed {
+			c.emit(EventTaskCreated, action, "", uuid, nil, c.after[uuid])
+		}
+	}
+}
+
+// publishChanged meldet alle Tasks, die sich seit beginTaskChange geändert haben (z. B. nach undo).
+func (c *taskChange) publishChanged(action string) {
+	c.loadAfter()
+	for _, uuid := range c.changed() {
+		before, after := c.before[uuid], c.after[uuid]
+		typ := EventTaskModified
+		switch {
+		case before == nil:
+			typ = EventTaskCreated
+		case after == nil:
+			typ = EventTaskRemoved
+		}
+		c.emit(typ, action, "", uuid, before, after)
+	}
+}
+
+// changed liefert die UUIDs (sortiert), deren Stand sich zwischen before und after unterscheidet.
+func (c *taskChange) changed() []string {
+	if c.before == nil || c.after == nil {
+		return nil
+	}
+	var out []string
+	for u, a := range c.after {
+		if !reflect.DeepEqual(c.before[u], a) {
+			out = append(out, u)
+		}
+	}
+	for u := range c.before {
+		if _, ok := c.after[u]; !ok {
+			out = append(out, u)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (c *taskChange) emit(typ, action, id, uuid string, before, after map[string]any) {
+	task := after
+	if task == nil {
+		task = before
+	}
+	if task != nil {
+		id = firstNonEmptyString(str(firstOf(task, "id", "ID")), id)
+		if id == "0" {
+			id = "" // erledigte Tasks haben keine ID mehr
+		}
+	}
+	c.s.events.Publish(Event{
+		Type:     typ,
+		Time:     c.at,
+		Repo:     c.key,
+		Actor:    c.actor,
+		TaskID:   id,
+		TaskUUID: uuid,
+		Summary:  trimQuotes(str(firstOf(task, "summary", "Summary"))),
+		Action:   action,
+		Before:   before,
+		After:    after,
+	})
+}
+
+// webhookPayload ist der JSON-Body der Webhook-Zustellungen.
+type webhookPayload struct {
+	Event  string         `json:"event"`
+	Action string         `json:"action"`
+	Time   time.Time      `json:"time"`
+	User   string         `json:"user"`
+	Repo   string         `json:"repo"` // Repo-Name; leer = Standard-Repo
+	Task   *webhookTask   `json:"task,omitempty"`
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after,omitempty"`
+}
+
+type webhookTask struct {
+	ID      string `json:"id,omitempty"`
+	UUID    string `json:"uuid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// enqueueWebhooks ist der Abonnent für ausgehende Webhooks.
+func (s *Server) enqueueWebhooks(ev Event) {
+	if !s.webhooks.Enabled() {
+		return
+	}
+	_, repo := config.SplitRepoKey(ev.Repo)
+	p := webhookPayload{
+		Event:  ev.Type,
+		Action: firstNonEmptyString(ev.Action, ev.Type[strings.LastIndex(ev.Type, ".")+1:]),
+		Time:   ev.Time,
+		User:   ev.Actor,
+		Repo:   repo,
+		Before: ev.Before,
+		After:  ev.After,
+	}
+	if ev.TaskID != "" || ev.TaskUUID != "" {
+		p.Task = &webhookTask{ID: ev.TaskID, UUID: ev.TaskUUID, Summary: ev.Summary}
+	}
+	s.webhooks.Enqueue(ev.Type, ev.Actor, p)
+}
+
+// handleWebhooks zeigt ausstehende Zustellungen und das Zustellprotokoll der Ereignisse des Nutzers.
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	type pendingRow struct {
+		ID, Hook, Event, LastError string
+		Attempts                   int
+		NextAttempt                time.Time
+	}
+	var pending []pendingRow
+	for _, d := range s.webhooks.Pending() {
+		if d.User == user {
+			pending = append(pending, pendingRow{ID: d.ID, Hook: webhook.MaskURLs(d.Hook), Event: d.Event, LastError: webhook.MaskURLs(d.LastError), Attempts: d.Attempts, NextAttempt: d.NextAttempt})
+		}
+	}
+	var log []map[string]any
+	for _, e := range s.webhooks.Log() {
+		if e.User == user {
+			ok := e.OK()
+			e.Hook, e.Error = webhook.MaskURLs(e.Hook), webhook.MaskURLs(e.Error)
+			log = append(log, map[string]any{"Entry": e, "OK": ok, "Ms": e.Duration.Milliseconds()})
+		}
+	}
+	// URLs nur bis zum Host zeigen: Pfad/Query enthalten oft das Token des Webhooks
+	hooks := make([]string, 0, len(s.cfg.Webhooks))
+	for _, h := range s.cfg.Webhooks {
+		hooks = append(hooks, webhook.MaskURLs(firstNonEmptyString(h.Name, h.URL)))
+	}
+	csrfToken := s.ensureCSRFToken(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t := template.Must(s.layoutTpl.Clone())
+	_, _ = t.New("content").Parse(`<h2>Webhook deliveries</h2>
+{{if .Hooks}}<p>Configured webhooks: {{range $i, $h := .Hooks}}{{if $i}}, {{end}}<code>{{$h}}</code>{{end}}. Only deliveries for your own changes are listed.</p>
+{{else}}<p>No webhooks configured (<code>webhooks</code> in config.yaml).</p>{{end}}
+{{if .Pending}}
+<h3>Pending</h3>
+<table>
+  <thead><tr><th>Event</th><th>Webhook</th><th>Attempts</th><th>Next attempt</th><th>Last error</th><th></th></tr></thead>
+  <tbody>
+  {{range .Pending}}<tr>
+    <td>{{.Event}}</td><td><code>{{.Hook}}</code></td><td>{{.Attempts}}</td>
+    <td>{{.NextAttempt.Format "2006-01-02 15:04:05"}}</td><td>{{.LastError}}</td>
+    <td><form method="post" action="/webhooks/retry" style="display:inline;">
+      <input type="hidden" name="csrf_token" value="{{$.CSRFToken}}"/>
+      <input type="hidden" name="id" value="{{.ID}}"/>
+      <button type="submit">Retry now</button>
+    </form></td>
+  </tr>{{end}}
+  </tbody>
+</table>
+{{end}}
+<h3>Log</h3>
+{{if .Log}}
+<table>
+  <thead><tr><th>When</th><th>Event</th><th>Webhook</th><th>Attempt</th><th>Result</th><th>Time</th></tr></thead>
+  <tbody>
+  {{range .Log}}<tr>
+    <td>{{.Entry.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Entry.Event}}</td><td><code>{{.Entry.Hook}}</code></td><td>{{.Entry.Attempt}}</td>
+    <td>{{if .OK}}<span style="color:#1a7f37;">✓ {{.Entry.Status}}</span>{{else}}<span style="color:#cf222e;">⚠ {{if .Entry.Status}}{{.Entry.Status}} {{end}}{{.Entry.Error}}{{if .Entry.GaveUp}} (gave up){{end}}</span>{{end}}</td>
+    <td>{{.Ms}} ms</td>
+  </tr>{{end}}
+  </tbody>
+</table>
+{{else}}
+<p>No deliveries yet.</p>
+{{end}}
+<p><a href="/notifications">Back to notifications</a></p>`)
+	_ = t.Execute(w, s.pageData(r, map[string]any{
+		"Hooks":     hooks,
+		"Pending":   pending,
+		"Log":       log,
+		"CSRFToken": csrfToken,
+	}))
+}
+
+// handleWebhookRetry stellt eine ausstehende Zustellung sofort erneut an.
+func (s *Server) handleWebhookRetry(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePostCSRF(w, r) {
+		return
+	}
+	user, _ := auth.UsernameFromRequest(r)
+	id := r.FormValue("id")
+	owned := false
+	for _, d := range s.webhooks.Pending() {
+		if d.ID == id && d.User == user {
+			owned = true
+		}
+	}
+	if owned && s.webhooks.Retry(id) {
+		s.setFlash(w, "success", "Delivery scheduled.")
+	} else {
+		s.setFlash(w, "error", "Delivery not found.")
+	}
+	http.Redirect(w, r, "/webhooks", http.StatusSeeOther)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	"github.com/elpatron68/dstask-ui/internal/webhook"
+)
+
+func TestWebhooks_TaskDoneWithBeforeAfter(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	var sigs []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		sigs = append(sigs, r.Header.Get(webhook.HeaderSignature))
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	dir := t.TempDir()
+	home, _ := newTaskHome(t, dir, false)
+	// "done" setzt eine Markierung, danach exportiert der Stub den Task als erledigt
+	marker := filepath.Join(dir, "done")
+	script := `if [ "$1" = "done" ]; then touch "` + marker + `"; exit 0; fi
+if [ "$1" = "export" ]; then
+  if [ -f "` + marker + `" ]; then
+    echo '[{"id":0,"uuid":"11111111-1111-1111-1111-111111111111","status":"resolved","summary":"Ship it"}]'
+  else
+    echo '[{"id":1,"uuid":"11111111-1111-1111-1111-111111111111","status":"pending","summary":"Ship it"}]'
+  fi
+  exit 0
+fi
+echo '[]'
+`
+	stub := writeShellStub(t, dir, script)
+	s := newTestServerWithStub(t, stub, home)
+	s.cfg.DataDir = filepath.Join(dir, "data")
+	s.cfg.Webhooks = []config.WebhookConfig{{Name: "bot", URL: target.URL, Secret: "k", Events: []string{EventTaskDone}}}
+
+	rr := testPost(s, "/tasks/1/done", nil)
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("done: %d %s", rr.Code, rr.Body.String())
+	}
+	if n := s.webhooks.DeliverDue(context.Background(), time.Now()); n != 1 {
+		t.Fatalf("delivered %d, want 1", n)
+	}
+	mu.Lock()
+	body, sig := bodies[0], sigs[0]
+	mu.Unlock()
+	if sig != webhook.Sign("k", []byte(body)) {
+		t.Fatalf("bad signature %q", sig)
+	}
+	var p webhookPayload
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != EventTaskDone || p.Action != "done" || p.User != "admin" || p.Task == nil ||
+		p.Task.UUID != "11111111-1111-1111-1111-111111111111" || p.Task.ID != "" || p.Task.Summary != "Ship it" {
+		t.Fatalf("unexpected payload: %s", body)
+	}
+	if p.Before["status"] != "pending" || p.After["status"] != "resolved" {
+		t.Fatalf("unexpected before/after: %s", body)
+	}
+
+	rr = testGet(s, "/webhooks")
+	if !strings.Contains(rr.Body.String(), "task.done") || !strings.Contains(rr.Body.String(), "✓ 200") {
+		t.Fatalf("delivery log missing: %s", rr.Body.String())
+	}
+
+	// Webhook-URLs ohne Namen erscheinen nur bis zum Host (Slack-Token im Pfad)
+	s.cfg.Webhooks = append(s.cfg.Webhooks, config.WebhookConfig{URL: "https://hooks.slack.com/services/T000/B000/SECRETTOKEN", Events: []string{"none"}})
+	if b := testGet(s, "/webhooks").Body.String(); strings.Contains(b, "SECRETTOKEN") || !strings.Contains(b, "https://hooks.slack.com/…") {
+		t.Fatalf("webhook URL not masked: %s", b)
+	}
+}
@@ -0,0 +1,364 @@
+// Package webhook stellt Task-Ereignisse per HTTP POST an die konfigurierten Webhooks zu.
+// Zustellungen liegen in einer persistenten Warteschlange im Datenverzeichnis und werden bei
+// Fehlern mit wachsendem Abstand wiederholt.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+	applog "github.com/elpatron68/dstask-ui/internal/log"
+)
+
+// Header der Zustellungen.
+const (
+	HeaderEvent     = "X-Dstask-Event"
+	HeaderDelivery  = "X-Dstask-Delivery"
+	HeaderSignature = "X-Dstask-Signature" // "sha256=" + hex(HMAC-SHA256(secret, body))
+)
+
+// retryDelays sind die Wartezeiten vor dem 2., 3., … Versuch; danach wird aufgegeben.
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour}
+
+// logMax begrenzt das Zustellprotokoll.
+const logMax = 200
+
+// Delivery ist eine ausstehende Zustellung an einen Webhook.
+type Delivery struct {
+	ID          string    `json:"id"`
+	Hook        string    `json:"hook"` // Name (oder URL) des Webhooks
+	Event       string    `json:"event"`
+	User        string    `json:"user"`
+	Body        string    `json:"body"` // JSON, als String gespeichert, damit die signierten Bytes erhalten bleiben
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	Created     time.Time `json:"created"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// LogEntry ist ein Zustellversuch im Protokoll.
+type LogEntry struct {
+	Delivery string        `json:"delivery"`
+	Hook     string        `json:"hook"`
+	Event    string        `json:"event"`
+	User     string        `json:"user"`
+	Time     time.Time     `json:"time"`
+	Attempt  int           `json:"attempt"`
+	Status   int           `json:"status,omitempty"` // HTTP-Status; 0 bei Verbindungsfehlern
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	GaveUp   bool          `json:"gaveUp,omitempty"`
+}
+
+// OK meldet, ob der Versuch erfolgreich war (2xx).
+func (e LogEntry) OK() bool { return e.Error == "" && e.Status >= 200 && e.Status < 300 }
+
+type state struct {
+	Queue []Delivery `json:"queue"`
+	Log   []LogEntry `json:"log"` // neueste zuerst
+}
+
+// Dispatcher verwaltet Warteschlange und Protokoll unter <dataDir>/webhooks.json.
+type Dispatcher struct {
+	cfg    *config.Config
+	client *http.Client
+	wake   chan struct{}
+
+	mu sync.Mutex
+	st *state // lazy geladen
+}
+
+// New erzeugt einen Dispatcher; zugestellt wird erst mit Run bzw. DeliverDue.
+func New(cfg *config.Config) *Dispatcher {
+	return &Dispatcher{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}, wake: make(chan struct{}, 1)}
+}
+
+// Enabled meldet, ob Webhooks konfiguriert sind.
+func (d *Dispatcher) Enabled() bool { return len(d.cfg.Webhooks) > 0 }
+
+func (d *Dispatcher) path() string {
+	return filepath.Join(config.ResolveDataDir(d.cfg), "webhooks.json")
+}
+
+// load liefert den Zustand; d.mu muss gehalten werden.
+func (d *Dispatcher) load() *state {
+	if d.st != nil {
+		return d.st
+	}
+	d.st = &state{}
+	b, err := os.ReadFile(d.path())
+	if err == nil {
+		if err := json.Unmarshal(b, d.st); err != nil {
+			applog.Warnf("webhooks: %s unreadable, starting with an empty queue: %v", d.path(), err)
+			d.st = &state{}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		applog.Warnf("webhooks: reading %s failed: %v", d.path(), err)
+	}
+	return d.st
+}
+
+// save schreibt den Zustand; d.mu muss gehalten werden.
+func (d *Dispatcher) save() {
+	b, err := json.MarshalIndent(d.st, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(d.path()), 0700); err == nil {
+			tmp := d.path() + ".tmp"
+			if err = os.WriteFile(tmp, b, 0600); err == nil {
+				err = os.Rename(tmp, d.path())
+			}
+		}
+	}
+	if err != nil {
+		applog.Warnf("webhooks: saving queue failed: %v", err)
+	}
+}
+
+// Enqueue legt für jeden passenden Webhook eine Zustellung von payload an und liefert deren Anzahl.
+func (d *Dispatcher) Enqueue(event, user string, payload any) int {
+	var hooks []config.WebhookConfig
+	for _, h := range d.cfg.Webhooks {
+		if strings.TrimSpace(h.URL) != "" && h.Matches(event, user) {
+			hooks = append(hooks, h)
+		}
+	}
+	if len(hooks) == 0 {
+		return 0
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		applog.Warnf("webhooks: encoding %s payload failed: %v", event, err)
+		return 0
+	}
+	now := time.Now()
+	d.mu.Lock()
+	st := d.load()
+	for _, h := range hooks {
+		st.Queue = append(st.Queue, Delivery{ID: newID(), Hook: hookName(h), Event: event, User: user, Body: string(body), NextAttempt: now, Created: now})
+	}
+	d.save()
+	d.mu.Unlock()
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return len(hooks)
+}
+
+// Run stellt zu, bis ctx abgebrochen wird.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		d.DeliverDue(ctx, time.Now())
+		wait := time.Minute
+		if next, ok := d.nextAttempt(); ok {
+			wait = min(wait, max(time.Until(next), time.Second))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-d.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (d *Dispatcher) nextAttempt() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var next time.Time
+	for _, dl := range d.load().Queue {
+		if next.IsZero() || dl.NextAttempt.Before(next) {
+			next = dl.NextAttempt
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// DeliverDue versucht alle fälligen Zustellungen einmal und liefert die Anzahl erfolgreicher.
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) int {
+	d.mu.Lock()
+	var due []Delivery
+	for _, dl := range d.load().Queue {
+		if !dl.NextAttempt.After(now) {
+			due = append(due, dl)
+		}
+	}
+	d.mu.Unlock()
+	ok := 0
+	for _, dl := range due {
+		hook, found := d.hook(dl.Hook)
+		entry := LogEntry{Delivery: dl.ID, Hook: dl.Hook, Event: dl.Event, User: dl.User, Time: time.Now(), Attempt: dl.Attempts + 1}
+		if !found {
+			entry.Error = "webhook no longer configured"
+			entry.GaveUp = true
+		} else {
+			entry.Status, entry.Error = d.post(ctx, hook, dl)
+			entry.Duration = time.Since(entry.Time)
+		}
+		d.mu.Lock()
+		st := d.load()
+		for i := range st.Queue {
+			if st.Queue[i].ID != dl.ID {
+				continue
+			}
+			switch {
+			case entry.OK():
+				ok++
+				st.Queue = append(st.Queue[:i], st.Queue[i+1:]...)
+			case entry.GaveUp || dl.Attempts >= len(retryDelays):
+				entry.GaveUp = true
+				applog.Warnf("webhooks: giving up on %s delivery %s to %s: %s", dl.Event, dl.ID, dl.Hook, entry.Error)
+				st.Queue = append(st.Queue[:i], st.Queue[i+1:]...)
+			default:
+				st.Queue[i].Attempts++
+				st.Queue[i].LastError = entry.Error
+				st.Queue[i].NextAttempt = now.Add(retryDelays[dl.Attempts])
+			}
+			break
+		}
+		st.Log = append([]LogEntry{entry}, st.Log...)
+		if len(st.Log) > logMax {
+			st.Log = st.Log[:logMax]
+		}
+		d.save()
+		d.mu.Unlock()
+	}
+	return ok
+}
+
+func (d *Dispatcher) hook(name string) (config.WebhookConfig, bool) {
+	for _, h := range d.cfg.Webhooks {
+		if hookName(h) == name {
+			return h, true
+		}
+	}
+	return config.WebhookConfig{}, false
+}
+
+// post stellt eine Zustellung zu; liefert HTTP-Status und eine Fehlermeldung (leer bei 2xx).
+func (d *Dispatcher) post(ctx context.Context, hook config.WebhookConfig, dl Delivery) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(dl.Body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dstask-ui-webhook")
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, []byte(dl.Body)))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// *url.Error enthält die volle URL (samt Token im Pfad); nur die Ursache protokollieren
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			return 0, ue.Err.Error()
+		}
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, resp.Status
+	}
+	return resp.StatusCode, ""
+}
+
+// Sign liefert den Wert von X-Dstask-Signature für body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Pending liefert die ausstehenden Zustellungen (älteste zuerst).
+func (d *Dispatcher) Pending() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Delivery(nil), d.load().Queue...)
+}
+
+// Log liefert das Zustellprotokoll (neueste zuerst).
+func (d *Dispatcher) Log() []LogEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]LogEntry(nil), d.load().Log...)
+}
+
+// Retry stellt eine ausstehende Zustellung sofort erneut an.
+func (d *Dispatcher) Retry(id string) bool {
+	d.mu.Lock()
+	st := d.load()
+	found := false
+	for i := range st.Queue {
+		if st.Queue[i].ID == id {
+			st.Queue[i].NextAttempt = time.Now()
+			found = true
+		}
+	}
+	if found {
+		d.save()
+	}
+	d.mu.Unlock()
+	if found {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return found
+}
+
+// urlRe findet URLs in Texten (Webhook-Namen ohne name, ältere Fehlermeldungen).
+var urlRe = regexp.MustCompile(`(?i)https?://[^\s"'<>]+`)
+
+// MaskURLs kürzt alle URLs in text auf Schema und Host ("https://hooks.slack.com/…"): Pfad und Query von
+// Webhook-URLs enthalten bei Slack, Discord u. a. das Token.
+func MaskURLs(text string) string {
+	return urlRe.ReplaceAllStringFunc(text, func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return "…"
+		}
+		masked := u.Scheme + "://" + u.Host
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			masked += "/…"
+		}
+		return masked
+	})
+}
+
+func hookName(h config.WebhookConfig) string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.URL
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/config"
+)
+
+func TestDispatcher_SignsRetriesAndPersists(t *testing.T) {
+	var calls atomic.Int32
+	var lastSig, lastBody, lastEvent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		lastSig, lastBody, lastEvent = r.Header.Get(HeaderSignature), string(b), r.Header.Get(HeaderEvent)
+		if calls.Add(1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	cfg.Webhooks = []config.WebhookConfig{
+		{Name: "ci", URL: srv.URL, Secret: "s3cret", Events: []string{"task.done"}},
+		{Name: "bob-only", URL: srv.URL, Users: []string{"bob"}},
+	}
+	d := New(cfg)
+	if n := d.Enqueue("task.done", "alice", map[string]string{"action": "done"}); n != 1 {
+		t.Fatalf("enqueued %d deliveries, want 1", n)
+	}
+	if d.Enqueue("task.created", "alice", map[string]string{}) != 0 {
+		t.Fatalf("event filter ignored")
+	}
+
+	now := time.Now()
+	if ok := d.DeliverDue(context.Background(), now); ok != 0 {
+		t.Fatalf("first attempt should fail")
+	}
+	pending := d.Pending()
+	if len(pending) != 1 || pending[0].Attempts != 1 || !pending[0].NextAttempt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected queue after failure: %+v", pending)
+	}
+
+	// Warteschlange überlebt einen Neustart
+	d = New(cfg)
+	if ok := d.DeliverDue(context.Background(), now.Add(30*time.Second)); ok != 0 || calls.Load() != 1 {
+		t.Fatalf("retried before the backoff elapsed")
+	}
+	if ok := d.DeliverDue(context.Background(), now.Add(time.Minute)); ok != 1 {
+		t.Fatalf("retry did not succeed")
+	}
+	if lastBody != `{"action":"done"}` || lastEvent != "task.done" || lastSig != Sign("s3cret", []byte(lastBody)) {
+		t.Fatalf("unexpected request: %q %q %q", lastBody, lastEvent, lastSig)
+	}
+	log := d.Log()
+	if len(d.Pending()) != 0 || len(log) != 2 || !log[0].OK() || log[1].Status != http.StatusServiceUnavailable || log[1].OK() {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+}
+
+func TestMaskURLs(t *testing.T) {
+	cases := map[string]string{
+		"https://hooks.slack.com/services/T0/B0/secret":    "https://hooks.slack.com/…",
+		"https://discord.com/api/webhooks/1/tok?wait=true": "https://discord.com/…",
+		"bot":                "bot",
+		"http://example.org": "http://example.org",
+		`Post "https://x.example/hook/abc": dial tcp: connection refused`: `Post "https://x.example/…": dial tcp: connection refused`,
+	}
+	for in, want := range cases {
+		if got := MaskURLs(in); got != want {
+			t.Fatalf("MaskURLs(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package webpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// vapidFile ist das Format von <dataDir>/vapid.json.
+type vapidFile struct {
+	PublicKey  string    `json:"publicKey"`
+	PrivateKey string    `json:"privateKey"`
+	Created    time.Time `json:"created"`
+}
+
+// LoadOrCreateVAPID liest das Schlüsselpaar aus path oder erzeugt und speichert ein neues (0600).
+// Ein neues Schlüsselpaar macht alle bestehenden Abonnements ungültig; die Datei gehört daher ins Backup.
+func LoadOrCreateVAPID(path string) (*VAPID, error) {
+	var f vapidFile
+	err := readJSON(path, &f)
+	if err != nil {
+		return nil, err
+	}
+	if f.PrivateKey != "" {
+		v, err := ParseVAPID(f.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return v, nil
+	}
+	v, err := GenerateVAPID()
+	if err != nil {
+		return nil, err
+	}
+	f = vapidFile{PublicKey: v.PublicKey(), PrivateKey: v.PrivateKey(), Created: time.Now().UTC()}
+	if err := writeJSON(path, f); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Store verwaltet die Push-Abonnements aller Nutzer in einer JSON-Datei (Nutzer -> Abonnements).
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewStore liefert einen Store für path, z. B. <dataDir>/push-subscriptions.json.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (st *Store) load() (map[string][]Subscription, error) {
+	m := map[string][]Subscription{}
+	return m, readJSON(st.path, &m)
+}
+
+// List liefert die Abonnements eines Nutzers.
+func (st *Store) List(user string) ([]Subscription, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m, err := st.load()
+	return m[user], err
+}
+
+// Users liefert die Nutzer mit mindestens einem Abonnement (sortiert).
+func (st *Store) Users() ([]string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m, err := st.load()
+	users := make([]string, 0, len(m))
+	for u, subs := range m {
+		if len(subs) > 0 {
+			users = append(users, u)
+		}
+	}
+	sort.Strings(users)
+	return users, err
+}
+
+// Add prüft und speichert ein Abonnement; ein bestehendes mit gleichem Endpoint wird ersetzt.
+func (st *Store) Add(user string, sub Subscription) error {
+	if err := sub.Validate(); err != nil {
+		return err
+	}
+	if sub.Created.IsZero() {
+		sub.Created = time.Now().UTC()
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m, err := st.load()
+	if err != nil {
+		return err
+	}
+	subs := m[user][:0:0]
+	for _, s := range m[user] {
+		if s.Endpoint != sub.Endpoint {
+			subs = append(subs, s)
+		}
+	}
+	m[user] = append(subs, sub)
+	return writeJSON(st.path, m)
+}
+
+// Remove löscht das Abonnement mit endpoint und meldet, ob es existierte.
+func (st *Store) Remove(user, endpoint string) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	m, err := st.load()
+	if err != nil {
+		return false, err
+	}
+	var kept []Subscription
+	for _, s := range m[user] {
+		if s.Endpoint != endpoint {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == len(m[user]) {
+		return false, nil
+	}
+	if len(kept) == 0 {
+		delete(m, user)
+	} else {
+		m[user] = kept
+	}
+	return true, writeJSON(st.path, m)
+}
+
+func readJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
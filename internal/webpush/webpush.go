@@ -0,0 +1,310 @@
+// Package webpush implementiert das Web-Push-Protokoll: Verschlüsselung der Nachrichten nach RFC 8291
+// (aes128gcm), Absender-Authentifizierung per VAPID (RFC 8292) sowie die VAPID-Schlüssel und die
+// Push-Abonnements der Nutzer im Datenverzeichnis.
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// recordSize ist die Record-Größe im aes128gcm-Header; Nachrichten bestehen immer aus einem Record.
+const recordSize = 4096
+
+// MaxPayload ist die größte Nutzlast, die in einen Record passt (Header, Tag und Trennbyte abgezogen).
+const MaxPayload = recordSize - 86 - 16 - 1
+
+// ErrGone: der Push-Dienst kennt das Abonnement nicht mehr (404/410); es sollte gelöscht werden.
+var ErrGone = errors.New("push subscription expired")
+
+var b64 = base64.RawURLEncoding
+
+// Subscription entspricht PushSubscription.toJSON() im Browser.
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"` // öffentlicher Schlüssel des Browsers (unkomprimierter P-256-Punkt)
+		Auth   string `json:"auth"`   // 16 Byte Auth-Secret
+	} `json:"keys"`
+	Created   time.Time `json:"created,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+}
+
+// Validate prüft Endpoint und Schlüssel. Endpoints müssen HTTPS sein, auch für Loopback-Adressen
+// (lokale Test-Dienste laufen mit TLS, siehe webpushtest).
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid endpoint")
+	}
+	if u.Scheme != "https" {
+		return errors.New("endpoint must use https")
+	}
+	if _, err := s.uaKey(); err != nil {
+		return err
+	}
+	if a, err := b64.DecodeString(strings.TrimRight(s.Keys.Auth, "=")); err != nil || len(a) != 16 {
+		return errors.New("invalid auth secret")
+	}
+	return nil
+}
+
+func (s Subscription) uaKey() (*ecdh.PublicKey, error) {
+	raw, err := b64.DecodeString(strings.TrimRight(s.Keys.P256dh, "="))
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	k, err := ecdh.P256().NewPublicKey(raw)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	return k, nil
+}
+
+// Encrypt verschlüsselt payload für das Abonnement (RFC 8291, ein aes128gcm-Record).
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, fmt.Errorf("payload too large (%d bytes)", len(payload))
+	}
+	uaPub, err := sub.uaKey()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := b64.DecodeString(strings.TrimRight(sub.Keys.Auth, "="))
+	if err != nil {
+		return nil, errors.New("invalid auth secret")
+	}
+	asPriv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	secret, err := asPriv.ECDH(uaPub)
+	if err != nil {
+		return nil, err
+	}
+	asPub := asPriv.PublicKey().Bytes()
+	cek, nonce, err := deriveKeys(secret, auth, salt, uaPub.Bytes(), asPub)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, 21+len(asPub))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPub)))
+	header = append(header, asPub...)
+	plain := append(append([]byte{}, payload...), 0x02) // Trennbyte des letzten Records
+	return gcm.Seal(header, nonce, plain, nil), nil
+}
+
+// Decrypt ist die Gegenrichtung von Encrypt aus Sicht des Browsers (für Tests und Push-Dienst-Stand-ins).
+func Decrypt(uaPriv *ecdh.PrivateKey, auth, body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("body too short")
+	}
+	salt, idlen := body[:16], int(body[20])
+	if len(body) < 21+idlen {
+		return nil, errors.New("body too short")
+	}
+	asPub, err := ecdh.P256().NewPublicKey(body[21 : 21+idlen])
+	if err != nil {
+		return nil, err
+	}
+	secret, err := uaPriv.ECDH(asPub)
+	if err != nil {
+		return nil, err
+	}
+	cek, nonce, err := deriveKeys(secret, auth, salt, uaPriv.PublicKey().Bytes(), asPub.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, body[21+idlen:], nil)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(plain); n == 0 || plain[n-1] != 0x02 {
+		return nil, errors.New("missing record delimiter")
+	}
+	return plain[:len(plain)-1], nil
+}
+
+// deriveKeys leitet Content-Encryption-Key und Nonce nach RFC 8291 Abschnitt 3.3/3.4 ab.
+func deriveKeys(secret, auth, salt, uaPub, asPub []byte) (cek, nonce []byte, err error) {
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPub...), asPub...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, auth, keyInfo), ikm); err != nil {
+		return nil, nil, err
+	}
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek = make([]byte, 16)
+	nonce = make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// VAPID ist das Schlüsselpaar, mit dem sich der Server bei den Push-Diensten ausweist.
+type VAPID struct {
+	key *ecdsa.PrivateKey
+}
+
+// GenerateVAPID erzeugt ein neues P-256-Schlüsselpaar.
+func GenerateVAPID() (*VAPID, error) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPID{key: k}, nil
+}
+
+// ParseVAPID liest einen privaten Schlüssel (32 Byte, base64url) ein.
+func ParseVAPID(private string) (*VAPID, error) {
+	d, err := b64.DecodeString(private)
+	if err != nil || len(d) != 32 {
+		return nil, errors.New("invalid VAPID private key")
+	}
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, err
+	}
+	pub := priv.PublicKey().Bytes() // 0x04 || X || Y
+	k := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	k.Curve = elliptic.P256()
+	k.X, k.Y = new(big.Int).SetBytes(pub[1:33]), new(big.Int).SetBytes(pub[33:])
+	return &VAPID{key: k}, nil
+}
+
+// PrivateKey liefert den privaten Schlüssel (base64url) zum Speichern.
+func (v *VAPID) PrivateKey() string {
+	return b64.EncodeToString(v.key.D.FillBytes(make([]byte, 32)))
+}
+
+// PublicKey liefert den öffentlichen Schlüssel als base64url-codierten unkomprimierten Punkt
+// (applicationServerKey im Browser).
+func (v *VAPID) PublicKey() string {
+	pub := make([]byte, 65)
+	pub[0] = 4
+	v.key.X.FillBytes(pub[1:33])
+	v.key.Y.FillBytes(pub[33:])
+	return b64.EncodeToString(pub)
+}
+
+// Authorization liefert den Header "vapid t=<JWT>, k=<Schlüssel>" für den Push-Dienst von endpoint.
+func (v *VAPID) Authorization(endpoint, subject string, exp time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{"aud": u.Scheme + "://" + u.Host, "exp": exp.Unix(), "sub": subject})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + b64.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, v.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return "vapid t=" + unsigned + "." + b64.EncodeToString(sig) + ", k=" + v.PublicKey(), nil
+}
+
+// Options steuern eine Zustellung.
+type Options struct {
+	Subject string        // mailto:- oder https:-Kontakt für den Push-Dienst
+	TTL     time.Duration // Aufbewahrung beim Push-Dienst, wenn der Browser offline ist
+	Urgency string        // very-low | low | normal | high (leer = normal)
+	Topic   string        // ersetzt eine noch nicht zugestellte Nachricht mit gleichem Topic
+}
+
+// Send verschlüsselt payload und stellt es beim Push-Dienst des Abonnements ein. Bei 404/410 wird
+// ErrGone geliefert.
+func Send(ctx context.Context, client *http.Client, v *VAPID, sub Subscription, payload []byte, opts Options) error {
+	if err := sub.Validate(); err != nil {
+		return err
+	}
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	auth, err := v.Authorization(sub.Endpoint, opts.Subject, time.Now().Add(12*time.Hour))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(opts.TTL/time.Second)))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("push service: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
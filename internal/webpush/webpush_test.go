@@ -0,0 +1,94 @@
+package webpush_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/webpush"
+	"github.com/elpatron68/dstask-ui/internal/webpush/webpushtest"
+)
+
+func TestSend_LocalPushService(t *testing.T) {
+	svc := webpushtest.NewService(t)
+	sub := svc.Subscribe(t)
+	v, err := webpush.LoadOrCreateVAPID(filepath.Join(t.TempDir(), "vapid.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := webpush.Options{Subject: "mailto:admin@example.org", TTL: time.Hour, Urgency: "high", Topic: "due"}
+	if err := webpush.Send(context.Background(), svc.Client(), v, sub, []byte(`{"title":"Task due"}`), opts); err != nil {
+		t.Fatal(err)
+	}
+	msgs := svc.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages", len(msgs))
+	}
+	m := msgs[0]
+	if string(m.Payload) != `{"title":"Task due"}` || m.TTL != 3600 || m.Urgency != "high" || m.Topic != "due" ||
+		m.VAPIDKey != v.PublicKey() || m.Subject != "mailto:admin@example.org" {
+		t.Fatalf("unexpected message: %+v", m)
+	}
+
+	svc.Expire(sub.Endpoint)
+	if err := webpush.Send(context.Background(), svc.Client(), v, sub, []byte("x"), opts); !errors.Is(err, webpush.ErrGone) {
+		t.Fatalf("expected ErrGone, got %v", err)
+	}
+	if err := webpush.Send(context.Background(), svc.Client(), v, sub, make([]byte, webpush.MaxPayload+1), opts); err == nil {
+		t.Fatal("expected error for oversized payload")
+	}
+}
+
+func TestLoadOrCreateVAPID_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "vapid.json")
+	a, err := webpush.LoadOrCreateVAPID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := webpush.LoadOrCreateVAPID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.PublicKey() != b.PublicKey() || len(a.PublicKey()) != 87 {
+		t.Fatalf("keys differ or malformed: %s / %s", a.PublicKey(), b.PublicKey())
+	}
+}
+
+func TestStore_AddRemoveValidate(t *testing.T) {
+	svc := webpushtest.NewService(t)
+	st := webpush.NewStore(filepath.Join(t.TempDir(), "push-subscriptions.json"))
+	sub := svc.Subscribe(t)
+	if err := st.Add("alice", sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Add("alice", sub); err != nil { // gleiches Gerät erneut: ersetzt
+		t.Fatal(err)
+	}
+	if subs, _ := st.List("alice"); len(subs) != 1 {
+		t.Fatalf("expected 1 subscription, got %d", len(subs))
+	}
+	if users, _ := st.Users(); strings.Join(users, ",") != "alice" {
+		t.Fatalf("unexpected users %v", users)
+	}
+	bad := sub
+	for _, ep := range []string{"http://push.example.org/x", "http://127.0.0.1:8080/x", "http://localhost/x", "http://[::1]/x"} {
+		bad.Endpoint = ep
+		if err := st.Add("alice", bad); err == nil {
+			t.Fatalf("expected error for non-https endpoint %s", ep)
+		}
+	}
+	bad = sub
+	bad.Keys.Auth = "short"
+	if err := st.Add("alice", bad); err == nil {
+		t.Fatal("expected error for invalid auth secret")
+	}
+	if ok, err := st.Remove("alice", sub.Endpoint); !ok || err != nil {
+		t.Fatalf("remove: %v %v", ok, err)
+	}
+	if users, _ := st.Users(); len(users) != 0 {
+		t.Fatalf("expected no users, got %v", users)
+	}
+}
@@ -0,0 +1,228 @@
+// Package webpushtest enthält einen lokalen Stand-in für Push-Dienste (wie FCM oder Mozilla autopush)
+// samt simuliertem Browser: Abonnements mit echten Schlüsseln, Prüfung des VAPID-Tokens und
+// Entschlüsselung der Nachrichten.
+package webpushtest
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elpatron68/dstask-ui/internal/webpush"
+)
+
+var b64 = base64.RawURLEncoding
+
+// Message ist eine vom Stand-in angenommene und entschlüsselte Push-Nachricht.
+type Message struct {
+	Endpoint string
+	Payload  []byte
+	TTL      int
+	Urgency  string
+	Topic    string
+	VAPIDKey string // k= aus dem Authorization-Header
+	Subject  string // sub-Claim des VAPID-Tokens
+}
+
+// Service ist der Push-Dienst-Stand-in auf 127.0.0.1 (HTTPS mit Test-Zertifikat).
+type Service struct {
+	URL string
+
+	srv      *httptest.Server
+	mu       sync.Mutex
+	browsers map[string]*browser // Pfad -> Schlüssel des Browsers
+	gone     map[string]bool
+	msgs     []Message
+	notify   chan Message
+	next     int
+}
+
+type browser struct {
+	priv *ecdh.PrivateKey
+	auth []byte
+}
+
+// NewService startet den Stand-in; er wird am Testende beendet.
+func NewService(t testing.TB) *Service {
+	t.Helper()
+	s := &Service{browsers: map[string]*browser{}, gone: map[string]bool{}, notify: make(chan Message, 64)}
+	s.srv = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	s.URL = s.srv.URL
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// Client liefert einen HTTP-Client, der dem Test-Zertifikat des Stand-ins vertraut.
+func (s *Service) Client() *http.Client { return s.srv.Client() }
+
+// TLSConfig liefert eine TLS-Konfiguration, die dem Test-Zertifikat des Stand-ins vertraut (für eigene Clients).
+func (s *Service) TLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.srv.Certificate())
+	return &tls.Config{RootCAs: pool}
+}
+
+// Subscribe erzeugt ein Abonnement wie PushManager.subscribe() im Browser.
+func (s *Service) Subscribe(t testing.TB) webpush.Subscription {
+	t.Helper()
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.next++
+	path := "/push/" + strconv.Itoa(s.next)
+	s.browsers[path] = &browser{priv: priv, auth: auth}
+	s.mu.Unlock()
+	var sub webpush.Subscription
+	sub.Endpoint = s.URL + path
+	sub.Keys.P256dh = b64.EncodeToString(priv.PublicKey().Bytes())
+	sub.Keys.Auth = b64.EncodeToString(auth)
+	return sub
+}
+
+// Expire lässt den Dienst Nachrichten an endpoint mit 410 Gone ablehnen.
+func (s *Service) Expire(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gone[strings.TrimPrefix(endpoint, s.URL)] = true
+}
+
+// Messages liefert die bisher angenommenen Nachrichten.
+func (s *Service) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.msgs...)
+}
+
+// Wait wartet auf die nächste Nachricht (für asynchrone Zustellungen).
+func (s *Service) Wait(t testing.TB, timeout time.Duration) Message {
+	t.Helper()
+	select {
+	case m := <-s.notify:
+		return m
+	case <-time.After(timeout):
+		t.Fatal("no push message received")
+	}
+	return Message{}
+}
+
+func (s *Service) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	b, ok := s.browsers[r.URL.Path]
+	gone := s.gone[r.URL.Path]
+	s.mu.Unlock()
+	if !ok || gone {
+		http.Error(w, "subscription expired", http.StatusGone)
+		return
+	}
+	if r.Method != http.MethodPost || r.Header.Get("Content-Encoding") != "aes128gcm" {
+		http.Error(w, "expected POST with Content-Encoding aes128gcm", http.StatusBadRequest)
+		return
+	}
+	key, subject, err := verifyVAPID(r.Header.Get("Authorization"), s.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get("TTL"))
+	if err != nil {
+		http.Error(w, "missing TTL", http.StatusBadRequest)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	payload, err := webpush.Decrypt(b.priv, b.auth, body)
+	if err != nil {
+		http.Error(w, "decrypt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	m := Message{
+		Endpoint: s.URL + r.URL.Path, Payload: payload, TTL: ttl,
+		Urgency: r.Header.Get("Urgency"), Topic: r.Header.Get("Topic"), VAPIDKey: key, Subject: subject,
+	}
+	s.mu.Lock()
+	s.msgs = append(s.msgs, m)
+	s.mu.Unlock()
+	select {
+	case s.notify <- m:
+	default:
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// verifyVAPID prüft "vapid t=<JWT>, k=<Schlüssel>": ES256-Signatur, aud und exp.
+func verifyVAPID(header, origin string) (key, subject string, err error) {
+	rest, ok := strings.CutPrefix(header, "vapid ")
+	if !ok {
+		return "", "", errors.New("missing vapid authorization")
+	}
+	var token string
+	for _, part := range strings.Split(rest, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			token = v
+		case "k":
+			key = v
+		}
+	}
+	raw, err := b64.DecodeString(key)
+	if err != nil || len(raw) != 65 {
+		return "", "", errors.New("invalid k")
+	}
+	if _, err := ecdh.P256().NewPublicKey(raw); err != nil {
+		return "", "", errors.New("invalid k")
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(raw[1:33]), Y: new(big.Int).SetBytes(raw[33:])}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", "", errors.New("invalid token")
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return "", "", errors.New("invalid signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return "", "", errors.New("signature mismatch")
+	}
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return "", "", errors.New("invalid claims")
+	}
+	switch {
+	case claims.Aud != origin:
+		return "", "", fmt.Errorf("aud %q, want %q", claims.Aud, origin)
+	case time.Unix(claims.Exp, 0).Before(time.Now()):
+		return "", "", errors.New("token expired")
+	case claims.Exp > time.Now().Add(24*time.Hour).Unix():
+		return "", "", errors.New("exp more than 24h ahead")
+	}
+	return key, claims.Sub, nil
+}